@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api/ocplifecycle"
+	"github.com/openshift/ci-tools/pkg/branchcuts"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/promotion"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+type options struct {
+	promotion.FutureOptions
+
+	releaseRepo         string
+	bumpRelease         string
+	lifecycleConfigFile string
+	excludedReposFile   string
+	overrideTimeRaw     string
+	overrideTime        *time.Time
+	skipBranchCheck     bool
+}
+
+func (o *options) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.releaseRepo, "release-repo", "", "Path to a checkout of the release repository.")
+	fs.StringVar(&o.bumpRelease, "bump-release", "", "Plan bumping the dev config to this release.")
+	fs.StringVar(&o.lifecycleConfigFile, "lifecycle-config", "", "Path to the lifecycle config file. If unset, the changes of the config managers are not planned.")
+	fs.StringVar(&o.excludedReposFile, "excluded-repos-config", "", "Path to the GA's excluded repos config file.")
+	fs.StringVar(&o.overrideTimeRaw, "override-time", "", "Act as if this was the current time, must be in RFC3339 format")
+	fs.BoolVar(&o.skipBranchCheck, "skip-branch-check", false, "Do not check on GitHub whether the future branches exist.")
+	o.FutureOptions.Bind(fs)
+}
+
+func (o *options) validate() error {
+	if o.releaseRepo == "" {
+		return errors.New("--release-repo is required")
+	}
+	if o.Confirm {
+		return errors.New("--confirm is not supported, the plan never changes the release repository")
+	}
+	if o.ConfigDir == "" {
+		o.ConfigDir = filepath.Join(o.releaseRepo, config.CiopConfigInRepoPath)
+	}
+	if o.overrideTimeRaw != "" {
+		parsed, err := time.Parse(time.RFC3339, o.overrideTimeRaw)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q as RFC3339 time: %w", o.overrideTimeRaw, err)
+		}
+		o.overrideTime = &parsed
+	}
+	if err := o.FutureOptions.Validate(); err != nil {
+		return err
+	}
+	if futureReleases := sets.NewString(o.FutureReleases.Strings()...); o.bumpRelease != "" && !futureReleases.Has(o.bumpRelease) {
+		return fmt.Errorf("future releases %v do not contain bump release %v", futureReleases.List(), o.bumpRelease)
+	}
+	return nil
+}
+
+func gatherOptions() options {
+	o := options{}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	o.bind(fs)
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatal("could not parse input")
+	}
+	return o
+}
+
+func (o *options) planner() (*branchcuts.Planner, error) {
+	planner := &branchcuts.Planner{
+		ReleaseRepo: o.releaseRepo,
+		Options:     o.FutureOptions,
+		BumpRelease: o.bumpRelease,
+		Now:         time.Now(),
+	}
+	if o.overrideTime != nil {
+		planner.Now = *o.overrideTime
+	}
+	if !o.skipBranchCheck {
+		planner.BranchExists = branchExists
+	}
+
+	registryPath := filepath.Join(o.releaseRepo, config.RegistryPath)
+	if _, err := os.Stat(registryPath); err == nil {
+		refs, chains, workflows, _, _, observers, _, err := load.Registry(registryPath, load.RegistryFlag(0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load registry: %w", err)
+		}
+		planner.Resolver = registry.NewResolver(refs, chains, workflows, observers)
+	}
+
+	if o.lifecycleConfigFile != "" {
+		var err error
+		if planner.Lifecycle, err = ocplifecycle.LoadConfig(o.lifecycleConfigFile); err != nil {
+			return nil, fmt.Errorf("failed to load the lifecycle configuration: %w", err)
+		}
+	}
+	if o.excludedReposFile != "" {
+		if err := planner.ExcludedRepos.LoadExcludedReposConfig(o.excludedReposFile); err != nil {
+			return nil, fmt.Errorf("failed to load the excluded repos configuration: %w", err)
+		}
+	}
+	return planner, nil
+}
+
+// branchExists asks GitHub whether the branch exists, the same way the
+// repo-brancher does
+func branchExists(org, repo, branch string) (bool, error) {
+	remote := fmt.Sprintf("https://github.com/%s/%s", org, repo)
+	cmd := exec.Command("git", "ls-remote", "--exit-code", "--heads", remote, fmt.Sprintf("refs/heads/%s", branch))
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 2:
+		// --exit-code makes ls-remote exit with 2 when no ref matches
+		return false, nil
+	default:
+		return false, fmt.Errorf("'%s' failed with error=%w, output:\n%s", cmd.Args, err, out)
+	}
+}
+
+// This tool plans a branch cut: it determines what the config-brancher, the
+// repo-brancher and the tide, bugzilla and fast-forwarding config managers
+// would change in a checkout of the release repository and prints a report
+// of the changes by repository, without changing anything.
+func main() {
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		logrus.Fatalf("Invalid options: %v", err)
+	}
+	planner, err := o.planner()
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not set up the plan.")
+	}
+	plan, err := planner.Plan()
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not plan the branch cut.")
+	}
+	raw, err := yaml.Marshal(plan)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not serialize the plan.")
+	}
+	fmt.Print(string(raw))
+	for _, repo := range plan.Repos {
+		for _, warning := range repo.Warnings {
+			logrus.WithFields(logrus.Fields{"org": repo.Org, "repo": repo.Repo}).Warn(warning)
+		}
+	}
+}
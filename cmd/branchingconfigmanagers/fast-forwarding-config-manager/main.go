@@ -6,25 +6,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"sort"
-	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
-	"k8s.io/apimachinery/pkg/util/sets"
 	prowconfig "k8s.io/test-infra/prow/config"
 	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/ci-tools/pkg/api/ocplifecycle"
-)
-
-const (
-	jobName           = "periodic-openshift-release-fast-forward"
-	ocpProductName    = "ocp"
-	currentReleaseArg = "--current-release"
-	futureReleaseArg  = "--future-release"
+	"github.com/openshift/ci-tools/pkg/branchcuts/fastforwarding"
 )
 
 var fs = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -73,7 +64,7 @@ func main() {
 		logrus.WithError(err).Fatal("failed to read job configuration")
 	}
 
-	periodic, err := getPeriodicJob(jobName, c.Periodics)
+	periodic, err := fastforwarding.GetPeriodicJob(fastforwarding.JobName, c.Periodics)
 	if err != nil {
 		logrus.WithError(err).Fatal("")
 	}
@@ -88,10 +79,10 @@ func main() {
 		now = *o.overwriteTime
 	}
 
-	if err := reconcile(lifecycleConfig, now, periodic); err != nil {
+	if err := fastforwarding.Reconcile(lifecycleConfig, now, periodic); err != nil {
 		logrus.WithError(err).Fatal("failed to reconcile job from the lifecycle configuration")
 	}
-	updatePeriodicJob(&c, periodic)
+	fastforwarding.UpdatePeriodicJob(&c, periodic)
 
 	periodicsRaw, err := yaml.Marshal(c)
 	if err != nil {
@@ -102,62 +93,3 @@ func main() {
 		logrus.WithError(err).Fatal("failed to write main plugin config")
 	}
 }
-
-func reconcile(lifecycleConfig ocplifecycle.Config, now time.Time, job *prowconfig.Periodic) error {
-	timelineOpts := ocplifecycle.TimelineOptions{
-		OnlyEvents: sets.NewString([]string{
-			string(ocplifecycle.LifecycleEventOpen),
-			string(ocplifecycle.LifecycleEventFeatureFreeze),
-		}...),
-	}
-
-	timeline := lifecycleConfig.GetTimeline(ocpProductName, timelineOpts)
-	before, after := timeline.DeterminePlaceInTime(now)
-
-	if after.LifecyclePhase.Event == ocplifecycle.LifecycleEventOpen {
-		parsedVersion, err := ocplifecycle.ParseMajorMinor(after.ProductVersion)
-		if err != nil {
-			return fmt.Errorf("failed to parse %s as majorMinor version: %w", after.ProductVersion, err)
-		}
-
-		job.Spec.Containers[0].Args = append(job.Spec.Containers[0].Args, fmt.Sprintf("%s=%s", futureReleaseArg, parsedVersion))
-		sort.Strings(job.Spec.Containers[0].Args)
-	} else if before.LifecyclePhase.Event == ocplifecycle.LifecycleEventOpen || before.LifecyclePhase.Event == ocplifecycle.LifecycleEventFeatureFreeze {
-		parsedVersion, err := ocplifecycle.ParseMajorMinor(before.ProductVersion)
-		if err != nil {
-			return fmt.Errorf("failed to parse %s as majorMinor version: %w", before.ProductVersion, err)
-		}
-
-		var args []string
-		for _, arg := range job.Spec.Containers[0].Args {
-			if !strings.Contains(arg, currentReleaseArg) && !strings.Contains(arg, futureReleaseArg) {
-				args = append(args, arg)
-			}
-		}
-
-		args = append(args, fmt.Sprintf("%s=%s", currentReleaseArg, parsedVersion))
-		args = append(args, fmt.Sprintf("%s=%s", futureReleaseArg, parsedVersion))
-		sort.Strings(job.Spec.Containers[0].Args)
-		job.Spec.Containers[0].Args = args
-	}
-
-	return nil
-}
-
-func getPeriodicJob(jobName string, periodics []prowconfig.Periodic) (*prowconfig.Periodic, error) {
-	for _, job := range periodics {
-		if job.Name == jobName {
-			return &job, nil
-		}
-	}
-
-	return nil, fmt.Errorf("failed to find the job: %s", jobName)
-}
-
-func updatePeriodicJob(config *prowconfig.JobConfig, job *prowconfig.Periodic) {
-	for i, periodic := range config.Periodics {
-		if periodic.Name == job.Name {
-			config.Periodics[i] = *job
-		}
-	}
-}
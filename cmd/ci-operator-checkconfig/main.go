@@ -10,10 +10,14 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	prowconfig "k8s.io/test-infra/prow/config"
+
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/config"
 	"github.com/openshift/ci-tools/pkg/defaults"
+	"github.com/openshift/ci-tools/pkg/jobconfig"
 	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/prowgen"
 	"github.com/openshift/ci-tools/pkg/registry"
 	"github.com/openshift/ci-tools/pkg/steps/release"
 	"github.com/openshift/ci-tools/pkg/util"
@@ -78,12 +82,17 @@ func (o *options) validate() (ret []error) {
 	}
 	outputCh := make(chan promotedTag)
 	errCh := make(chan error)
+	jobNames := validation.NewJobNames()
 	map_ := func() error {
 		validator := validation.NewValidator()
+		prowgenConfigs := map[string]*config.Prowgen{}
 		for item := range inputCh {
 			if err := o.validateConfiguration(&validator, outputCh, item.configuration, item.repoInfo); err != nil {
 				errCh <- fmt.Errorf("failed to validate configuration %s: %w", item.repoInfo.Filename, err)
 			}
+			for _, err := range o.validateJobNames(jobNames, prowgenConfigs, item.configuration, item.repoInfo) {
+				errCh <- fmt.Errorf("failed to validate jobs for configuration %s: %w", item.repoInfo.Filename, err)
+			}
 		}
 		return nil
 	}
@@ -98,14 +107,15 @@ func (o *options) validate() (ret []error) {
 	if err := util.ProduceMapReduce(0, produce, map_, reduce, done, errCh); err != nil {
 		ret = append(ret, err)
 	}
-	return append(ret, validateTags(seen)...)
+	ret = append(ret, validateTags(seen)...)
+	return append(ret, jobNames.Validate()...)
 }
 
 func (o *options) loadResolver(path string) error {
 	if path == "" {
 		return nil
 	}
-	refs, chains, workflows, _, _, observers, err := load.Registry(path, load.RegistryFlag(0))
+	refs, chains, workflows, _, _, observers, _, err := load.Registry(path, load.RegistryFlag(0))
 	if err != nil {
 		return err
 	}
@@ -139,6 +149,20 @@ func (o *options) validateConfiguration(
 	return nil
 }
 
+// validateJobNames generates the jobs for a configuration and records them to
+// find the names and contexts that collide with those of other configurations
+func (o *options) validateJobNames(jobNames *validation.JobNames, prowgenConfigs map[string]*config.Prowgen, configuration *api.ReleaseBuildConfiguration, repoInfo *config.Info) []error {
+	generated := map[string]*prowconfig.JobConfig{}
+	if err := prowgen.GenerateJobsInto(o.resolver, prowgenConfigs, generated)(configuration, repoInfo); err != nil {
+		return []error{err}
+	}
+	jobs := &prowconfig.JobConfig{}
+	for _, part := range generated {
+		jobconfig.Append(jobs, part)
+	}
+	return jobNames.Add(repoInfo.Metadata, jobs)
+}
+
 func validateTags(seen tagSet) []error {
 	var dupes []error
 	for tag, infos := range seen {
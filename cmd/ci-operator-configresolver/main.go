@@ -41,6 +41,7 @@ type options struct {
 	gracePeriod            time.Duration
 	validateOnly           bool
 	flatRegistry           bool
+	candidateRegistryRoot  string
 	instrumentationOptions flagutil.InstrumentationOptions
 }
 
@@ -63,6 +64,7 @@ func gatherOptions() (options, error) {
 	_ = fs.Duration("cycle", time.Minute*2, "Legacy flag kept for compatibility. Does nothing")
 	fs.BoolVar(&o.validateOnly, "validate-only", false, "Load the config and registry, validate them and exit.")
 	fs.BoolVar(&o.flatRegistry, "flat-registry", false, "Disable directory structure based registry validation")
+	fs.StringVar(&o.candidateRegistryRoot, "candidate-registry-root", "", "Directory containing candidate registries that resolved configurations can be compared with. Comparing with candidates is disabled if unset.")
 	o.instrumentationOptions.AddFlags(fs)
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return o, fmt.Errorf("failed to parse flags: %w", err)
@@ -201,11 +203,14 @@ func main() {
 		l("resolve"),
 		l("configGeneration"),
 		l("registryGeneration"),
+		l("resolvedDiff"),
+		l("snapshot"),
 	))
 
 	uisimplifier := simplifypath.NewSimplifier(l("", // shadow element mimicing the root
 		l(""),
 		l("search"),
+		l("registry-search"),
 		l("job"),
 		l("reference"),
 		l("chain"),
@@ -220,6 +225,8 @@ func main() {
 	http.HandleFunc("/resolve", handler(registryserver.ResolveLiteralConfig(registryAgent, configresolverMetrics)).ServeHTTP)
 	http.HandleFunc("/configGeneration", handler(getConfigGeneration(configAgent)).ServeHTTP)
 	http.HandleFunc("/registryGeneration", handler(getRegistryGeneration(registryAgent)).ServeHTTP)
+	http.HandleFunc("/resolvedDiff", handler(registryserver.ResolveConfigDiff(configAgent, registryAgent, o.candidateRegistryRoot, configresolverMetrics)).ServeHTTP)
+	http.HandleFunc("/snapshot", handler(registryserver.ServeSnapshot(configAgent, registryAgent, configresolverMetrics)).ServeHTTP)
 	http.HandleFunc("/readyz", func(_ http.ResponseWriter, _ *http.Request) {})
 	interrupts.ListenAndServe(&http.Server{Addr: ":" + strconv.Itoa(o.port)}, o.gracePeriod)
 	uiMux := http.NewServeMux()
@@ -14,7 +14,6 @@ import (
 
 	prowconfig "k8s.io/test-infra/prow/config"
 
-	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/config"
 	jc "github.com/openshift/ci-tools/pkg/jobconfig"
 	"github.com/openshift/ci-tools/pkg/load"
@@ -35,6 +34,11 @@ type options struct {
 	registryPath string
 	resolver     registry.Resolver
 
+	scheduler    string
+	schedulerDir string
+	namespace    string
+	generator    prowgen.JobGenerator
+
 	help bool
 }
 
@@ -49,6 +53,10 @@ func bindOptions(flag *flag.FlagSet) *options {
 
 	flag.StringVar(&opt.registryPath, "registry", "", "Path to the step registry directory")
 
+	flag.StringVar(&opt.scheduler, "scheduler", string(prowgen.SchedulerProw), fmt.Sprintf("Scheduler to generate jobs for, one of: %s. Jobs for schedulers other than Prow are written to ORG/REPO/ORG-REPO-SCHEDULER.yaml in --scheduler-dir", schedulerNames()))
+	flag.StringVar(&opt.schedulerDir, "scheduler-dir", "", "Path to a directory to write the objects generated for schedulers other than Prow to. Prow jobs are still written to --to-dir, if set")
+	flag.StringVar(&opt.namespace, "namespace", "", "Namespace of the objects generated for schedulers other than Prow")
+
 	flag.BoolVar(&opt.help, "h", false, "Show help for ci-operator-prowgen")
 
 	opt.Options.Bind(flag)
@@ -75,7 +83,18 @@ func (o *options) process() error {
 		return fmt.Errorf("ci-operator-prowgen needs exactly one of `--from-{dir,release-repo}` options")
 	}
 
-	if o.toDir == "" {
+	if scheduler := prowgen.Scheduler(o.scheduler); scheduler != prowgen.SchedulerProw {
+		if o.generator, err = prowgen.JobGeneratorFor(scheduler, o.namespace); err != nil {
+			return fmt.Errorf("--scheduler error: %w", err)
+		}
+		if o.schedulerDir == "" {
+			return fmt.Errorf("--scheduler-dir is required to generate jobs for %s", scheduler)
+		}
+		// Prow loads every file in the job directory as job configuration
+		if o.toDir != "" && isWithin(o.schedulerDir, o.toDir) {
+			return fmt.Errorf("--scheduler-dir must not be in --to-dir, as Prow would load the %s objects as jobs", scheduler)
+		}
+	} else if o.toDir == "" {
 		return fmt.Errorf("ci-operator-prowgen needs exactly one of `--to-{dir,release-repo}` options")
 	}
 
@@ -88,7 +107,7 @@ func (o *options) process() error {
 		return fmt.Errorf("failed to complete config options: %w", err)
 	}
 	if o.registryPath != "" {
-		refs, chains, workflows, _, _, observers, err := load.Registry(o.registryPath, load.RegistryFlag(0))
+		refs, chains, workflows, _, _, observers, _, err := load.Registry(o.registryPath, load.RegistryFlag(0))
 		if err != nil {
 			return fmt.Errorf("failed to load registry: %w", err)
 		}
@@ -97,33 +116,22 @@ func (o *options) process() error {
 	return nil
 }
 
-func readProwgenConfig(path string) (*config.Prowgen, error) {
-	var pConfig *config.Prowgen
-	b, err := ioutil.ReadFile(path)
-	if err != nil && !os.IsNotExist(err) {
-		return nil, fmt.Errorf("prowgen config found in path %s but couldn't read the file: %w", path, err)
-	}
-
-	if err == nil {
-		if err := yaml.Unmarshal(b, &pConfig); err != nil {
-			return nil, fmt.Errorf("prowgen config found in path %sbut couldn't unmarshal it: %w", path, err)
-		}
-		if err := pConfig.Validate(); err != nil {
-			return nil, fmt.Errorf("prowgen config found in path %s is not valid: %w", path, err)
-		}
-	}
-
-	return pConfig, nil
-}
-
 // generateJobsToDir generates prow job configuration into the dir provided by
 // consuming ci-operator configuration.
 func (o *options) generateJobsToDir(subDir string, prowConfig map[string]*config.Prowgen) error {
 	generated := map[string]*prowconfig.JobConfig{}
-	genJobsFunc := generateJobs(o.resolver, prowConfig, generated)
+	genJobsFunc := prowgen.GenerateJobsInto(o.resolver, prowConfig, generated)
 	if err := o.OperateOnCIOperatorConfigDir(filepath.Join(o.fromDir, subDir), genJobsFunc); err != nil {
 		return fmt.Errorf("failed to generate jobs: %w", err)
 	}
+	if o.generator != nil {
+		if err := writeObjectsToDir(o.schedulerDir, prowgen.Scheduler(o.scheduler), o.generator, generated); err != nil {
+			return err
+		}
+		if o.toDir == "" {
+			return nil
+		}
+	}
 	if err := o.OperateOnJobConfigSubdirPaths(o.toDir, subDir, func(info *jc.Info) error {
 		key := fmt.Sprintf("%s/%s", info.Org, info.Repo)
 		if _, ok := generated[key]; !ok {
@@ -136,54 +144,6 @@ func (o *options) generateJobsToDir(subDir string, prowConfig map[string]*config
 	return writeToDir(o.toDir, generated)
 }
 
-func generateJobs(resolver registry.Resolver, cache map[string]*config.Prowgen, output map[string]*prowconfig.JobConfig) func(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *config.Info) error {
-	return func(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *config.Info) error {
-		orgRepo := fmt.Sprintf("%s/%s", info.Org, info.Repo)
-		pInfo := &prowgen.ProwgenInfo{Metadata: info.Metadata, Config: config.Prowgen{Private: false, Expose: false}}
-		var ok bool
-		var err error
-		var orgConfig, repoConfig *config.Prowgen
-
-		if orgConfig, ok = cache[info.Org]; !ok {
-			if cache[info.Org], err = readProwgenConfig(filepath.Join(info.OrgPath, config.ProwgenFile)); err != nil {
-				return err
-			}
-			orgConfig = cache[info.Org]
-		}
-
-		if repoConfig, ok = cache[orgRepo]; !ok {
-			if cache[orgRepo], err = readProwgenConfig(filepath.Join(info.RepoPath, config.ProwgenFile)); err != nil {
-				return err
-			}
-			repoConfig = cache[orgRepo]
-		}
-
-		switch {
-		case orgConfig != nil:
-			pInfo.Config = *orgConfig
-		case repoConfig != nil:
-			pInfo.Config = *repoConfig
-		}
-		if resolver != nil {
-			resolved, err := registry.ResolveConfig(resolver, *configSpec)
-			if err != nil {
-				return fmt.Errorf("failed to resolve configuration: %w", err)
-			}
-			configSpec = &resolved
-		}
-		generated, err := prowgen.GenerateJobs(configSpec, pInfo)
-		if err != nil {
-			return err
-		}
-		if o, ok := output[orgRepo]; ok {
-			jc.Append(o, generated)
-		} else {
-			output[orgRepo] = generated
-		}
-		return nil
-	}
-}
-
 func getReleaseRepoDir(directory string) (string, error) {
 	tentative := filepath.Join(build.Default.GOPATH, "src/github.com/openshift/release", directory)
 	if stat, err := os.Stat(tentative); err == nil && stat.IsDir() {
@@ -219,6 +179,61 @@ func writeToDir(dir string, c map[string]*prowconfig.JobConfig) error {
 	return util.ProduceMap(0, produce, map_, errCh)
 }
 
+// isWithin determines whether the path is the directory or a path inside of it
+func isWithin(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func schedulerNames() string {
+	var names []string
+	for _, scheduler := range prowgen.Schedulers {
+		names = append(names, string(scheduler))
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeObjectsToDir generates the objects for a scheduler other than Prow from
+// the jobs of every repository and writes them to a file in its directory
+func writeObjectsToDir(dir string, scheduler prowgen.Scheduler, generator prowgen.JobGenerator, generated map[string]*prowconfig.JobConfig) error {
+	for orgRepo, jobs := range generated {
+		objects, err := generator.Generate(jobs)
+		if err != nil {
+			return fmt.Errorf("failed to generate %s objects for %s: %w", scheduler, orgRepo, err)
+		}
+		if len(objects) == 0 {
+			continue
+		}
+		var documents []string
+		for _, object := range objects {
+			raw, err := yaml.Marshal(object)
+			if err != nil {
+				return fmt.Errorf("failed to marshal %s objects for %s: %w", scheduler, orgRepo, err)
+			}
+			documents = append(documents, string(raw))
+		}
+		i := strings.Index(orgRepo, "/")
+		org, repo := orgRepo[:i], orgRepo[i+1:]
+		repoDir := filepath.Join(dir, org, repo)
+		if err := os.MkdirAll(repoDir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", orgRepo, err)
+		}
+		path := filepath.Join(repoDir, fmt.Sprintf("%s-%s-%s.yaml", org, repo, scheduler))
+		if err := ioutil.WriteFile(path, []byte(strings.Join(documents, "---\n")), 0644); err != nil {
+			return fmt.Errorf("failed to write %s objects for %s: %w", scheduler, orgRepo, err)
+		}
+	}
+	return nil
+}
+
 func main() {
 	flagSet := flag.NewFlagSet("", flag.ExitOnError)
 	opt := bindOptions(flagSet)
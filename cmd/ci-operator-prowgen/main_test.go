@@ -304,3 +304,20 @@ tests:
 		})
 	}
 }
+
+func TestIsWithin(t *testing.T) {
+	var testCases = []struct {
+		path, dir string
+		expected  bool
+	}{
+		{path: "/release/ci-operator/jobs", dir: "/release/ci-operator/jobs", expected: true},
+		{path: "/release/ci-operator/jobs/tekton", dir: "/release/ci-operator/jobs", expected: true},
+		{path: "/release/ci-operator/jobs-tekton", dir: "/release/ci-operator/jobs"},
+		{path: "/release/ci-operator/tekton", dir: "/release/ci-operator/jobs"},
+	}
+	for _, tc := range testCases {
+		if actual := isWithin(tc.path, tc.dir); actual != tc.expected {
+			t.Errorf("%s in %s: expected %v, got %v", tc.path, tc.dir, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// ci-operator-resolver-snapshot downloads a self-contained snapshot of all
+// ci-operator configurations and the step registry from the configresolver,
+// which ci-operator can then use with --resolver-snapshot instead of talking
+// to the configresolver
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	registryserver "github.com/openshift/ci-tools/pkg/registry/server"
+)
+
+type options struct {
+	resolverAddress string
+	output          string
+}
+
+func (o *options) Validate() error {
+	if o.resolverAddress == "" {
+		return errors.New("--resolver-address is required")
+	}
+	if o.output == "" {
+		return errors.New("--output is required")
+	}
+	return nil
+}
+
+func gatherOptions() (options, error) {
+	o := options{}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.StringVar(&o.resolverAddress, "resolver-address", api.URLForService(api.ServiceConfig), "Address of configresolver")
+	fs.StringVar(&o.output, "output", "", "Path to write the gzipped snapshot to.")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return options{}, fmt.Errorf("could not parse input: %w", err)
+	}
+	return o, nil
+}
+
+func writeSnapshot(snapshot *registryserver.Snapshot, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+	writer := gzip.NewWriter(f)
+	if err := json.NewEncoder(writer).Encode(snapshot); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return f.Close()
+}
+
+func main() {
+	o, err := gatherOptions()
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to gather options")
+	}
+	if err := o.Validate(); err != nil {
+		logrus.WithError(err).Fatal("invalid options")
+	}
+	snapshot, err := registryserver.FetchSnapshot(o.resolverAddress)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to download snapshot")
+	}
+	if err := writeSnapshot(snapshot, o.output); err != nil {
+		logrus.WithError(err).Fatal("failed to write snapshot")
+	}
+	logrus.WithFields(logrus.Fields{
+		"configs":             len(snapshot.Configs),
+		"config-generation":   snapshot.ConfigGeneration,
+		"registry-generation": snapshot.RegistryGeneration,
+	}).Infof("Wrote snapshot to %s", o.output)
+}
@@ -70,6 +70,7 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/api/nsttl"
+	"github.com/openshift/ci-tools/pkg/config"
 	"github.com/openshift/ci-tools/pkg/defaults"
 	"github.com/openshift/ci-tools/pkg/interrupt"
 	"github.com/openshift/ci-tools/pkg/junit"
@@ -380,8 +381,9 @@ type options struct {
 	impersonateUser               string
 	authors                       []string
 
-	resolverAddress string
-	resolverClient  server.ResolverClient
+	resolverAddress      string
+	resolverSnapshotPath string
+	resolverClient       server.ResolverClient
 
 	registryPath string
 	org          string
@@ -464,6 +466,7 @@ func bindOptions(flag *flag.FlagSet) *options {
 
 	// flags needed for the configresolver
 	flag.StringVar(&opt.resolverAddress, "resolver-address", configResolverAddress, "Address of configresolver")
+	flag.StringVar(&opt.resolverSnapshotPath, "resolver-snapshot", "", "Path to a configresolver snapshot to resolve the configuration from instead of the configresolver at --resolver-address")
 	flag.StringVar(&opt.org, "org", "", "Org of the project (used by configresolver)")
 	flag.StringVar(&opt.repo, "repo", "", "Repo of the project (used by configresolver)")
 	flag.StringVar(&opt.branch, "branch", "", "Branch of the project (used by configresolver)")
@@ -513,13 +516,21 @@ func (o *options) Complete() error {
 	o.jobSpec.Target = target
 
 	info := o.getResolverInfo(jobSpec)
-	o.resolverClient = server.NewResolverClient(o.resolverAddress)
+	if o.resolverSnapshotPath != "" {
+		snapshot, err := server.LoadSnapshot(o.resolverSnapshotPath)
+		if err != nil {
+			return fmt.Errorf("--resolver-snapshot error: %w", err)
+		}
+		o.resolverClient = server.NewSnapshotResolverClient(snapshot)
+	} else {
+		o.resolverClient = server.NewResolverClient(o.resolverAddress)
+	}
 
 	if o.unresolvedConfigPath != "" && o.configSpecPath != "" {
 		return errors.New("cannot set --config and --unresolved-config at the same time")
 	}
-	if o.unresolvedConfigPath != "" && o.resolverAddress == "" {
-		return errors.New("cannot request resolved config with --unresolved-config unless providing --resolver-address")
+	if o.unresolvedConfigPath != "" && o.resolverAddress == "" && o.resolverSnapshotPath == "" {
+		return errors.New("cannot request resolved config with --unresolved-config unless providing --resolver-address or --resolver-snapshot")
 	}
 
 	injectTest, err := o.getInjectTest()
@@ -529,8 +540,8 @@ func (o *options) Complete() error {
 
 	var config *api.ReleaseBuildConfiguration
 	if injectTest != nil {
-		if o.resolverAddress == "" {
-			return errors.New("cannot request config with injected test without providing --resolver-address")
+		if o.resolverAddress == "" && o.resolverSnapshotPath == "" {
+			return errors.New("cannot request config with injected test without providing --resolver-address or --resolver-snapshot")
 		}
 		if o.unresolvedConfigPath != "" || o.configSpecPath != "" {
 			return errors.New("cannot request injecting test into locally provided config")
@@ -556,6 +567,7 @@ func (o *options) Complete() error {
 	if err := validation.IsValidGraphConfiguration(o.graphConfig.Steps); err != nil {
 		return results.ForReason("validating_config").ForError(err)
 	}
+	warnAboutDeprecations(o.configSpec, o.targets.values)
 	if o.verbose {
 		config, _ := yaml.Marshal(o.configSpec)
 		logrus.WithField("config", string(config)).Trace("Resolved configuration.")
@@ -813,6 +825,22 @@ func (o *options) Report(errs ...error) {
 	}
 }
 
+// reportCost reports the resources consumed by every step that ran
+func (o *options) reportCost(graph api.CIOperatorStepGraph) {
+	reporter, err := o.resultsOptions.Reporter(o.jobSpec, o.consoleHost)
+	if err != nil {
+		logrus.WithError(err).Warn("Could not load result reporting options.")
+		return
+	}
+	costs := map[string]api.CIOperatorStepCost{}
+	for _, step := range graph {
+		if step.Cost != nil {
+			costs[step.StepName] = *step.Cost
+		}
+	}
+	reporter.ReportCost(costs)
+}
+
 func (o *options) Run() []error {
 	start := time.Now()
 	defer func() {
@@ -875,6 +903,7 @@ func (o *options) Run() []error {
 		}
 
 		_ = api.SaveArtifact(o.censor, api.CIOperatorStepGraphJSONFilename, serializedGraph)
+		o.reportCost(*graph)
 	}()
 	// initialize the namespace if necessary and create any resources that must
 	// exist prior to execution
@@ -958,6 +987,7 @@ func runStep(ctx context.Context, step api.Step) (api.CIOperatorStepDetails, err
 			FinishedAt:  func() *time.Time { start.Add(duration); return &start }(),
 			Duration:    &duration,
 			Failed:      &failed,
+			Cost:        steps.StepCost(step, step.Objects(), subSteps, start.Add(duration)),
 		},
 		Substeps: subSteps,
 	}, err
@@ -1998,7 +2028,8 @@ func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration
 
 	switch {
 	case len(o.configSpecPath) > 0:
-		data, err := gzip.ReadFileMaybeGZIP(o.configSpecPath)
+		// configurations may extend others in the same configuration directory
+		data, err := config.ReadConfigFile(o.configSpecPath)
 		if err != nil {
 			return nil, fmt.Errorf("--config error: %w", err)
 		}
@@ -2034,6 +2065,11 @@ func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration
 		err = results.ForReason("config_resolver").ForError(err)
 		return configSpec, err
 	}
+	// the configuration that one in CONFIG_SPEC extends cannot be found, so
+	// these must be passed with the configurations they extend merged in
+	if extends, err := config.ExtendsOf([]byte(raw)); err == nil && extends != "" {
+		return nil, fmt.Errorf("configuration in CONFIG_SPEC extends %s, which cannot be resolved: pass it with --config in its configuration directory or resolve it with the configresolver", extends)
+	}
 	configSpec := api.ReleaseBuildConfiguration{}
 	if err := yaml.UnmarshalStrict([]byte(raw), &configSpec); err != nil {
 		if len(o.configSpecPath) > 0 {
@@ -2042,11 +2078,11 @@ func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration
 		return nil, fmt.Errorf("invalid configuration: %w\nvalue:\n%s", err, raw)
 	}
 	if o.registryPath != "" {
-		refs, chains, workflows, _, _, observers, err := load.Registry(o.registryPath, load.RegistryFlag(0))
+		refs, chains, workflows, _, _, observers, deprecations, err := load.Registry(o.registryPath, load.RegistryFlag(0))
 		if err != nil {
 			return nil, fmt.Errorf("failed to load registry: %w", err)
 		}
-		configSpec, err = registry.ResolveConfig(registry.NewResolver(refs, chains, workflows, observers), configSpec)
+		configSpec, err = registry.ResolveConfig(registry.NewResolver(refs, chains, workflows, observers, registry.WithDeprecations(deprecations)), configSpec)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve configuration: %w", err)
 		}
@@ -2054,6 +2090,20 @@ func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration
 	return &configSpec, nil
 }
 
+// warnAboutDeprecations logs the warnings about deprecated registry components
+// recorded during the resolution of the targeted tests
+func warnAboutDeprecations(config *api.ReleaseBuildConfiguration, targets []string) {
+	targeted := sets.NewString(targets...)
+	for _, test := range config.Tests {
+		if test.MultiStageTestConfigurationLiteral == nil || (targeted.Len() > 0 && !targeted.Has(test.As)) {
+			continue
+		}
+		for _, deprecation := range test.MultiStageTestConfigurationLiteral.Deprecations {
+			logrus.Warnf("Test %s uses a deprecated registry component: %s", test.As, deprecation)
+		}
+	}
+}
+
 func monitorNamespace(ctx context.Context, cancel func(), namespace string, client coreclientset.NamespaceInterface) {
 reset:
 	for {
@@ -907,6 +907,20 @@ func TestConfig(t *testing.T) {
 			expected:      nil,
 			expectedError: true,
 		},
+		{
+			name:          "extending another configuration in the env results in error",
+			config:        "extends: org/repo@master\n",
+			asEnv:         true,
+			expected:      nil,
+			expectedError: true,
+		},
+		{
+			name:          "extending another configuration outside of a configuration directory results in error",
+			config:        "extends: org/repo@master\n",
+			asFile:        true,
+			expected:      nil,
+			expectedError: true,
+		},
 	}
 
 	for _, testCase := range testCases {
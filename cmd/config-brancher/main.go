@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/getlantern/deepcopy"
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -70,7 +69,7 @@ func main() {
 
 	var toCommit []config.DataWithInfo
 	if err := o.OperateOnCIOperatorConfigDir(o.ConfigDir, api.WithOKD, func(configuration *api.ReleaseBuildConfiguration, info *config.Info) error {
-		for _, output := range generateBranchedConfigs(o.CurrentRelease, o.BumpRelease, o.FutureReleases.Strings(), config.DataWithInfo{Configuration: *configuration, Info: *info}) {
+		for _, output := range promotion.GenerateBranchedConfigs(o.CurrentRelease, o.BumpRelease, o.FutureReleases.Strings(), config.DataWithInfo{Configuration: *configuration, Info: *info}) {
 			if !o.Confirm {
 				output.Logger().Info("Would commit new file.")
 				continue
@@ -95,106 +94,3 @@ func main() {
 		logrus.Fatal("Failed to commit configuration to disk.")
 	}
 }
-
-func generateBranchedConfigs(currentRelease, bumpRelease string, futureReleases []string, input config.DataWithInfo) []config.DataWithInfo {
-	var output []config.DataWithInfo
-	input.Logger().Info("Branching configuration.")
-	currentConfig := input.Configuration
-
-	// if we are asked to bump, we need to update the config for the dev branch
-	devRelease := currentRelease
-	if bumpRelease != "" && promotion.IsBumpable(input.Info.Branch, currentRelease) {
-		devRelease = bumpRelease
-		updateRelease(&currentConfig, bumpRelease)
-		updateImages(&currentConfig, currentRelease, bumpRelease)
-		// this config will continue to run for the dev branch but will be bumped
-		output = append(output, config.DataWithInfo{Configuration: currentConfig, Info: input.Info})
-	}
-
-	for _, futureRelease := range futureReleases {
-		futureBranch, err := promotion.DetermineReleaseBranch(currentRelease, futureRelease, input.Info.Branch)
-		if err != nil {
-			input.Logger().WithError(err).Error("could not determine future branch that would promote to current imagestream")
-			return nil
-		}
-		if futureBranch == input.Info.Branch {
-			// some repos release on their dev branch, so we don't need
-			// to make any changes for this one
-			continue
-		}
-
-		var futureConfig api.ReleaseBuildConfiguration
-		if err := deepcopy.Copy(&futureConfig, &currentConfig); err != nil {
-			input.Logger().WithError(err).Error("failed to copy input CI Operator configuration")
-			return nil
-		}
-
-		// the new config will point to the future release
-		updateRelease(&futureConfig, futureRelease)
-		// we cannot have two configs promoting to the same output, so
-		// we need to make sure the release branch config is disabled
-		futureConfig.PromotionConfiguration.Disabled = futureRelease == devRelease
-		// users can reference the release streams via build roots or
-		// input images, so we need to update those, too
-		updateImages(&futureConfig, devRelease, futureRelease)
-		// we need to make sure this relates to the right branch
-		futureConfig.Metadata.Branch = futureBranch
-
-		// this config will promote to the new location on the release branch
-		output = append(output, config.DataWithInfo{Configuration: futureConfig, Info: copyInfoSwappingBranches(input.Info, futureBranch)})
-	}
-	return output
-}
-
-// updateRelease updates the release that is promoted to and that
-// which is used to source the release payload for testing
-func updateRelease(config *api.ReleaseBuildConfiguration, futureRelease string) {
-	if config.PromotionConfiguration != nil {
-		config.PromotionConfiguration.Name = futureRelease
-	}
-	if config.ReleaseTagConfiguration != nil {
-		config.ReleaseTagConfiguration.Name = futureRelease
-	}
-	for name, release := range config.Releases {
-		if release.Integration != nil {
-			updated := *release.Integration
-			updated.Name = futureRelease
-			config.Releases[name] = api.UnresolvedRelease{Integration: &updated}
-		}
-	}
-}
-
-// updateImages updates the release that is used for input images
-// if it matches the release we are updating from
-func updateImages(config *api.ReleaseBuildConfiguration, currentRelease, futureRelease string) {
-	for name := range config.InputConfiguration.BaseImages {
-		image := config.InputConfiguration.BaseImages[name]
-		if api.RefersToOfficialImage(image.Namespace, api.WithOKD) && image.Name == currentRelease {
-			image.Name = futureRelease
-		}
-		config.InputConfiguration.BaseImages[name] = image
-	}
-
-	for i := range config.InputConfiguration.BaseRPMImages {
-		image := config.InputConfiguration.BaseRPMImages[i]
-		if api.RefersToOfficialImage(image.Namespace, api.WithOKD) && image.Name == currentRelease {
-			image.Name = futureRelease
-		}
-		config.InputConfiguration.BaseRPMImages[i] = image
-	}
-
-	if config.InputConfiguration.BuildRootImage != nil {
-		image := config.InputConfiguration.BuildRootImage.ImageStreamTagReference
-		if image != nil && api.RefersToOfficialImage(image.Namespace, api.WithOKD) && image.Name == currentRelease {
-			image.Name = futureRelease
-		}
-		config.InputConfiguration.BuildRootImage.ImageStreamTagReference = image
-	}
-}
-
-func copyInfoSwappingBranches(input config.Info, newBranch string) config.Info {
-	intermediate := &input
-	output := *intermediate
-	output.Branch = newBranch
-	return output
-}
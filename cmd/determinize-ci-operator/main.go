@@ -3,8 +3,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/sirupsen/logrus"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -31,6 +34,7 @@ type options struct {
 	templateMigrationAllowedBranches        flagutil.Strings
 	templateMigrationAllowedOrgs            flagutil.Strings
 	templateMigrationAllowedClusterProfiles flagutil.Strings
+	showFlattened                           bool
 }
 
 func (o options) validate() error {
@@ -53,6 +57,7 @@ func gatherOptions() options {
 	flag.Var(&o.templateMigrationAllowedBranches, "template-migration-allowed-branch", "Allowed branches to automigrate templates on. Can be passed multiple times. All branches are allowed if unset.")
 	flag.Var(&o.templateMigrationAllowedOrgs, "template-migration-allowed-org", "Allowed orgs to automigrate templates on. Can be passed multiple times. All orgs are allowed if unset.")
 	flag.Var(&o.templateMigrationAllowedClusterProfiles, "template-migration-allowed-cluster-profile", "Allowed cluster profiles to automigrate templates on. Can be passed multiple times. All cluster profiles are allowed if unset.")
+	flag.BoolVar(&o.showFlattened, "show-flattened", false, "Print the configurations that extend others with the inherited fields resolved instead of re-formatting them.")
 	flag.Parse()
 
 	return o
@@ -68,6 +73,15 @@ func main() {
 		logrus.Fatalf("Couldn't complete the config options: %v", err)
 	}
 
+	if o.showFlattened {
+		if err := o.OperateOnCIOperatorConfigDir(o.ConfigDir, func(configuration *api.ReleaseBuildConfiguration, info *config.Info) error {
+			return showFlattened(os.Stdout, configuration, info)
+		}); err != nil {
+			logrus.WithError(err).Fatal("Could not show flattened configurations.")
+		}
+		return
+	}
+
 	var migratedCount int
 	var toCommit []config.DataWithInfo
 	if err := o.OperateOnCIOperatorConfigDir(o.ConfigDir, func(configuration *api.ReleaseBuildConfiguration, info *config.Info) error {
@@ -110,6 +124,20 @@ func main() {
 	}
 }
 
+// showFlattened prints a configuration that extends another one with all
+// inherited fields resolved
+func showFlattened(out io.Writer, configuration *api.ReleaseBuildConfiguration, info *config.Info) error {
+	if info.Extends == nil {
+		return nil
+	}
+	raw, err := yaml.Marshal(configuration)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", info.RelativePath(), err)
+	}
+	_, err = fmt.Fprintf(out, "# %s extends %s\n%s---\n", info.RelativePath(), info.Extends.AsString(), raw)
+	return err
+}
+
 func upgradeWorkflowForClusterProfile(clusterProfile api.ClusterProfile) string {
 	return fmt.Sprintf("openshift-upgrade-%s", clusterProfile)
 }
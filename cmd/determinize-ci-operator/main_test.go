@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -407,3 +408,36 @@ func TestMigrateOpenshiftInstallerTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestShowFlattened(t *testing.T) {
+	t.Parallel()
+	configuration := &api.ReleaseBuildConfiguration{
+		Metadata:            api.Metadata{Org: "org", Repo: "repo", Branch: "release-4.10"},
+		BinaryBuildCommands: "make build",
+	}
+	metadata := configuration.Metadata
+
+	var out bytes.Buffer
+	if err := showFlattened(&out, configuration, &config.Info{Metadata: metadata}); err != nil {
+		t.Fatalf("failed to show configuration: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected configurations that do not extend others to be skipped, got %q", out.String())
+	}
+
+	extends := &api.Metadata{Org: "org", Repo: "repo", Branch: "master"}
+	if err := showFlattened(&out, configuration, &config.Info{Metadata: metadata, Extends: extends}); err != nil {
+		t.Fatalf("failed to show configuration: %v", err)
+	}
+	expected := `# org/repo/org-repo-release-4.10.yaml extends org/repo@master
+binary_build_commands: make build
+zz_generated_metadata:
+  branch: release-4.10
+  org: org
+  repo: repo
+---
+`
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("output differs from expected: %s", diff)
+	}
+}
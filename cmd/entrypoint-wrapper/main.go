@@ -83,6 +83,9 @@ type options struct {
 	updateSharedDir  bool
 	cmd              []string
 	client           coreclientset.SecretInterface
+	// observerPod is the name of the Pod whose events we relay to an observer
+	observerPod string
+	pods        coreclientset.PodInterface
 }
 
 func bindOptions(flag *flag.FlagSet) *options {
@@ -126,10 +129,15 @@ func (o *options) complete(flagSet *flag.FlagSet) error {
 	}
 
 	if !o.dry && o.mode != skipKubeconfigMode {
-		var err error
-		if o.client, err = loadClient(ns); err != nil {
+		client, err := loadClient()
+		if err != nil {
 			return err
 		}
+		o.client = client.Secrets(ns)
+		if o.mode == observerMode {
+			o.observerPod = os.Getenv(api.ObserverPodNameEnv)
+			o.pods = client.Pods(ns)
+		}
 	}
 	return nil
 }
@@ -183,7 +191,7 @@ func (o *options) run() error {
 	return utilerrors.NewAggregate(errs)
 }
 
-func loadClient(namespace string) (coreclientset.SecretInterface, error) {
+func loadClient() (coreclientset.CoreV1Interface, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load cluster config: %w", err)
@@ -192,7 +200,7 @@ func loadClient(namespace string) (coreclientset.SecretInterface, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
-	return client.Secrets(namespace), nil
+	return client, nil
 }
 
 func copyDir(dst, src string) error {
@@ -284,6 +292,14 @@ func (o *options) execCmd() error {
 			return err
 		}
 	}
+	var eventsFile string
+	if o.observerPod != "" {
+		eventsFile = filepath.Join(os.TempDir(), "observer-events")
+		if err := ioutil.WriteFile(eventsFile, nil, 0644); err != nil {
+			return fmt.Errorf("could not create observer events file: %w", err)
+		}
+		proc.Env = append(proc.Env, fmt.Sprintf("%s=%s", api.ObserverEventsFileEnv, eventsFile))
+	}
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	if err := proc.Start(); err != nil {
@@ -291,6 +307,9 @@ func (o *options) execCmd() error {
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	if eventsFile != "" {
+		go relayObserverEvents(ctx, o.pods, o.observerPod, eventsFile, sig)
+	}
 	go func() {
 		for {
 			select {
@@ -431,3 +450,51 @@ func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface,
 		log.Printf("Failed to upload $KUBECONFIG: %v: %v\n", err, uploadErr)
 	}
 }
+
+// relayObserverEvents watches the observer Pod for events in the lifecycle of
+// the multi-stage test, which ci-operator records in an annotation, and writes
+// all events seen so far to the events file. Once the post steps start, the
+// observer is interrupted so it can exit on its own and its Pod completes like
+// the Pod of any other step, instead of being deleted.
+func relayObserverEvents(ctx context.Context, client coreclientset.PodInterface, name, eventsFile string, sig chan<- os.Signal) {
+	var sent int
+	if err := wait.PollUntil(2*time.Second, func() (done bool, err error) {
+		pod, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("Failed to get observer Pod %s: %v\n", name, err)
+			return false, nil
+		}
+		value := pod.Annotations[api.ObserverEventsAnnotation]
+		if value == "" {
+			return false, nil
+		}
+		events := strings.Split(value, ",")
+		if len(events) <= sent {
+			return false, nil
+		}
+		// write to a temporary file and rename, so observers never read a partial list
+		tmp := eventsFile + ".tmp"
+		if err := ioutil.WriteFile(tmp, []byte(strings.Join(events, "\n")+"\n"), 0644); err != nil {
+			log.Printf("Failed to write observer events: %v\n", err)
+			return false, nil
+		}
+		if err := os.Rename(tmp, eventsFile); err != nil {
+			log.Printf("Failed to write observer events: %v\n", err)
+			return false, nil
+		}
+		for _, event := range events[sent:] {
+			fmt.Fprintf(os.Stderr, "received observer event %s\n", event)
+			if event == string(api.ObserverEventPostStarted) {
+				select {
+				case sig <- syscall.SIGINT:
+				default:
+					// a signal is already pending
+				}
+			}
+		}
+		sent = len(events)
+		return false, nil
+	}, ctx.Done()); err != nil && !errors.Is(err, wait.ErrWaitTimeout) {
+		log.Printf("Failed to relay observer events: %v\n", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/test-infra/prow/config/secret"
+	prowflagutil "k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/git/types"
+	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/interrupts"
+	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/pjutil"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/rehearse"
+)
+
+type options struct {
+	logLevel                 string
+	dryRun                   bool
+	githubEventServerOptions githubeventserver.Options
+	github                   prowflagutil.GitHubOptions
+	git                      prowflagutil.GitOptions
+	kubernetesOptions        prowflagutil.KubernetesOptions
+	prowjobKubeconfig        string
+	namespace                string
+	webhookSecretFile        string
+
+	noTemplates       bool
+	noRegistry        bool
+	noClusterProfiles bool
+
+	normalLimit int
+	moreLimit   int
+	maxLimit    int
+}
+
+func gatherOptions() options {
+	o := options{kubernetesOptions: prowflagutil.KubernetesOptions{NOInClusterConfigDefault: true}}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	fs.StringVar(&o.logLevel, "log-level", "info", "Level at which to log output.")
+	fs.BoolVar(&o.dryRun, "dry-run", true, "Whether to actually submit rehearsal jobs to Prow")
+	fs.StringVar(&o.webhookSecretFile, "hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret.")
+	fs.StringVar(&o.prowjobKubeconfig, "prowjob-kubeconfig", "", "Path to the prowjob kubeconfig. If unset, default kubeconfig will be used for prowjobs.")
+	fs.StringVar(&o.namespace, "namespace", "ci", "Namespace the ProwJobs of rehearsals are created in.")
+	fs.BoolVar(&o.noTemplates, "no-templates", false, "If true, do not attempt to compare templates")
+	fs.BoolVar(&o.noRegistry, "no-registry", false, "If true, do not attempt to compare step registry content")
+	fs.BoolVar(&o.noClusterProfiles, "no-cluster-profiles", false, "If true, do not attempt to compare cluster profiles")
+	fs.IntVar(&o.normalLimit, "normal-limit", 10, "Upper limit of jobs rehearsed by /pj-rehearse")
+	fs.IntVar(&o.moreLimit, "more-limit", 20, "Upper limit of jobs rehearsed by /pj-rehearse more")
+	fs.IntVar(&o.maxLimit, "max-limit", 35, "Upper limit of jobs rehearsed by /pj-rehearse max, and of any rehearsal request")
+
+	o.github.AddFlags(fs)
+	o.git.AddFlags(fs)
+	o.githubEventServerOptions.Bind(fs)
+	o.kubernetesOptions.AddFlags(fs)
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	return o
+}
+
+func (o *options) Validate() error {
+	if _, err := logrus.ParseLevel(o.logLevel); err != nil {
+		return fmt.Errorf("invalid --log-level: %w", err)
+	}
+	if o.normalLimit < 1 || o.moreLimit < o.normalLimit || o.maxLimit < o.moreLimit {
+		return fmt.Errorf("limits must be positive and satisfy --normal-limit <= --more-limit <= --max-limit")
+	}
+	if err := o.github.Validate(o.dryRun); err != nil {
+		return err
+	}
+	if err := o.kubernetesOptions.Validate(o.dryRun); err != nil {
+		return err
+	}
+	return o.githubEventServerOptions.DefaultAndValidate()
+}
+
+func main() {
+	logrusutil.ComponentInit()
+	logger := logrus.WithField("plugin", pluginName)
+
+	o := gatherOptions()
+	if err := o.Validate(); err != nil {
+		logger.Fatalf("Invalid options: %v", err)
+	}
+	level, _ := logrus.ParseLevel(o.logLevel)
+	logrus.SetLevel(level)
+
+	if err := imagev1.Install(scheme.Scheme); err != nil {
+		logger.WithError(err).Fatal("failed to register imagev1 scheme")
+	}
+	if err := secret.Add(o.github.TokenPath, o.webhookSecretFile); err != nil {
+		logger.WithError(err).Fatal("Error starting secrets agent.")
+	}
+
+	githubClient, err := o.github.GitHubClient(o.dryRun)
+	if err != nil {
+		logger.WithError(err).Fatal("Error getting GitHub client.")
+	}
+	gitClient, err := o.git.GitClient(githubClient, secret.GetTokenGenerator(o.github.TokenPath), secret.Censor, o.dryRun)
+	if err != nil {
+		logger.WithError(err).Fatal("Error getting Git client.")
+	}
+
+	rc := rehearse.RehearsalConfig{
+		ProwjobKubeconfig: o.prowjobKubeconfig,
+		KubernetesOptions: o.kubernetesOptions,
+		NoTemplates:       o.noTemplates,
+		NoRegistry:        o.noRegistry,
+		NoClusterProfiles: o.noClusterProfiles,
+		NormalLimit:       o.normalLimit,
+		MoreLimit:         o.moreLimit,
+		MaxLimit:          o.maxLimit,
+		DryRun:            o.dryRun,
+	}
+	pjclient, err := rc.ProwJobClient()
+	if err != nil {
+		logger.WithError(err).Fatal("could not create a ProwJob client")
+	}
+
+	serv := &server{
+		ghc:            githubClient,
+		pjclient:       pjclient,
+		namespace:      o.namespace,
+		trustedChecker: &githubTrustedChecker{githubClient: githubClient},
+		rehearser:      &configRehearser{rc: rc},
+		checkout: func(org, repo string, number int, baseSHA, headSHA string) (string, func(), error) {
+			repoClient, err := gitClient.ClientFor(org, repo)
+			if err != nil {
+				return "", nil, fmt.Errorf("could not clone %s/%s: %w", org, repo, err)
+			}
+			cleanup := func() {
+				if err := repoClient.Clean(); err != nil {
+					logger.WithError(err).Warn("could not clean up the working copy")
+				}
+			}
+			// the pull request is merged into the base branch the same way
+			// clonerefs does it for the jobs, so that changes that merged into
+			// the base branch since the pull request was branched off of it are
+			// not mistaken for changes made by the pull request
+			for _, setting := range [][2]string{{"user.name", "ci-robot"}, {"user.email", "ci-robot@openshift.io"}, {"commit.gpgsign", "false"}} {
+				if err := repoClient.Config(setting[0], setting[1]); err != nil {
+					cleanup()
+					return "", nil, fmt.Errorf("could not set %s in the working copy: %w", setting[0], err)
+				}
+			}
+			if err := repoClient.FetchRef(fmt.Sprintf("pull/%d/head", number)); err != nil {
+				cleanup()
+				return "", nil, fmt.Errorf("could not fetch pull request %d: %w", number, err)
+			}
+			if err := repoClient.MergeAndCheckout(baseSHA, string(types.MergeMerge), headSHA); err != nil {
+				cleanup()
+				return "", nil, fmt.Errorf("could not merge pull request %d into %s: %w", number, baseSHA, err)
+			}
+			return repoClient.Directory(), cleanup, nil
+		},
+		limits: map[commandKind]int{
+			commandRehearse: rc.NormalLimit,
+			commandMore:     rc.MoreLimit,
+			commandMax:      rc.MaxLimit,
+		},
+		prs: map[string]*prState{},
+	}
+
+	eventServer := githubeventserver.New(o.githubEventServerOptions, secret.GetTokenGenerator(o.webhookSecretFile), logger)
+	eventServer.RegisterHandleIssueCommentEvent(serv.handleIssueComment)
+	eventServer.RegisterHandlePullRequestEvent(serv.handlePullRequest)
+	eventServer.RegisterHelpProvider(helpProvider, logger)
+
+	interrupts.OnInterrupt(func() {
+		eventServer.GracefulShutdown()
+		if err := gitClient.Clean(); err != nil {
+			logger.WithError(err).Error("Could not clean up git client cache.")
+		}
+	})
+
+	health := pjutil.NewHealth()
+	health.ServeReady()
+
+	interrupts.ListenAndServe(eventServer, time.Second*30)
+	interrupts.WaitForGracefulShutdown()
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins/trigger"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/rehearse"
+)
+
+const (
+	pluginName = "pj-rehearse"
+
+	// ackLabel marks pull requests whose author acknowledged that no (more)
+	// rehearsals are necessary
+	ackLabel = "rehearsals-ack"
+)
+
+type githubClient interface {
+	CreateComment(owner, repo string, number int, comment string) error
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	GetRef(org, repo, ref string) (string, error)
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+}
+
+type trustedChecker interface {
+	trustedUser(author, org, repo string, num int) (bool, error)
+}
+
+type githubTrustedChecker struct {
+	githubClient github.Client
+}
+
+func (c *githubTrustedChecker) trustedUser(author, org, repo string, _ int) (bool, error) {
+	triggerTrustedResponse, err := trigger.TrustedUser(c.githubClient, false, []string{}, "", author, org, repo)
+	if err != nil {
+		return false, fmt.Errorf("error checking %s for trust: %w", author, err)
+	}
+	return triggerTrustedResponse.IsTrusted, nil
+}
+
+type commandKind string
+
+const (
+	commandRehearse commandKind = "rehearse"
+	commandMore     commandKind = "more"
+	commandMax      commandKind = "max"
+	commandFailed   commandKind = "failed"
+	commandJobs     commandKind = "jobs"
+	commandSkip     commandKind = "skip"
+	commandAbort    commandKind = "abort"
+)
+
+type command struct {
+	kind commandKind
+	jobs []string
+}
+
+var commandPattern = regexp.MustCompile(`(?m)^/pj-rehearse(?:[ \t]+(?P<args>[^\r\n]*?))?[ \t]*\r?$`)
+
+func commandsFromComment(comment string) []command {
+	var commands []command
+	for _, match := range commandPattern.FindAllStringSubmatch(comment, -1) {
+		args := strings.Fields(match[commandPattern.SubexpIndex("args")])
+		switch {
+		case len(args) == 0:
+			commands = append(commands, command{kind: commandRehearse})
+		case len(args) == 1 && sets.NewString(string(commandMore), string(commandMax), string(commandFailed), string(commandSkip), string(commandAbort)).Has(args[0]):
+			commands = append(commands, command{kind: commandKind(args[0])})
+		default:
+			commands = append(commands, command{kind: commandJobs, jobs: args})
+		}
+	}
+	return commands
+}
+
+func helpProvider(_ []prowconfig.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	pluginHelp := &pluginhelp.PluginHelp{
+		Description: `The pj-rehearse plugin rehearses the jobs affected by changes to the configuration in the release repository`,
+	}
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       "/pj-rehearse [more|max|failed|skip|abort|<job>...]",
+		Description: "Rehearse a limited number of affected jobs, more or as many as allowed of them, only the ones that failed their last rehearsal or the given ones. Skip acknowledges that no rehearsals are necessary, abort stops running rehearsals.",
+		WhoCanUse:   "Members of the trusted organization for the repo.",
+		Examples:    []string{"/pj-rehearse", "/pj-rehearse more", "/pj-rehearse failed", "/pj-rehearse pull-ci-openshift-ci-tools-master-unit", "/pj-rehearse skip", "/pj-rehearse abort"},
+	})
+	return pluginHelp, nil
+}
+
+// affectedJobs are the jobs affected by a revision of a pull request
+type affectedJobs struct {
+	presubmits config.Presubmits
+	periodics  config.Periodics
+	templates  *rehearse.ConfigMaps
+	profiles   *rehearse.ConfigMaps
+}
+
+func (a *affectedJobs) names() sets.String {
+	names := sets.NewString()
+	for _, jobs := range a.presubmits {
+		for _, job := range jobs {
+			names.Insert(job.Name)
+		}
+	}
+	for name := range a.periodics {
+		names.Insert(name)
+	}
+	return names
+}
+
+// subset returns the affected jobs limited to the given names
+func (a *affectedJobs) subset(names sets.String) *affectedJobs {
+	ret := &affectedJobs{presubmits: config.Presubmits{}, periodics: config.Periodics{}, templates: a.templates, profiles: a.profiles}
+	for repo, jobs := range a.presubmits {
+		for _, job := range jobs {
+			if names.Has(job.Name) {
+				ret.presubmits[repo] = append(ret.presubmits[repo], job)
+			}
+		}
+	}
+	for name, job := range a.periodics {
+		if names.Has(name) {
+			ret.periodics[name] = job
+		}
+	}
+	return ret
+}
+
+// rehearser determines which jobs a pull request affects and rehearses them
+type rehearser interface {
+	determineAffected(candidate rehearse.RehearsalCandidate, path string, logger *logrus.Entry) (*affectedJobs, error)
+	// setup configures the rehearsals of at most limit of the jobs and returns their
+	// names, along with a function that runs them and waits for their results
+	setup(candidate rehearse.RehearsalCandidate, path string, jobs *affectedJobs, limit int, logger *logrus.Entry) ([]string, func() error, error)
+}
+
+type configRehearser struct {
+	rc rehearse.RehearsalConfig
+}
+
+func (r *configRehearser) determineAffected(candidate rehearse.RehearsalCandidate, path string, logger *logrus.Entry) (*affectedJobs, error) {
+	presubmits, periodics, templates, profiles, err := r.rc.DetermineAffectedJobs(candidate, path, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &affectedJobs{presubmits: presubmits, periodics: periodics, templates: templates, profiles: profiles}, nil
+}
+
+func (r *configRehearser) setup(candidate rehearse.RehearsalCandidate, path string, jobs *affectedJobs, limit int, logger *logrus.Entry) ([]string, func() error, error) {
+	loggers := rehearse.Loggers{Job: logger, Debug: logger}
+	prConfig, prRefs, imageStreamTags, presubmits, _, err := r.rc.SetupJobs(candidate, path, jobs.presubmits, jobs.periodics, jobs.templates, jobs.profiles, limit, loggers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not set up rehearsals: %w", err)
+	}
+	if len(presubmits) == 0 {
+		return nil, nil, nil
+	}
+	if err := prConfig.Prow.ValidateJobConfig(); err != nil {
+		return nil, nil, fmt.Errorf("rehearsals are invalid: %w", err)
+	}
+	var names []string
+	for _, job := range presubmits {
+		names = append(names, job.Name)
+	}
+	sort.Strings(names)
+	return names, func() error {
+		_, err := r.rc.RehearseJobs(candidate, path, prConfig, prRefs, imageStreamTags, presubmits, jobs.templates, jobs.profiles, loggers)
+		return err
+	}, nil
+}
+
+// prState is what the plugin remembers about a pull request
+type prState struct {
+	// sha is the revision of the pull request the state is valid for
+	sha string
+	// base is the revision of the base branch the pull request was merged into
+	base string
+	// affected caches the jobs affected by the revision, as determining them is
+	// expensive and several commands may be issued for the same revision
+	affected *affectedJobs
+}
+
+type server struct {
+	ghc            githubClient
+	pjclient       ctrlruntimeclient.Client
+	namespace      string
+	trustedChecker trustedChecker
+	rehearser      rehearser
+	// checkout prepares a working copy of the pull request at the head revision
+	// merged into the base revision and returns its path and a function cleaning
+	// it up
+	checkout func(org, repo string, number int, baseSHA, headSHA string) (string, func(), error)
+	limits   map[commandKind]int
+
+	lock sync.Mutex
+	prs  map[string]*prState
+	// wg tracks running rehearsals, allowing tests to wait for them
+	wg sync.WaitGroup
+}
+
+func prKey(org, repo string, number int) string {
+	return fmt.Sprintf("%s/%s#%d", org, repo, number)
+}
+
+// handlePullRequest forgets the state of pull requests that were updated, as the
+// jobs affected by them and any acknowledgement no longer apply
+func (s *server) handlePullRequest(l *logrus.Entry, pre github.PullRequestEvent) {
+	if pre.Action != github.PullRequestActionSynchronize && pre.Action != github.PullRequestActionClosed {
+		return
+	}
+	org, repo, number := pre.Repo.Owner.Login, pre.Repo.Name, pre.Number
+	s.lock.Lock()
+	delete(s.prs, prKey(org, repo, number))
+	s.lock.Unlock()
+
+	if pre.Action == github.PullRequestActionSynchronize && github.HasLabel(ackLabel, pre.PullRequest.Labels) {
+		if err := s.ghc.RemoveLabel(org, repo, number, ackLabel); err != nil {
+			l.WithError(err).Warn("failed to remove the acknowledgement of rehearsals")
+		}
+	}
+}
+
+func (s *server) handleIssueComment(l *logrus.Entry, ic github.IssueCommentEvent) {
+	if comment := s.handle(l, ic); comment != "" {
+		if err := s.ghc.CreateComment(ic.Repo.Owner.Login, ic.Repo.Name, ic.Issue.Number, fmt.Sprintf("@%s: %s", ic.Comment.User.Login, comment)); err != nil {
+			l.WithError(err).Error("failed to create a comment")
+		}
+	}
+}
+
+func (s *server) handle(l *logrus.Entry, ic github.IssueCommentEvent) string {
+	org, repo, number := ic.Repo.Owner.Login, ic.Repo.Name, ic.Issue.Number
+	logger := l.WithFields(logrus.Fields{
+		github.OrgLogField:  org,
+		github.RepoLogField: repo,
+		github.PrLogField:   number,
+		github.EventGUID:    ic.GUID,
+	})
+	if !ic.Issue.IsPullRequest() || ic.Action != github.IssueCommentActionCreated {
+		return ""
+	}
+	commands := commandsFromComment(ic.Comment.Body)
+	if len(commands) == 0 {
+		return ""
+	}
+
+	trusted, err := s.trustedChecker.trustedUser(ic.Comment.User.Login, org, repo, number)
+	if err != nil {
+		logger.WithError(err).Error("could not check if the user is trusted")
+		return fmt.Sprintf("could not check if you are trusted: %v", err)
+	}
+	if !trusted {
+		return fmt.Sprintf("user %s is not trusted for pull request %s", ic.Comment.User.Login, prKey(org, repo, number))
+	}
+
+	var messages []string
+	for _, cmd := range commands {
+		messages = append(messages, s.execute(logger.WithField("command", cmd.kind), org, repo, number, cmd))
+	}
+	return strings.Join(messages, "\n\n")
+}
+
+func (s *server) execute(logger *logrus.Entry, org, repo string, number int, cmd command) string {
+	switch cmd.kind {
+	case commandSkip:
+		if err := s.ghc.AddLabel(org, repo, number, ackLabel); err != nil {
+			logger.WithError(err).Error("failed to acknowledge rehearsals")
+			return fmt.Sprintf("could not acknowledge rehearsals: %v", err)
+		}
+		return fmt.Sprintf("rehearsals of this revision are acknowledged as unnecessary with the `%s` label. Pushing a new revision removes it.", ackLabel)
+	case commandAbort:
+		aborted, err := s.abort(number)
+		if err != nil {
+			logger.WithError(err).Error("failed to abort rehearsals")
+			return fmt.Sprintf("could not abort rehearsals: %v", err)
+		}
+		if len(aborted) == 0 {
+			return "there are no running rehearsals to abort"
+		}
+		return fmt.Sprintf("aborted %d rehearsals: %s", len(aborted), formatJobs(aborted))
+	}
+
+	pr, err := s.ghc.GetPullRequest(org, repo, number)
+	if err != nil {
+		logger.WithError(err).Error("could not get pull request")
+		return fmt.Sprintf("could not get the pull request: %v", err)
+	}
+	baseSHA, err := s.ghc.GetRef(org, repo, "heads/"+pr.Base.Ref)
+	if err != nil {
+		logger.WithError(err).Error("could not get the base revision")
+		return fmt.Sprintf("could not get the revision of %s: %v", pr.Base.Ref, err)
+	}
+	candidate := rehearse.RehearsalCandidateFromPullRequest(pr, baseSHA)
+	path, cleanup, err := s.checkout(org, repo, number, baseSHA, pr.Head.SHA)
+	if err != nil {
+		logger.WithError(err).Error("could not check out the pull request")
+		return fmt.Sprintf("could not check out the pull request: %v", err)
+	}
+
+	affected, err := s.affected(logger, candidate, pr.Head.SHA, baseSHA, path, prKey(org, repo, number))
+	if err != nil {
+		cleanup()
+		logger.WithError(err).Error("could not determine affected jobs")
+		return fmt.Sprintf("could not determine the jobs affected by this pull request: %v", err)
+	}
+
+	all := affected.names()
+	requested, limit := all, s.limits[commandMax]
+	switch cmd.kind {
+	case commandRehearse, commandMore, commandMax:
+		limit = s.limits[cmd.kind]
+	case commandFailed:
+		failed, err := s.failed(number, pr.Head.SHA)
+		if err != nil {
+			cleanup()
+			logger.WithError(err).Error("could not determine failed rehearsals")
+			return fmt.Sprintf("could not determine which rehearsals failed: %v", err)
+		}
+		requested = all.Intersection(failed)
+	case commandJobs:
+		requested = sets.NewString(cmd.jobs...)
+		if unknown := requested.Difference(all); unknown.Len() > 0 {
+			cleanup()
+			return fmt.Sprintf("the following jobs are not affected by this pull request and cannot be rehearsed: %s", formatJobs(unknown.List()))
+		}
+	}
+	if requested.Len() == 0 {
+		cleanup()
+		return "there are no jobs to rehearse"
+	}
+
+	names, run, err := s.rehearser.setup(candidate, path, affected.subset(requested), limit, logger)
+	if err != nil {
+		cleanup()
+		logger.WithError(err).Error("could not set up rehearsals")
+		return fmt.Sprintf("could not set up rehearsals: %v", err)
+	}
+	if len(names) == 0 {
+		cleanup()
+		return "there are no jobs to rehearse"
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cleanup()
+		if err := run(); err != nil {
+			logger.WithError(err).Info("rehearsals failed")
+		}
+	}()
+	message := fmt.Sprintf("rehearsing %d of %d affected jobs: %s", len(names), all.Len(), formatJobs(names))
+	if remaining := all.Len() - len(names); remaining > 0 && cmd.kind != commandJobs && cmd.kind != commandFailed {
+		message += fmt.Sprintf("\n\n%d more jobs are affected, use `/pj-rehearse more`, `/pj-rehearse max` or `/pj-rehearse <job>` to rehearse them.", remaining)
+	}
+	return message
+}
+
+// affected returns the jobs affected by the revision of the pull request, using
+// the cached result if neither the revision nor the base revision it was merged
+// into changed since it was determined
+func (s *server) affected(logger *logrus.Entry, candidate rehearse.RehearsalCandidate, sha, base, path, key string) (*affectedJobs, error) {
+	s.lock.Lock()
+	state, ok := s.prs[key]
+	s.lock.Unlock()
+	if ok && state.sha == sha && state.base == base {
+		return state.affected, nil
+	}
+	affected, err := s.rehearser.determineAffected(candidate, path, logger)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.prs[key] = &prState{sha: sha, base: base, affected: affected}
+	s.lock.Unlock()
+	return affected, nil
+}
+
+func (s *server) rehearsals(number int) ([]pjapi.ProwJob, error) {
+	list := &pjapi.ProwJobList{}
+	if err := s.pjclient.List(context.Background(), list, ctrlruntimeclient.MatchingLabels{rehearse.Label: strconv.Itoa(number)}, ctrlruntimeclient.InNamespace(s.namespace)); err != nil {
+		return nil, fmt.Errorf("could not list rehearsals: %w", err)
+	}
+	return list.Items, nil
+}
+
+// failed returns the names of the jobs whose latest rehearsal for the revision
+// of the pull request failed
+func (s *server) failed(number int, sha string) (sets.String, error) {
+	rehearsals, err := s.rehearsals(number)
+	if err != nil {
+		return nil, err
+	}
+	latest := map[string]pjapi.ProwJob{}
+	for _, pj := range rehearsals {
+		if pj.Spec.Refs == nil || len(pj.Spec.Refs.Pulls) == 0 || pj.Spec.Refs.Pulls[0].SHA != sha {
+			continue
+		}
+		if current, ok := latest[pj.Spec.Job]; !ok || current.Status.StartTime.Before(&pj.Status.StartTime) {
+			latest[pj.Spec.Job] = pj
+		}
+	}
+	failed := sets.NewString()
+	prefix := fmt.Sprintf("rehearse-%d-", number)
+	for job, pj := range latest {
+		switch pj.Status.State {
+		case pjapi.FailureState, pjapi.ErrorState, pjapi.AbortedState:
+			failed.Insert(strings.TrimPrefix(job, prefix))
+		}
+	}
+	return failed, nil
+}
+
+// abort aborts all rehearsals of the pull request that did not complete yet, the
+// same way Prow aborts jobs superseded by newer ones
+func (s *server) abort(number int) ([]string, error) {
+	rehearsals, err := s.rehearsals(number)
+	if err != nil {
+		return nil, err
+	}
+	var aborted []string
+	for i := range rehearsals {
+		pj := &rehearsals[i]
+		if pj.Complete() {
+			continue
+		}
+		original := pj.DeepCopy()
+		pj.SetComplete()
+		pj.Status.State = pjapi.AbortedState
+		pj.Status.Description = "Aborted by /pj-rehearse abort."
+		if err := s.pjclient.Patch(context.Background(), pj, ctrlruntimeclient.MergeFrom(original)); err != nil {
+			return aborted, fmt.Errorf("could not abort %s: %w", pj.Name, err)
+		}
+		aborted = append(aborted, pj.Spec.Job)
+	}
+	sort.Strings(aborted)
+	return aborted, nil
+}
+
+func formatJobs(jobs []string) string {
+	var formatted []string
+	for _, job := range jobs {
+		formatted = append(formatted, fmt.Sprintf("`%s`", job))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/rehearse"
+)
+
+func init() {
+	if err := pjapi.AddToScheme(fakectrlruntimeclient.NewClientBuilder().Build().Scheme()); err != nil {
+		panic(err)
+	}
+}
+
+func TestCommandsFromComment(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		comment  string
+		expected []command
+	}{
+		{
+			name:    "no command",
+			comment: "this looks good, /pj-rehearse later",
+		},
+		{
+			name:     "plain command",
+			comment:  "/pj-rehearse",
+			expected: []command{{kind: commandRehearse}},
+		},
+		{
+			name:     "keywords",
+			comment:  "/pj-rehearse more\r\n/pj-rehearse skip \n/pj-rehearse abort",
+			expected: []command{{kind: commandMore}, {kind: commandSkip}, {kind: commandAbort}},
+		},
+		{
+			name:     "jobs",
+			comment:  "please\n/pj-rehearse pull-ci-org-repo-master-unit periodic-ci-org-repo-master-e2e\nthanks",
+			expected: []command{{kind: commandJobs, jobs: []string{"pull-ci-org-repo-master-unit", "periodic-ci-org-repo-master-e2e"}}},
+		},
+		{
+			name:    "other command with the same prefix",
+			comment: "/pj-rehearse-all",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, commandsFromComment(tc.comment), cmp.AllowUnexported(command{})); diff != "" {
+				t.Errorf("commands differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+type fakeGitHubClient struct {
+	comments []string
+	labels   sets.String
+}
+
+func (f *fakeGitHubClient) CreateComment(_, _ string, _ int, comment string) error {
+	f.comments = append(f.comments, comment)
+	return nil
+}
+
+func (f *fakeGitHubClient) GetPullRequest(org, repo string, number int) (*github.PullRequest, error) {
+	return &github.PullRequest{
+		Number: number,
+		Base:   github.PullRequestBranch{Ref: "master", Repo: github.Repo{Name: repo, Owner: github.User{Login: org}}},
+		Head:   github.PullRequestBranch{SHA: "head"},
+	}, nil
+}
+
+func (f *fakeGitHubClient) GetRef(_, _, _ string) (string, error) {
+	return "base", nil
+}
+
+func (f *fakeGitHubClient) AddLabel(_, _ string, _ int, label string) error {
+	f.labels.Insert(label)
+	return nil
+}
+
+func (f *fakeGitHubClient) RemoveLabel(_, _ string, _ int, label string) error {
+	f.labels.Delete(label)
+	return nil
+}
+
+type fakeTrustedChecker struct{}
+
+func (fakeTrustedChecker) trustedUser(author, _, _ string, _ int) (bool, error) {
+	return author == "trusted", nil
+}
+
+type fakeRehearser struct {
+	determined int
+	// rehearsed are the names of the jobs set up to be rehearsed, along with the limit
+	rehearsed [][]string
+	limits    []int
+}
+
+func (f *fakeRehearser) determineAffected(_ rehearse.RehearsalCandidate, _ string, _ *logrus.Entry) (*affectedJobs, error) {
+	f.determined++
+	return &affectedJobs{
+		presubmits: config.Presubmits{"org/repo": {
+			{JobBase: prowconfig.JobBase{Name: "pull-ci-org-repo-master-e2e"}},
+			{JobBase: prowconfig.JobBase{Name: "pull-ci-org-repo-master-unit"}},
+		}},
+		periodics: config.Periodics{"periodic-ci-org-repo-master-e2e": {JobBase: prowconfig.JobBase{Name: "periodic-ci-org-repo-master-e2e"}}},
+	}, nil
+}
+
+func (f *fakeRehearser) setup(_ rehearse.RehearsalCandidate, _ string, jobs *affectedJobs, limit int, _ *logrus.Entry) ([]string, func() error, error) {
+	names := jobs.names().List()
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	f.rehearsed = append(f.rehearsed, names)
+	f.limits = append(f.limits, limit)
+	return names, func() error { return errors.New("some rehearsals failed") }, nil
+}
+
+func TestHandle(t *testing.T) {
+	rehearsal := func(name, job, sha string, state pjapi.ProwJobState) ctrlruntimeclient.Object {
+		pj := &pjapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ci", Labels: map[string]string{rehearse.Label: "123"}},
+			Spec:       pjapi.ProwJobSpec{Job: "rehearse-123-" + job, Refs: &pjapi.Refs{Pulls: []pjapi.Pull{{Number: 123, SHA: sha}}}},
+			Status:     pjapi.ProwJobStatus{State: state},
+		}
+		if state != pjapi.PendingState {
+			pj.SetComplete()
+		}
+		return pj
+	}
+
+	var testCases = []struct {
+		name              string
+		comments          []string
+		user              string
+		expectedComments  []string
+		expectedRehearsed [][]string
+		expectedLimits    []int
+		expectedLabels    sets.String
+		expectedAborted   sets.String
+	}{
+		{
+			name:             "untrusted user",
+			comments:         []string{"/pj-rehearse"},
+			user:             "someone",
+			expectedComments: []string{"@someone: user someone is not trusted for pull request openshift/release#123"},
+		},
+		{
+			name:     "rehearsals are limited, affected jobs are determined once per revision",
+			comments: []string{"/pj-rehearse", "/pj-rehearse more"},
+			expectedComments: []string{
+				"@trusted: rehearsing 1 of 3 affected jobs: `periodic-ci-org-repo-master-e2e`\n\n2 more jobs are affected, use `/pj-rehearse more`, `/pj-rehearse max` or `/pj-rehearse <job>` to rehearse them.",
+				"@trusted: rehearsing 2 of 3 affected jobs: `periodic-ci-org-repo-master-e2e`, `pull-ci-org-repo-master-e2e`\n\n1 more jobs are affected, use `/pj-rehearse more`, `/pj-rehearse max` or `/pj-rehearse <job>` to rehearse them.",
+			},
+			expectedRehearsed: [][]string{{"periodic-ci-org-repo-master-e2e"}, {"periodic-ci-org-repo-master-e2e", "pull-ci-org-repo-master-e2e"}},
+			expectedLimits:    []int{1, 2},
+		},
+		{
+			name:              "specific jobs",
+			comments:          []string{"/pj-rehearse pull-ci-org-repo-master-unit"},
+			expectedComments:  []string{"@trusted: rehearsing 1 of 3 affected jobs: `pull-ci-org-repo-master-unit`"},
+			expectedRehearsed: [][]string{{"pull-ci-org-repo-master-unit"}},
+			expectedLimits:    []int{3},
+		},
+		{
+			name:             "jobs that are not affected",
+			comments:         []string{"/pj-rehearse pull-ci-org-repo-master-unit pull-ci-org-repo-master-lint"},
+			expectedComments: []string{"@trusted: the following jobs are not affected by this pull request and cannot be rehearsed: `pull-ci-org-repo-master-lint`"},
+		},
+		{
+			name:              "failed rehearsals of the current revision",
+			comments:          []string{"/pj-rehearse failed"},
+			expectedComments:  []string{"@trusted: rehearsing 1 of 3 affected jobs: `pull-ci-org-repo-master-e2e`"},
+			expectedRehearsed: [][]string{{"pull-ci-org-repo-master-e2e"}},
+			expectedLimits:    []int{3},
+		},
+		{
+			name:             "skip",
+			comments:         []string{"/pj-rehearse skip"},
+			expectedComments: []string{"@trusted: rehearsals of this revision are acknowledged as unnecessary with the `rehearsals-ack` label. Pushing a new revision removes it."},
+			expectedLabels:   sets.NewString(ackLabel),
+		},
+		{
+			name:             "abort",
+			comments:         []string{"/pj-rehearse abort"},
+			expectedComments: []string{"@trusted: aborted 1 rehearsals: `rehearse-123-pull-ci-org-repo-master-unit`"},
+			expectedAborted:  sets.NewString("running"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghc := &fakeGitHubClient{labels: sets.NewString()}
+			r := &fakeRehearser{}
+			pjclient := fakectrlruntimeclient.NewClientBuilder().WithObjects(
+				rehearsal("old", "pull-ci-org-repo-master-unit", "old", pjapi.FailureState),
+				rehearsal("failed", "pull-ci-org-repo-master-e2e", "head", pjapi.FailureState),
+				rehearsal("passed", "periodic-ci-org-repo-master-e2e", "head", pjapi.SuccessState),
+				rehearsal("running", "pull-ci-org-repo-master-unit", "head", pjapi.PendingState),
+			).Build()
+			s := &server{
+				ghc:            ghc,
+				pjclient:       pjclient,
+				namespace:      "ci",
+				trustedChecker: fakeTrustedChecker{},
+				rehearser:      r,
+				checkout: func(_, _ string, _ int, baseSHA, headSHA string) (string, func(), error) {
+					if baseSHA != "base" || headSHA != "head" {
+						t.Errorf("expected head to be merged into base, got %s into %s", headSHA, baseSHA)
+					}
+					return "/tmp/release", func() {}, nil
+				},
+				limits: map[commandKind]int{commandRehearse: 1, commandMore: 2, commandMax: 3},
+				prs:    map[string]*prState{},
+			}
+			user := tc.user
+			if user == "" {
+				user = "trusted"
+			}
+			for _, comment := range tc.comments {
+				s.handleIssueComment(logrus.NewEntry(logrus.StandardLogger()), github.IssueCommentEvent{
+					Action:  github.IssueCommentActionCreated,
+					Issue:   github.Issue{Number: 123, PullRequest: &struct{}{}},
+					Comment: github.IssueComment{Body: comment, User: github.User{Login: user}},
+					Repo:    github.Repo{Name: "release", Owner: github.User{Login: "openshift"}},
+				})
+			}
+			s.wg.Wait()
+
+			if diff := cmp.Diff(tc.expectedComments, ghc.comments); diff != "" {
+				t.Errorf("comments differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRehearsed, r.rehearsed); diff != "" {
+				t.Errorf("rehearsed jobs differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedLimits, r.limits); diff != "" {
+				t.Errorf("limits differ from expected: %s", diff)
+			}
+			if r.determined > 1 {
+				t.Errorf("expected affected jobs to be determined at most once, got %d", r.determined)
+			}
+			if tc.expectedLabels == nil {
+				tc.expectedLabels = sets.NewString()
+			}
+			if diff := cmp.Diff(tc.expectedLabels, ghc.labels); diff != "" {
+				t.Errorf("labels differ from expected: %s", diff)
+			}
+			if tc.expectedAborted == nil {
+				tc.expectedAborted = sets.NewString()
+			}
+			list := &pjapi.ProwJobList{}
+			if err := pjclient.List(context.Background(), list); err != nil {
+				t.Fatalf("failed to list ProwJobs: %v", err)
+			}
+			aborted := sets.NewString()
+			for _, pj := range list.Items {
+				if pj.Status.State == pjapi.AbortedState {
+					aborted.Insert(pj.Name)
+				}
+			}
+			if diff := cmp.Diff(tc.expectedAborted, aborted); diff != "" {
+				t.Errorf("aborted rehearsals differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestHandlePullRequest(t *testing.T) {
+	ghc := &fakeGitHubClient{labels: sets.NewString(ackLabel)}
+	s := &server{ghc: ghc, prs: map[string]*prState{"openshift/release#123": {sha: "old"}, "openshift/release#456": {sha: "other"}}}
+	s.handlePullRequest(logrus.NewEntry(logrus.StandardLogger()), github.PullRequestEvent{
+		Action:      github.PullRequestActionSynchronize,
+		Number:      123,
+		PullRequest: github.PullRequest{Labels: []github.Label{{Name: ackLabel}}},
+		Repo:        github.Repo{Name: "release", Owner: github.User{Login: "openshift"}},
+	})
+	if diff := cmp.Diff(sets.NewString("openshift/release#456"), sets.StringKeySet(s.prs)); diff != "" {
+		t.Errorf("remembered pull requests differ from expected: %s", diff)
+	}
+	if ghc.labels.Has(ackLabel) {
+		t.Error("expected the acknowledgement to be removed")
+	}
+}
+
+func TestAffectedIsCachedForRevisions(t *testing.T) {
+	r := &fakeRehearser{}
+	s := &server{rehearser: r, prs: map[string]*prState{}}
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	for _, revisions := range [][2]string{{"head", "base"}, {"head", "base"}, {"head", "newer-base"}, {"newer-head", "newer-base"}} {
+		if _, err := s.affected(logger, rehearse.RehearsalCandidate{}, revisions[0], revisions[1], "/tmp/release", "openshift/release#123"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if r.determined != 3 {
+		t.Errorf("expected affected jobs to be determined once per pair of revisions, got %d times", r.determined)
+	}
+}
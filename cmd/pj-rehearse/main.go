@@ -1,20 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"unicode/utf8"
 
+	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/api/option"
 
 	"k8s.io/client-go/kubernetes/scheme"
 	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config/secret"
 	"k8s.io/test-infra/prow/flagutil"
 	prowgithub "k8s.io/test-infra/prow/github"
 	pjdwapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
 
 	imagev1 "github.com/openshift/api/image/v1"
 
+	"github.com/openshift/ci-tools/pkg/config"
 	"github.com/openshift/ci-tools/pkg/rehearse"
 )
 
@@ -29,6 +36,18 @@ type options struct {
 
 	releaseRepoPath string
 	rehearsalLimit  int
+	passRatesPath   string
+
+	postRegistryDiff bool
+	postSelection    bool
+	github           flagutil.GitHubOptions
+
+	postSummary        bool
+	gcsCredentialsFile string
+	baselineRuns       int
+
+	ciOperatorImage    string
+	representativeJobs int
 }
 
 func gatherOptions() (options, error) {
@@ -46,6 +65,15 @@ func gatherOptions() (options, error) {
 	fs.BoolVar(&o.noClusterProfiles, "no-cluster-profiles", false, "If true, do not attempt to compare cluster profiles")
 
 	fs.IntVar(&o.rehearsalLimit, "rehearsal-limit", 35, "Upper limit of jobs attempted to rehearse (if more jobs are being touched, only this many will be rehearsed)")
+	fs.StringVar(&o.passRatesPath, "pass-rates", "", "Path to a JSON file mapping job names to their historical pass rates, used to prefer reliable jobs when selecting a subset to rehearse")
+	fs.BoolVar(&o.postRegistryDiff, "post-registry-diff", false, "If true, post a summary of the changes to resolved test configurations caused by step registry changes on the pull request")
+	fs.BoolVar(&o.postSelection, "post-selection", false, "If true, post the jobs selected for rehearsal and the rationale for the selection on the pull request when more jobs are affected than the limit")
+	fs.BoolVar(&o.postSummary, "post-summary", false, "If true, post a summary of the failures of the rehearsals on the pull request, compared with recent runs of the rehearsed jobs")
+	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "File where GCS credentials are stored, used to read the results of jobs for --post-summary")
+	fs.IntVar(&o.baselineRuns, "baseline-runs", 5, "How many recent runs of a rehearsed job to compare the failures of its rehearsal with")
+	fs.StringVar(&o.ciOperatorImage, "ci-operator-image", "", "Pull spec of a ci-operator image built from a ci-tools pull request. When set, representative production jobs are rehearsed with this image instead of jobs affected by a release repo change")
+	fs.IntVar(&o.representativeJobs, "representative-jobs", 2, "How many jobs of every kind (container, multi-stage and template tests, image builds of promoting configurations, bundles) to rehearse with --ci-operator-image")
+	o.github.AddFlags(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return o, fmt.Errorf("failed to parse flags: %w", err)
@@ -57,18 +85,176 @@ func validateOptions(o options) error {
 	if len(o.releaseRepoPath) == 0 {
 		return fmt.Errorf("--candidate-path was not provided")
 	}
+	if o.postRegistryDiff && o.noRegistry {
+		return fmt.Errorf("--post-registry-diff and --no-registry are mutually exclusive")
+	}
+	if o.ciOperatorImage != "" {
+		if o.postRegistryDiff {
+			return fmt.Errorf("--post-registry-diff and --ci-operator-image are mutually exclusive")
+		}
+		if o.representativeJobs < 1 {
+			return fmt.Errorf("--representative-jobs must be positive")
+		}
+	}
+	if o.postSummary {
+		if o.dryRun {
+			return fmt.Errorf("--post-summary requires --dry-run=false, as no rehearsals are run otherwise")
+		}
+		if o.gcsCredentialsFile == "" {
+			return fmt.Errorf("--post-summary requires --gcs-credentials-file")
+		}
+		if o.baselineRuns < 1 {
+			return fmt.Errorf("--baseline-runs must be positive")
+		}
+	}
+	if o.postRegistryDiff || o.postSelection || o.postSummary {
+		if err := o.github.Validate(o.dryRun); err != nil {
+			return err
+		}
+	}
 	return o.kubernetesOptions.Validate(o.dryRun)
 }
 
-func rehearsalConfigFromOptions(o options) rehearse.RehearsalConfig {
+// postRegistryDiff comments on the pull request with a summary of the changes
+// to the resolved configuration of tests
+func postRegistryDiff(o options, rc rehearse.RehearsalConfig, candidate rehearse.RehearsalCandidate, org, repo string, prNumber int, logger *logrus.Entry) error {
+	summary, err := rc.DiffResolvedConfigs(candidate, o.releaseRepoPath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to compare resolved configs: %w", err)
+	}
+	client, err := githubClient(o)
+	if err != nil {
+		return err
+	}
+	return upsertComment(client, org, repo, prNumber, registryDiffMarker, fmt.Sprintf("[REHEARSALNOTIFIER] Step registry changes:\n\n%s", summary))
+}
+
+// postSelection comments on the pull request with the jobs selected for
+// rehearsal and the reasons they were chosen
+func postSelection(o options, selection rehearse.Selection, org, repo string, prNumber int) error {
+	summary := selection.Summary()
+	if summary == "" {
+		return nil
+	}
+	client, err := githubClient(o)
+	if err != nil {
+		return err
+	}
+	return upsertComment(client, org, repo, prNumber, selectionMarker, fmt.Sprintf("[REHEARSALNOTIFIER] Selected rehearsals:\n\n%s", summary))
+}
+
+// postRepresentativeJobs comments on the pull request with the production jobs
+// selected to rehearse a ci-operator build with
+func postRepresentativeJobs(o options, names map[rehearse.JobKind][]string, org, repo string, prNumber int) error {
+	client, err := githubClient(o)
+	if err != nil {
+		return err
+	}
+	return upsertComment(client, org, repo, prNumber, representativeJobsMarker, fmt.Sprintf("[REHEARSALNOTIFIER] Rehearsing representative jobs with `%s`:\n\n%s", o.ciOperatorImage, rehearse.RepresentativeJobsSummary(names)))
+}
+
+// postSummary comments on the pull request with the failures of the rehearsals,
+// classified by comparing them to recent runs of the rehearsed jobs
+func postSummary(o options, rc rehearse.RehearsalConfig, candidate rehearse.RehearsalCandidate, namespace, org, repo string, prNumber int) error {
+	gcsClient, err := storage.NewClient(context.Background(), option.WithCredentialsFile(o.gcsCredentialsFile))
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	defer gcsClient.Close()
+	summary, err := rc.SummarizeRehearsals(candidate, namespace, rehearse.NewGCSResultsFetcher(gcsClient), o.baselineRuns)
+	if err != nil {
+		return fmt.Errorf("failed to summarize rehearsals: %w", err)
+	}
+	client, err := githubClient(o)
+	if err != nil {
+		return err
+	}
+	return upsertComment(client, org, repo, prNumber, summaryMarker, fmt.Sprintf("[REHEARSALNOTIFIER] Rehearsal results:\n\n%s", summary))
+}
+
+func githubClient(o options) (prowgithub.Client, error) {
+	if err := secret.Add(o.github.TokenPath); err != nil {
+		return nil, fmt.Errorf("failed to start secrets agent: %w", err)
+	}
+	client, err := o.github.GitHubClient(o.dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub client: %w", err)
+	}
+	return client, nil
+}
+
+const (
+	// maxCommentLength is the maximum size of a comment GitHub accepts
+	maxCommentLength = 65536
+
+	registryDiffMarker       = "<!-- pj-rehearse: registry-diff -->"
+	selectionMarker          = "<!-- pj-rehearse: selection -->"
+	summaryMarker            = "<!-- pj-rehearse: summary -->"
+	representativeJobsMarker = "<!-- pj-rehearse: representative-jobs -->"
+)
+
+type commentClient interface {
+	BotUserChecker() (func(candidate string) bool, error)
+	ListIssueComments(org, repo string, number int) ([]prowgithub.IssueComment, error)
+	CreateComment(org, repo string, number int, comment string) error
+	EditComment(org, repo string, id int, comment string) error
+}
+
+// upsertComment keeps a single comment identified by the marker on the pull
+// request: an earlier comment of the bot is edited instead of posting a new
+// one, and nothing is posted when its content did not change
+func upsertComment(client commentClient, org, repo string, prNumber int, marker, body string) error {
+	body = truncateComment(marker + "\n" + body)
+	isBot, err := client.BotUserChecker()
+	if err != nil {
+		return fmt.Errorf("failed to get the bot user: %w", err)
+	}
+	comments, err := client.ListIssueComments(org, repo, prNumber)
+	if err != nil {
+		return fmt.Errorf("failed to list comments: %w", err)
+	}
+	for _, c := range comments {
+		if !isBot(c.User.Login) || !strings.Contains(c.Body, marker) {
+			continue
+		}
+		if c.Body == body {
+			return nil
+		}
+		return client.EditComment(org, repo, c.ID, body)
+	}
+	return client.CreateComment(org, repo, prNumber, body)
+}
+
+func truncateComment(body string) string {
+	if len(body) <= maxCommentLength {
+		return body
+	}
+	const suffix = "\n\n...(truncated)"
+	cut := maxCommentLength - len(suffix)
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return body[:cut] + suffix
+}
+
+func rehearsalConfigFromOptions(o options) (rehearse.RehearsalConfig, error) {
+	var passRates rehearse.PassRates
+	if o.passRatesPath != "" {
+		var err error
+		if passRates, err = rehearse.LoadPassRates(o.passRatesPath); err != nil {
+			return rehearse.RehearsalConfig{}, err
+		}
+	}
 	return rehearse.RehearsalConfig{
 		ProwjobKubeconfig: o.prowjobKubeconfig,
 		KubernetesOptions: o.kubernetesOptions,
 		NoTemplates:       o.noTemplates,
 		NoRegistry:        o.noRegistry,
 		NoClusterProfiles: o.noClusterProfiles,
+		PassRates:         passRates,
+		CIOperatorImage:   o.ciOperatorImage,
 		DryRun:            o.dryRun,
-	}
+	}, nil
 }
 
 const (
@@ -127,11 +313,41 @@ func rehearseMain() error {
 	org, repo, prNumber := jobSpec.Refs.Org, jobSpec.Refs.Repo, pr.Number
 	logger.Infof("Rehearsing Prow jobs for configuration PR %s/%s#%d", org, repo, prNumber)
 
-	rc := rehearsalConfigFromOptions(o)
-	candidate := rehearse.RehearsalCandidateFromJobSpec(jobSpec)
-	presubmits, periodics, changedTemplates, changedClusterProfiles, err := rc.DetermineAffectedJobs(candidate, o.releaseRepoPath, logger)
+	rc, err := rehearsalConfigFromOptions(o)
 	if err != nil {
-		return fmt.Errorf("error determining affected jobs: %w: %s", err, misconfigurationOutput)
+		logger.WithError(err).Error("could not load the rehearsal configuration")
+		return fmt.Errorf(misconfigurationOutput)
+	}
+	candidate := rehearse.RehearsalCandidateFromJobSpec(jobSpec)
+	var presubmits config.Presubmits
+	var periodics config.Periodics
+	var changedTemplates, changedClusterProfiles *rehearse.ConfigMaps
+	if o.ciOperatorImage != "" {
+		// The pull request changes ci-operator itself, so the release repo is
+		// unchanged and production jobs are rehearsed with the new build
+		logger.Infof("Rehearsing representative jobs with ci-operator image %s", o.ciOperatorImage)
+		var representative map[rehearse.JobKind][]string
+		presubmits, representative, err = rc.DetermineRepresentativeJobs(o.releaseRepoPath, o.representativeJobs, logger)
+		if err != nil {
+			return fmt.Errorf("error determining representative jobs: %w: %s", err, misconfigurationOutput)
+		}
+		changedTemplates, changedClusterProfiles = &rehearse.ConfigMaps{}, &rehearse.ConfigMaps{}
+		if o.postSelection {
+			if err := postRepresentativeJobs(o, representative, org, repo, prNumber); err != nil {
+				logger.WithError(err).Warn("Failed to post the representative jobs")
+			}
+		}
+	} else {
+		presubmits, periodics, changedTemplates, changedClusterProfiles, err = rc.DetermineAffectedJobs(candidate, o.releaseRepoPath, logger)
+		if err != nil {
+			return fmt.Errorf("error determining affected jobs: %w: %s", err, misconfigurationOutput)
+		}
+	}
+	if o.postRegistryDiff {
+		if err := postRegistryDiff(o, rc, candidate, org, repo, prNumber, logger); err != nil {
+			// the summary is informational, failing to post it should not fail the rehearsal
+			logger.WithError(err).Warn("Failed to post the summary of resolved config changes")
+		}
 	}
 	if len(presubmits) == 0 && len(periodics) == 0 {
 		// Nothing to rehearse
@@ -151,16 +367,28 @@ func rehearseMain() error {
 	}
 	loggers := rehearse.Loggers{Job: logger, Debug: debugLogger.WithField(prowgithub.PrLogField, prNumber)}
 
-	prConfig, prRefs, imageStreamTags, presubmitsToRehearse, err := rc.SetupJobs(candidate, o.releaseRepoPath, presubmits, periodics, changedTemplates, changedClusterProfiles, o.rehearsalLimit, loggers)
+	prConfig, prRefs, imageStreamTags, presubmitsToRehearse, selection, err := rc.SetupJobs(candidate, o.releaseRepoPath, presubmits, periodics, changedTemplates, changedClusterProfiles, o.rehearsalLimit, loggers)
 	if err != nil {
 		return fmt.Errorf("error setting up jobs: %w: %s", err, failedSetupOutput)
 	}
+	if o.postSelection {
+		if err := postSelection(o, selection, org, repo, prNumber); err != nil {
+			// the rationale is informational, failing to post it should not fail the rehearsal
+			logger.WithError(err).Warn("Failed to post the selected rehearsals")
+		}
+	}
 
 	if err := prConfig.Prow.ValidateJobConfig(); err != nil {
 		return fmt.Errorf("%s: %w", jobValidationOutput, err)
 	}
 
 	jobsTriggered, err := rc.RehearseJobs(candidate, o.releaseRepoPath, prConfig, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, loggers)
+	if jobsTriggered && o.postSummary {
+		if err := postSummary(o, rc, candidate, prConfig.Prow.ProwJobNamespace, org, repo, prNumber); err != nil {
+			// the summary is informational, failing to post it should not change the result of the rehearsal
+			logger.WithError(err).Warn("Failed to post the summary of rehearsal results")
+		}
+	}
 	if err != nil {
 		if jobsTriggered {
 			return fmt.Errorf(jobsFailureOutput)
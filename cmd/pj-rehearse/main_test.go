@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestUpsertComment(t *testing.T) {
+	const marker = "<!-- marker -->"
+	testCases := []struct {
+		name         string
+		comments     []github.IssueComment
+		expectAdded  int
+		expectEdited int
+	}{
+		{
+			name:        "no earlier comment creates one",
+			expectAdded: 1,
+		},
+		{
+			name: "comment of another user with the marker is ignored",
+			comments: []github.IssueComment{
+				{ID: 1, Body: marker + "\nbody", User: github.User{Login: "someone"}},
+			},
+			expectAdded: 1,
+		},
+		{
+			name: "earlier comment of the bot is edited",
+			comments: []github.IssueComment{
+				{ID: 1, Body: marker + "\nold body", User: github.User{Login: "k8s-ci-robot"}},
+			},
+			expectEdited: 1,
+		},
+		{
+			name: "unchanged comment is not posted again",
+			comments: []github.IssueComment{
+				{ID: 1, Body: marker + "\nbody", User: github.User{Login: "k8s-ci-robot"}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakegithub.NewFakeClient()
+			client.IssueComments[1] = tc.comments
+			if err := upsertComment(client, "org", "repo", 1, marker, "body"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if added := len(client.IssueCommentsAdded); added != tc.expectAdded {
+				t.Errorf("expected %d comments to be added, got %d", tc.expectAdded, added)
+			}
+			if edited := len(client.IssueCommentsEdited); edited != tc.expectEdited {
+				t.Errorf("expected %d comments to be edited, got %d", tc.expectEdited, edited)
+			}
+		})
+	}
+}
+
+func TestTruncateComment(t *testing.T) {
+	if body := truncateComment("short"); body != "short" {
+		t.Errorf("expected a short comment to be unchanged, got %q", body)
+	}
+	body := truncateComment(strings.Repeat("ü", maxCommentLength))
+	if len(body) > maxCommentLength {
+		t.Errorf("expected the comment to be truncated to %d bytes, got %d", maxCommentLength, len(body))
+	}
+	if !utf8.ValidString(body) {
+		t.Error("expected the truncated comment to be valid UTF-8")
+	}
+	if !strings.HasSuffix(body, "...(truncated)") {
+		t.Error("expected the truncated comment to say it was truncated")
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/github/prcreation"
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+const (
+	githubOrg      = "openshift"
+	githubRepo     = "release"
+	githubTeam     = "openshift/test-platform"
+	upstreamBranch = "master"
+	prTitle        = "Update configured resource requests to pod scaler recommendations"
+)
+
+type options struct {
+	podScalerURL   string
+	releaseRepoDir string
+	threshold      float64
+	createPR       bool
+	assign         string
+	prcreation.PRCreationOptions
+}
+
+func gatherOptions() (*options, error) {
+	o := &options{}
+	o.PRCreationOptions.AddFlags(flag.CommandLine)
+	flag.StringVar(&o.podScalerURL, "pod-scaler-url", "", "URL of the pod-scaler frontend serving recommendations.")
+	flag.StringVar(&o.releaseRepoDir, "release-repo-dir", "", "Path to a checkout of the openshift/release repository.")
+	flag.Float64Var(&o.threshold, "threshold", 2, "Configured requests are updated when they are larger or smaller than the recommendation by more than this factor.")
+	flag.BoolVar(&o.createPR, "create-pr", false, "Create a PR with the updated configurations.")
+	flag.StringVar(&o.assign, "assign", githubTeam, "The GitHub username or group name to assign the created PR to.")
+	flag.Parse()
+
+	var errs []error
+	if o.podScalerURL == "" {
+		errs = append(errs, errors.New("--pod-scaler-url is required"))
+	}
+	if o.releaseRepoDir == "" {
+		errs = append(errs, errors.New("--release-repo-dir is required"))
+	}
+	if o.threshold <= 1 {
+		errs = append(errs, errors.New("--threshold must be larger than 1"))
+	}
+	return o, utilerrors.NewAggregate(errs)
+}
+
+func main() {
+	o, err := gatherOptions()
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid options.")
+	}
+	if o.createPR {
+		if err := o.PRCreationOptions.Finalize(); err != nil {
+			logrus.WithError(err).Fatal("Failed to set up PR creation options.")
+		}
+	}
+
+	recommendations, err := fetchRecommendations(&http.Client{Timeout: 5 * time.Minute}, o.podScalerURL)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to fetch recommendations.")
+	}
+	byConfig := recommendationsByConfig(recommendations)
+
+	configDir := filepath.Join(o.releaseRepoDir, config.CiopConfigInRepoPath)
+	var lock sync.Mutex
+	var updates []update
+	var errs []error
+	if err := config.OperateOnCIOperatorConfigDir(configDir, func(configuration *api.ReleaseBuildConfiguration, info *config.Info) error {
+		updated := updateResources(configuration, byConfig[info.Metadata], o.threshold)
+		if len(updated) == 0 {
+			return nil
+		}
+		output := config.DataWithInfo{Configuration: *configuration, Info: *info}
+		lock.Lock()
+		defer lock.Unlock()
+		if err := output.CommitTo(configDir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s: %w", info.RelativePath(), err))
+			return nil
+		}
+		for i := range updated {
+			updated[i].file = info.RelativePath()
+			output.Logger().WithFields(logrus.Fields{
+				"step":        updated[i].step,
+				"resource":    updated[i].resource,
+				"configured":  updated[i].configured,
+				"recommended": updated[i].recommended,
+			}).Info("Updating configured request.")
+		}
+		updates = append(updates, updated...)
+		return nil
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to operate on ci-operator configuration: %w", err))
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		logrus.WithError(err).Fatal("Failed to update ci-operator configuration.")
+	}
+	logrus.Infof("Updated %d configured requests.", len(updates))
+
+	if !o.createPR || len(updates) == 0 {
+		return
+	}
+	if err := o.PRCreationOptions.UpsertPR(o.releaseRepoDir, githubOrg, githubRepo, upstreamBranch, prTitle, prcreation.PrBody(prBody(updates)), prcreation.PrAssignee(o.assign)); err != nil {
+		logrus.WithError(err).Fatal("Failed to create PR.")
+	}
+}
+
+// fetchRecommendations loads the recommendations served by the pod-scaler frontend
+func fetchRecommendations(client *http.Client, url string) ([]pod_scaler.Recommendation, error) {
+	response, err := client.Get(strings.TrimSuffix(url, "/") + "/api/recommendations")
+	if err != nil {
+		return nil, fmt.Errorf("could not request recommendations: %w", err)
+	}
+	defer response.Body.Close()
+	raw, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read recommendations: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch recommendations: %s: %s", response.Status, string(raw))
+	}
+	var recommendations []pod_scaler.Recommendation
+	if err := json.Unmarshal(raw, &recommendations); err != nil {
+		return nil, fmt.Errorf("could not unmarshal recommendations: %w", err)
+	}
+	return recommendations, nil
+}
+
+// recommendationsByConfig maps recommendations to the names under which the
+// requests for the workloads are configured in the ci-operator configuration.
+func recommendationsByConfig(recommendations []pod_scaler.Recommendation) map[api.Metadata]map[string]corev1.ResourceList {
+	byConfig := map[api.Metadata]map[string]corev1.ResourceList{}
+	for _, recommendation := range recommendations {
+		meta := recommendation.Metadata
+		name, configured := pod_scaler.ConfiguredName(meta)
+		if !configured {
+			continue
+		}
+		if _, exists := byConfig[meta.Metadata]; !exists {
+			byConfig[meta.Metadata] = map[string]corev1.ResourceList{}
+		}
+		if _, exists := byConfig[meta.Metadata][name]; !exists {
+			byConfig[meta.Metadata][name] = corev1.ResourceList{}
+		}
+		for field, value := range recommendation.Requests {
+			if current, exists := byConfig[meta.Metadata][name][field]; !exists || current.Cmp(value) == -1 {
+				byConfig[meta.Metadata][name][field] = value
+			}
+		}
+	}
+	return byConfig
+}
+
+// configuredNames lists the names of builds and container tests in the configuration,
+// as we do not want to configure requests for workloads that no longer exist
+func configuredNames(configuration *api.ReleaseBuildConfiguration) sets.String {
+	names := sets.NewString(string(api.PipelineImageStreamTagReferenceSource))
+	if configuration.BinaryBuildCommands != "" {
+		names.Insert(string(api.PipelineImageStreamTagReferenceBinaries))
+	}
+	if configuration.TestBinaryBuildCommands != "" {
+		names.Insert(string(api.PipelineImageStreamTagReferenceTestBinaries))
+	}
+	if configuration.RpmBuildCommands != "" {
+		names.Insert(string(api.PipelineImageStreamTagReferenceRPMs))
+	}
+	for _, image := range configuration.Images {
+		names.Insert(string(image.To))
+	}
+	for _, test := range configuration.Tests {
+		if test.ContainerTestConfiguration != nil {
+			names.Insert(test.As)
+		}
+	}
+	return names
+}
+
+// update describes a configured request that we updated
+type update struct {
+	file, step, resource, configured, recommended string
+}
+
+// updateResources updates the configured requests that are wildly off from the
+// recommendations, where the configured request is larger or smaller than the
+// recommendation by more than the threshold factor.
+func updateResources(configuration *api.ReleaseBuildConfiguration, recommendations map[string]corev1.ResourceList, threshold float64) []update {
+	names := configuredNames(configuration)
+	var steps []string
+	for name := range recommendations {
+		if names.Has(name) {
+			steps = append(steps, name)
+		}
+	}
+	sort.Strings(steps)
+
+	var updates []update
+	for _, step := range steps {
+		requirements := configuration.Resources.RequirementsForStep(step)
+		for _, field := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			recommended, hasRecommendation := recommendations[step][field]
+			if !hasRecommendation || recommended.IsZero() {
+				continue
+			}
+			raw, hasRequest := requirements.Requests[string(field)]
+			if !hasRequest {
+				continue
+			}
+			configured, err := resource.ParseQuantity(raw)
+			if err != nil || !wildlyOff(configured, recommended, threshold) {
+				continue
+			}
+			value := rounded(field, recommended)
+			if rawLimit, hasLimit := requirements.Limits[string(field)]; hasLimit {
+				if limit, err := resource.ParseQuantity(rawLimit); err == nil && limit.Cmp(value) == -1 {
+					// we cannot request more than the limit, and raising limits is not our call to make
+					continue
+				}
+			}
+			if configuration.Resources == nil {
+				configuration.Resources = api.ResourceConfiguration{}
+			}
+			stepRequirements := configuration.Resources[step]
+			if stepRequirements.Requests == nil {
+				stepRequirements.Requests = api.ResourceList{}
+			}
+			stepRequirements.Requests[string(field)] = value.String()
+			configuration.Resources[step] = stepRequirements
+			updates = append(updates, update{step: step, resource: string(field), configured: raw, recommended: value.String()})
+		}
+	}
+	return updates
+}
+
+func wildlyOff(configured, recommended resource.Quantity, threshold float64) bool {
+	ours, theirs := float64(recommended.MilliValue()), float64(configured.MilliValue())
+	return theirs > ours*threshold || theirs*threshold < ours
+}
+
+const mebibyte = 1024 * 1024
+
+// rounded rounds the recommendation up to values that read well in configuration
+func rounded(field corev1.ResourceName, recommended resource.Quantity) resource.Quantity {
+	switch field {
+	case corev1.ResourceCPU:
+		return *resource.NewMilliQuantity((recommended.MilliValue()+9)/10*10, resource.DecimalSI)
+	default:
+		return *resource.NewQuantity((recommended.Value()+mebibyte-1)/mebibyte*mebibyte, resource.BinarySI)
+	}
+}
+
+func prBody(updates []update) string {
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].file != updates[j].file {
+			return updates[i].file < updates[j].file
+		}
+		if updates[i].step != updates[j].step {
+			return updates[i].step < updates[j].step
+		}
+		return updates[i].resource < updates[j].resource
+	})
+	body := &strings.Builder{}
+	body.WriteString(`This is an autogenerated PR that updates resource requests in ci-operator
+configurations which are far off from what the pod-scaler recommends based on
+the actual usage of the workloads. The pod-scaler only ever raises requests
+when Pods are created, so requests that are configured too large waste capacity
+and requests that are configured too small misrepresent what workloads need.
+
+| Configuration | Name | Resource | Configured | Recommended |
+| --- | --- | --- | --- | --- |
+`)
+	for _, u := range updates {
+		fmt.Fprintf(body, "| `%s` | `%s` | %s | %s | %s |\n", u.file, u.step, u.resource, u.configured, u.recommended)
+	}
+	return body.String()
+}
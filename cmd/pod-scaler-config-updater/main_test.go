@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+func requests(cpu, memory string) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+var quantityComparer = cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })
+
+func TestFetchRecommendations(t *testing.T) {
+	recommendations := []pod_scaler.Recommendation{{
+		Metadata: pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Target: "unit", Pod: "unit", Container: "test"},
+		Requests: requests("100m", "200Mi"),
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/recommendations" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(recommendations); err != nil {
+			t.Errorf("failed to encode recommendations: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	actual, err := fetchRecommendations(server.Client(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("failed to fetch recommendations: %v", err)
+	}
+	if diff := cmp.Diff(recommendations, actual, quantityComparer); diff != "" {
+		t.Errorf("got incorrect recommendations: %v", diff)
+	}
+}
+
+func TestRecommendationsByConfig(t *testing.T) {
+	meta := api.Metadata{Org: "org", Repo: "repo", Branch: "master"}
+	recommendations := []pod_scaler.Recommendation{
+		{Metadata: pod_scaler.FullMetadata{Metadata: meta, Pod: "src-build", Container: "docker"}, Requests: requests("1", "1Gi")},
+		{Metadata: pod_scaler.FullMetadata{Metadata: meta, Pod: "src-build", Container: "manage-dockerfile"}, Requests: requests("5", "5Gi")},
+		{Metadata: pod_scaler.FullMetadata{Metadata: meta, Target: "unit", Pod: "unit", Container: "test"}, Requests: requests("2", "2Gi")},
+		{Metadata: pod_scaler.FullMetadata{Metadata: meta, Target: "unit", Pod: "unit", Container: "sidecar"}, Requests: requests("5", "5Gi")},
+		{Metadata: pod_scaler.FullMetadata{Metadata: meta, Target: "e2e", Step: "test", Pod: "e2e-test", Container: "test"}, Requests: requests("5", "5Gi")},
+	}
+	expected := map[api.Metadata]map[string]corev1.ResourceList{
+		meta: {
+			"src":  requests("1", "1Gi"),
+			"unit": requests("2", "2Gi"),
+		},
+	}
+	if diff := cmp.Diff(expected, recommendationsByConfig(recommendations), quantityComparer); diff != "" {
+		t.Errorf("got incorrect recommendations: %v", diff)
+	}
+}
+
+func TestUpdateResources(t *testing.T) {
+	var testCases = []struct {
+		name            string
+		configuration   *api.ReleaseBuildConfiguration
+		recommendations map[string]corev1.ResourceList
+		expected        api.ResourceConfiguration
+		expectedUpdates []update
+	}{
+		{
+			name: "requests within the threshold are left alone",
+			configuration: &api.ReleaseBuildConfiguration{
+				Resources: api.ResourceConfiguration{"*": {Requests: api.ResourceList{"cpu": "100m", "memory": "200Mi"}}},
+			},
+			recommendations: map[string]corev1.ResourceList{"src": requests("150m", "300Mi")},
+			expected:        api.ResourceConfiguration{"*": {Requests: api.ResourceList{"cpu": "100m", "memory": "200Mi"}}},
+		},
+		{
+			name: "wildly off requests are updated for the step, without changing the defaults",
+			configuration: &api.ReleaseBuildConfiguration{
+				Resources: api.ResourceConfiguration{"*": {Requests: api.ResourceList{"cpu": "100m", "memory": "200Mi"}}},
+				Tests:     []api.TestStepConfiguration{{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}}},
+			},
+			recommendations: map[string]corev1.ResourceList{"unit": requests("1234m", "50Mi")},
+			expected: api.ResourceConfiguration{
+				"*":    {Requests: api.ResourceList{"cpu": "100m", "memory": "200Mi"}},
+				"unit": {Requests: api.ResourceList{"cpu": "1240m", "memory": "50Mi"}},
+			},
+			expectedUpdates: []update{
+				{step: "unit", resource: "cpu", configured: "100m", recommended: "1240m"},
+				{step: "unit", resource: "memory", configured: "200Mi", recommended: "50Mi"},
+			},
+		},
+		{
+			name: "workloads that are no longer configured are not updated",
+			configuration: &api.ReleaseBuildConfiguration{
+				Resources: api.ResourceConfiguration{"*": {Requests: api.ResourceList{"cpu": "100m"}}},
+			},
+			recommendations: map[string]corev1.ResourceList{"removed": requests("4", "4Gi")},
+			expected:        api.ResourceConfiguration{"*": {Requests: api.ResourceList{"cpu": "100m"}}},
+		},
+		{
+			name: "requests are not raised beyond limits",
+			configuration: &api.ReleaseBuildConfiguration{
+				Resources: api.ResourceConfiguration{"src": {Requests: api.ResourceList{"memory": "100Mi"}, Limits: api.ResourceList{"memory": "1Gi"}}},
+			},
+			recommendations: map[string]corev1.ResourceList{"src": requests("100m", "4Gi")},
+			expected:        api.ResourceConfiguration{"src": {Requests: api.ResourceList{"memory": "100Mi"}, Limits: api.ResourceList{"memory": "1Gi"}}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			updates := updateResources(testCase.configuration, testCase.recommendations, 2)
+			if diff := cmp.Diff(testCase.expected, testCase.configuration.Resources); diff != "" {
+				t.Errorf("got incorrect resources: %v", diff)
+			}
+			if diff := cmp.Diff(testCase.expectedUpdates, updates, cmp.AllowUnexported(update{})); diff != "" {
+				t.Errorf("got incorrect updates: %v", diff)
+			}
+		})
+	}
+}
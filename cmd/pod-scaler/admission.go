@@ -33,7 +33,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/steps"
 )
 
-func admit(port, healthPort int, certDir string, client buildclientv1.BuildV1Interface, loaders map[string][]*cacheReloader, mutateResourceLimits bool, cpuCap int64, memoryCap string, reporter results.PodScalerReporter) {
+func admit(port, healthPort int, certDir string, client buildclientv1.BuildV1Interface, loaders map[string][]*cacheReloader, mutateResourceLimits bool, cpuCap int64, memoryCap, ephemeralStorageCap string, reporter results.PodScalerReporter, downscaler *downscaler, hinter *schedulingHinter) {
 	logger := logrus.WithField("component", "pod-scaler admission")
 	logger.Infof("Initializing admission webhook server with %d loaders.", len(loaders))
 	health := pjutil.NewHealthOnPort(healthPort)
@@ -46,7 +46,7 @@ func admit(port, healthPort int, certDir string, client buildclientv1.BuildV1Int
 		Port:    port,
 		CertDir: certDir,
 	}
-	server.Register("/pods", &webhook.Admission{Handler: &podMutator{logger: logger, client: client, decoder: decoder, resources: resources, mutateResourceLimits: mutateResourceLimits, cpuCap: cpuCap, memoryCap: memoryCap, reporter: reporter}})
+	server.Register("/pods", &webhook.Admission{Handler: &podMutator{logger: logger, client: client, decoder: decoder, resources: resources, mutateResourceLimits: mutateResourceLimits, cpuCap: cpuCap, memoryCap: memoryCap, ephemeralStorageCap: ephemeralStorageCap, reporter: reporter, downscaler: downscaler, hinter: hinter}})
 	logger.Info("Serving admission webhooks.")
 	if err := server.StartStandalone(interrupts.Context(), nil); err != nil {
 		logrus.WithError(err).Fatal("Failed to serve webhooks.")
@@ -61,7 +61,10 @@ type podMutator struct {
 	decoder              *admission.Decoder
 	cpuCap               int64
 	memoryCap            string
+	ephemeralStorageCap  string
 	reporter             results.PodScalerReporter
+	downscaler           *downscaler
+	hinter               *schedulingHinter
 }
 
 func (m *podMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
@@ -98,7 +101,8 @@ func (m *podMutator) Handle(ctx context.Context, req admission.Request) admissio
 		logger.WithError(err).Error("Failed to handle rehearsal Pod.")
 		return admission.Allowed("Failed to handle rehearsal Pod, ignoring.")
 	}
-	mutatePodResources(pod, m.resources, m.mutateResourceLimits, m.cpuCap, m.memoryCap, m.reporter, logger)
+	mutatePodResources(pod, m.resources, m.mutateResourceLimits, m.cpuCap, m.memoryCap, m.ephemeralStorageCap, m.reporter, m.downscaler, logger)
+	m.hinter.hint(pod, m.resources, logger)
 
 	marshaledPod, err := json.Marshal(pod)
 	if err != nil {
@@ -213,7 +217,7 @@ func useOursIfLarger(allOfOurs, allOfTheirs *corev1.ResourceRequirements, podNam
 		{ours: &allOfOurs.Requests, theirs: &allOfTheirs.Requests, resource: "request"},
 		{ours: &allOfOurs.Limits, theirs: &allOfTheirs.Limits, resource: "limit"},
 	} {
-		for _, field := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		for _, field := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
 			our := (*pair.ours)[field]
 			their := (*pair.theirs)[field]
 			if our.Cmp(their) == 1 {
@@ -229,7 +233,8 @@ func useOursIfLarger(allOfOurs, allOfTheirs *corev1.ResourceRequirements, podNam
 
 // reconcileLimits ensures that container resource limits do not set anything for CPU (as we
 // are fairly certain this is never a useful thing to do) and that the limits are >=200% of
-// requests (which they may not be any longer if we've changed requests)
+// requests (which they may not be any longer if we've changed requests). Ephemeral storage
+// limits, when set, are raised to at least the request, as the pod is rejected otherwise.
 func reconcileLimits(resources *corev1.ResourceRequirements) {
 	if resources.Limits == nil {
 		return
@@ -246,9 +251,15 @@ func reconcileLimits(resources *corev1.ResourceRequirements) {
 	if currentLimit.Cmp(minimumLimit) == -1 {
 		resources.Limits[corev1.ResourceMemory] = minimumLimit
 	}
+	if currentLimit, set := resources.Limits[corev1.ResourceEphemeralStorage]; set {
+		request := resources.Requests[corev1.ResourceEphemeralStorage]
+		if currentLimit.Cmp(request) == -1 {
+			resources.Limits[corev1.ResourceEphemeralStorage] = request
+		}
+	}
 }
 
-func preventUnschedulable(resources *corev1.ResourceRequirements, cpuCap int64, memoryCap string, logger *logrus.Entry) {
+func preventUnschedulable(resources *corev1.ResourceRequirements, cpuCap int64, memoryCap, ephemeralStorageCap string, logger *logrus.Entry) {
 	if resources.Requests == nil {
 		logger.Debug("no requests, skipping")
 		return
@@ -270,20 +281,29 @@ func preventUnschedulable(resources *corev1.ResourceRequirements, cpuCap int64,
 			resources.Requests[corev1.ResourceMemory] = memoryRequestCap
 		}
 	}
+
+	if _, ok := resources.Requests[corev1.ResourceEphemeralStorage]; ok {
+		ephemeralStorageRequestCap := resource.MustParse(ephemeralStorageCap)
+		if resources.Requests.StorageEphemeral().Cmp(ephemeralStorageRequestCap) == 1 {
+			logger.Debugf("setting original ephemeral storage request of: %s to cap", resources.Requests.StorageEphemeral())
+			resources.Requests[corev1.ResourceEphemeralStorage] = ephemeralStorageRequestCap
+		}
+	}
 }
 
-func mutatePodResources(pod *corev1.Pod, server *resourceServer, mutateResourceLimits bool, cpuCap int64, memoryCap string, reporter results.PodScalerReporter, logger *logrus.Entry) {
+func mutatePodResources(pod *corev1.Pod, server *resourceServer, mutateResourceLimits bool, cpuCap int64, memoryCap, ephemeralStorageCap string, reporter results.PodScalerReporter, downscaler *downscaler, logger *logrus.Entry) {
 	for i := range pod.Spec.InitContainers {
 		meta := pod_scaler.MetadataFor(pod.ObjectMeta.Labels, pod.ObjectMeta.Name, pod.Spec.InitContainers[i].Name)
 		resources, recommendationExists := server.recommendedRequestFor(meta)
 		if recommendationExists {
 			logger.Debugf("recommendation exists for: %s", pod.Spec.InitContainers[i].Name)
 			useOursIfLarger(&resources, &pod.Spec.InitContainers[i].Resources, pod.Spec.InitContainers[i].Name, reporter, logger)
+			downscaler.downscale(meta, pod.ObjectMeta.Labels, &resources, &pod.Spec.InitContainers[i].Resources, server.incidentsFor(meta), pod.Spec.InitContainers[i].Name, reporter, logger)
 			if mutateResourceLimits {
 				reconcileLimits(&pod.Spec.InitContainers[i].Resources)
 			}
 		}
-		preventUnschedulable(&pod.Spec.InitContainers[i].Resources, cpuCap, memoryCap, logger)
+		preventUnschedulable(&pod.Spec.InitContainers[i].Resources, cpuCap, memoryCap, ephemeralStorageCap, logger)
 	}
 	for i := range pod.Spec.Containers {
 		meta := pod_scaler.MetadataFor(pod.ObjectMeta.Labels, pod.ObjectMeta.Name, pod.Spec.Containers[i].Name)
@@ -291,10 +311,11 @@ func mutatePodResources(pod *corev1.Pod, server *resourceServer, mutateResourceL
 		if recommendationExists {
 			logger.Debugf("recommendation exists for: %s", pod.Spec.Containers[i].Name)
 			useOursIfLarger(&resources, &pod.Spec.Containers[i].Resources, pod.Name, reporter, logger)
+			downscaler.downscale(meta, pod.ObjectMeta.Labels, &resources, &pod.Spec.Containers[i].Resources, server.incidentsFor(meta), pod.Name, reporter, logger)
 			if mutateResourceLimits {
 				reconcileLimits(&pod.Spec.Containers[i].Resources)
 			}
 		}
-		preventUnschedulable(&pod.Spec.Containers[i].Resources, cpuCap, memoryCap, logger)
+		preventUnschedulable(&pod.Spec.Containers[i].Resources, cpuCap, memoryCap, ephemeralStorageCap, logger)
 	}
 }
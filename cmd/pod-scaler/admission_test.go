@@ -38,6 +38,10 @@ func (r *mockReporter) ReportMemoryConfigurationWarning(string, string, string)
 	r.called = true
 }
 
+func (r *mockReporter) ReportDownscaling(string, string, string, string, bool) {
+	r.called = true
+}
+
 var defaultReporter = mockReporter{client: &http.Client{}}
 
 func TestMutatePods(t *testing.T) {
@@ -106,6 +110,7 @@ func TestMutatePods(t *testing.T) {
 		decoder:              decoder,
 		cpuCap:               10,
 		memoryCap:            "20Gi",
+		ephemeralStorageCap:  "50Gi",
 		reporter:             &defaultReporter,
 	}
 
@@ -555,7 +560,7 @@ func TestMutatePodResources(t *testing.T) {
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
 			original := testCase.pod.DeepCopy()
-			mutatePodResources(testCase.pod, testCase.server, testCase.mutateResourceLimits, 10, "20Gi", &defaultReporter, logrus.WithField("test", testCase.name))
+			mutatePodResources(testCase.pod, testCase.server, testCase.mutateResourceLimits, 10, "20Gi", "50Gi", &defaultReporter, nil, logrus.WithField("test", testCase.name))
 			diff := cmp.Diff(original, testCase.pod)
 			// In some cases, cmp.Diff decides to use non-breaking spaces, and it's not
 			// particularly deterministic about this. We don't care.
@@ -605,6 +610,27 @@ func TestUseOursIfLarger(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ours has larger ephemeral storage",
+			ours: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+			theirs: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              *resource.NewQuantity(100, resource.DecimalSI),
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(1e10, resource.BinarySI),
+				},
+			},
+			expected: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{},
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              *resource.NewQuantity(100, resource.DecimalSI),
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+		},
 		{
 			name: "nothing in ours",
 			theirs: corev1.ResourceRequirements{
@@ -881,6 +907,46 @@ func TestReconcileLimits(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "increase ephemeral storage limits below the request",
+			input: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(1e10, resource.BinarySI),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+			expected: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+		},
+		{
+			name: "do not set ephemeral storage limits",
+			input: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: *resource.NewQuantity(4e10, resource.BinarySI),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory:           *resource.NewQuantity(2e10, resource.BinarySI),
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+			expected: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: *resource.NewQuantity(4e10, resource.BinarySI),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory:           *resource.NewQuantity(2e10, resource.BinarySI),
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -928,6 +994,7 @@ func TestRehearsalMetadata(t *testing.T) {
 func TestPreventUnschedulable(t *testing.T) {
 	cpuCap := int64(10)
 	memoryCap := "20Gi"
+	ephemeralStorageCap := "50Gi"
 	testCases := []struct {
 		name      string
 		resources *corev1.ResourceRequirements
@@ -989,6 +1056,21 @@ func TestPreventUnschedulable(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "too much ephemeral storage",
+			resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              *resource.NewQuantity(9, resource.DecimalSI),
+					corev1.ResourceEphemeralStorage: resource.MustParse("100Gi"),
+				},
+			},
+			expected: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              *resource.NewQuantity(9, resource.DecimalSI),
+					corev1.ResourceEphemeralStorage: resource.MustParse(ephemeralStorageCap),
+				},
+			},
+		},
 		{
 			name:      "no requests",
 			resources: &corev1.ResourceRequirements{},
@@ -997,7 +1079,7 @@ func TestPreventUnschedulable(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			preventUnschedulable(tc.resources, cpuCap, memoryCap, logrus.WithField("test", tc.name))
+			preventUnschedulable(tc.resources, cpuCap, memoryCap, ephemeralStorageCap, logrus.WithField("test", tc.name))
 			if diff := cmp.Diff(tc.expected, tc.resources); diff != "" {
 				t.Fatalf("result doesn't match expected, diff: %s", diff)
 			}
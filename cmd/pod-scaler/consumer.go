@@ -84,6 +84,9 @@ func (c *cacheReloader) reload() {
 	logger.Debug("Newer update loaded.")
 }
 
+// digestAll subscribes the digesters to the data they handle and marks the health
+// as ready once all data was digested once. Health may be nil for data that is
+// not necessary to serve, so that its absence does not block readiness.
 func digestAll(data map[string][]*cacheReloader, digesters map[string]digester, health *pjutil.Health, logger *logrus.Entry) {
 	var infos []digestInfo
 	for id, d := range digesters {
@@ -104,7 +107,9 @@ func digestAll(data map[string][]*cacheReloader, digesters map[string]digester,
 			return
 		case <-loadDone:
 			logger.Debug("Ready to serve.")
-			health.ServeReady()
+			if health != nil {
+				health.ServeReady()
+			}
 		}
 	})
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// downscalingLabel lets the Pod of a workload opt into the reduction of its
+// requests when set to "true", or out of it when set to "false", regardless of
+// the selectors in the configuration.
+const downscalingLabel = "pod-scaler.openshift.io/downscaling"
+
+// downscalingConfig configures the reduction of resource requests that are larger
+// than what we recommend. Requests are only ever reduced for workloads that opted in.
+type downscalingConfig struct {
+	// OptIn selects the workloads for which requests may be reduced, in addition
+	// to those that opt in with a label.
+	OptIn []downscalingSelector `json:"opt_in,omitempty"`
+	// Floors are the smallest requests we will reduce to.
+	Floors corev1.ResourceList `json:"floors,omitempty"`
+	// MaxWeeklyReduction is the largest fraction by which we reduce a request
+	// in any week, so that requests approach the recommendation gradually.
+	MaxWeeklyReduction float64 `json:"max_weekly_reduction"`
+}
+
+// downscalingSelector selects workloads by their metadata. Empty fields match
+// all values.
+type downscalingSelector struct {
+	Org  string `json:"org"`
+	Repo string `json:"repo,omitempty"`
+	Step string `json:"step,omitempty"`
+}
+
+func (s downscalingSelector) matches(meta pod_scaler.FullMetadata) bool {
+	return s.Org == meta.Metadata.Org &&
+		(s.Repo == "" || s.Repo == meta.Metadata.Repo) &&
+		(s.Step == "" || s.Step == meta.Step)
+}
+
+func (c *downscalingConfig) validate() error {
+	var errs []error
+	for i, selector := range c.OptIn {
+		if selector.Org == "" {
+			errs = append(errs, fmt.Errorf("opt_in[%d].org must be set", i))
+		}
+	}
+	for name, floor := range c.Floors {
+		if name != corev1.ResourceCPU && name != corev1.ResourceMemory {
+			errs = append(errs, fmt.Errorf("floors: only %s and %s can be set, not %s", corev1.ResourceCPU, corev1.ResourceMemory, name))
+		}
+		if floor.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("floors.%s must not be negative", name))
+		}
+	}
+	if c.MaxWeeklyReduction <= 0 || c.MaxWeeklyReduction >= 1 {
+		errs = append(errs, errors.New("max_weekly_reduction must be larger than 0 and smaller than 1"))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func loadDownscalingConfig(path string) (*downscalingConfig, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read downscaling configuration: %w", err)
+	}
+	var config downscalingConfig
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("could not unmarshal downscaling configuration: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid downscaling configuration: %w", err)
+	}
+	return &config, nil
+}
+
+const (
+	week = 7 * 24 * time.Hour
+	// incidentWindow is how long we stop reducing requests for after we saw
+	// a workload run out of the resource
+	incidentWindow = week
+	// downscalingStaleAfter is how long a workload needs to go without being
+	// admitted before we forget about the reduction of its requests
+	downscalingStaleAfter = 4 * week
+	// downscalingPruneInterval is how often we look for stale workloads
+	downscalingPruneInterval = time.Hour
+)
+
+func newDownscaler(config *downscalingConfig) *downscaler {
+	return &downscaler{
+		config:   config,
+		since:    map[pod_scaler.FullMetadata]map[corev1.ResourceName]time.Time{},
+		lastSeen: map[pod_scaler.FullMetadata]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// downscaler reduces resource requests towards our recommendations, within the
+// guardrails in its configuration.
+type downscaler struct {
+	config *downscalingConfig
+
+	lock sync.Mutex
+	// since records when we started to reduce a request of a workload, as the
+	// reduction is limited by the weeks that passed since. This is only held in
+	// memory, so a restart starts the gradual reduction from the configured
+	// request again, which is the conservative choice.
+	since map[pod_scaler.FullMetadata]map[corev1.ResourceName]time.Time
+	// lastSeen records when we last admitted a workload, so that we can forget
+	// the reductions of workloads that no longer run.
+	lastSeen   map[pod_scaler.FullMetadata]time.Time
+	lastPruned time.Time
+	now        func() time.Time
+}
+
+// downscalingReport is a reduction or rollback we report once we are done
+// with the requests of a workload
+type downscalingReport struct {
+	resource, configured, determined string
+	rolledBack                       bool
+}
+
+func (d *downscaler) enabledFor(meta pod_scaler.FullMetadata, labels map[string]string) bool {
+	if d == nil {
+		return false
+	}
+	switch labels[downscalingLabel] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	for _, selector := range d.config.OptIn {
+		if selector.matches(meta) {
+			return true
+		}
+	}
+	return false
+}
+
+// downscale reduces requests in theirs towards the requests in ours, but never
+// below the floors or further than the weekly reduction allows. When usage
+// incidents were recently observed for a resource, reductions are rolled back.
+func (d *downscaler) downscale(meta pod_scaler.FullMetadata, labels map[string]string, ours, theirs *corev1.ResourceRequirements, incidents map[corev1.ResourceName]time.Time, workloadName string, reporter results.PodScalerReporter, logger *logrus.Entry) {
+	if !d.enabledFor(meta, labels) || theirs.Requests == nil {
+		return
+	}
+	// reporting is a round-trip to the result aggregator, which we do not
+	// want to make every admission request wait for behind the lock
+	for _, report := range d.reduce(meta, ours, theirs, incidents, logger) {
+		reporter.ReportDownscaling(workloadName, report.resource, report.configured, report.determined, report.rolledBack)
+	}
+}
+
+func (d *downscaler) reduce(meta pod_scaler.FullMetadata, ours, theirs *corev1.ResourceRequirements, incidents map[corev1.ResourceName]time.Time, logger *logrus.Entry) []downscalingReport {
+	now := d.now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.lastSeen[meta] = now
+	if now.Sub(d.lastPruned) >= downscalingPruneInterval {
+		d.prune(now)
+	}
+	var reports []downscalingReport
+	for _, field := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		our, their := ours.Requests[field], theirs.Requests[field]
+		if our.IsZero() || their.IsZero() || our.Cmp(their) != -1 {
+			continue
+		}
+		if incident, recent := incidents[field]; recent && now.Sub(incident) < incidentWindow {
+			if _, downscaled := d.since[meta][field]; downscaled {
+				logger.Infof("rolling back reduction of %s request after resource usage incident on %s", field, incident.Format(pod_scaler.DayFormat))
+				delete(d.since[meta], field)
+				if len(d.since[meta]) == 0 {
+					delete(d.since, meta)
+				}
+				reports = append(reports, downscalingReport{resource: string(field), configured: their.String(), determined: their.String(), rolledBack: true})
+			}
+			continue
+		}
+		if d.since[meta] == nil {
+			d.since[meta] = map[corev1.ResourceName]time.Time{}
+		}
+		since, downscaled := d.since[meta][field]
+		if !downscaled {
+			since = now
+			d.since[meta][field] = since
+		}
+		weeks := int(now.Sub(since) / week)
+		factor := math.Pow(1-d.config.MaxWeeklyReduction, float64(weeks+1))
+		target := *resource.NewMilliQuantity(int64(float64(their.MilliValue())*factor), their.Format)
+		if target.Cmp(our) == -1 {
+			target = our
+		}
+		if floor, hasFloor := d.config.Floors[field]; hasFloor && target.Cmp(floor) == -1 {
+			target = floor
+		}
+		if target.Cmp(their) != -1 {
+			continue
+		}
+		logger.Debugf("reducing %s request of %s to %s", field, their.String(), target.String())
+		theirs.Requests[field] = target
+		reports = append(reports, downscalingReport{resource: string(field), configured: their.String(), determined: target.String()})
+	}
+	return reports
+}
+
+// prune forgets the workloads we have not admitted in a long time, so a
+// workload that runs again starts its reduction from the configured request
+func (d *downscaler) prune(now time.Time) {
+	for meta, seen := range d.lastSeen {
+		if now.Sub(seen) >= downscalingStaleAfter {
+			delete(d.lastSeen, meta)
+			delete(d.since, meta)
+		}
+	}
+	d.lastPruned = now
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+type recordingReporter struct {
+	reports []downscalingReport
+}
+
+func (r *recordingReporter) ReportMemoryConfigurationWarning(string, string, string) {}
+
+func (r *recordingReporter) ReportDownscaling(_, resource, configured, determined string, rolledBack bool) {
+	r.reports = append(r.reports, downscalingReport{resource: resource, configured: configured, determined: determined, rolledBack: rolledBack})
+}
+
+func TestDownscale(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	meta := pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Step: "unit"}
+	requests := func(cpu, memory string) *corev1.ResourceRequirements {
+		return &corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+	config := &downscalingConfig{
+		OptIn:              []downscalingSelector{{Org: "org", Repo: "repo"}},
+		Floors:             corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("100Mi")},
+		MaxWeeklyReduction: 0.5,
+	}
+
+	var testCases = []struct {
+		name            string
+		config          *downscalingConfig
+		meta            pod_scaler.FullMetadata
+		labels          map[string]string
+		since           map[corev1.ResourceName]time.Time
+		incidents       map[corev1.ResourceName]time.Time
+		ours, theirs    *corev1.ResourceRequirements
+		expected        *corev1.ResourceRequirements
+		expectedReports []downscalingReport
+		expectedSince   map[corev1.ResourceName]time.Time
+	}{
+		{
+			name:     "workloads that did not opt in are not reduced",
+			config:   config,
+			meta:     pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "other"}},
+			ours:     requests("100m", "200Mi"),
+			theirs:   requests("4", "8Gi"),
+			expected: requests("4", "8Gi"),
+		},
+		{
+			name:     "workloads opt in with a label",
+			config:   config,
+			meta:     pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "other"}},
+			labels:   map[string]string{downscalingLabel: "true"},
+			ours:     requests("100m", "200Mi"),
+			theirs:   requests("4", "8Gi"),
+			expected: requests("2", "4Gi"),
+			expectedReports: []downscalingReport{
+				{resource: "cpu", configured: "4", determined: "2"},
+				{resource: "memory", configured: "8Gi", determined: "4Gi"},
+			},
+		},
+		{
+			name:     "workloads opt out with a label",
+			config:   config,
+			meta:     meta,
+			labels:   map[string]string{downscalingLabel: "false"},
+			ours:     requests("100m", "200Mi"),
+			theirs:   requests("4", "8Gi"),
+			expected: requests("4", "8Gi"),
+		},
+		{
+			name:     "the first week reduces by the maximum weekly reduction",
+			config:   config,
+			meta:     meta,
+			ours:     requests("100m", "200Mi"),
+			theirs:   requests("4", "8Gi"),
+			expected: requests("2", "4Gi"),
+			expectedReports: []downscalingReport{
+				{resource: "cpu", configured: "4", determined: "2"},
+				{resource: "memory", configured: "8Gi", determined: "4Gi"},
+			},
+			expectedSince: map[corev1.ResourceName]time.Time{corev1.ResourceCPU: now, corev1.ResourceMemory: now},
+		},
+		{
+			name:     "later weeks reduce further, but not below our recommendation",
+			config:   config,
+			meta:     meta,
+			since:    map[corev1.ResourceName]time.Time{corev1.ResourceCPU: now.Add(-2 * week), corev1.ResourceMemory: now.Add(-2 * week)},
+			ours:     requests("1", "200Mi"),
+			theirs:   requests("4", "8Gi"),
+			expected: requests("1", "1Gi"),
+			expectedReports: []downscalingReport{
+				{resource: "cpu", configured: "4", determined: "1"},
+				{resource: "memory", configured: "8Gi", determined: "1Gi"},
+			},
+			expectedSince: map[corev1.ResourceName]time.Time{corev1.ResourceCPU: now.Add(-2 * week), corev1.ResourceMemory: now.Add(-2 * week)},
+		},
+		{
+			name:     "requests are never reduced below the floors",
+			config:   config,
+			meta:     meta,
+			since:    map[corev1.ResourceName]time.Time{corev1.ResourceMemory: now.Add(-10 * week)},
+			ours:     requests("4", "10Mi"),
+			theirs:   requests("4", "1Gi"),
+			expected: requests("4", "100Mi"),
+			expectedReports: []downscalingReport{
+				{resource: "memory", configured: "1Gi", determined: "100Mi"},
+			},
+			expectedSince: map[corev1.ResourceName]time.Time{corev1.ResourceMemory: now.Add(-10 * week)},
+		},
+		{
+			name:      "recent incidents roll back the reduction",
+			config:    config,
+			meta:      meta,
+			since:     map[corev1.ResourceName]time.Time{corev1.ResourceCPU: now.Add(-2 * week), corev1.ResourceMemory: now.Add(-2 * week)},
+			incidents: map[corev1.ResourceName]time.Time{corev1.ResourceMemory: now.Add(-24 * time.Hour), corev1.ResourceCPU: now.Add(-2 * week)},
+			ours:      requests("1", "200Mi"),
+			theirs:    requests("4", "8Gi"),
+			expected:  requests("1", "8Gi"),
+			expectedReports: []downscalingReport{
+				{resource: "cpu", configured: "4", determined: "1"},
+				{resource: "memory", configured: "8Gi", determined: "8Gi", rolledBack: true},
+			},
+			expectedSince: map[corev1.ResourceName]time.Time{corev1.ResourceCPU: now.Add(-2 * week)},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			d := newDownscaler(testCase.config)
+			d.now = func() time.Time { return now }
+			if testCase.since != nil {
+				d.since[testCase.meta] = testCase.since
+			}
+			reporter := &recordingReporter{}
+			d.downscale(testCase.meta, testCase.labels, testCase.ours, testCase.theirs, testCase.incidents, "pod", reporter, logrus.WithField("test", testCase.name))
+			if diff := cmp.Diff(testCase.expected, testCase.theirs, cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })); diff != "" {
+				t.Errorf("incorrect requests: %v", diff)
+			}
+			if diff := cmp.Diff(testCase.expectedReports, reporter.reports, cmp.AllowUnexported(downscalingReport{})); diff != "" {
+				t.Errorf("incorrect reports: %v", diff)
+			}
+			if diff := cmp.Diff(testCase.expectedSince, d.since[testCase.meta]); testCase.expectedSince != nil && diff != "" {
+				t.Errorf("incorrect reduction state: %v", diff)
+			}
+		})
+	}
+}
+
+func TestDownscalerPrune(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	stale := pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "org", Repo: "stale"}}
+	recent := pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "org", Repo: "recent"}}
+	d := newDownscaler(&downscalingConfig{MaxWeeklyReduction: 0.5})
+	for meta, seen := range map[pod_scaler.FullMetadata]time.Time{stale: now.Add(-downscalingStaleAfter), recent: now.Add(-week)} {
+		d.lastSeen[meta] = seen
+		d.since[meta] = map[corev1.ResourceName]time.Time{corev1.ResourceCPU: seen}
+	}
+	d.prune(now)
+	if diff := cmp.Diff(map[pod_scaler.FullMetadata]time.Time{recent: now.Add(-week)}, d.lastSeen); diff != "" {
+		t.Errorf("incorrect workloads seen: %v", diff)
+	}
+	if _, exists := d.since[stale]; exists {
+		t.Error("expected the reduction of the stale workload to be forgotten")
+	}
+	if _, exists := d.since[recent]; !exists {
+		t.Error("expected the reduction of the recent workload to be kept")
+	}
+}
+
+func TestDownscalingConfigValidate(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		config   downscalingConfig
+		expected error
+	}{
+		{
+			name: "valid config",
+			config: downscalingConfig{
+				OptIn:              []downscalingSelector{{Org: "org"}},
+				Floors:             corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+				MaxWeeklyReduction: 0.2,
+			},
+		},
+		{
+			name: "invalid config",
+			config: downscalingConfig{
+				OptIn:              []downscalingSelector{{Repo: "repo"}},
+				Floors:             corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+				MaxWeeklyReduction: 1,
+			},
+			expected: errors.New("[opt_in[0].org must be set, floors: only cpu and memory can be set, not ephemeral-storage, max_weekly_reduction must be larger than 0 and smaller than 1]"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if diff := cmp.Diff(testCase.expected, testCase.config.validate(), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("incorrect error: %v", diff)
+			}
+		})
+	}
+}
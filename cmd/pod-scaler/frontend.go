@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/openhistogram/circonusllhist"
+	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +29,7 @@ import (
 	"k8s.io/test-infra/prow/simplifypath"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load/agents"
 	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
 )
 
@@ -201,7 +203,7 @@ var (
 	static embed.FS
 )
 
-func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheReloader) {
+func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheReloader, configs agents.ConfigAgent) {
 	logger := logrus.WithField("component", "pod-scaler frontend")
 	server := &frontendServer{
 		logger:   logger,
@@ -209,12 +211,20 @@ func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheRe
 		mappings: endpoints(),
 		indices:  map[string][]*IndexNode{},
 		dataDir:  dataDir,
+
+		recommendations: map[pod_scaler.FullMetadata]corev1.ResourceList{},
+		configs:         configs,
 	}
 	health := pjutil.NewHealthOnPort(healthPort)
 	digestAll(loaders, map[string]digester{
 		MetricNameCPUUsage:         server.digestCPU,
 		MetricNameMemoryWorkingSet: server.digestMemory,
 	}, health, logger)
+	// ephemeral storage data is newer than the rest and may not exist for all
+	// clusters yet, so we do not wait for it to serve
+	digestAll(loaders, map[string]digester{
+		MetricNameEphemeralStorageUsage: server.digestEphemeralStorage,
+	}, nil, logger)
 
 	var nodes []simplifypath.Node
 	for name := range server.mappings {
@@ -230,6 +240,7 @@ func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheRe
 			l("indicies",
 				nodes...,
 			),
+			l("recommendations"),
 		),
 	))
 	handler := metrics.TraceHandler(simplifier, uiMetrics.HTTPRequestDuration, uiMetrics.HTTPResponseSize)
@@ -259,6 +270,7 @@ func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheRe
 		mux.HandleFunc(fmt.Sprintf("/api/data/%s", name), handler(server.getData(name)).ServeHTTP)
 		mux.HandleFunc(fmt.Sprintf("/api/indices/%s", name), handler(server.getIndex(name)).ServeHTTP)
 	}
+	mux.HandleFunc("/api/recommendations", handler(server.getRecommendations()).ServeHTTP)
 	httpServer := &http.Server{Addr: ":" + strconv.Itoa(port), Handler: mux}
 	interrupts.ListenAndServe(httpServer, 5*time.Second)
 	logger.Debug("Ready to serve HTTP requests.")
@@ -323,6 +335,12 @@ type frontendServer struct {
 
 	// dataDir is where we hold sharded data by metadata identifier
 	dataDir string
+
+	// recommendations hold the requests we recommend for every workload
+	recommendations map[pod_scaler.FullMetadata]corev1.ResourceList
+	// configs holds the ci-operator configurations, from which we serve the
+	// configured resources next to our recommendations, when set
+	configs agents.ConfigAgent
 }
 
 // dataForDisplay caches precomputed values for displaying data
@@ -331,6 +349,27 @@ type dataForDisplay struct {
 	LowerBound float64                     `json:"lower_bound"`
 	Merged     *circonusllhist.Histogram   `json:"merged"`
 	Histograms []*circonusllhist.Histogram `json:"histograms"`
+	// Trend holds the value at the quantile for every day we have data for,
+	// in chronological order
+	Trend []trendPoint `json:"trend,omitempty"`
+}
+
+// trendPoint is the value at the quantile for the data recorded on one day
+type trendPoint struct {
+	Day   string  `json:"day"`
+	Value float64 `json:"value"`
+}
+
+// trendFor determines the value at the quantile for every day of data
+func trendFor(data *pod_scaler.CachedQuery, fingerprints []model.Fingerprint, quantile float64) []trendPoint {
+	var trend []trendPoint
+	for day, hist := range data.HistogramsByDay(fingerprints) {
+		trend = append(trend, trendPoint{Day: day, Value: hist.ValueAtQuantile(quantile)})
+	}
+	sort.Slice(trend, func(i, j int) bool {
+		return trend[i].Day < trend[j].Day
+	})
+	return trend
 }
 
 func (s *frontendServer) getIndex(index string) http.HandlerFunc {
@@ -352,6 +391,66 @@ func (s *frontendServer) getIndex(index string) http.HandlerFunc {
 	}
 }
 
+// getRecommendations serves the requests we recommend for all workloads, so
+// that they can be compared to the requests configured for them
+func (s *frontendServer) getRecommendations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		s.lock.RLock()
+		recommendations := s.listRecommendations()
+		s.lock.RUnlock()
+		raw, err := json.Marshal(recommendations)
+		if err != nil {
+			metrics.RecordError("failed to marshal recommendations", uiMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to marshal recommendations to JSON: %v", err)
+			s.logger.WithError(err).Errorf("Failed to marshal recommendations to JSON.")
+			return
+		}
+		if _, err := w.Write(raw); err != nil {
+			s.logger.WithError(err).Error("failed to write recommendations response")
+		}
+	}
+}
+
+// listRecommendations lists recommendations in a stable order, callers must hold the lock
+func (s *frontendServer) listRecommendations() []pod_scaler.Recommendation {
+	recommendations := make([]pod_scaler.Recommendation, 0, len(s.recommendations))
+	for meta, requests := range s.recommendations {
+		recommendations = append(recommendations, pod_scaler.Recommendation{Metadata: meta, Requests: requests.DeepCopy(), Configured: s.configuredFor(meta)})
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		return sortKey(recommendations[i].Metadata) < sortKey(recommendations[j].Metadata)
+	})
+	return recommendations
+}
+
+// configuredFor determines the requests and limits configured for the workload,
+// which are only known for builds and container tests
+func (s *frontendServer) configuredFor(meta pod_scaler.FullMetadata) *api.ResourceRequirements {
+	if s.configs == nil {
+		return nil
+	}
+	name, configured := pod_scaler.ConfiguredName(meta)
+	if !configured {
+		return nil
+	}
+	configuration, err := s.configs.GetMatchingConfig(meta.Metadata)
+	if err != nil {
+		return nil
+	}
+	requirements := configuration.Resources.RequirementsForStep(name)
+	return &requirements
+}
+
+func sortKey(meta pod_scaler.FullMetadata) string {
+	return strings.Join([]string{meta.Org, meta.Repo, meta.Branch, meta.Variant, meta.Target, meta.Step, meta.Pod, meta.Container}, "/")
+}
+
 func (s *frontendServer) getData(index string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
@@ -463,16 +562,22 @@ func (s *frontendServer) setDatum(meta pod_scaler.FullMetadata, resource corev1.
 
 func (s *frontendServer) digestCPU(data *pod_scaler.CachedQuery) {
 	s.logger.Debugf("Digesting new CPU consumption metrics.")
-	s.digestData(data, corev1.ResourceCPU, cpuRequestQuantile)
+	s.digestData(data, corev1.ResourceCPU, cpuRequestQuantile, formatCPU())
 }
 
 func (s *frontendServer) digestMemory(data *pod_scaler.CachedQuery) {
 	s.logger.Debugf("Digesting new Memory consumption metrics.")
-	s.digestData(data, corev1.ResourceMemory, memRequestQuantile)
+	s.digestData(data, corev1.ResourceMemory, memRequestQuantile, formatMemory())
 }
 
-func (s *frontendServer) digestData(data *pod_scaler.CachedQuery, metric corev1.ResourceName, quantile float64) {
+func (s *frontendServer) digestEphemeralStorage(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new ephemeral storage consumption metrics.")
+	s.digestData(data, corev1.ResourceEphemeralStorage, ephemeralStorageRequestQuantile, formatEphemeralStorage())
+}
+
+func (s *frontendServer) digestData(data *pod_scaler.CachedQuery, metric corev1.ResourceName, quantile float64, quantity toQuantity) {
 	s.logger.Debugf("Digesting %d identifiers.", len(data.DataByMetaData))
+	now := time.Now()
 	for meta, fingerprints := range data.DataByMetaData {
 		s.lock.Lock()
 		for name, mapping := range s.mappings {
@@ -495,14 +600,24 @@ func (s *frontendServer) digestData(data *pod_scaler.CachedQuery, metric corev1.
 			overall.Merge(data.Data[fingerprint].Histogram())
 			members = append(members, data.Data[fingerprint].Histogram())
 		}
+		cutoff, err := recommendedValue(data, fingerprints, quantile, now)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to weigh data.")
+			cutoff = overall.ValueAtQuantile(quantile)
+		}
 		if err := s.setDatum(meta, metric, dataForDisplay{
-			Cutoff:     overall.ValueAtQuantile(quantile),
+			Cutoff:     cutoff,
 			LowerBound: overall.ValueAtQuantile(.001),
 			Merged:     overall,
 			Histograms: members,
+			Trend:      trendFor(data, fingerprints, quantile),
 		}); err != nil {
 			s.logger.WithError(err).Error("Could not record data.")
 		}
+		if _, exists := s.recommendations[meta]; !exists {
+			s.recommendations[meta] = corev1.ResourceList{}
+		}
+		s.recommendations[meta][metric] = *quantity(cutoff)
 		s.lock.Unlock()
 	}
 
@@ -3,6 +3,7 @@ import * as React from 'react';
 import {Alert, Flex, Spinner} from '@patternfly/react-core';
 import {DeserializeHistogram, Histogram} from "@app/CircLLHist/CircLLHist";
 import {LogarithmicComparativePlot} from "@app/CircLLHist/LogarithmicComparativePlot";
+import {Trend, TrendPoint} from "@app/Trend/Trend";
 
 export interface HistogramsProps {
     /** URL to fetch raw data from */
@@ -16,6 +17,7 @@ export interface rawData {
     lower_bound: string;
     merged: string;
     histograms: string[];
+    trend?: TrendPoint[];
 }
 
 export interface Data {
@@ -23,6 +25,7 @@ export interface Data {
     lower_bound: number;
     merged: Histogram;
     histograms: Histogram[];
+    trend: TrendPoint[];
 }
 
 export type HistogramData = Record<string, Data>;
@@ -35,6 +38,7 @@ const process = (raw: Record<string, rawData>): HistogramData => {
                 lower_bound: parseFloat(raw[resource].lower_bound),
                 merged: DeserializeHistogram(Buffer.from(raw[resource].merged, 'base64')),
                 histograms: [],
+                trend: raw[resource].trend || [],
             };
             for (const histogram of raw[resource].histograms) {
                 datum.histograms.push(DeserializeHistogram(Buffer.from(histogram, 'base64')))
@@ -44,6 +48,22 @@ const process = (raw: Record<string, rawData>): HistogramData => {
     return data;
 }
 
+const formatCPU = (value: number): string => {
+    const n: number = value * 1000;
+    if (value > 10) {
+        Math.round(n).toString();
+    }
+    return n.toFixed(2);
+}
+
+const formatMemory = (value: number): string => {
+    const n: number = value / Math.pow(2, 20);
+    if (value > 10) {
+        Math.round(n).toString();
+    }
+    return n.toFixed(2);
+}
+
 export const Histograms: React.FunctionComponent<HistogramsProps> = (
     {
         dataUrl,
@@ -87,36 +107,45 @@ export const Histograms: React.FunctionComponent<HistogramsProps> = (
                  justifyContent={{default: 'justifyContentSpaceAround'}}
                  alignItems={{default: 'alignItemsCenter'}}
                  alignContent={{default: 'alignContentStretch'}}>
-        {data["cpu"] && <LogarithmicComparativePlot
-            {...data["cpu"]}
-            canvasProps={{
-                title: "CPU Usage",
-                yAxisFormatter(value: number): string {
-                    const n: number = value * 1000;
-                    if (value > 10) {
-                        Math.round(n).toString();
-                    }
-                    return n.toFixed(2);
-                },
-                yAxisMin: 1e-5,
-                yAxisTitle: "CPU Used",
-                yAxisUnit: "mCPU",
-            }}/>}
-        {data["memory"] && <LogarithmicComparativePlot
-            {...data["memory"]}
-            canvasProps={{
-                title: "Memory Usage",
-                yAxisFormatter(value: number): string {
-                    const n: number = value / Math.pow(2, 20);
-                    if (value > 10) {
-                        Math.round(n).toString();
-                    }
-                    return n.toFixed(2);
-                },
-                yAxisMin: 10 * Math.pow(2, 20),
-                yAxisTitle: "Memory Used",
-                yAxisUnit: "MiB",
-            }}/>}
+        {data["cpu"] && <Flex direction={{default: 'column'}}>
+            <LogarithmicComparativePlot
+                {...data["cpu"]}
+                canvasProps={{
+                    title: "CPU Usage",
+                    yAxisFormatter: formatCPU,
+                    yAxisMin: 1e-5,
+                    yAxisTitle: "CPU Used",
+                    yAxisUnit: "mCPU",
+                }}/>
+            <Trend title="Daily CPU Usage Trend" trend={data["cpu"].trend} cutoff={data["cpu"].cutoff}
+                   formatter={formatCPU} unit="mCPU"/>
+        </Flex>}
+        {data["memory"] && <Flex direction={{default: 'column'}}>
+            <LogarithmicComparativePlot
+                {...data["memory"]}
+                canvasProps={{
+                    title: "Memory Usage",
+                    yAxisFormatter: formatMemory,
+                    yAxisMin: 10 * Math.pow(2, 20),
+                    yAxisTitle: "Memory Used",
+                    yAxisUnit: "MiB",
+                }}/>
+            <Trend title="Daily Memory Usage Trend" trend={data["memory"].trend} cutoff={data["memory"].cutoff}
+                   formatter={formatMemory} unit="MiB"/>
+        </Flex>}
+        {data["ephemeral-storage"] && <Flex direction={{default: 'column'}}>
+            <LogarithmicComparativePlot
+                {...data["ephemeral-storage"]}
+                canvasProps={{
+                    title: "Ephemeral Storage Usage",
+                    yAxisFormatter: formatMemory,
+                    yAxisMin: 10 * Math.pow(2, 20),
+                    yAxisTitle: "Ephemeral Storage Used",
+                    yAxisUnit: "MiB",
+                }}/>
+            <Trend title="Daily Ephemeral Storage Usage Trend" trend={data["ephemeral-storage"].trend}
+                   cutoff={data["ephemeral-storage"].cutoff} formatter={formatMemory} unit="MiB"/>
+        </Flex>}
     </Flex>;
 };
 
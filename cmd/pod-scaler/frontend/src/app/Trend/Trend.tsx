@@ -0,0 +1,72 @@
+import * as React from 'react';
+import {Text, TextContent, TextVariants} from '@patternfly/react-core';
+
+export interface TrendPoint {
+  /** the day on which the data was recorded, as YYYY-MM-DD */
+  day: string;
+  /** the value at the quantile for the data recorded on the day */
+  value: number;
+}
+
+export interface TrendProps {
+  /** title of the trend */
+  title: string;
+  /** the daily values to plot, in chronological order */
+  trend: TrendPoint[];
+  /** the recommended value, drawn as a horizontal line */
+  cutoff: number;
+  /** formatter for values */
+  formatter: (value: number) => string;
+  /** units of the values */
+  unit: string;
+}
+
+const width = 400;
+const height = 100;
+const padding = 5;
+
+export const Trend: React.FunctionComponent<TrendProps> = (
+  {
+    title,
+    trend,
+    cutoff,
+    formatter,
+    unit,
+  }: TrendProps) => {
+  if (!trend || trend.length === 0) {
+    return null;
+  }
+
+  const values = trend.map((point) => point.value).concat(cutoff);
+  const max = Math.max(...values);
+  const min = Math.min(...values);
+  const span = max - min > 0 ? max - min : 1;
+  const x = (index: number): number => {
+    if (trend.length === 1) {
+      return width / 2;
+    }
+    return padding + index * (width - 2 * padding) / (trend.length - 1);
+  };
+  const y = (value: number): number => height - padding - (value - min) * (height - 2 * padding) / span;
+  const points = trend.map((point, index) => `${x(index)},${y(point.value)}`).join(" ");
+  const first = trend[0];
+  const last = trend[trend.length - 1];
+
+  return <TextContent>
+    <Text component={TextVariants.h4}>{title}</Text>
+    <svg width={width} height={height} role="img">
+      <title>{`${title}: ${formatter(first.value)} ${unit} on ${first.day}, ${formatter(last.value)} ${unit} on ${last.day}`}</title>
+      <line x1={padding} x2={width - padding} y1={y(cutoff)} y2={y(cutoff)} stroke="#c9190b"
+            strokeDasharray="4"/>
+      <polyline points={points} fill="none" stroke="#06c" strokeWidth={2}/>
+      {trend.map((point, index) => <circle key={point.day} cx={x(index)} cy={y(point.value)} r={2} fill="#06c">
+        <title>{`${point.day}: ${formatter(point.value)} ${unit}`}</title>
+      </circle>)}
+    </svg>
+    <Text component={TextVariants.small}>
+      {`${formatter(first.value)} ${unit} on ${first.day} to ${formatter(last.value)} ${unit} on ${last.day}; recommended: ${formatter(cutoff)} ${unit}`}
+    </Text>
+  </TextContent>;
+};
+
+Trend.displayName = 'Trend';
@@ -6,8 +6,16 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/openhistogram/circonusllhist"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load/agents"
 	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
 )
 
@@ -100,3 +108,58 @@ type fakeWriter struct{}
 func (w *fakeWriter) Header() http.Header        { return nil }
 func (w *fakeWriter) Write([]byte) (int, error)  { return 0, nil }
 func (w *fakeWriter) WriteHeader(statusCode int) {}
+
+func TestDigestDataRecordsRecommendations(t *testing.T) {
+	unit := pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Target: "unit", Pod: "unit", Container: "test"}
+	build := pod_scaler.FullMetadata{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Pod: "src-build", Container: "docker"}
+	server := &frontendServer{
+		logger:          logrus.WithField("test", t.Name()),
+		mappings:        endpoints(),
+		indices:         map[string][]*IndexNode{},
+		dataDir:         t.TempDir(),
+		recommendations: map[pod_scaler.FullMetadata]corev1.ResourceList{},
+		configs: agents.NewFakeConfigAgent(config.ByOrgRepo{"org": {"repo": {{
+			Metadata: unit.Metadata,
+			Resources: api.ResourceConfiguration{
+				"*":    {Requests: api.ResourceList{"cpu": "100m"}},
+				"unit": {Requests: api.ResourceList{"memory": "4Gi"}, Limits: api.ResourceList{"memory": "8Gi"}},
+			},
+		}}}}),
+	}
+	data := func(value float64) *pod_scaler.CachedQuery {
+		return &pod_scaler.CachedQuery{
+			Data: map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{
+				1: histogramWith(t, value, 100),
+				2: histogramWith(t, value*2, 100),
+			},
+			DataByMetaData: map[pod_scaler.FullMetadata][]model.Fingerprint{unit: {1}, build: {2}},
+		}
+	}
+	server.digestData(data(1), corev1.ResourceCPU, cpuRequestQuantile, formatCPU())
+	server.digestData(data(1024*1024*1024), corev1.ResourceMemory, memRequestQuantile, formatMemory())
+
+	recommendations := server.listRecommendations()
+	if diff := cmp.Diff([]pod_scaler.FullMetadata{build, unit}, []pod_scaler.FullMetadata{recommendations[0].Metadata, recommendations[1].Metadata}); diff != "" {
+		t.Fatalf("got recommendations in incorrect order: %v", diff)
+	}
+	for i, expected := range []corev1.ResourceList{
+		{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("2Gi")},
+		{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+	} {
+		for name, value := range expected {
+			// histograms store values in bins, so check the value with their precision
+			actual := recommendations[i].Requests[name]
+			if diff := actual.MilliValue() - value.MilliValue(); diff > value.MilliValue()/10 || diff < -value.MilliValue()/10 {
+				t.Errorf("expected %s recommendation for %s to be about %s, got %s", name, recommendations[i].Metadata.Pod, value.String(), actual.String())
+			}
+		}
+	}
+	for i, expected := range []*api.ResourceRequirements{
+		{Requests: api.ResourceList{"cpu": "100m"}, Limits: api.ResourceList{}},
+		{Requests: api.ResourceList{"cpu": "100m", "memory": "4Gi"}, Limits: api.ResourceList{"memory": "8Gi"}},
+	} {
+		if diff := cmp.Diff(expected, recommendations[i].Configured); diff != "" {
+			t.Errorf("incorrect configured resources for %s: %v", recommendations[i].Metadata.Pod, diff)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/bombsimon/logrusr/v3"
 	prometheusclient "github.com/prometheus/client_golang/api"
 	prometheusapi "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -29,6 +31,7 @@ import (
 	buildclientset "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
 	routeclientset "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
 
+	"github.com/openshift/ci-tools/pkg/load/agents"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/util"
 )
@@ -47,6 +50,11 @@ type options struct {
 	cacheBucket        string
 	gcsCredentialsFile string
 
+	s3Bucket          string
+	s3Endpoint        string
+	s3Region          string
+	s3CredentialsFile string
+
 	resultsOptions results.Options
 }
 
@@ -61,10 +69,15 @@ type consumerOptions struct {
 	uiPort int
 
 	dataDir              string
+	ciOperatorConfigDir  string
 	certDir              string
 	mutateResourceLimits bool
 	cpuCap               int64
 	memoryCap            string
+	ephemeralStorageCap  string
+	downscalingConfig    string
+	schedulingConfig     string
+	buildFarm            string
 }
 
 func bindOptions(fs *flag.FlagSet) *options {
@@ -80,12 +93,21 @@ func bindOptions(fs *flag.FlagSet) *options {
 	fs.BoolVar(&o.mutateResourceLimits, "mutate-resource-limits", false, "Enable resource limit mutation in the admission webhook.")
 	fs.StringVar(&o.loglevel, "loglevel", "debug", "Logging level.")
 	fs.StringVar(&o.logStyle, "log-style", "json", "Logging style: json or text.")
-	fs.StringVar(&o.cacheDir, "cache-dir", "", "Local directory holding cache data (for development mode).")
+	fs.StringVar(&o.cacheDir, "cache-dir", "", "Local directory holding cache data (for development mode, or a volume shared between producer and consumers).")
 	fs.StringVar(&o.dataDir, "data-dir", "", "Local directory to cache UI data into.")
+	fs.StringVar(&o.ciOperatorConfigDir, "ci-operator-config-dir", "", "Path to ci-operator configurations, used to serve the configured resources next to the recommendations. Configured resources are not served when unset.")
 	fs.StringVar(&o.cacheBucket, "cache-bucket", "", "GCS bucket name holding cached Prometheus data.")
 	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "File where GCS credentials are stored.")
+	fs.StringVar(&o.s3Bucket, "s3-bucket", "", "S3 bucket name holding cached Prometheus data.")
+	fs.StringVar(&o.s3Endpoint, "s3-endpoint", "", "Endpoint of S3-compatible storage holding the S3 bucket. Uses AWS S3 when unset.")
+	fs.StringVar(&o.s3Region, "s3-region", "us-east-1", "Region of the S3 bucket.")
+	fs.StringVar(&o.s3CredentialsFile, "s3-credentials-file", "", "File where S3 credentials are stored, in the AWS shared credentials format. Credentials are loaded from the environment when unset.")
 	fs.Int64Var(&o.cpuCap, "cpu-cap", 10, "The maximum CPU request value, ex: 10")
 	fs.StringVar(&o.memoryCap, "memory-cap", "20Gi", "The maximum memory request value, ex: '20Gi'")
+	fs.StringVar(&o.ephemeralStorageCap, "ephemeral-storage-cap", "50Gi", "The maximum ephemeral storage request value, ex: '50Gi'")
+	fs.StringVar(&o.downscalingConfig, "downscaling-config", "", "Path to the configuration for reducing configured requests. Requests are never reduced when unset.")
+	fs.StringVar(&o.schedulingConfig, "scheduling-config", "", "Path to the configuration for scheduling hints added to Pods by the class of their workload. No hints are added when unset.")
+	fs.StringVar(&o.buildFarm, "build-farm", "", "Name of the build farm the admission webhook serves, selecting the scheduling hints to add.")
 	o.resultsOptions.Bind(fs)
 	return &o
 }
@@ -119,6 +141,12 @@ func (o *options) validate() error {
 		if memoryCap := resource.MustParse(o.memoryCap); memoryCap.Sign() <= 0 {
 			return errors.New("--memory-cap must be greater than 0")
 		}
+		if ephemeralStorageCap, err := resource.ParseQuantity(o.ephemeralStorageCap); err != nil || ephemeralStorageCap.Sign() <= 0 {
+			return errors.New("--ephemeral-storage-cap must be a quantity greater than 0")
+		}
+		if o.schedulingConfig != "" && o.buildFarm == "" {
+			return errors.New("--build-farm is required when --scheduling-config is set")
+		}
 		if err := o.resultsOptions.Validate(); err != nil {
 			return err
 		}
@@ -126,7 +154,16 @@ func (o *options) validate() error {
 	default:
 		return errors.New("--mode must be either \"producer\", \"consumer.ui\", or \"consumer.admission\"")
 	}
-	if o.cacheDir == "" {
+	var backends int
+	for _, backend := range []string{o.cacheDir, o.cacheBucket, o.s3Bucket} {
+		if backend != "" {
+			backends++
+		}
+	}
+	if backends > 1 {
+		return errors.New("only one of --cache-dir, --cache-bucket and --s3-bucket may be set")
+	}
+	if o.cacheDir == "" && o.s3Bucket == "" {
 		if o.cacheBucket == "" {
 			return errors.New("--cache-bucket is required")
 		}
@@ -146,6 +183,18 @@ func (o *options) validate() error {
 	return o.instrumentationOptions.Validate(false)
 }
 
+func (o *options) s3Config() *aws.Config {
+	config := aws.NewConfig().WithRegion(o.s3Region)
+	if o.s3Endpoint != "" {
+		// S3-compatible storage does not necessarily serve buckets as subdomains
+		config = config.WithEndpoint(o.s3Endpoint).WithS3ForcePathStyle(true)
+	}
+	if o.s3CredentialsFile != "" {
+		config = config.WithCredentials(credentials.NewSharedCredentials(o.s3CredentialsFile, ""))
+	}
+	return config
+}
+
 func main() {
 	flagSet := flag.NewFlagSet("", flag.ExitOnError)
 	opts := bindOptions(flagSet)
@@ -172,9 +221,16 @@ func main() {
 	metrics.ExposeMetrics("pod-scaler", prowConfig.PushGateway{}, opts.instrumentationOptions.MetricsPort)
 
 	var cache cache
-	if opts.cacheDir != "" {
+	switch {
+	case opts.cacheDir != "":
 		cache = &localCache{dir: opts.cacheDir}
-	} else {
+	case opts.s3Bucket != "":
+		s3Cache, err := newS3Cache(opts.s3Config(), opts.s3Bucket)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not initialize S3 client.")
+		}
+		cache = s3Cache
+	default:
 		gcsClient, err := storage.NewClient(interrupts.Context(), option.WithCredentialsFile(opts.gcsCredentialsFile))
 		if err != nil {
 			logrus.WithError(err).Fatal("Could not initialize GCS client.")
@@ -238,7 +294,14 @@ func mainProduce(opts *options, cache cache) {
 }
 
 func mainUI(opts *options, cache cache) {
-	go serveUI(opts.uiPort, opts.instrumentationOptions.HealthPort, opts.dataDir, loaders(cache))
+	var configs agents.ConfigAgent
+	if opts.ciOperatorConfigDir != "" {
+		var err error
+		if configs, err = agents.NewConfigAgent(opts.ciOperatorConfigDir); err != nil {
+			logrus.WithError(err).Fatal("Failed to load ci-operator configurations.")
+		}
+	}
+	go serveUI(opts.uiPort, opts.instrumentationOptions.HealthPort, opts.dataDir, loaders(cache, MetricNameCPUUsage, MetricNameMemoryWorkingSet, MetricNameEphemeralStorageUsage), configs)
 }
 
 func mainAdmission(opts *options, cache cache) {
@@ -256,15 +319,34 @@ func mainAdmission(opts *options, cache cache) {
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to create pod-scaler reporter.")
 	}
+	var downscaler *downscaler
+	if opts.downscalingConfig != "" {
+		config, err := loadDownscalingConfig(opts.downscalingConfig)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to load downscaling configuration.")
+		}
+		downscaler = newDownscaler(config)
+	}
+	var hinter *schedulingHinter
+	if opts.schedulingConfig != "" {
+		hinter, err = loadSchedulingHinter(opts.schedulingConfig, opts.buildFarm)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to load scheduling configuration.")
+		}
+		if hinter == nil {
+			logrus.Infof("No scheduling hints are configured for build farm %s.", opts.buildFarm)
+		}
+	}
 
-	go admit(opts.port, opts.instrumentationOptions.HealthPort, opts.certDir, client, loaders(cache), opts.mutateResourceLimits, opts.cpuCap, opts.memoryCap, reporter)
+	go admit(opts.port, opts.instrumentationOptions.HealthPort, opts.certDir, client, loaders(cache, MetricNameCPUUsage, MetricNameMemoryWorkingSet, MetricNameEphemeralStorageUsage, MetricNameCPUThrottling, MetricNameOOMKilled), opts.mutateResourceLimits, opts.cpuCap, opts.memoryCap, opts.ephemeralStorageCap, reporter, downscaler, hinter)
 }
 
-func loaders(cache cache) map[string][]*cacheReloader {
+func loaders(cache cache, metrics ...string) map[string][]*cacheReloader {
 	l := map[string][]*cacheReloader{}
 	for _, prefix := range []string{prowjobsCachePrefix, podsCachePrefix, stepsCachePrefix} {
-		l[MetricNameCPUUsage] = append(l[MetricNameCPUUsage], newReloader(prefix+"/"+MetricNameCPUUsage, cache))
-		l[MetricNameMemoryWorkingSet] = append(l[MetricNameMemoryWorkingSet], newReloader(prefix+"/"+MetricNameMemoryWorkingSet, cache))
+		for _, metric := range metrics {
+			l[metric] = append(l[metric], newReloader(prefix+"/"+metric, cache))
+		}
 	}
 	return l
 }
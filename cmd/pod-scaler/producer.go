@@ -23,8 +23,19 @@ import (
 const (
 	MetricNameCPUUsage         = `container_cpu_usage_seconds_total`
 	MetricNameMemoryWorkingSet = `container_memory_working_set_bytes`
+	// MetricNameEphemeralStorageUsage holds the usage of the writable layer and logs of containers
+	MetricNameEphemeralStorageUsage = `container_fs_usage_bytes`
+	// MetricNameCPUThrottling holds the fraction of CPU periods in which a container was throttled
+	MetricNameCPUThrottling = `container_cpu_cfs_throttled_periods_total`
+	// MetricNameOOMKilled holds samples for containers terminated by the OOM killer,
+	// either for good or before they were restarted
+	MetricNameOOMKilled = `kube_pod_container_status_terminated_reason`
+	// metricNameOOMRestarted holds samples for containers that were restarted after
+	// they were terminated by the OOM killer
+	metricNameOOMRestarted = `kube_pod_container_status_last_terminated_reason`
 
 	containerFilter = `{container!="POD",container!=""}`
+	oomKilledFilter = `{container!="POD",container!="",reason="OOMKilled"}`
 
 	// MaxSamplesPerRequest is the maximum number of samples that Prometheus will allow a client to ask for in
 	// one request. We also use this to approximate the maximum number of samples we should be asking any one
@@ -61,8 +72,11 @@ func queriesByMetric() map[string]string {
 		},
 	} {
 		for name, metric := range map[string]string{
-			MetricNameCPUUsage:         `rate(` + MetricNameCPUUsage + containerFilter + `[3m])`,
-			MetricNameMemoryWorkingSet: MetricNameMemoryWorkingSet + containerFilter,
+			MetricNameCPUUsage:              `rate(` + MetricNameCPUUsage + containerFilter + `[3m])`,
+			MetricNameMemoryWorkingSet:      MetricNameMemoryWorkingSet + containerFilter,
+			MetricNameEphemeralStorageUsage: MetricNameEphemeralStorageUsage + containerFilter,
+			MetricNameCPUThrottling:         `rate(` + MetricNameCPUThrottling + containerFilter + `[3m]) / rate(container_cpu_cfs_periods_total` + containerFilter + `[3m])`,
+			MetricNameOOMKilled:             `(` + MetricNameOOMKilled + oomKilledFilter + ` or ` + metricNameOOMRestarted + oomKilledFilter + `)`,
 		} {
 			queries[fmt.Sprintf("%s/%s", info.prefix, name)] = queryFor(metric, info.selector, info.labels)
 		}
@@ -97,6 +111,7 @@ func produce(clients map[string]prometheusapi.API, dataCache cache, ignoreLatest
 					RangesByCluster: ranges,
 					Data:            map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{},
 					DataByMetaData:  map[pod_scaler.FullMetadata][]model.Fingerprint{},
+					DataByDay:       map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{},
 				}
 			} else if err != nil {
 				logrus.WithError(err).Error("Failed to load data from storage.")
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"math"
 	"sync"
+	"time"
 
 	"github.com/openhistogram/circonusllhist"
+	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 
 	corev1 "k8s.io/api/core/v1"
@@ -19,11 +22,22 @@ func newResourceServer(loaders map[string][]*cacheReloader, health *pjutil.Healt
 		logger:     logger,
 		lock:       sync.RWMutex{},
 		byMetaData: map[pod_scaler.FullMetadata]corev1.ResourceRequirements{},
+		incidents:  map[pod_scaler.FullMetadata]map[corev1.ResourceName]time.Time{},
+		oomKills:   map[pod_scaler.FullMetadata]oomKills{},
+		burstiness: map[pod_scaler.FullMetadata]burstiness{},
 	}
 	digestAll(loaders, map[string]digester{
 		MetricNameCPUUsage:         server.digestCPU,
 		MetricNameMemoryWorkingSet: server.digestMemory,
 	}, health, logger)
+	// incidents only guard the reduction of requests and bump requests beyond
+	// our recommendations, and ephemeral storage requests are only set once we
+	// have data for them, so we can serve without them
+	digestAll(loaders, map[string]digester{
+		MetricNameEphemeralStorageUsage: server.digestEphemeralStorage,
+		MetricNameCPUThrottling:         server.digestCPUThrottling,
+		MetricNameOOMKilled:             server.digestOOMKills,
+	}, nil, logger)
 
 	return server
 }
@@ -34,6 +48,32 @@ type resourceServer struct {
 	// byMetaData caches resource requirements calculated for the full assortment of
 	// metadata labels.
 	byMetaData map[pod_scaler.FullMetadata]corev1.ResourceRequirements
+	// incidents holds the last day on which workloads were observed to run out
+	// of a resource, by being throttled or killed for lack of memory.
+	incidents map[pod_scaler.FullMetadata]map[corev1.ResourceName]time.Time
+	// oomKills holds the recent OOM kills of workloads, for which we recommend
+	// more memory than their usage suggests, as usage data of a container that
+	// was killed never shows how much memory it would have needed.
+	oomKills map[pod_scaler.FullMetadata]oomKills
+	// burstiness holds the ratio of peak to median CPU usage of workloads, which
+	// is used to classify workloads for scheduling hints.
+	burstiness map[pod_scaler.FullMetadata]burstiness
+}
+
+// burstiness describes how bursty the CPU usage of a workload is
+type burstiness struct {
+	// ratio is the ratio of peak to median CPU usage
+	ratio float64
+	// last is the last day for which we have CPU usage data of the workload
+	last time.Time
+}
+
+// oomKills describes the OOM kills of a workload in the OOM kill window
+type oomKills struct {
+	// executions is the number of executions that were OOM killed
+	executions int
+	// last is the last day on which an execution was OOM killed
+	last time.Time
 }
 
 const (
@@ -50,6 +90,47 @@ func formatCPU() toQuantity {
 func (s *resourceServer) digestCPU(data *pod_scaler.CachedQuery) {
 	s.logger.Debugf("Digesting new CPU consumption metrics.")
 	s.digestData(data, cpuRequestQuantile, corev1.ResourceCPU, formatCPU())
+	s.digestBurstiness(data)
+}
+
+// digestBurstiness determines the ratio of peak to median CPU usage of workloads
+func (s *resourceServer) digestBurstiness(data *pod_scaler.CachedQuery) {
+	now := time.Now()
+	for meta, fingerprints := range data.DataByMetaData {
+		overall := circonusllhist.New()
+		for _, fingerprint := range fingerprints {
+			if hist, exists := data.Data[fingerprint]; exists {
+				overall.Merge(hist.Histogram())
+			}
+		}
+		median := overall.ValueAtQuantile(0.5)
+		if overall.Count() == 0 || median <= 0 {
+			continue
+		}
+		var lastDay string
+		for day := range data.HistogramsByDay(fingerprints) {
+			if day > lastDay {
+				lastDay = day
+			}
+		}
+		// data that predates recording days is not aged out, so it counts as current
+		last := now
+		if day, err := time.Parse(pod_scaler.DayFormat, lastDay); err == nil {
+			last = day
+		}
+		s.lock.Lock()
+		s.burstiness[meta] = burstiness{ratio: overall.ValueAtQuantile(burstyQuantile) / median, last: last}
+		s.lock.Unlock()
+	}
+	// workloads that do not show up in the data anymore would otherwise be
+	// held forever, so we forget them once their data would have aged out
+	s.lock.Lock()
+	for meta, b := range s.burstiness {
+		if now.Sub(b.last) > dataRetention {
+			delete(s.burstiness, meta)
+		}
+	}
+	s.lock.Unlock()
 }
 
 const (
@@ -68,20 +149,77 @@ func (s *resourceServer) digestMemory(data *pod_scaler.CachedQuery) {
 	s.digestData(data, memRequestQuantile, corev1.ResourceMemory, formatMemory())
 }
 
+const (
+	// ephemeralStorageRequestQuantile is the quantile of ephemeral storage usage data to use
+	// as the ephemeral storage request. Usage only grows over the lifetime of a container and
+	// running out of it gets the Pod evicted, so we aim closer to the peak than for memory.
+	ephemeralStorageRequestQuantile = 0.9
+)
+
+func formatEphemeralStorage() toQuantity {
+	return func(valueAtQuantile float64) *resource.Quantity {
+		return resource.NewQuantity(int64(valueAtQuantile), resource.BinarySI)
+	}
+}
+
+func (s *resourceServer) digestEphemeralStorage(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new ephemeral storage consumption metrics.")
+	s.digestData(data, ephemeralStorageRequestQuantile, corev1.ResourceEphemeralStorage, formatEphemeralStorage())
+}
+
 type toQuantity func(valueAtQuantile float64) (quantity *resource.Quantity)
 
+const (
+	// dataHalfLife is the age at which usage data weighs half as much as usage
+	// data from today when determining recommendations
+	dataHalfLife = 14 * 24 * time.Hour
+	// trendWindow is the window of most recent usage data which is considered on
+	// its own, so that growth in usage is not hidden by older data
+	trendWindow = 3 * 24 * time.Hour
+)
+
+func decayAt(now time.Time) pod_scaler.Decay {
+	return pod_scaler.Decay{Now: now, HalfLife: dataHalfLife, LegacyAge: dataRetention}
+}
+
+// recommendedValue determines the value at the quantile of the usage data, where
+// recent data weighs more than older data. As decay only lets growth in usage
+// affect the value slowly, the value at the quantile of the data in the trend
+// window is used instead when it is larger.
+func recommendedValue(data *pod_scaler.CachedQuery, fingerprints []model.Fingerprint, quantile float64, now time.Time) (float64, error) {
+	weighted, err := data.WeightedHistogram(fingerprints, decayAt(now))
+	if err != nil {
+		return 0, err
+	}
+	value := weighted.ValueAtQuantile(quantile)
+	recent := circonusllhist.New()
+	cutoff := now.Add(-trendWindow).UTC().Format(pod_scaler.DayFormat)
+	for day, hist := range data.HistogramsByDay(fingerprints) {
+		if day >= cutoff {
+			recent.Merge(hist)
+		}
+	}
+	if recent.Count() > 0 {
+		if recentValue := recent.ValueAtQuantile(quantile); recentValue > value {
+			value = recentValue
+		}
+	}
+	return value, nil
+}
+
 func (s *resourceServer) digestData(data *pod_scaler.CachedQuery, quantile float64, request corev1.ResourceName, quantity toQuantity) {
 	logger := s.logger.WithField("resource", request)
 	logger.Debugf("Digesting %d identifiers.", len(data.DataByMetaData))
+	now := time.Now()
 	for meta, fingerprints := range data.DataByMetaData {
-		overall := circonusllhist.New()
 		metaLogger := logger.WithField("meta", meta)
 		metaLogger.Tracef("digesting %d fingerprints", len(fingerprints))
-		for _, fingerprint := range fingerprints {
-			overall.Merge(data.Data[fingerprint].Histogram())
+		valueAtQuantile, err := recommendedValue(data, fingerprints, quantile, now)
+		if err != nil {
+			metaLogger.WithError(err).Warn("Failed to weigh data.")
+			continue
 		}
 		metaLogger.Trace("merged all fingerprints")
-		valueAtQuantile := overall.ValueAtQuantile(quantile)
 		metaLogger.Trace("locking for value update")
 		s.lock.Lock()
 		if _, exists := s.byMetaData[meta]; !exists {
@@ -98,9 +236,167 @@ func (s *resourceServer) digestData(data *pod_scaler.CachedQuery, quantile float
 	logger.Debug("Finished digesting new data.")
 }
 
+const (
+	// throttlingQuantile is the quantile of the fraction of throttled CPU periods
+	// that is compared to the threshold to determine if a workload lacked CPU
+	throttlingQuantile = 0.9
+	// throttlingThreshold is the fraction of throttled CPU periods above which
+	// we consider a workload to lack CPU
+	throttlingThreshold = 0.25
+)
+
+func (s *resourceServer) digestCPUThrottling(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new CPU throttling metrics.")
+	s.digestIncidents(data, corev1.ResourceCPU, func(hist *circonusllhist.Histogram) bool {
+		return hist.ValueAtQuantile(throttlingQuantile) > throttlingThreshold
+	})
+}
+
+const (
+	// oomKillWindow is how long OOM kills of a workload affect the memory we recommend
+	oomKillWindow = 2 * week
+	// oomKillBump is the fraction by which we increase the recommended memory for
+	// every execution of a workload that was OOM killed in the window
+	oomKillBump = 0.25
+	// maxOOMKillBump is the largest fraction by which we increase the recommended
+	// memory for a workload that was OOM killed
+	maxOOMKillBump = 1.0
+)
+
+// isOOMKill determines if the data shows an OOM kill, as the metric is set
+// to one for containers that were OOM killed
+func isOOMKill(hist *circonusllhist.Histogram) bool {
+	return hist.ValueAtQuantile(1) > 0.5
+}
+
+func (s *resourceServer) digestOOMKills(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new OOM kill metrics.")
+	s.digestIncidents(data, corev1.ResourceMemory, isOOMKill)
+
+	now := time.Now()
+	cutoff := now.Add(-oomKillWindow).UTC().Format(pod_scaler.DayFormat)
+	for meta, fingerprints := range data.DataByMetaData {
+		var kills oomKills
+		var last string
+		// every fingerprint identifies one execution of the container
+		for _, fingerprint := range fingerprints {
+			killed := false
+			for day, hist := range data.DataByDay[fingerprint] {
+				if day >= cutoff && isOOMKill(hist.Histogram()) {
+					killed = true
+					if day > last {
+						last = day
+					}
+				}
+			}
+			if killed {
+				kills.executions++
+			}
+		}
+		s.lock.Lock()
+		if kills.executions == 0 {
+			delete(s.oomKills, meta)
+		} else if day, err := time.Parse(pod_scaler.DayFormat, last); err != nil {
+			s.logger.WithError(err).Warn("Failed to parse the day of an OOM kill.")
+		} else {
+			kills.last = day
+			s.oomKills[meta] = kills
+		}
+		s.lock.Unlock()
+	}
+	// workloads that do not show up in the data anymore would otherwise be
+	// held forever, even though their OOM kills no longer affect anything
+	s.lock.Lock()
+	for meta, kills := range s.oomKills {
+		if now.Sub(kills.last) >= oomKillWindow {
+			delete(s.oomKills, meta)
+		}
+	}
+	s.lock.Unlock()
+}
+
+// bumpAfterOOMKills increases the recommended memory for workloads that were
+// recently OOM killed, more so the more executions were killed
+func bumpAfterOOMKills(requirements *corev1.ResourceRequirements, kills oomKills, now time.Time) {
+	if kills.executions == 0 || now.Sub(kills.last) >= oomKillWindow {
+		return
+	}
+	memory, ok := requirements.Requests[corev1.ResourceMemory]
+	if !ok {
+		return
+	}
+	bump := math.Min(float64(kills.executions)*oomKillBump, maxOOMKillBump)
+	requirements.Requests[corev1.ResourceMemory] = *resource.NewQuantity(int64(float64(memory.Value())*(1+bump)), memory.Format)
+}
+
+// digestIncidents records the last day on which each workload had data that
+// shows an incident for the resource
+func (s *resourceServer) digestIncidents(data *pod_scaler.CachedQuery, resource corev1.ResourceName, isIncident func(hist *circonusllhist.Histogram) bool) {
+	logger := s.logger.WithField("resource", resource)
+	now := time.Now()
+	cutoff := now.Add(-incidentWindow).UTC().Format(pod_scaler.DayFormat)
+	for meta, fingerprints := range data.DataByMetaData {
+		var last string
+		for day, hist := range data.HistogramsByDay(fingerprints) {
+			if day >= cutoff && day > last && isIncident(hist) {
+				last = day
+			}
+		}
+		if last == "" {
+			continue
+		}
+		incident, err := time.Parse(pod_scaler.DayFormat, last)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to parse the day of an incident.")
+			continue
+		}
+		s.lock.Lock()
+		if _, exists := s.incidents[meta]; !exists {
+			s.incidents[meta] = map[corev1.ResourceName]time.Time{}
+		}
+		s.incidents[meta][resource] = incident
+		s.lock.Unlock()
+	}
+	// incidents outside of the window no longer matter, and workloads that do
+	// not show up in the data anymore would otherwise be held forever
+	s.lock.Lock()
+	for meta, incidents := range s.incidents {
+		if incident, recorded := incidents[resource]; recorded && now.Sub(incident) >= incidentWindow {
+			delete(incidents, resource)
+		}
+		if len(incidents) == 0 {
+			delete(s.incidents, meta)
+		}
+	}
+	s.lock.Unlock()
+	logger.Debug("Finished digesting new incident data.")
+}
+
+// incidentsFor returns the last days on which the workload ran out of resources
+func (s *resourceServer) incidentsFor(meta pod_scaler.FullMetadata) map[corev1.ResourceName]time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	incidents := map[corev1.ResourceName]time.Time{}
+	for resource, incident := range s.incidents[meta] {
+		incidents[resource] = incident
+	}
+	return incidents
+}
+
+func (s *resourceServer) burstinessFor(meta pod_scaler.FullMetadata) float64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.burstiness[meta].ratio
+}
+
 func (s *resourceServer) recommendedRequestFor(meta pod_scaler.FullMetadata) (corev1.ResourceRequirements, bool) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	data, ok := s.byMetaData[meta]
+	if !ok {
+		return data, ok
+	}
+	data = *data.DeepCopy()
+	bumpAfterOOMKills(&data, s.oomKills[meta], time.Now())
 	return data, ok
 }
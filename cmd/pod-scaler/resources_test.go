@@ -0,0 +1,221 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openhistogram/circonusllhist"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+func histogramWith(t *testing.T, value float64, count int64) *circonusllhist.HistogramWithoutLookups {
+	hist := circonusllhist.New(circonusllhist.NoLookup())
+	if err := hist.RecordValues(value, count); err != nil {
+		t.Fatalf("failed to insert value into histogram, this should never happen: %v", err)
+	}
+	return circonusllhist.NewHistogramWithoutLookups(hist)
+}
+
+func TestRecommendedValue(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := func(daysAgo int) string {
+		return now.Add(-time.Duration(daysAgo) * 24 * time.Hour).Format(pod_scaler.DayFormat)
+	}
+	var testCases = []struct {
+		name     string
+		byDay    map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups
+		expected float64
+	}{
+		{
+			name: "old high usage decays away",
+			byDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+				1: {day(42): histogramWith(t, 100, 100)},
+				2: {day(7): histogramWith(t, 10, 100)},
+			},
+			expected: 10,
+		},
+		{
+			name: "recent growth is honored immediately",
+			byDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+				1: {day(10): histogramWith(t, 10, 1000)},
+				2: {day(1): histogramWith(t, 100, 100)},
+			},
+			expected: 100,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data := &pod_scaler.CachedQuery{
+				Data:      map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{},
+				DataByDay: testCase.byDay,
+			}
+			var fingerprints []model.Fingerprint
+			for fingerprint, byDay := range testCase.byDay {
+				fingerprints = append(fingerprints, fingerprint)
+				for _, hist := range byDay {
+					data.Data[fingerprint] = hist
+				}
+			}
+			actual, err := recommendedValue(data, fingerprints, 0.8, now)
+			if err != nil {
+				t.Fatalf("failed to determine recommendation: %v", err)
+			}
+			// histograms store values in bins, so check the value with their precision
+			if math.Abs(actual-testCase.expected) > testCase.expected/10 {
+				t.Errorf("expected recommendation to be about %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTrendFor(t *testing.T) {
+	data := &pod_scaler.CachedQuery{
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			1: {"2021-01-02": histogramWith(t, 2, 10), "2021-01-01": histogramWith(t, 1, 10)},
+			2: {"2021-01-03": histogramWith(t, 3, 10)},
+			3: {"2021-01-04": histogramWith(t, 4, 10)},
+		},
+	}
+	var days []string
+	for _, point := range trendFor(data, []model.Fingerprint{1, 2}, 0.8) {
+		days = append(days, point.Day)
+	}
+	if diff := cmp.Diff([]string{"2021-01-01", "2021-01-02", "2021-01-03"}, days); diff != "" {
+		t.Errorf("got incorrect days in trend: %v", diff)
+	}
+}
+
+func TestDigestOOMKills(t *testing.T) {
+	today := time.Now().UTC()
+	recent := today.Add(-2 * 24 * time.Hour).Format(pod_scaler.DayFormat)
+	old := today.Add(-30 * 24 * time.Hour).Format(pod_scaler.DayFormat)
+	killed := pod_scaler.FullMetadata{Step: "killed"}
+	killedLongAgo := pod_scaler.FullMetadata{Step: "killed-long-ago"}
+	healthy := pod_scaler.FullMetadata{Step: "healthy"}
+	gone := pod_scaler.FullMetadata{Step: "gone"}
+	data := &pod_scaler.CachedQuery{
+		DataByMetaData: map[pod_scaler.FullMetadata][]model.Fingerprint{
+			killed:        {1, 2},
+			killedLongAgo: {3},
+			healthy:       {4},
+		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			1: {recent: histogramWith(t, 1, 5)},
+			2: {old: histogramWith(t, 1, 5)},
+			3: {old: histogramWith(t, 1, 5)},
+			4: {recent: histogramWith(t, 0, 5)},
+		},
+	}
+	server := &resourceServer{
+		logger: logrus.WithField("test", t.Name()),
+		incidents: map[pod_scaler.FullMetadata]map[corev1.ResourceName]time.Time{
+			gone: {corev1.ResourceMemory: today.Add(-30 * 24 * time.Hour)},
+		},
+		oomKills: map[pod_scaler.FullMetadata]oomKills{
+			healthy: {executions: 1},
+			gone:    {executions: 1, last: today.Add(-30 * 24 * time.Hour)},
+		},
+	}
+	server.digestOOMKills(data)
+
+	expected, err := time.Parse(pod_scaler.DayFormat, recent)
+	if err != nil {
+		t.Fatalf("failed to parse day: %v", err)
+	}
+	if diff := cmp.Diff(map[pod_scaler.FullMetadata]map[corev1.ResourceName]time.Time{
+		killed: {corev1.ResourceMemory: expected},
+	}, server.incidents); diff != "" {
+		t.Errorf("incorrect incidents: %v", diff)
+	}
+	if diff := cmp.Diff(map[pod_scaler.FullMetadata]oomKills{
+		killed: {executions: 1, last: expected},
+	}, server.oomKills, cmp.AllowUnexported(oomKills{})); diff != "" {
+		t.Errorf("incorrect OOM kills: %v", diff)
+	}
+}
+
+func TestBumpAfterOOMKills(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		name     string
+		kills    oomKills
+		expected string
+	}{
+		{
+			name:     "no OOM kills",
+			expected: "1Gi",
+		},
+		{
+			name:     "OOM kills outside of the window",
+			kills:    oomKills{executions: 2, last: now.Add(-3 * week)},
+			expected: "1Gi",
+		},
+		{
+			name:     "recent OOM kill",
+			kills:    oomKills{executions: 1, last: now.Add(-24 * time.Hour)},
+			expected: "1280Mi",
+		},
+		{
+			name:     "many recent OOM kills are capped",
+			kills:    oomKills{executions: 10, last: now.Add(-24 * time.Hour)},
+			expected: "2Gi",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requirements := &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}}
+			bumpAfterOOMKills(requirements, testCase.kills, now)
+			if actual, expected := requirements.Requests[corev1.ResourceMemory], resource.MustParse(testCase.expected); actual.Cmp(expected) != 0 {
+				t.Errorf("expected memory request %s, got %s", expected.String(), actual.String())
+			}
+		})
+	}
+}
+
+func TestDigestBurstiness(t *testing.T) {
+	today := time.Now().UTC()
+	recent := today.Add(-2 * 24 * time.Hour).Format(pod_scaler.DayFormat)
+	bursty := pod_scaler.FullMetadata{Step: "bursty"}
+	gone := pod_scaler.FullMetadata{Step: "gone"}
+	hist := circonusllhist.New(circonusllhist.NoLookup())
+	if err := hist.RecordValues(1, 100); err != nil {
+		t.Fatalf("failed to insert value into histogram: %v", err)
+	}
+	if err := hist.RecordValues(10, 2); err != nil {
+		t.Fatalf("failed to insert value into histogram: %v", err)
+	}
+	data := &pod_scaler.CachedQuery{
+		Data:           map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{1: circonusllhist.NewHistogramWithoutLookups(hist)},
+		DataByMetaData: map[pod_scaler.FullMetadata][]model.Fingerprint{bursty: {1}},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			1: {recent: circonusllhist.NewHistogramWithoutLookups(hist)},
+		},
+	}
+	server := &resourceServer{
+		logger:     logrus.WithField("test", t.Name()),
+		burstiness: map[pod_scaler.FullMetadata]burstiness{gone: {ratio: 10, last: today.Add(-dataRetention - 24*time.Hour)}},
+	}
+	server.digestBurstiness(data)
+
+	if _, exists := server.burstiness[gone]; exists {
+		t.Error("expected the burstiness of a workload without recent data to be forgotten")
+	}
+	if ratio := server.burstinessFor(bursty); ratio < 5 {
+		t.Errorf("expected the workload to be bursty, got a ratio of %v", ratio)
+	}
+	expected, err := time.Parse(pod_scaler.DayFormat, recent)
+	if err != nil {
+		t.Fatalf("failed to parse day: %v", err)
+	}
+	if last := server.burstiness[bursty].last; !last.Equal(expected) {
+		t.Errorf("expected the last day of data to be %v, got %v", expected, last)
+	}
+}
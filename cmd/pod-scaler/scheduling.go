@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+// workloadClass describes the shape of the resource usage of a workload
+type workloadClass string
+
+const (
+	workloadClassCPUHeavy    workloadClass = "cpu-heavy"
+	workloadClassMemoryHeavy workloadClass = "memory-heavy"
+	workloadClassBalanced    workloadClass = "balanced"
+	// workloadClassBursty workloads use much more CPU at their peaks than usual
+	workloadClassBursty workloadClass = "bursty"
+)
+
+// workloadClassLabel is added to Pods to record their class, so that they can
+// be spread out over nodes by their class
+const workloadClassLabel = "pod-scaler.openshift.io/workload-class"
+
+const (
+	defaultCPUHeavyCoresPerGiB   = 1
+	defaultMemoryHeavyGiBPerCore = 8
+	defaultBurstyRatio           = 4
+	// burstyQuantile is the quantile of CPU usage that is compared to the median
+	// to determine how bursty a workload is
+	burstyQuantile = 0.99
+)
+
+// schedulingConfig configures the scheduling hints we add to Pods by the class
+// of their workload, for every build farm
+type schedulingConfig struct {
+	BuildFarms map[string]buildFarmSchedulingConfig `json:"build_farms"`
+}
+
+type buildFarmSchedulingConfig struct {
+	// CPUHeavyCoresPerGiB is the number of CPU cores per GiB of memory at or above
+	// which workloads are CPU-heavy.
+	CPUHeavyCoresPerGiB float64 `json:"cpu_heavy_cores_per_gib,omitempty"`
+	// MemoryHeavyGiBPerCore is the GiB of memory per CPU core at or above which
+	// workloads are memory-heavy.
+	MemoryHeavyGiBPerCore float64 `json:"memory_heavy_gib_per_core,omitempty"`
+	// BurstyRatio is the ratio of peak to median CPU usage at or above which
+	// workloads are bursty, regardless of the ratio of their requests.
+	BurstyRatio float64 `json:"bursty_ratio,omitempty"`
+	// Hints are added to Pods by the class of their workload.
+	Hints map[workloadClass]schedulingHints `json:"hints"`
+}
+
+// schedulingHints are added to Pods to inform the scheduler of the shape of their workload
+type schedulingHints struct {
+	// PreferredNodeAffinity is added to the preferred node affinity of the Pod.
+	PreferredNodeAffinity []corev1.PreferredSchedulingTerm `json:"preferred_node_affinity,omitempty"`
+	// PriorityClassName is set for Pods that do not have a priority class yet.
+	PriorityClassName string `json:"priority_class_name,omitempty"`
+	// Priority is the value of the priority class, which needs to be set with it as
+	// the priority admission plugin resolves priority classes before we mutate Pods.
+	Priority *int32 `json:"priority,omitempty"`
+	// TopologySpreadKey is the topology key over which Pods of the class are spread.
+	TopologySpreadKey string `json:"topology_spread_key,omitempty"`
+}
+
+func (c *buildFarmSchedulingConfig) defaulted() {
+	if c.CPUHeavyCoresPerGiB == 0 {
+		c.CPUHeavyCoresPerGiB = defaultCPUHeavyCoresPerGiB
+	}
+	if c.MemoryHeavyGiBPerCore == 0 {
+		c.MemoryHeavyGiBPerCore = defaultMemoryHeavyGiBPerCore
+	}
+	if c.BurstyRatio == 0 {
+		c.BurstyRatio = defaultBurstyRatio
+	}
+}
+
+func (c *schedulingConfig) validate() error {
+	var errs []error
+	for name, farm := range c.BuildFarms {
+		if farm.CPUHeavyCoresPerGiB < 0 {
+			errs = append(errs, fmt.Errorf("build_farms.%s.cpu_heavy_cores_per_gib must not be negative", name))
+		}
+		if farm.MemoryHeavyGiBPerCore < 0 {
+			errs = append(errs, fmt.Errorf("build_farms.%s.memory_heavy_gib_per_core must not be negative", name))
+		}
+		if farm.BurstyRatio != 0 && farm.BurstyRatio <= 1 {
+			errs = append(errs, fmt.Errorf("build_farms.%s.bursty_ratio must be larger than 1", name))
+		}
+		for class, hints := range farm.Hints {
+			switch class {
+			case workloadClassCPUHeavy, workloadClassMemoryHeavy, workloadClassBalanced, workloadClassBursty:
+			default:
+				errs = append(errs, fmt.Errorf("build_farms.%s.hints: unknown workload class %s", name, class))
+			}
+			if (hints.PriorityClassName == "") != (hints.Priority == nil) {
+				errs = append(errs, fmt.Errorf("build_farms.%s.hints.%s: priority_class_name and priority must be set together", name, class))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// loadSchedulingHinter loads the scheduling configuration for the build farm, if there is any
+func loadSchedulingHinter(path, buildFarm string) (*schedulingHinter, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read scheduling configuration: %w", err)
+	}
+	var config schedulingConfig
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("could not unmarshal scheduling configuration: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scheduling configuration: %w", err)
+	}
+	farm, configured := config.BuildFarms[buildFarm]
+	if !configured {
+		return nil, nil
+	}
+	return newSchedulingHinter(farm), nil
+}
+
+func newSchedulingHinter(config buildFarmSchedulingConfig) *schedulingHinter {
+	config.defaulted()
+	return &schedulingHinter{config: config}
+}
+
+// schedulingHinter classifies workloads by their historical resource usage and adds
+// scheduling hints to their Pods, so that workloads of a similar shape do not pile
+// onto the same nodes
+type schedulingHinter struct {
+	config buildFarmSchedulingConfig
+}
+
+// classify determines the class of a workload from the ratio of its CPU to
+// memory requests and the burstiness of its CPU usage
+func (h *schedulingHinter) classify(requests corev1.ResourceList, burstiness float64) (workloadClass, bool) {
+	cpu, memory := requests[corev1.ResourceCPU], requests[corev1.ResourceMemory]
+	cores, gibibytes := float64(cpu.MilliValue())/1000, float64(memory.Value())/(1024*1024*1024)
+	switch {
+	case cores == 0 && gibibytes == 0:
+		return "", false
+	case burstiness >= h.config.BurstyRatio:
+		return workloadClassBursty, true
+	case gibibytes == 0 || cores/gibibytes >= h.config.CPUHeavyCoresPerGiB:
+		return workloadClassCPUHeavy, true
+	case cores == 0 || gibibytes/cores >= h.config.MemoryHeavyGiBPerCore:
+		return workloadClassMemoryHeavy, true
+	default:
+		return workloadClassBalanced, true
+	}
+}
+
+// hint classifies the workload of the Pod by the recommendations for its containers
+// and adds the hints configured for the class
+func (h *schedulingHinter) hint(pod *corev1.Pod, server *resourceServer, logger *logrus.Entry) {
+	if h == nil {
+		return
+	}
+	requests := corev1.ResourceList{}
+	var burstiness float64
+	for _, container := range pod.Spec.Containers {
+		meta := pod_scaler.MetadataFor(pod.ObjectMeta.Labels, pod.ObjectMeta.Name, container.Name)
+		resources, recommendationExists := server.recommendedRequestFor(meta)
+		if !recommendationExists {
+			continue
+		}
+		for _, field := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			total := requests[field]
+			total.Add(resources.Requests[field])
+			requests[field] = total
+		}
+		if containerBurstiness := server.burstinessFor(meta); containerBurstiness > burstiness {
+			burstiness = containerBurstiness
+		}
+	}
+	class, classified := h.classify(requests, burstiness)
+	if !classified {
+		return
+	}
+	logger.Debugf("classified workload as %s", class)
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[workloadClassLabel] = string(class)
+
+	hints, configured := h.config.Hints[class]
+	if !configured {
+		return
+	}
+	if len(hints.PreferredNodeAffinity) > 0 {
+		if pod.Spec.Affinity == nil {
+			pod.Spec.Affinity = &corev1.Affinity{}
+		}
+		if pod.Spec.Affinity.NodeAffinity == nil {
+			pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+		}
+		pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, hints.PreferredNodeAffinity...)
+	}
+	if hints.PriorityClassName != "" && pod.Spec.PriorityClassName == "" {
+		pod.Spec.PriorityClassName = hints.PriorityClassName
+		priority := *hints.Priority
+		pod.Spec.Priority = &priority
+	}
+	if hints.TopologySpreadKey != "" && !spreadsOver(pod, hints.TopologySpreadKey) {
+		pod.Spec.TopologySpreadConstraints = append(pod.Spec.TopologySpreadConstraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       hints.TopologySpreadKey,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{workloadClassLabel: string(class)}},
+		})
+	}
+}
+
+// spreadsOver determines if the Pod already is spread over the topology key, as
+// only one constraint may exist for a key and a kind of unsatisfiability
+func spreadsOver(pod *corev1.Pod, key string) bool {
+	for _, constraint := range pod.Spec.TopologySpreadConstraints {
+		if constraint.TopologyKey == key && constraint.WhenUnsatisfiable == corev1.ScheduleAnyway {
+			return true
+		}
+	}
+	return false
+}
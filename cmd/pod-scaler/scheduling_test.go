@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestClassify(t *testing.T) {
+	hinter := newSchedulingHinter(buildFarmSchedulingConfig{})
+	var testCases = []struct {
+		name        string
+		cpu, memory string
+		burstiness  float64
+		expected    workloadClass
+		classified  bool
+	}{
+		{
+			name:   "no requests",
+			cpu:    "0",
+			memory: "0",
+		},
+		{
+			name:       "many cores per GiB",
+			cpu:        "4",
+			memory:     "2Gi",
+			expected:   workloadClassCPUHeavy,
+			classified: true,
+		},
+		{
+			name:       "many GiB per core",
+			cpu:        "500m",
+			memory:     "8Gi",
+			expected:   workloadClassMemoryHeavy,
+			classified: true,
+		},
+		{
+			name:       "balanced",
+			cpu:        "1",
+			memory:     "4Gi",
+			expected:   workloadClassBalanced,
+			classified: true,
+		},
+		{
+			name:       "bursty regardless of ratio",
+			cpu:        "4",
+			memory:     "2Gi",
+			burstiness: 10,
+			expected:   workloadClassBursty,
+			classified: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			class, classified := hinter.classify(corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(testCase.cpu),
+				corev1.ResourceMemory: resource.MustParse(testCase.memory),
+			}, testCase.burstiness)
+			if class != testCase.expected || classified != testCase.classified {
+				t.Errorf("expected class %q (classified: %v), got %q (classified: %v)", testCase.expected, testCase.classified, class, classified)
+			}
+		})
+	}
+}
+
+func TestHint(t *testing.T) {
+	labels := map[string]string{
+		"ci.openshift.io/metadata.org":    "org",
+		"ci.openshift.io/metadata.repo":   "repo",
+		"ci.openshift.io/metadata.branch": "master",
+		"ci.openshift.io/metadata.target": "unit",
+		"created-by-ci":                   "true",
+	}
+	meta := pod_scaler.MetadataFor(labels, "unit", "test")
+	server := &resourceServer{
+		byMetaData: map[pod_scaler.FullMetadata]corev1.ResourceRequirements{
+			meta: {Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+		},
+		burstiness: map[pod_scaler.FullMetadata]burstiness{meta: {ratio: 1.5}},
+	}
+	priority := int32(100)
+	hints := schedulingHints{
+		PreferredNodeAffinity: []corev1.PreferredSchedulingTerm{{
+			Weight: 10,
+			Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{
+				Key: "node-role.kubernetes.io/compute", Operator: corev1.NodeSelectorOpExists,
+			}}},
+		}},
+		PriorityClassName: "cpu-heavy",
+		Priority:          &priority,
+		TopologySpreadKey: "kubernetes.io/hostname",
+	}
+
+	var testCases = []struct {
+		name     string
+		hinter   *schedulingHinter
+		pod      *corev1.Pod
+		expected *corev1.Pod
+	}{
+		{
+			name:     "no hinter",
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: labels}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}}},
+			expected: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: labels}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}}},
+		},
+		{
+			name:     "no recommendations",
+			hinter:   newSchedulingHinter(buildFarmSchedulingConfig{Hints: map[workloadClass]schedulingHints{workloadClassCPUHeavy: hints}}),
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: labels}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "other"}}}},
+			expected: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: labels}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "other"}}}},
+		},
+		{
+			name:   "classified without hints for the class",
+			hinter: newSchedulingHinter(buildFarmSchedulingConfig{Hints: map[workloadClass]schedulingHints{workloadClassMemoryHeavy: hints}}),
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: labels}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}}},
+			expected: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: withLabel(labels, workloadClassLabel, "cpu-heavy")},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}},
+			},
+		},
+		{
+			name:   "hints are added",
+			hinter: newSchedulingHinter(buildFarmSchedulingConfig{Hints: map[workloadClass]schedulingHints{workloadClassCPUHeavy: hints}}),
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: labels}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}}},
+			expected: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: withLabel(labels, workloadClassLabel, "cpu-heavy")},
+				Spec: corev1.PodSpec{
+					Containers:        []corev1.Container{{Name: "test"}},
+					Affinity:          &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{PreferredDuringSchedulingIgnoredDuringExecution: hints.PreferredNodeAffinity}},
+					PriorityClassName: "cpu-heavy",
+					Priority:          &priority,
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+						MaxSkew:           1,
+						TopologyKey:       "kubernetes.io/hostname",
+						WhenUnsatisfiable: corev1.ScheduleAnyway,
+						LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{workloadClassLabel: "cpu-heavy"}},
+					}},
+				},
+			},
+		},
+		{
+			name:   "existing priority classes are not overridden",
+			hinter: newSchedulingHinter(buildFarmSchedulingConfig{Hints: map[workloadClass]schedulingHints{workloadClassCPUHeavy: {PriorityClassName: "cpu-heavy", Priority: &priority}}}),
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: labels}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}, PriorityClassName: "important"}},
+			expected: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "unit", Labels: withLabel(labels, workloadClassLabel, "cpu-heavy")},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}, PriorityClassName: "important"},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pod := testCase.pod.DeepCopy()
+			testCase.hinter.hint(pod, server, logrus.WithField("test", testCase.name))
+			if diff := cmp.Diff(testCase.expected, pod); diff != "" {
+				t.Errorf("got incorrect pod: %v", diff)
+			}
+		})
+	}
+}
+
+func withLabel(labels map[string]string, key, value string) map[string]string {
+	out := map[string]string{key: value}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+func TestSchedulingConfigValidate(t *testing.T) {
+	priority := int32(1)
+	var testCases = []struct {
+		name     string
+		config   schedulingConfig
+		expected error
+	}{
+		{
+			name: "valid config",
+			config: schedulingConfig{BuildFarms: map[string]buildFarmSchedulingConfig{
+				"build01": {BurstyRatio: 2, Hints: map[workloadClass]schedulingHints{workloadClassBursty: {PriorityClassName: "bursty", Priority: &priority}}},
+			}},
+		},
+		{
+			name: "invalid config",
+			config: schedulingConfig{BuildFarms: map[string]buildFarmSchedulingConfig{
+				"build01": {BurstyRatio: 0.5, Hints: map[workloadClass]schedulingHints{"huge": {PriorityClassName: "huge"}}},
+			}},
+			expected: errors.New("[build_farms.build01.bursty_ratio must be larger than 1, build_farms.build01.hints: unknown workload class huge, build_farms.build01.hints.huge: priority_class_name and priority must be set together]"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if diff := cmp.Diff(testCase.expected, testCase.config.validate(), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("incorrect error: %v", diff)
+			}
+		})
+	}
+}
@@ -13,6 +13,12 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/sirupsen/logrus"
 
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -72,6 +78,92 @@ func (b *bucketCache) lastUpdated(ctx context.Context, name string) (time.Time,
 	return attrs.Updated, nil
 }
 
+func newS3Cache(config *aws.Config, bucket string) (*s3Cache, error) {
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create S3 session: %w", err)
+	}
+	client := s3.New(sess)
+	return &s3Cache{client: client, uploader: s3manager.NewUploaderWithClient(client), bucket: bucket}, nil
+}
+
+// s3Cache stores data in S3 or any storage with an S3-compatible API
+type s3Cache struct {
+	client   s3iface.S3API
+	uploader *s3manager.Uploader
+	bucket   string
+}
+
+var _ cache = &s3Cache{}
+
+func (c *s3Cache) load(ctx context.Context, name string) (io.ReadCloser, error) {
+	output, err := c.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			err = notExist{wrapped: err}
+		}
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (c *s3Cache) store(ctx context.Context, name string) (io.WriteCloser, error) {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket: aws.String(c.bucket),
+			Key:    aws.String(name),
+			Body:   reader,
+		})
+		// if the upload failed, writers must not block on a pipe nobody reads
+		reader.CloseWithError(err)
+		done <- err
+	}()
+	return &s3Writer{writer: writer, done: done}, nil
+}
+
+func (c *s3Cache) lastUpdated(ctx context.Context, name string) (time.Time, error) {
+	output, err := c.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not query cache for attributes: %w", err)
+	}
+	if output.LastModified == nil {
+		return time.Time{}, errors.New("could not query cache for attributes: no modification time returned")
+	}
+	return *output.LastModified, nil
+}
+
+// s3Writer streams data to an upload, which is only done once the writer is closed
+type s3Writer struct {
+	writer *io.PipeWriter
+	done   <-chan error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
+// isS3NotFound determines if the error is due to a nonexistent object, which is
+// reported with a different code when we only ask for the object's metadata
+func isS3NotFound(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound")
+}
+
 type localCache struct {
 	dir string
 }
@@ -161,10 +253,15 @@ func loadFrom(loader loader, metricName string) ([]byte, error) {
 	return data, readErr
 }
 
-// storeCache prunes and stores cached query data to the given storage storer.
+// dataRetention is the age after which usage data is removed from the cache, so
+// that resource usage that has since changed does not affect recommendations.
+const dataRetention = 8 * 7 * 24 * time.Hour
+
+// storeCache ages out, prunes and stores cached query data to the given storage storer.
 func storeCache(storer storer, metricName string, data *pod_scaler.CachedQuery, logger *logrus.Entry) error {
 	pruneStart := time.Now()
 	logger.Debug("Pruning cached Prometheus data.")
+	data.AgeOut(pruneStart.Add(-dataRetention))
 	data.Prune()
 	logger.Debugf("Pruned cached Prometheus data after %s.", time.Since(pruneStart).Round(time.Second))
 
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeS3 is a stand-in for S3-compatible storage, serving path-style requests
+// for objects in buckets
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	updated map[string]time.Time
+	now     time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[key] = data
+		f.updated[key] = f.now
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		data, exists := f.objects[key]
+		if !exists {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			}
+			return
+		}
+		w.Header().Set("Last-Modified", f.updated[key].Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestS3Cache(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}, updated: map[string]time.Time{}, now: now})
+	defer server.Close()
+	config := aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithS3ForcePathStyle(true).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", ""))
+	cache, err := newS3Cache(config, "bucket")
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := cache.load(ctx, "missing.json"); !errors.Is(err, notExist{}) {
+		t.Errorf("expected a nonexistent object to be reported as such, got %v", err)
+	}
+
+	writer, err := cache.store(ctx, "metric.json")
+	if err != nil {
+		t.Fatalf("failed to open cache for writing: %v", err)
+	}
+	if _, err := writer.Write([]byte(`{"data":{}}`)); err != nil {
+		t.Fatalf("failed to write to cache: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close cache writer: %v", err)
+	}
+
+	reader, err := cache.load(ctx, "metric.json")
+	if err != nil {
+		t.Fatalf("failed to load from cache: %v", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read from cache: %v", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatalf("failed to close cache reader: %v", err)
+	}
+	if diff := cmp.Diff(`{"data":{}}`, string(data)); diff != "" {
+		t.Errorf("got incorrect data from cache: %v", diff)
+	}
+
+	updated, err := cache.lastUpdated(ctx, "metric.json")
+	if err != nil {
+		t.Fatalf("failed to determine when the cache was updated: %v", err)
+	}
+	if !updated.Equal(now) {
+		t.Errorf("expected cache to be updated at %s, got %s", now, updated)
+	}
+	if _, err := cache.lastUpdated(ctx, "missing.json"); err == nil {
+		t.Error("expected an error determining when a nonexistent object was updated")
+	}
+}
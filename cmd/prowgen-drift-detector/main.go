@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/config"
+	jc "github.com/openshift/ci-tools/pkg/jobconfig"
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/prowgen"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+type options struct {
+	config.Options
+
+	prowJobConfigDir string
+	registryPath     string
+	fix              bool
+}
+
+func bindOptions(flag *flag.FlagSet) *options {
+	opt := &options{}
+	opt.Options.Bind(flag)
+	flag.StringVar(&opt.prowJobConfigDir, "prow-jobs-dir", "", "Path to a root of directory structure with Prow job config files (ci-operator/jobs in openshift/release)")
+	flag.StringVar(&opt.registryPath, "registry", "", "Path to the step registry directory")
+	flag.BoolVar(&opt.fix, "fix", false, "Regenerate the jobs of every repository with drift instead of failing")
+	return opt
+}
+
+func (o *options) validate() error {
+	if o.prowJobConfigDir == "" {
+		return errors.New("required flag --prow-jobs-dir was unset")
+	}
+	if err := o.Options.Validate(); err != nil {
+		return err
+	}
+	return o.Options.Complete()
+}
+
+// expectedJobs generates the jobs for all ci-operator configurations
+func (o *options) expectedJobs() (map[string]*prowconfig.JobConfig, error) {
+	var resolver registry.Resolver
+	if o.registryPath != "" {
+		refs, chains, workflows, _, _, observers, _, err := load.Registry(o.registryPath, load.RegistryFlag(0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load registry: %w", err)
+		}
+		resolver = registry.NewResolver(refs, chains, workflows, observers)
+	}
+	expected := map[string]*prowconfig.JobConfig{}
+	if err := o.OperateOnCIOperatorConfigDir(o.ConfigDir, prowgen.GenerateJobsInto(resolver, map[string]*config.Prowgen{}, expected)); err != nil {
+		return nil, fmt.Errorf("failed to generate jobs: %w", err)
+	}
+	return expected, nil
+}
+
+// actualJobs loads the jobs from the job configuration files by org/repo
+func (o *options) actualJobs() (map[string]*prowconfig.JobConfig, error) {
+	selected := sets.NewString()
+	if err := o.OperateOnJobConfigSubdirPaths(o.prowJobConfigDir, "", func(info *jc.Info) error {
+		selected.Insert(info.Filename)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read job directory paths: %w", err)
+	}
+	actual := map[string]*prowconfig.JobConfig{}
+	if err := jc.OperateOnJobConfigDir(o.prowJobConfigDir, func(jobConfig *prowconfig.JobConfig, info *jc.Info) error {
+		if !selected.Has(info.Filename) {
+			return nil
+		}
+		orgRepo := fmt.Sprintf("%s/%s", info.Org, info.Repo)
+		if _, ok := actual[orgRepo]; !ok {
+			actual[orgRepo] = &prowconfig.JobConfig{}
+		}
+		jc.Append(actual[orgRepo], jobConfig)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load job configuration: %w", err)
+	}
+	return actual, nil
+}
+
+// fixDrift regenerates the jobs of every repository with drift, which also
+// prunes the orphaned ones
+func fixDrift(dir string, drifts []jc.Drift, expected map[string]*prowconfig.JobConfig) error {
+	repos := sets.NewString()
+	for _, drift := range drifts {
+		repos.Insert(drift.OrgRepo)
+	}
+	for _, orgRepo := range repos.List() {
+		jobs, ok := expected[orgRepo]
+		if !ok {
+			jobs = &prowconfig.JobConfig{}
+		}
+		i := strings.Index(orgRepo, "/")
+		if err := jc.WriteToDir(dir, orgRepo[:i], orgRepo[i+1:], jobs, prowgen.Generator, nil); err != nil {
+			return fmt.Errorf("failed to regenerate jobs for %s: %w", orgRepo, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	flagSet := flag.NewFlagSet("", flag.ExitOnError)
+	o := bindOptions(flagSet)
+	if err := flagSet.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatal("Failed to parse flags")
+	}
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+
+	expected, err := o.expectedJobs()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to generate the expected jobs")
+	}
+	actual, err := o.actualJobs()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load the jobs")
+	}
+	drifts, err := jc.DetectDrift(expected, actual, prowgen.Generator)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to detect drift")
+	}
+	fmt.Print(jc.DriftReport(drifts))
+	if len(drifts) == 0 {
+		return
+	}
+	if !o.fix {
+		logrus.Fatalf("Found %d differences between the job configuration and the generated jobs, run with --fix to regenerate them", len(drifts))
+	}
+	if err := fixDrift(o.prowJobConfigDir, drifts, expected); err != nil {
+		logrus.WithError(err).Fatal("Failed to fix drift")
+	}
+	logrus.Infof("Regenerated the jobs of the repositories with drift")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kataras/tablewriter"
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/deprecateregistry"
+	"github.com/openshift/ci-tools/pkg/load"
+)
+
+type options struct {
+	configDir     string
+	registryPath  string
+	allowlistPath string
+	prune         bool
+	printStats    bool
+	hideTotals    bool
+	checks        bool
+
+	help bool
+}
+
+func bindOptions(fs *flag.FlagSet) *options {
+	opt := &options{}
+
+	fs.StringVar(&opt.configDir, "config-dir", "", "Path to the ci-operator configuration directory")
+	fs.StringVar(&opt.registryPath, "registry", "", "Path to the step registry directory")
+	fs.StringVar(&opt.allowlistPath, "allowlist-path", "", "Path to registry deprecation allowlist")
+	fs.BoolVar(&opt.prune, "prune", false, "If set, remove from allowlist all tests that either no longer exist or no longer use a deprecated component")
+	fs.BoolVar(&opt.printStats, "stats", false, "If true, print deprecated component usage stats")
+	fs.BoolVar(&opt.hideTotals, "hide-totals", false, "If true, hide totals in deprecated component usage stats")
+	fs.BoolVar(&opt.checks, "checks", true, "If true (default), validate allowlist for correctness after update")
+
+	return opt
+}
+
+func (o *options) validate() error {
+	for param, value := range map[string]string{
+		"--config-dir":     o.configDir,
+		"--registry":       o.registryPath,
+		"--allowlist-path": o.allowlistPath,
+	} {
+		if value == "" {
+			return fmt.Errorf("mandatory argument %s was not set", param)
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	opt := bindOptions(flag.CommandLine)
+	flag.Parse()
+
+	if opt.help {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	if err := opt.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid parameters")
+	}
+
+	_, chains, workflows, _, _, _, deprecations, err := load.Registry(opt.registryPath, load.RegistryFlag(0))
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load step registry")
+	}
+
+	enforcer, err := deprecateregistry.NewEnforcer(opt.allowlistPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize registry deprecator")
+	}
+
+	enforcer.LoadRegistry(chains, workflows, deprecations)
+	if err := config.OperateOnCIOperatorConfigDir(opt.configDir, enforcer.ProcessConfig); err != nil {
+		logrus.WithError(err).Fatal("Failed to process ci-operator configuration")
+	}
+
+	if opt.prune {
+		enforcer.Prune()
+	}
+
+	if opt.printStats {
+		header, footer, data := enforcer.Stats(opt.hideTotals)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader(header)
+		table.SetFooter(footer)
+		table.AppendBulk(data)
+		table.Render()
+	}
+
+	if opt.checks {
+		if violations := enforcer.Validate(); len(violations) > 0 {
+			fmt.Printf("ERROR: Registry deprecation allowlist has errors:\n")
+			for idx, violation := range violations {
+				fmt.Printf("\nERROR: %d)\n", idx+1)
+				fmt.Printf("%s\n", violation)
+			}
+			fmt.Println()
+			logrus.Fatalf("Registry deprecation allowlist failed validation")
+		}
+	}
+
+	if err := enforcer.SaveAllowlist(opt.allowlistPath); err != nil {
+		logrus.WithError(err).Fatal("Failed to save registry deprecation allowlist")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -39,10 +40,65 @@ var (
 		},
 		[]string{"workload_name", "configured_memory", "determined_memory"},
 	)
+	podScalerDownscalingCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pod_scaler_admission_downscaling",
+			Help: "number of times pod-scaler reduced or stopped reducing a configured request, sorted by label/type",
+		},
+		[]string{"workload_name", "resource", "rolled_back"},
+	)
+)
+
+var (
+	costLabels = []string{"org", "repo", "step", "cluster"}
+
+	cpuCoreHours = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ci_operator_cpu_core_hours",
+			Help: "CPU core-hours requested by ci-operator steps: the cores requested by their Pods and Builds multiplied by how long those ran",
+		},
+		costLabels,
+	)
+	memoryGiBHours = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ci_operator_memory_gib_hours",
+			Help: "Memory GiB-hours requested by ci-operator steps: the memory requested by their Pods and Builds multiplied by how long those ran",
+		},
+		costLabels,
+	)
+	podHours = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ci_operator_pod_hours",
+			Help: "Hours the Pods of ci-operator steps ran for, summed over all Pods of a step",
+		},
+		costLabels,
+	)
+	buildHours = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ci_operator_build_hours",
+			Help: "Hours the Builds of ci-operator steps ran for, summed over all Builds of a step",
+		},
+		costLabels,
+	)
+	leaseHours = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ci_operator_lease_hours",
+			Help: "Hours leases of a resource type were held by ci-operator steps, multiplied by the number of leases held",
+		},
+		append(append([]string{}, costLabels...), "resource_type"),
+	)
+	clusterClaimHours = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ci_operator_cluster_claim_hours",
+			Help: "Hours clusters claimed from cluster pools were held by ci-operator steps",
+		},
+		costLabels,
+	)
 )
 
 func init() {
-	prometheus.MustRegister(errorRate, podScalerHighMemCounter)
+	prometheus.MustRegister(errorRate, podScalerHighMemCounter, podScalerDownscalingCounter)
+	prometheus.MustRegister(cpuCoreHours, memoryGiBHours, podHours, buildHours, leaseHours, clusterClaimHours)
 }
 
 type options struct {
@@ -50,6 +106,9 @@ type options struct {
 	address     string
 	gracePeriod time.Duration
 	passwdFile  string
+	resultsFile string
+	retention   time.Duration
+	prowURL     string
 }
 
 func gatherOptions() (options, error) {
@@ -59,6 +118,9 @@ func gatherOptions() (options, error) {
 	fs.StringVar(&o.address, "address", ":8080", "Address to run server on")
 	fs.DurationVar(&o.gracePeriod, "gracePeriod", time.Second*10, "Grace period for server shutdown")
 	fs.StringVar(&o.passwdFile, "passwd-file", "", "Authenticate against a file. Each line of the file is with the form `<username>:<password>`.")
+	fs.StringVar(&o.resultsFile, "results-file", "", "File to persist ci-operator results in. When set, failure analytics are served from the persisted results.")
+	fs.DurationVar(&o.retention, "retention", 14*24*time.Hour, "How long to keep persisted ci-operator results for.")
+	fs.StringVar(&o.prowURL, "prow-url", "https://prow.ci.openshift.org", "Base URL of the Prow deployment, to link to jobs.")
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return o, fmt.Errorf("failed to parse flags: %w", err)
 	}
@@ -73,6 +135,9 @@ func validateOptions(o options) error {
 	if o.passwdFile == "" {
 		return errors.New("--passwd-file must be specified")
 	}
+	if o.resultsFile != "" && o.retention <= 0 {
+		return errors.New("--retention must be positive")
+	}
 	return nil
 }
 
@@ -108,6 +173,51 @@ func validatePodScalerRequest(request *results.PodScalerRequest) error {
 	return nil
 }
 
+func validatePodScalerDownscalingRequest(request *results.PodScalerDownscalingRequest) error {
+	if request.WorkloadName == "" {
+		return fmt.Errorf("workload_name field in request is empty")
+	}
+	if request.Resource == "" {
+		return fmt.Errorf("resource field in request is empty")
+	}
+	if request.Configured == "" {
+		return fmt.Errorf("configured field in request is empty")
+	}
+	if request.Determined == "" {
+		return fmt.Errorf("determined field in request is empty")
+	}
+	return nil
+}
+
+func validateCostRequest(request *results.CostRequest) error {
+	if request.JobName == "" {
+		return fmt.Errorf("job_name field in request is empty")
+	}
+	if request.Step == "" {
+		return fmt.Errorf("step field in request is empty")
+	}
+	if request.Cluster == "" {
+		return fmt.Errorf("cluster field in request is empty")
+	}
+	for name, value := range map[string]float64{
+		"cpu_core_hours":      request.CPUCoreHours,
+		"memory_gib_hours":    request.MemoryGiBHours,
+		"pod_hours":           request.PodHours,
+		"build_hours":         request.BuildHours,
+		"cluster_claim_hours": request.ClusterClaimHours,
+	} {
+		if value < 0 {
+			return fmt.Errorf("%s field in request is negative", name)
+		}
+	}
+	for resourceType, value := range request.LeaseHours {
+		if value < 0 {
+			return fmt.Errorf("lease_hours field in request is negative for %s", resourceType)
+		}
+	}
+	return nil
+}
+
 func handleError(w http.ResponseWriter, err error) {
 	w.WriteHeader(http.StatusBadRequest)
 	fmt.Fprint(w, err)
@@ -133,6 +243,36 @@ func recordHighMemory(request *results.PodScalerRequest) {
 	podScalerHighMemCounter.With(labels).Inc()
 }
 
+func recordDownscaling(request *results.PodScalerDownscalingRequest) {
+	labels := prometheus.Labels{
+		"workload_name": request.WorkloadName,
+		"resource":      request.Resource,
+		"rolled_back":   strconv.FormatBool(request.RolledBack),
+	}
+	podScalerDownscalingCounter.With(labels).Inc()
+}
+
+func recordCost(request *results.CostRequest) {
+	labels := prometheus.Labels{
+		"org":     request.Org,
+		"repo":    request.Repo,
+		"step":    request.Step,
+		"cluster": request.Cluster,
+	}
+	cpuCoreHours.With(labels).Add(request.CPUCoreHours)
+	memoryGiBHours.With(labels).Add(request.MemoryGiBHours)
+	podHours.With(labels).Add(request.PodHours)
+	buildHours.With(labels).Add(request.BuildHours)
+	clusterClaimHours.With(labels).Add(request.ClusterClaimHours)
+	for resourceType, hours := range request.LeaseHours {
+		leaseLabels := prometheus.Labels{"resource_type": resourceType}
+		for label, value := range labels {
+			leaseLabels[label] = value
+		}
+		leaseHours.With(leaseLabels).Add(hours)
+	}
+}
+
 type validator interface {
 	Validate(username, password string) bool
 }
@@ -148,7 +288,7 @@ func loginHandler(validator validator, next http.Handler) http.Handler {
 	})
 }
 
-func handleCIOperatorResult() http.HandlerFunc {
+func handleCIOperatorResult(store *store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -170,6 +310,11 @@ func handleCIOperatorResult() http.HandlerFunc {
 		}
 
 		withErrorRate(request)
+		if store != nil {
+			if err := store.add(*request); err != nil {
+				log.WithError(err).Error("Failed to persist result.")
+			}
+		}
 
 		w.WriteHeader(http.StatusOK)
 
@@ -203,6 +348,63 @@ func handlePodScalerResult() http.HandlerFunc {
 	}
 }
 
+func handlePodScalerDownscaling() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		bytes, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, fmt.Errorf("unable to read pod-scaler downscaling request body: %w", err))
+			return
+		}
+
+		request := &results.PodScalerDownscalingRequest{}
+		if err = json.Unmarshal(bytes, request); err != nil {
+			handleError(w, fmt.Errorf("unable to decode pod-scaler downscaling request body: %w", err))
+			return
+		}
+
+		if err := validatePodScalerDownscalingRequest(request); err != nil {
+			handleError(w, err)
+			return
+		}
+
+		recordDownscaling(request)
+		w.WriteHeader(http.StatusOK)
+		log.WithFields(log.Fields{"request": request, "duration": time.Since(start).String()}).Info("Pod-scaler downscaling request processed")
+	}
+}
+
+func handleCost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		bytes, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, fmt.Errorf("unable to read cost request body: %w", err))
+			return
+		}
+
+		// ci-operator reports the cost of all steps of a job in one request
+		var requests []results.CostRequest
+		if err = json.Unmarshal(bytes, &requests); err != nil {
+			handleError(w, fmt.Errorf("unable to decode cost request body: %w", err))
+			return
+		}
+
+		for i := range requests {
+			if err := validateCostRequest(&requests[i]); err != nil {
+				handleError(w, fmt.Errorf("invalid cost for step %q: %w", requests[i].Step, err))
+				return
+			}
+		}
+
+		for i := range requests {
+			recordCost(&requests[i])
+		}
+		w.WriteHeader(http.StatusOK)
+		log.WithFields(log.Fields{"requests": requests, "duration": time.Since(start).String()}).Info("Cost request processed")
+	}
+}
+
 func main() {
 	o, err := gatherOptions()
 	if err != nil {
@@ -217,12 +419,38 @@ func main() {
 	logrusutil.ComponentInit()
 	health := pjutil.NewHealth()
 
-	http.HandleFunc("/", http.NotFound)
+	var resultStore *store
+	if o.resultsFile != "" {
+		resultStore, err = newStore(o.resultsFile, o.retention)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load persisted results.")
+		}
+		interrupts.TickLiteral(func() {
+			if err := resultStore.compact(); err != nil {
+				log.WithError(err).Error("Failed to compact persisted results.")
+			}
+		}, time.Hour)
+		interrupts.OnInterrupt(func() {
+			if err := resultStore.close(); err != nil {
+				log.WithError(err).Error("Failed to close persisted results.")
+			}
+		})
+
+		server := &queryServer{store: resultStore, prowURL: o.prowURL, now: time.Now}
+		http.HandleFunc("/", server.handleUI())
+		http.HandleFunc("/api/reasons", server.handleReasons())
+		http.HandleFunc("/api/trend", server.handleTrend())
+		http.HandleFunc("/api/results", server.handleResults())
+	} else {
+		http.HandleFunc("/", http.NotFound)
+	}
 
 	validator := &multi{delegates: []validator{&passwdFile{file: o.passwdFile}}}
 
-	http.Handle("/result", loginHandler(validator, handleCIOperatorResult()))
+	http.Handle("/result", loginHandler(validator, handleCIOperatorResult(resultStore)))
 	http.Handle("/pod-scaler", loginHandler(validator, handlePodScalerResult()))
+	http.Handle("/pod-scaler/downscaling", loginHandler(validator, handlePodScalerDownscaling()))
+	http.Handle("/cost", loginHandler(validator, handleCost()))
 
 	metrics.ExposeMetrics("result-aggregator", prowConfig.PushGateway{}, flagutil.DefaultMetricsPort)
 
@@ -12,6 +12,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 
+	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
@@ -215,3 +216,113 @@ func TestValidatePodScalerRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePodScalerDownscalingRequest(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		request  *results.PodScalerDownscalingRequest
+		expected error
+	}{
+		{
+			name: "everything ok",
+			request: &results.PodScalerDownscalingRequest{
+				WorkloadName: "name",
+				Resource:     "memory",
+				Configured:   "400",
+				Determined:   "100",
+			},
+			expected: nil,
+		},
+		{
+			name: "empty workload name",
+			request: &results.PodScalerDownscalingRequest{
+				Resource:   "memory",
+				Configured: "400",
+				Determined: "100",
+			},
+			expected: fmt.Errorf("workload_name field in request is empty"),
+		},
+		{
+			name: "empty resource",
+			request: &results.PodScalerDownscalingRequest{
+				WorkloadName: "name",
+				Configured:   "400",
+				Determined:   "100",
+			},
+			expected: fmt.Errorf("resource field in request is empty"),
+		},
+		{
+			name: "empty determined value",
+			request: &results.PodScalerDownscalingRequest{
+				WorkloadName: "name",
+				Resource:     "memory",
+				Configured:   "400",
+			},
+			expected: fmt.Errorf("determined field in request is empty"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual := validatePodScalerDownscalingRequest(testCase.request)
+			if diff := cmp.Diff(testCase.expected, actual, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("actual error doesn't match expected error, diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateCostRequest(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		request  *results.CostRequest
+		expected error
+	}{
+		{
+			name: "everything ok",
+			request: &results.CostRequest{
+				JobName:            "job",
+				Cluster:            "build01",
+				Step:               "e2e",
+				CIOperatorStepCost: api.CIOperatorStepCost{CPUCoreHours: 2, LeaseHours: map[string]float64{"aws-quota-slice": 1}},
+			},
+		},
+		{
+			name: "empty step",
+			request: &results.CostRequest{
+				JobName: "job",
+				Cluster: "build01",
+			},
+			expected: fmt.Errorf("step field in request is empty"),
+		},
+		{
+			name: "negative cost",
+			request: &results.CostRequest{
+				JobName:            "job",
+				Cluster:            "build01",
+				Step:               "e2e",
+				CIOperatorStepCost: api.CIOperatorStepCost{PodHours: -1},
+			},
+			expected: fmt.Errorf("pod_hours field in request is negative"),
+		},
+		{
+			name: "negative lease cost",
+			request: &results.CostRequest{
+				JobName:            "job",
+				Cluster:            "build01",
+				Step:               "e2e",
+				CIOperatorStepCost: api.CIOperatorStepCost{LeaseHours: map[string]float64{"aws-quota-slice": -1}},
+			},
+			expected: fmt.Errorf("lease_hours field in request is negative for aws-quota-slice"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual := validateCostRequest(testCase.request)
+			if diff := cmp.Diff(testCase.expected, actual, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("actual error doesn't match expected error, diff: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+const (
+	dayFormat = "2006-01-02"
+	// defaultWindow is how far back we look when a query does not specify
+	defaultWindow = 24 * time.Hour
+	defaultLimit  = 20
+)
+
+// filter selects the records a query is about
+type filter struct {
+	since, until   time.Time
+	state          string
+	cluster        string
+	org, repo      string
+	step           string
+	jobName        string
+	classification string
+	// reason matches records with the reason or any more specific reason
+	reason string
+}
+
+func filterFrom(values url.Values, now time.Time) (filter, error) {
+	f := filter{
+		since:          now.Add(-defaultWindow),
+		until:          now,
+		state:          values.Get("state"),
+		cluster:        values.Get("cluster"),
+		org:            values.Get("org"),
+		repo:           values.Get("repo"),
+		step:           values.Get("step"),
+		jobName:        values.Get("job_name"),
+		classification: values.Get("classification"),
+		reason:         values.Get("reason"),
+	}
+	if f.state == "" {
+		f.state = results.StateFailed
+	}
+	for _, bound := range []struct {
+		query string
+		into  *time.Time
+	}{
+		{query: "since", into: &f.since},
+		{query: "until", into: &f.until},
+	} {
+		raw := values.Get(bound.query)
+		if raw == "" {
+			continue
+		}
+		parsed, err := parseTime(raw)
+		if err != nil {
+			return filter{}, fmt.Errorf("invalid %s: %w", bound.query, err)
+		}
+		*bound.into = parsed
+	}
+	return f, nil
+}
+
+func parseTime(raw string) (time.Time, error) {
+	if parsed, err := time.Parse(dayFormat, raw); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func (f filter) matches(r record) bool {
+	return !r.Timestamp.Before(f.since) && r.Timestamp.Before(f.until) &&
+		(f.state == r.State) &&
+		(f.cluster == "" || f.cluster == r.Cluster) &&
+		(f.org == "" || f.org == r.Org) &&
+		(f.repo == "" || f.repo == r.Repo) &&
+		(f.step == "" || f.step == r.Step) &&
+		(f.jobName == "" || f.jobName == r.JobName) &&
+		(f.classification == "" || f.classification == r.Classification) &&
+		(f.reason == "" || r.Reason == f.reason || strings.HasPrefix(r.Reason, f.reason+":"))
+}
+
+// dimension is what we group records by
+type dimension string
+
+const (
+	dimensionNone    dimension = ""
+	dimensionRepo    dimension = "repo"
+	dimensionCluster dimension = "cluster"
+	dimensionStep    dimension = "step"
+	dimensionJob     dimension = "job_name"
+)
+
+func (d dimension) of(r record) string {
+	switch d {
+	case dimensionRepo:
+		if r.Org == "" && r.Repo == "" {
+			return ""
+		}
+		return r.Org + "/" + r.Repo
+	case dimensionCluster:
+		return r.Cluster
+	case dimensionStep:
+		return r.Step
+	case dimensionJob:
+		return r.JobName
+	default:
+		return ""
+	}
+}
+
+func dimensionFrom(raw string) (dimension, error) {
+	switch d := dimension(raw); d {
+	case dimensionNone, dimensionRepo, dimensionCluster, dimensionStep, dimensionJob:
+		return d, nil
+	default:
+		return "", fmt.Errorf("invalid by: %q, must be one of %s, %s, %s or %s", raw, dimensionRepo, dimensionCluster, dimensionStep, dimensionJob)
+	}
+}
+
+// reasonCount is the number of records with a reason for a value of the dimension
+type reasonCount struct {
+	Key    string `json:"key,omitempty"`
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// topReasons counts the records by reason and the value of the dimension, most frequent first
+func topReasons(records []record, by dimension, limit int) []reasonCount {
+	counts := map[reasonCount]int{}
+	for _, r := range records {
+		counts[reasonCount{Key: by.of(r), Reason: r.Reason}]++
+	}
+	top := make([]reasonCount, 0, len(counts))
+	for key, count := range counts {
+		key.Count = count
+		top = append(top, key)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		if top[i].Key != top[j].Key {
+			return top[i].Key < top[j].Key
+		}
+		return top[i].Reason < top[j].Reason
+	})
+	if limit > 0 && len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// dayCount is the number of records on a day
+type dayCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// trend counts the records by day, in chronological order
+func trend(records []record) []dayCount {
+	counts := map[string]int{}
+	for _, r := range records {
+		counts[r.Timestamp.UTC().Format(dayFormat)]++
+	}
+	days := make([]dayCount, 0, len(counts))
+	for day, count := range counts {
+		days = append(days, dayCount{Day: day, Count: count})
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Day < days[j].Day
+	})
+	return days
+}
+
+// result is a record as we serve it, with a link to the job
+type result struct {
+	record
+	JobURL string `json:"job_url,omitempty"`
+}
+
+// latest returns the most recent records, with links to their jobs
+func latest(records []record, limit int, prowURL string) []result {
+	sorted := make([]record, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.After(sorted[j].Timestamp)
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	out := make([]result, 0, len(sorted))
+	for _, r := range sorted {
+		out = append(out, result{record: r, JobURL: jobURL(prowURL, r.ProwJobID)})
+	}
+	return out
+}
+
+func jobURL(prowURL, prowJobID string) string {
+	if prowURL == "" || prowJobID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/prowjob?prowjob=%s", strings.TrimSuffix(prowURL, "/"), url.QueryEscape(prowJobID))
+}
+
+func limitFrom(values url.Values) (int, error) {
+	raw := values.Get("limit")
+	if raw == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid limit: %q", raw)
+	}
+	return limit, nil
+}
+
+// queryServer serves the results in the store
+type queryServer struct {
+	store   *store
+	prowURL string
+	now     func() time.Time
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "failed to marshal response: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(raw); err != nil {
+		logrus.WithError(err).Warn("Failed to write response.")
+	}
+}
+
+func (s *queryServer) handleReasons() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, err := filterFrom(r.URL.Query(), s.now())
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		by, err := dimensionFrom(r.URL.Query().Get("by"))
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		limit, err := limitFrom(r.URL.Query())
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		writeJSON(w, topReasons(s.store.matching(f), by, limit))
+	}
+}
+
+func (s *queryServer) handleTrend() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, err := filterFrom(r.URL.Query(), s.now())
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		writeJSON(w, trend(s.store.matching(f)))
+	}
+}
+
+func (s *queryServer) handleResults() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, err := filterFrom(r.URL.Query(), s.now())
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		limit, err := limitFrom(r.URL.Query())
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		writeJSON(w, latest(s.store.matching(f), limit, s.prowURL))
+	}
+}
+
+// page is what the UI renders for a query
+type page struct {
+	Query   url.Values
+	Error   string
+	By      dimension
+	Reasons []reasonCount
+	Trend   []dayCount
+	Max     int
+	Results []result
+}
+
+// DrillDown links to the results with the reason for the value of the dimension
+func (p page) DrillDown(count reasonCount) string {
+	query := url.Values{}
+	for key, values := range p.Query {
+		query[key] = values
+	}
+	query.Set("reason", count.Reason)
+	query.Del("by")
+	if p.By != dimensionNone {
+		key := count.Key
+		if p.By == dimensionRepo {
+			if parts := strings.SplitN(key, "/", 2); len(parts) == 2 {
+				query.Set("org", parts[0])
+				key = parts[1]
+			}
+		}
+		query.Set(string(p.By), key)
+	}
+	return "?" + query.Encode()
+}
+
+// Width scales the count for a bar in the trend
+func (p page) Width(count int) int {
+	if p.Max == 0 {
+		return 0
+	}
+	return count * 400 / p.Max
+}
+
+func (s *queryServer) handleUI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		p := page{Query: r.URL.Query()}
+		f, err := filterFrom(r.URL.Query(), s.now())
+		if err == nil {
+			p.By, err = dimensionFrom(r.URL.Query().Get("by"))
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			p.Error = err.Error()
+		} else {
+			records := s.store.matching(f)
+			p.Trend = trend(records)
+			for _, day := range p.Trend {
+				if day.Count > p.Max {
+					p.Max = day.Count
+				}
+			}
+			if f.reason != "" {
+				p.Results = latest(records, 100, s.prowURL)
+			} else {
+				p.Reasons = topReasons(records, p.By, 50)
+			}
+		}
+		if err := uiTemplate.Execute(w, p); err != nil {
+			logrus.WithError(err).Warn("Failed to render UI.")
+		}
+	}
+}
+
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>ci-operator Failure Reasons</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+.bar { background: #c9190b; height: 0.8em; display: inline-block; }
+form input, form select { margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>ci-operator Failure Reasons</h1>
+<form method="GET" action="/">
+<label>Since <input name="since" placeholder="YYYY-MM-DD" value="{{ .Query.Get "since" }}"></label>
+<label>Until <input name="until" placeholder="YYYY-MM-DD" value="{{ .Query.Get "until" }}"></label>
+<label>Cluster <input name="cluster" value="{{ .Query.Get "cluster" }}"></label>
+<label>Org <input name="org" value="{{ .Query.Get "org" }}"></label>
+<label>Repo <input name="repo" value="{{ .Query.Get "repo" }}"></label>
+<label>Step <input name="step" value="{{ .Query.Get "step" }}"></label>
+<label>Reason <input name="reason" value="{{ .Query.Get "reason" }}"></label>
+<label>Classification <select name="classification">
+<option value="" {{ if eq (.Query.Get "classification") "" }}selected{{ end }}>any</option>
+<option value="infrastructure" {{ if eq (.Query.Get "classification") "infrastructure" }}selected{{ end }}>infrastructure</option>
+<option value="test" {{ if eq (.Query.Get "classification") "test" }}selected{{ end }}>test</option>
+<option value="configuration" {{ if eq (.Query.Get "classification") "configuration" }}selected{{ end }}>configuration</option>
+<option value="unknown" {{ if eq (.Query.Get "classification") "unknown" }}selected{{ end }}>unknown</option>
+</select></label>
+<label>Group by <select name="by">
+<option value="" {{ if eq .By "" }}selected{{ end }}>nothing</option>
+<option value="repo" {{ if eq .By "repo" }}selected{{ end }}>repo</option>
+<option value="cluster" {{ if eq .By "cluster" }}selected{{ end }}>cluster</option>
+<option value="step" {{ if eq .By "step" }}selected{{ end }}>step</option>
+<option value="job_name" {{ if eq .By "job_name" }}selected{{ end }}>job</option>
+</select></label>
+<input type="submit" value="Query">
+</form>
+{{ if .Error }}<p><strong>{{ .Error }}</strong></p>{{ end }}
+{{ if .Trend }}
+<h2>Failures by Day</h2>
+<table>
+{{ range .Trend }}<tr><td>{{ .Day }}</td><td>{{ .Count }}</td><td><span class="bar" style="width: {{ $.Width .Count }}px"></span></td></tr>
+{{ end }}</table>
+{{ end }}
+{{ if .Reasons }}
+<h2>Top Failure Reasons</h2>
+<table>
+<tr>{{ if .By }}<th>{{ .By }}</th>{{ end }}<th>Reason</th><th>Failures</th></tr>
+{{ range .Reasons }}<tr>{{ if $.By }}<td>{{ .Key }}</td>{{ end }}<td><a href="{{ $.DrillDown . }}">{{ .Reason }}</a></td><td>{{ .Count }}</td></tr>
+{{ end }}</table>
+{{ end }}
+{{ if .Results }}
+<h2>Failures</h2>
+<table>
+<tr><th>Time</th><th>Job</th><th>Repo</th><th>Cluster</th><th>Step</th><th>Reason</th><th>Classification</th></tr>
+{{ range .Results }}<tr><td>{{ .Timestamp.Format "2006-01-02 15:04:05" }}</td><td>{{ if .JobURL }}<a href="{{ .JobURL }}">{{ .JobName }}</a>{{ else }}{{ .JobName }}{{ end }}</td><td>{{ .Org }}/{{ .Repo }}</td><td>{{ .Cluster }}</td><td>{{ .Step }}</td><td>{{ .Reason }}</td><td>{{ .Classification }}</td></tr>
+{{ end }}</table>
+{{ end }}
+</body>
+</html>
+`))
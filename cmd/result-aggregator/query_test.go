@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestFilterFrom(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		name          string
+		query         url.Values
+		expected      filter
+		expectedError error
+	}{
+		{
+			name:     "defaults",
+			query:    url.Values{},
+			expected: filter{since: now.Add(-24 * time.Hour), until: now, state: results.StateFailed},
+		},
+		{
+			name:  "everything set",
+			query: url.Values{"since": {"2021-03-01"}, "until": {"2021-03-05T10:00:00Z"}, "state": {"succeeded"}, "cluster": {"build01"}, "org": {"org"}, "repo": {"repo"}, "step": {"e2e"}, "job_name": {"job"}, "classification": {"infrastructure"}, "reason": {"step_failed"}},
+			expected: filter{
+				since:          time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+				until:          time.Date(2021, 3, 5, 10, 0, 0, 0, time.UTC),
+				state:          results.StateSucceeded,
+				cluster:        "build01",
+				org:            "org",
+				repo:           "repo",
+				step:           "e2e",
+				jobName:        "job",
+				classification: "infrastructure",
+				reason:         "step_failed",
+			},
+		},
+		{
+			name:          "invalid time",
+			query:         url.Values{"since": {"yesterday"}},
+			expectedError: errors.New(`invalid since: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := filterFrom(testCase.query, now)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("incorrect error: %v", diff)
+			}
+			if diff := cmp.Diff(testCase.expected, actual, cmp.AllowUnexported(filter{})); diff != "" {
+				t.Errorf("incorrect filter: %v", diff)
+			}
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	r := record{Timestamp: now.Add(-time.Hour), Request: results.Request{State: results.StateFailed, Reason: "step_failed:test", Org: "org", Repo: "repo", Step: "unit", Cluster: "build01"}}
+	var testCases = []struct {
+		name     string
+		filter   filter
+		expected bool
+	}{
+		{
+			name:     "matching everything",
+			filter:   filter{since: now.Add(-2 * time.Hour), until: now, state: results.StateFailed},
+			expected: true,
+		},
+		{
+			name:   "too old",
+			filter: filter{since: now.Add(-time.Minute), until: now, state: results.StateFailed},
+		},
+		{
+			name:   "other state",
+			filter: filter{since: now.Add(-2 * time.Hour), until: now, state: results.StateSucceeded},
+		},
+		{
+			name:     "more general reason",
+			filter:   filter{since: now.Add(-2 * time.Hour), until: now, state: results.StateFailed, reason: "step_failed"},
+			expected: true,
+		},
+		{
+			name:   "reason sharing a prefix",
+			filter: filter{since: now.Add(-2 * time.Hour), until: now, state: results.StateFailed, reason: "step_fail"},
+		},
+		{
+			name:   "other classification",
+			filter: filter{since: now.Add(-2 * time.Hour), until: now, state: results.StateFailed, classification: "test"},
+		},
+		{
+			name:   "other repo",
+			filter: filter{since: now.Add(-2 * time.Hour), until: now, state: results.StateFailed, org: "org", repo: "other"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := testCase.filter.matches(r); actual != testCase.expected {
+				t.Errorf("expected match: %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func testRecords() []record {
+	day := time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)
+	return []record{
+		{Timestamp: day.Add(time.Hour), Request: results.Request{JobName: "a", Org: "org", Repo: "repo", Cluster: "build01", Step: "unit", Reason: "step_failed", ProwJobID: "1"}},
+		{Timestamp: day.Add(2 * time.Hour), Request: results.Request{JobName: "b", Org: "org", Repo: "repo", Cluster: "build02", Step: "unit", Reason: "step_failed", ProwJobID: "2"}},
+		{Timestamp: day.Add(-time.Hour), Request: results.Request{JobName: "c", Org: "org", Repo: "other", Cluster: "build01", Step: "lint", Reason: "building_image"}},
+	}
+}
+
+func TestTopReasons(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		by       dimension
+		limit    int
+		expected []reasonCount
+	}{
+		{
+			name:     "by reason",
+			expected: []reasonCount{{Reason: "step_failed", Count: 2}, {Reason: "building_image", Count: 1}},
+		},
+		{
+			name:     "by repo",
+			by:       dimensionRepo,
+			expected: []reasonCount{{Key: "org/repo", Reason: "step_failed", Count: 2}, {Key: "org/other", Reason: "building_image", Count: 1}},
+		},
+		{
+			name:     "by cluster with a limit",
+			by:       dimensionCluster,
+			limit:    2,
+			expected: []reasonCount{{Key: "build01", Reason: "building_image", Count: 1}, {Key: "build01", Reason: "step_failed", Count: 1}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if diff := cmp.Diff(testCase.expected, topReasons(testRecords(), testCase.by, testCase.limit)); diff != "" {
+				t.Errorf("incorrect top reasons: %v", diff)
+			}
+		})
+	}
+}
+
+func TestTrend(t *testing.T) {
+	expected := []dayCount{{Day: "2021-03-09", Count: 1}, {Day: "2021-03-10", Count: 2}}
+	if diff := cmp.Diff(expected, trend(testRecords())); diff != "" {
+		t.Errorf("incorrect trend: %v", diff)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	records := testRecords()
+	expected := []result{
+		{record: records[1], JobURL: "https://prow.ci.openshift.org/prowjob?prowjob=2"},
+		{record: records[0], JobURL: "https://prow.ci.openshift.org/prowjob?prowjob=1"},
+	}
+	if diff := cmp.Diff(expected, latest(records, 2, "https://prow.ci.openshift.org/"), cmp.AllowUnexported(result{})); diff != "" {
+		t.Errorf("incorrect results: %v", diff)
+	}
+}
+
+func TestQueryServer(t *testing.T) {
+	now := time.Date(2021, 3, 11, 0, 0, 0, 0, time.UTC)
+	records := testRecords()
+	for i := range records {
+		records[i].State = results.StateFailed
+	}
+	server := &queryServer{store: &store{records: records}, prowURL: "https://prow.ci.openshift.org", now: func() time.Time { return now }}
+
+	var testCases = []struct {
+		name         string
+		handler      http.HandlerFunc
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "reasons by step",
+			handler:      server.handleReasons(),
+			path:         "/api/reasons?by=step",
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"key":"unit","reason":"step_failed","count":2}]`,
+		},
+		{
+			name:         "reasons by an unknown dimension",
+			handler:      server.handleReasons(),
+			path:         "/api/reasons?by=color",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `invalid by: "color", must be one of repo, cluster, step or job_name`,
+		},
+		{
+			name:         "trend over two days",
+			handler:      server.handleTrend(),
+			path:         "/api/trend?since=2021-03-09",
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"day":"2021-03-09","count":1},{"day":"2021-03-10","count":2}]`,
+		},
+		{
+			name:         "unknown page",
+			handler:      server.handleUI(),
+			path:         "/other",
+			expectedCode: http.StatusNotFound,
+			expectedBody: "404 page not found\n",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			testCase.handler(recorder, httptest.NewRequest(http.MethodGet, testCase.path, nil))
+			if recorder.Code != testCase.expectedCode {
+				t.Errorf("expected code %d, got %d", testCase.expectedCode, recorder.Code)
+			}
+			if diff := cmp.Diff(testCase.expectedBody, recorder.Body.String()); diff != "" {
+				t.Errorf("incorrect body: %v", diff)
+			}
+		})
+	}
+
+	t.Run("results link to jobs", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.handleResults()(recorder, httptest.NewRequest(http.MethodGet, "/api/results?reason=step_failed&limit=1", nil))
+		var actual []map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil {
+			t.Fatalf("failed to unmarshal results: %v", err)
+		}
+		if len(actual) != 1 || actual[0]["job_url"] != "https://prow.ci.openshift.org/prowjob?prowjob=2" {
+			t.Errorf("expected the latest result with a link to its job, got %v", actual)
+		}
+	})
+
+	t.Run("UI drills down into reasons", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.handleUI()(recorder, httptest.NewRequest(http.MethodGet, "/?by=repo&since=2021-03-09", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected code %d, got %d", http.StatusOK, recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), `href="?org=org&amp;reason=step_failed&amp;repo=repo&amp;since=2021-03-09"`) {
+			t.Errorf("expected a drill-down link in the UI, got %s", recorder.Body.String())
+		}
+	})
+}
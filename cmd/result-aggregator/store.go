@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// record is a result reported to us, as we persist it
+type record struct {
+	Timestamp time.Time `json:"timestamp"`
+	results.Request
+}
+
+// store persists results in a file with one JSON record per line, holding
+// all records in the retention window in memory to serve queries
+type store struct {
+	lock      sync.RWMutex
+	path      string
+	retention time.Duration
+	records   []record
+	file      *os.File
+	now       func() time.Time
+}
+
+// newStore loads the records persisted at the path, dropping those that aged
+// out, and opens the file to persist new records
+func newStore(path string, retention time.Duration) (*store, error) {
+	s := &store{path: path, retention: retention, now: time.Now}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *store) load() error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open results: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var line int
+	for scanner.Scan() {
+		line++
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// a partially written line is expected if we were killed while writing
+			logrus.WithError(err).Warnf("Ignoring malformed result on line %d.", line)
+			continue
+		}
+		s.records = append(s.records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read results: %w", err)
+	}
+	return nil
+}
+
+// add persists the request as received now
+func (s *store) add(request results.Request) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r := record{Timestamp: s.now().UTC(), Request: request}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("could not marshal result: %w", err)
+	}
+	if _, err := s.file.Write(append(raw, '\n')); err != nil {
+		return fmt.Errorf("could not persist result: %w", err)
+	}
+	s.records = append(s.records, r)
+	return nil
+}
+
+// compact drops records that aged out and rewrites the file with the rest
+func (s *store) compact() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cutoff := s.now().Add(-s.retention)
+	var retained []record
+	for _, r := range s.records {
+		if r.Timestamp.After(cutoff) {
+			retained = append(retained, r)
+		}
+	}
+	s.records = retained
+
+	// we keep the handle we write the compacted results with to persist new
+	// records, so there is no need to reopen the file once it's replaced and
+	// we can keep using the old handle when anything goes wrong before that
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not create file to compact results into: %w", err)
+	}
+	discard := func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		discard()
+		return fmt.Errorf("could not set permissions on compacted results: %w", err)
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, r := range s.records {
+		if err := encoder.Encode(r); err != nil {
+			discard()
+			return fmt.Errorf("could not write compacted results: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		discard()
+		return fmt.Errorf("could not write compacted results: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		discard()
+		return fmt.Errorf("could not sync compacted results: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		discard()
+		return fmt.Errorf("could not replace results with compacted results: %w", err)
+	}
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			logrus.WithError(err).Warn("Could not close results file.")
+		}
+	}
+	s.file = tmp
+	return nil
+}
+
+// matching returns the records that match the filter
+func (s *store) matching(f filter) []record {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var matches []record
+	for _, r := range s.records {
+		if f.matches(r) {
+			matches = append(matches, r)
+		}
+	}
+	return matches
+}
+
+func (s *store) close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "results.json")
+	aged := record{Timestamp: now.Add(-15 * 24 * time.Hour), Request: results.Request{JobName: "aged", State: results.StateFailed, Reason: "a"}}
+	recent := record{Timestamp: now.Add(-time.Hour), Request: results.Request{JobName: "recent", State: results.StateFailed, Reason: "b"}}
+	if err := ioutil.WriteFile(path, []byte(`{"timestamp":"`+aged.Timestamp.Format(time.RFC3339)+`","job_name":"aged","state":"failed","reason":"a","type":"","cluster":""}
+{"timestamp":"`+recent.Timestamp.Format(time.RFC3339)+`","job_name":"recent","state":"failed","reason":"b","type":"","cluster":""}
+{"timestamp":"2021-03-10T`), 0644); err != nil {
+		t.Fatalf("failed to write results: %v", err)
+	}
+
+	s := &store{path: path, retention: 14 * 24 * time.Hour, now: func() time.Time { return now }}
+	if err := s.load(); err != nil {
+		t.Fatalf("failed to load results: %v", err)
+	}
+	if err := s.compact(); err != nil {
+		t.Fatalf("failed to compact results: %v", err)
+	}
+	everything := filter{since: now.Add(-30 * 24 * time.Hour), until: now.Add(time.Hour), state: results.StateFailed}
+	if diff := cmp.Diff([]record{recent}, s.matching(everything)); diff != "" {
+		t.Errorf("got incorrect records after compaction: %v", diff)
+	}
+
+	added := results.Request{JobName: "added", State: results.StateFailed, Reason: "c"}
+	if err := s.add(added); err != nil {
+		t.Fatalf("failed to add result: %v", err)
+	}
+	if err := s.close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+
+	reloaded := &store{path: path, retention: 14 * 24 * time.Hour, now: func() time.Time { return now }}
+	if err := reloaded.load(); err != nil {
+		t.Fatalf("failed to reload results: %v", err)
+	}
+	expected := []record{recent, {Timestamp: now, Request: added}}
+	if diff := cmp.Diff(expected, reloaded.matching(everything)); diff != "" {
+		t.Errorf("got incorrect records after reload: %v", diff)
+	}
+}
+
+func TestNewStoreWithoutFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	s, err := newStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer s.close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected results file to be created: %v", err)
+	}
+}
+
+func TestCompactFailureKeepsStoreWritable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.json")
+	s, err := newStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer s.close()
+	// a non-empty directory in place of the results can't be replaced
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove results: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocker"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := s.compact(); err == nil {
+		t.Fatal("expected compaction to fail")
+	}
+	if err := s.add(results.Request{JobName: "added", State: results.StateFailed}); err != nil {
+		t.Errorf("expected the store to stay writable after failed compaction: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the compacted results to be cleaned up, got %d entries", len(entries))
+	}
+}
@@ -565,6 +565,9 @@ func mergeSteps(into, from CIOperatorStepDetails) CIOperatorStepDetails {
 	if into.Failed == nil {
 		into.Failed = from.Failed
 	}
+	if into.Cost == nil {
+		into.Cost = from.Cost
+	}
 	if into.Substeps == nil {
 		into.Substeps = from.Substeps
 	}
@@ -589,6 +592,45 @@ type CIOperatorStepDetailInfo struct {
 	Manifests    []ctrlruntimeclient.Object `json:"manifests,omitempty"`
 	LogURL       string                     `json:"log_url,omitempty"`
 	Failed       *bool                      `json:"failed,omitempty"`
+	Cost         *CIOperatorStepCost        `json:"cost,omitempty"`
+}
+
+// CIOperatorStepCost holds the resources a step consumed, for accounting
+// +k8s:deepcopy-gen=false
+type CIOperatorStepCost struct {
+	// CPUCoreHours is the CPU requested by Pods and Builds multiplied by their runtime
+	CPUCoreHours float64 `json:"cpu_core_hours,omitempty"`
+	// MemoryGiBHours is the memory requested by Pods and Builds multiplied by their runtime
+	MemoryGiBHours float64 `json:"memory_gib_hours,omitempty"`
+	// PodHours is the runtime of all Pods
+	PodHours float64 `json:"pod_hours,omitempty"`
+	// BuildHours is the runtime of all Builds
+	BuildHours float64 `json:"build_hours,omitempty"`
+	// LeaseHours is the time leases were held multiplied by their count, by resource type
+	LeaseHours map[string]float64 `json:"lease_hours,omitempty"`
+	// ClusterClaimHours is the time a cluster from a pool was held
+	ClusterClaimHours float64 `json:"cluster_claim_hours,omitempty"`
+}
+
+// Add adds the other cost to this one
+func (c *CIOperatorStepCost) Add(other CIOperatorStepCost) {
+	c.CPUCoreHours += other.CPUCoreHours
+	c.MemoryGiBHours += other.MemoryGiBHours
+	c.PodHours += other.PodHours
+	c.BuildHours += other.BuildHours
+	c.ClusterClaimHours += other.ClusterClaimHours
+	for resourceType, hours := range other.LeaseHours {
+		if c.LeaseHours == nil {
+			c.LeaseHours = map[string]float64{}
+		}
+		c.LeaseHours[resourceType] += hours
+	}
+}
+
+// IsZero determines if nothing was consumed
+func (c CIOperatorStepCost) IsZero() bool {
+	return c.CPUCoreHours == 0 && c.MemoryGiBHours == 0 && c.PodHours == 0 && c.BuildHours == 0 &&
+		c.ClusterClaimHours == 0 && len(c.LeaseHours) == 0
 }
 
 func (c *CIOperatorStepDetailInfo) UnmarshalJSON(data []byte) error {
@@ -784,6 +784,8 @@ type RegistryReference struct {
 	LiteralTestStep `json:",inline"`
 	// Documentation describes what the step being referenced does.
 	Documentation string `json:"documentation,omitempty"`
+	// Deprecation marks the step as deprecated.
+	Deprecation *RegistryDeprecation `json:"deprecation,omitempty"`
 }
 
 // RegistryChainConfig is the struct that chain references are unmarshalled into.
@@ -804,6 +806,8 @@ type RegistryChain struct {
 	Environment []StepParameter `json:"env,omitempty"`
 	// Leases lists resources that should be acquired for the test.
 	Leases []StepLease `json:"leases,omitempty"`
+	// Deprecation marks the chain as deprecated.
+	Deprecation *RegistryDeprecation `json:"deprecation,omitempty"`
 }
 
 // RegistryWorkflowConfig is the struct that workflow references are unmarshalled into.
@@ -820,8 +824,26 @@ type RegistryWorkflow struct {
 	Steps MultiStageTestConfiguration `json:"steps,omitempty"`
 	// Documentation describes what the workflow does.
 	Documentation string `json:"documentation,omitempty"`
+	// Deprecation marks the workflow as deprecated.
+	Deprecation *RegistryDeprecation `json:"deprecation,omitempty"`
 }
 
+// RegistryDeprecation marks a registry component as deprecated. Tests using
+// deprecated components are warned about it and new usages are blocked.
+type RegistryDeprecation struct {
+	// Reason explains why the component is deprecated.
+	Reason string `json:"reason"`
+	// Replacement is the name of the component of the same type that should
+	// be used instead.
+	Replacement string `json:"replacement,omitempty"`
+	// Deadline is the date (in the YYYY-MM-DD format) after which the
+	// component may be removed from the registry.
+	Deadline string `json:"deadline,omitempty"`
+}
+
+// RegistryDeprecationDeadlineFormat is the format of RegistryDeprecation.Deadline
+const RegistryDeprecationDeadlineFormat = "2006-01-02"
+
 // RegistryObserverConfig is the struct that observer configs are unmarshalled into
 type RegistryObserverConfig struct {
 	// Observer is the top level field of an observer config
@@ -862,8 +884,42 @@ type Observer struct {
 	Commands string `json:"commands,omitempty"`
 	// Resources defines the resource requirements for the step.
 	Resources ResourceRequirements `json:"resources,omitempty"`
+	// GracePeriod is how long we will wait after sending SIGINT to send
+	// SIGKILL when the observer is stopped, which is the time the observer is
+	// guaranteed to have to flush its artifacts.
+	GracePeriod *prowv1.Duration `json:"grace_period,omitempty"`
 }
 
+// ObserverEvent is a transition in the lifecycle of a multi-stage test that
+// observers are notified about.
+type ObserverEvent string
+
+const (
+	// ObserverEventPreFinished is sent when the pre steps have run, whether
+	// they succeeded or not.
+	ObserverEventPreFinished ObserverEvent = "pre-finished"
+	// ObserverEventTestStarted is sent before the first test step runs.
+	// It is not sent when the pre steps fail, as test steps are skipped.
+	ObserverEventTestStarted ObserverEvent = "test-started"
+	// ObserverEventPostStarted is sent before the first post step runs. The
+	// observer is interrupted with SIGINT when it receives it and has its
+	// grace period to exit before its Pod is deleted.
+	ObserverEventPostStarted ObserverEvent = "post-started"
+)
+
+const (
+	// ObserverEventsAnnotation holds the comma-separated list of events sent
+	// to an observer Pod so far, in order.
+	ObserverEventsAnnotation = "ci.openshift.io/observer-events"
+	// ObserverEventsFileEnv is the env we use to expose to observers the path
+	// to a file listing the events sent so far, one per line. The file is
+	// replaced atomically every time an event is added.
+	ObserverEventsFileEnv = "OBSERVER_EVENTS_FILE"
+	// ObserverPodNameEnv is the env we use to tell the entrypoint wrapper the
+	// name of the observer Pod it should watch for events.
+	ObserverPodNameEnv = "OBSERVER_POD_NAME"
+)
+
 // Observers is a configuration for which observer pods should and should not
 // be run during a job
 type Observers struct {
@@ -1074,6 +1130,8 @@ type MultiStageTestConfigurationLiteral struct {
 	// DependencyOverrides allows a step to override a dependency with a fully-qualified pullspec. This will probably only ever
 	// be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.
 	DependencyOverrides DependencyOverrides `json:"dependency_overrides,omitempty"`
+	// Deprecations are warnings about deprecated registry components used by the test.
+	Deprecations []string `json:"deprecations,omitempty"`
 
 	// Override job timeout
 	Timeout *prowv1.Duration `json:"timeout,omitempty"`
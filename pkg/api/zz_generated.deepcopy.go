@@ -766,6 +766,11 @@ func (in *MultiStageTestConfigurationLiteral) DeepCopyInto(out *MultiStageTestCo
 			(*out)[key] = val
 		}
 	}
+	if in.Deprecations != nil {
+		in, out := &in.Deprecations, &out.Deprecations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Timeout != nil {
 		in, out := &in.Timeout, &out.Timeout
 		*out = new(v1.Duration)
@@ -792,6 +797,11 @@ func (in *Observer) DeepCopyInto(out *Observer) {
 		**out = **in
 	}
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Observer.
@@ -1234,6 +1244,11 @@ func (in *RegistryChain) DeepCopyInto(out *RegistryChain) {
 		*out = make([]StepLease, len(*in))
 		copy(*out, *in)
 	}
+	if in.Deprecation != nil {
+		in, out := &in.Deprecation, &out.Deprecation
+		*out = new(RegistryDeprecation)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryChain.
@@ -1262,6 +1277,21 @@ func (in *RegistryChainConfig) DeepCopy() *RegistryChainConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryDeprecation) DeepCopyInto(out *RegistryDeprecation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryDeprecation.
+func (in *RegistryDeprecation) DeepCopy() *RegistryDeprecation {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryDeprecation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegistryObserver) DeepCopyInto(out *RegistryObserver) {
 	*out = *in
@@ -1298,6 +1328,11 @@ func (in *RegistryObserverConfig) DeepCopy() *RegistryObserverConfig {
 func (in *RegistryReference) DeepCopyInto(out *RegistryReference) {
 	*out = *in
 	in.LiteralTestStep.DeepCopyInto(&out.LiteralTestStep)
+	if in.Deprecation != nil {
+		in, out := &in.Deprecation, &out.Deprecation
+		*out = new(RegistryDeprecation)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryReference.
@@ -1330,6 +1365,11 @@ func (in *RegistryReferenceConfig) DeepCopy() *RegistryReferenceConfig {
 func (in *RegistryWorkflow) DeepCopyInto(out *RegistryWorkflow) {
 	*out = *in
 	in.Steps.DeepCopyInto(&out.Steps)
+	if in.Deprecation != nil {
+		in, out := &in.Deprecation, &out.Deprecation
+		*out = new(RegistryDeprecation)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryWorkflow.
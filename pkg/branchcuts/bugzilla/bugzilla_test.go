@@ -1,4 +1,4 @@
-package main
+package bugzilla
 
 import (
 	"io/ioutil"
@@ -14,7 +14,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
-func TestRun(t *testing.T) {
+func TestReconcile(t *testing.T) {
 	files, err := ioutil.ReadDir("./testdata")
 	if err != nil {
 		t.Fatalf("failed to list testdata dir files: %v", err)
@@ -38,7 +38,7 @@ func TestRun(t *testing.T) {
 				t.Fatalf("failed to unmarshal lifecycleConfig: %v", err)
 			}
 
-			newConfig, err := run(lifecycleConfig, plugins.Bugzilla{}, time.Now())
+			newConfig, err := Reconcile(lifecycleConfig, plugins.Bugzilla{}, time.Now())
 			if err != nil {
 				t.Fatalf("failed to run: %v", err)
 			}
@@ -0,0 +1,86 @@
+package fastforwarding
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api/ocplifecycle"
+)
+
+const (
+	// JobName is the name of the periodic job that fast-forwards the
+	// release branches
+	JobName           = "periodic-openshift-release-fast-forward"
+	ocpProductName    = "ocp"
+	currentReleaseArg = "--current-release"
+	futureReleaseArg  = "--future-release"
+)
+
+// Reconcile updates the releases the fast-forwarding job syncs branches for
+// to match the phases of the OCP releases at the given time.
+func Reconcile(lifecycleConfig ocplifecycle.Config, now time.Time, job *prowconfig.Periodic) error {
+	timelineOpts := ocplifecycle.TimelineOptions{
+		OnlyEvents: sets.NewString([]string{
+			string(ocplifecycle.LifecycleEventOpen),
+			string(ocplifecycle.LifecycleEventFeatureFreeze),
+		}...),
+	}
+
+	timeline := lifecycleConfig.GetTimeline(ocpProductName, timelineOpts)
+	before, after := timeline.DeterminePlaceInTime(now)
+
+	if after.LifecyclePhase.Event == ocplifecycle.LifecycleEventOpen {
+		parsedVersion, err := ocplifecycle.ParseMajorMinor(after.ProductVersion)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as majorMinor version: %w", after.ProductVersion, err)
+		}
+
+		job.Spec.Containers[0].Args = append(job.Spec.Containers[0].Args, fmt.Sprintf("%s=%s", futureReleaseArg, parsedVersion))
+		sort.Strings(job.Spec.Containers[0].Args)
+	} else if before.LifecyclePhase.Event == ocplifecycle.LifecycleEventOpen || before.LifecyclePhase.Event == ocplifecycle.LifecycleEventFeatureFreeze {
+		parsedVersion, err := ocplifecycle.ParseMajorMinor(before.ProductVersion)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as majorMinor version: %w", before.ProductVersion, err)
+		}
+
+		var args []string
+		for _, arg := range job.Spec.Containers[0].Args {
+			if !strings.Contains(arg, currentReleaseArg) && !strings.Contains(arg, futureReleaseArg) {
+				args = append(args, arg)
+			}
+		}
+
+		args = append(args, fmt.Sprintf("%s=%s", currentReleaseArg, parsedVersion))
+		args = append(args, fmt.Sprintf("%s=%s", futureReleaseArg, parsedVersion))
+		sort.Strings(job.Spec.Containers[0].Args)
+		job.Spec.Containers[0].Args = args
+	}
+
+	return nil
+}
+
+// GetPeriodicJob finds the periodic job with the given name.
+func GetPeriodicJob(jobName string, periodics []prowconfig.Periodic) (*prowconfig.Periodic, error) {
+	for _, job := range periodics {
+		if job.Name == jobName {
+			return &job, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find the job: %s", jobName)
+}
+
+// UpdatePeriodicJob replaces the periodic job with the same name in the
+// job configuration.
+func UpdatePeriodicJob(config *prowconfig.JobConfig, job *prowconfig.Periodic) {
+	for i, periodic := range config.Periodics {
+		if periodic.Name == job.Name {
+			config.Periodics[i] = *job
+		}
+	}
+}
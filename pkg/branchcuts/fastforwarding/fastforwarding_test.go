@@ -1,4 +1,4 @@
-package main
+package fastforwarding
 
 import (
 	"testing"
@@ -134,7 +134,7 @@ func TestReconcile(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := reconcile(tc.lifecycleConfig, tc.now, tc.job)
+			err := Reconcile(tc.lifecycleConfig, tc.now, tc.job)
 			if err != nil {
 				t.Fatal(err)
 			}
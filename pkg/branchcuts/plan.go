@@ -0,0 +1,591 @@
+package branchcuts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/plugins"
+	"sigs.k8s.io/yaml"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/api/ocplifecycle"
+	"github.com/openshift/ci-tools/pkg/api/shardprowconfig"
+	"github.com/openshift/ci-tools/pkg/branchcuts/bugzilla"
+	"github.com/openshift/ci-tools/pkg/branchcuts/fastforwarding"
+	"github.com/openshift/ci-tools/pkg/branchcuts/tide"
+	"github.com/openshift/ci-tools/pkg/config"
+	jc "github.com/openshift/ci-tools/pkg/jobconfig"
+	"github.com/openshift/ci-tools/pkg/promotion"
+	"github.com/openshift/ci-tools/pkg/prowgen"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// InfraPeriodicsInRepoPath is the path of the infrastructure periodic jobs,
+// which include the fast-forwarding job, in the release repo
+const InfraPeriodicsInRepoPath = "ci-operator/jobs/infra-periodics.yaml"
+
+// Action is what a branch cut does to a piece of configuration
+type Action string
+
+const (
+	// ActionCreate adds configuration that does not exist yet
+	ActionCreate Action = "create"
+	// ActionChange changes existing configuration
+	ActionChange Action = "change"
+	// ActionRemove removes existing configuration
+	ActionRemove Action = "remove"
+)
+
+// Plan holds the changes that cutting the release branches would make to the
+// release repo. Changes to Bugzilla settings apply to the defaults for every
+// repository and changes to the fast-forwarding job to the release branches
+// of every repository, so they are not reported by repository.
+type Plan struct {
+	Repos          []*RepoPlan           `json:"repos,omitempty"`
+	Bugzilla       []BugzillaChange      `json:"bugzilla,omitempty"`
+	FastForwarding *FastForwardingChange `json:"fast_forwarding,omitempty"`
+}
+
+// RepoPlan holds the changes to the configuration of a repository. Org-wide
+// configuration is reported for a repository with an empty name.
+type RepoPlan struct {
+	Org  string `json:"org"`
+	Repo string `json:"repo,omitempty"`
+	// Branches are the future branches that the repo-brancher pushes
+	Branches    []BranchChange    `json:"branches,omitempty"`
+	Configs     []ConfigChange    `json:"configs,omitempty"`
+	Jobs        []JobChange       `json:"jobs,omitempty"`
+	TideQueries []TideQueryChange `json:"tide_queries,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"`
+}
+
+// BranchChange is a future branch pushed from the branch that promotes to
+// the current release
+type BranchChange struct {
+	Branch string `json:"branch"`
+	From   string `json:"from"`
+	Action Action `json:"action,omitempty"`
+}
+
+// ConfigChange is a ci-operator configuration file created or changed by the
+// config-brancher
+type ConfigChange struct {
+	Filename string `json:"filename"`
+	Action   Action `json:"action"`
+	// Fields are the top-level fields of a changed configuration that differ
+	Fields []string `json:"fields,omitempty"`
+}
+
+// JobChange is a job that prowgen generates differently for the branched
+// configurations than the job in the job configuration files
+type JobChange struct {
+	Name   string             `json:"name"`
+	Type   prowv1.ProwJobType `json:"type"`
+	Action Action             `json:"action"`
+	// Fields are the fields of a changed job that differ
+	Fields []string `json:"fields,omitempty"`
+}
+
+// TideQueryChange is a Tide query that the tide-config-manager adds to or
+// removes from a sharded Prow configuration file. A changed query is
+// reported as the removal of the old query and the creation of the new one.
+type TideQueryChange struct {
+	Action Action               `json:"action"`
+	Query  prowconfig.TideQuery `json:"query"`
+}
+
+// BugzillaChange is a change of the default Bugzilla plugin settings for a
+// branch made by the bugzilla-config-manager
+type BugzillaChange struct {
+	Branch string                         `json:"branch"`
+	Action Action                         `json:"action"`
+	Before *plugins.BugzillaBranchOptions `json:"before,omitempty"`
+	After  *plugins.BugzillaBranchOptions `json:"after,omitempty"`
+}
+
+// FastForwardingChange is a change of the arguments of the fast-forwarding
+// job made by the fast-forwarding-config-manager
+type FastForwardingChange struct {
+	Job    string   `json:"job"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// BranchChecker determines whether a branch exists in a repository
+type BranchChecker func(org, repo, branch string) (bool, error)
+
+// Planner determines what the config-brancher, repo-brancher and the config
+// managers would change in a checkout of the release repo without changing it
+type Planner struct {
+	// ReleaseRepo is the path to the checkout of the release repo
+	ReleaseRepo string
+	// Options select the configurations that are branched and the releases
+	// they are branched for; they must be validated
+	Options     promotion.FutureOptions
+	BumpRelease string
+	// Resolver resolves the configurations when the jobs for them are
+	// generated, if set
+	Resolver registry.Resolver
+	// BranchExists is used to warn about repositories that lack a future
+	// branch, if set
+	BranchExists BranchChecker
+
+	// Lifecycle is the OCP lifecycle configuration the config managers
+	// reconcile to; their changes are not planned when it is not set
+	Lifecycle     ocplifecycle.Config
+	Now           time.Time
+	ExcludedRepos tide.ExcludedRepos
+}
+
+func (p *Planner) configDir() string {
+	if p.Options.ConfigDir != "" {
+		return p.Options.ConfigDir
+	}
+	return filepath.Join(p.ReleaseRepo, config.CiopConfigInRepoPath)
+}
+
+func (p *Planner) prowConfigDir() string {
+	return filepath.Dir(filepath.Join(p.ReleaseRepo, config.ConfigInRepoPath))
+}
+
+// Plan determines the changes of all tools
+func (p *Planner) Plan() (*Plan, error) {
+	plan := &Plan{}
+	repos := map[string]*RepoPlan{}
+	repoPlan := func(org, repo string) *RepoPlan {
+		orgRepo := fmt.Sprintf("%s/%s", org, repo)
+		if _, ok := repos[orgRepo]; !ok {
+			repos[orgRepo] = &RepoPlan{Org: org, Repo: repo}
+		}
+		return repos[orgRepo]
+	}
+
+	if err := p.planConfigs(repoPlan); err != nil {
+		return nil, fmt.Errorf("failed to plan the branched configurations: %w", err)
+	}
+	if err := p.planBranches(repoPlan); err != nil {
+		return nil, fmt.Errorf("failed to plan the future branches: %w", err)
+	}
+	if p.Lifecycle != nil {
+		if err := p.planTideQueries(repoPlan); err != nil {
+			return nil, fmt.Errorf("failed to plan the Tide queries: %w", err)
+		}
+		bugzillaChanges, err := p.planBugzilla()
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan the Bugzilla settings: %w", err)
+		}
+		plan.Bugzilla = bugzillaChanges
+		fastForwarding, err := p.planFastForwarding()
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan the fast-forwarding job: %w", err)
+		}
+		plan.FastForwarding = fastForwarding
+	}
+
+	for _, orgRepo := range sets.StringKeySet(repos).List() {
+		repo := repos[orgRepo]
+		if len(repo.Branches)+len(repo.Configs)+len(repo.Jobs)+len(repo.TideQueries)+len(repo.Warnings) == 0 {
+			continue
+		}
+		sort.Slice(repo.Branches, func(i, j int) bool { return repo.Branches[i].Branch < repo.Branches[j].Branch })
+		sort.Slice(repo.Configs, func(i, j int) bool { return repo.Configs[i].Filename < repo.Configs[j].Filename })
+		plan.Repos = append(plan.Repos, repo)
+	}
+	return plan, nil
+}
+
+// planConfigs determines the configurations the config-brancher creates or
+// changes and the jobs generated for them
+func (p *Planner) planConfigs(repoPlan func(org, repo string) *RepoPlan) error {
+	generated := map[string]*prowconfig.JobConfig{}
+	generate := prowgen.GenerateJobsInto(p.Resolver, map[string]*config.Prowgen{}, generated)
+	if err := p.Options.OperateOnCIOperatorConfigDir(p.configDir(), cioperatorapi.WithOKD, func(configuration *cioperatorapi.ReleaseBuildConfiguration, info *config.Info) error {
+		plan := repoPlan(info.Org, info.Repo)
+		if _, err := promotion.DetermineReleaseBranch(p.Options.CurrentRelease, p.Options.CurrentRelease, info.Branch); err != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s promotes to %s but is not branched: %v", info.RelativePath(), p.Options.CurrentRelease, err))
+			return nil
+		}
+		for _, output := range promotion.GenerateBranchedConfigs(p.Options.CurrentRelease, p.BumpRelease, p.Options.FutureReleases.Strings(), config.DataWithInfo{Configuration: *configuration, Info: *info}) {
+			change, err := p.configChange(output)
+			if err != nil {
+				return err
+			}
+			if change == nil {
+				continue
+			}
+			plan.Configs = append(plan.Configs, *change)
+			if err := generate(&output.Configuration, &output.Info); err != nil {
+				return fmt.Errorf("failed to generate jobs for %s: %w", output.Info.RelativePath(), err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, orgRepo := range sets.StringKeySet(generated).List() {
+		org, repo := splitOrgRepo(orgRepo)
+		changes, err := p.jobChanges(orgRepo, generated[orgRepo])
+		if err != nil {
+			return err
+		}
+		plan := repoPlan(org, repo)
+		plan.Jobs = append(plan.Jobs, changes...)
+	}
+	return nil
+}
+
+// configChange compares a branched configuration with the file it would be
+// written to; it returns nil if the file would not change
+func (p *Planner) configChange(output config.DataWithInfo) (*ConfigChange, error) {
+	path := filepath.Join(p.configDir(), output.Info.RelativePath())
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return &ConfigChange{Filename: output.Info.RelativePath(), Action: ActionCreate}, nil
+	}
+	var existing *cioperatorapi.ReleaseBuildConfiguration
+	if err := config.OperateOnCIOperatorConfig(path, func(configuration *cioperatorapi.ReleaseBuildConfiguration, _ *config.Info) error {
+		existing = configuration
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	fields, err := differingFields(existing, output.Configuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s: %w", output.Info.RelativePath(), err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return &ConfigChange{Filename: output.Info.RelativePath(), Action: ActionChange, Fields: fields}, nil
+}
+
+// jobChanges compares the jobs generated for the branched configurations of
+// a repository with the jobs of the same name in the job configuration files
+func (p *Planner) jobChanges(orgRepo string, generated *prowconfig.JobConfig) ([]JobChange, error) {
+	org, repo := splitOrgRepo(orgRepo)
+	existing := &prowconfig.JobConfig{}
+	jobDir := filepath.Join(p.ReleaseRepo, config.JobConfigInRepoPath, org, repo)
+	if _, err := os.Stat(jobDir); err == nil {
+		if existing, err = jc.ReadFromDir(jobDir); err != nil {
+			return nil, fmt.Errorf("failed to load the jobs of %s: %w", orgRepo, err)
+		}
+	}
+	drifts, err := jc.DetectDrift(map[string]*prowconfig.JobConfig{orgRepo: generated}, map[string]*prowconfig.JobConfig{orgRepo: sameJobs(existing, generated)}, prowgen.Generator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the jobs of %s: %w", orgRepo, err)
+	}
+	var changes []JobChange
+	for _, drift := range drifts {
+		change := JobChange{Name: drift.Job, Type: drift.JobType, Action: ActionChange, Fields: drift.Fields}
+		switch drift.Kind {
+		case jc.DriftMissing:
+			change.Action = ActionCreate
+		case jc.DriftStaleLabels:
+			change.Fields = []string{"labels"}
+		case jc.DriftOrphaned:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// sameJobs selects the jobs that have the same name as a job in the other
+// job configuration, so that only the jobs of the branched configurations are
+// compared and the other jobs of the repository are not reported
+func sameJobs(jobs, other *prowconfig.JobConfig) *prowconfig.JobConfig {
+	names := sets.NewString()
+	for _, presubmits := range other.PresubmitsStatic {
+		for _, job := range presubmits {
+			names.Insert(job.Name)
+		}
+	}
+	for _, postsubmits := range other.PostsubmitsStatic {
+		for _, job := range postsubmits {
+			names.Insert(job.Name)
+		}
+	}
+	for _, job := range other.Periodics {
+		names.Insert(job.Name)
+	}
+
+	selected := &prowconfig.JobConfig{}
+	for repo, presubmits := range jobs.PresubmitsStatic {
+		for _, job := range presubmits {
+			if names.Has(job.Name) {
+				if selected.PresubmitsStatic == nil {
+					selected.PresubmitsStatic = map[string][]prowconfig.Presubmit{}
+				}
+				selected.PresubmitsStatic[repo] = append(selected.PresubmitsStatic[repo], job)
+			}
+		}
+	}
+	for repo, postsubmits := range jobs.PostsubmitsStatic {
+		for _, job := range postsubmits {
+			if names.Has(job.Name) {
+				if selected.PostsubmitsStatic == nil {
+					selected.PostsubmitsStatic = map[string][]prowconfig.Postsubmit{}
+				}
+				selected.PostsubmitsStatic[repo] = append(selected.PostsubmitsStatic[repo], job)
+			}
+		}
+	}
+	for _, job := range jobs.Periodics {
+		if names.Has(job.Name) {
+			selected.Periodics = append(selected.Periodics, job)
+		}
+	}
+	return selected
+}
+
+// planBranches determines the future branches the repo-brancher pushes and
+// warns about those that do not exist yet
+func (p *Planner) planBranches(repoPlan func(org, repo string) *RepoPlan) error {
+	return p.Options.OperateOnCIOperatorConfigDir(p.configDir(), cioperatorapi.WithoutOKD, func(configuration *cioperatorapi.ReleaseBuildConfiguration, info *config.Info) error {
+		plan := repoPlan(info.Org, info.Repo)
+		for _, futureRelease := range p.Options.FutureReleases.Strings() {
+			futureBranch, err := promotion.DetermineReleaseBranch(p.Options.CurrentRelease, futureRelease, info.Branch)
+			if err != nil {
+				// already reported when planning the configurations
+				return nil
+			}
+			if futureBranch == info.Branch {
+				continue
+			}
+			if hasBranch(plan.Branches, futureBranch) {
+				// variants of the configuration share the branch
+				continue
+			}
+			change := BranchChange{Branch: futureBranch, From: info.Branch}
+			if p.BranchExists != nil {
+				exists, err := p.BranchExists(info.Org, info.Repo, futureBranch)
+				switch {
+				case err != nil:
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf("could not determine whether the future branch %s exists: %v", futureBranch, err))
+				case exists:
+					change.Action = ActionChange
+				default:
+					change.Action = ActionCreate
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf("the future branch %s does not exist yet and will only exist once the repo-brancher creates it from %s", futureBranch, info.Branch))
+				}
+			}
+			plan.Branches = append(plan.Branches, change)
+		}
+		return nil
+	})
+}
+
+func hasBranch(branches []BranchChange, branch string) bool {
+	for _, change := range branches {
+		if change.Branch == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// planTideQueries determines the Tide queries the tide-config-manager adds to
+// or removes from the sharded Prow configuration
+func (p *Planner) planTideQueries(repoPlan func(org, repo string) *RepoPlan) error {
+	event := tide.EventAt(p.Lifecycle, p.Now)
+	if event == nil {
+		return nil
+	}
+	prowConfigDir := p.prowConfigDir()
+	prowConfig, err := prowconfig.LoadStrict(filepath.Join(p.ReleaseRepo, config.ConfigInRepoPath), "", []string{prowConfigDir}, config.SupplementalProwConfigFileName)
+	if err != nil {
+		return fmt.Errorf("failed to load Prow config in strict mode: %w", err)
+	}
+	target := afero.NewMemMapFs()
+	if err := tide.Reconcile(event, &prowConfig.ProwConfig, target, p.ExcludedRepos); err != nil {
+		return err
+	}
+	return afero.Walk(target, "", func(path string, info fs.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		reconciled, err := readTideQueries(afero.ReadFile(target, path))
+		if err != nil {
+			return fmt.Errorf("failed to read the reconciled %s: %w", path, err)
+		}
+		existing, err := readTideQueries(ioutil.ReadFile(filepath.Join(prowConfigDir, path)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		changes := tideQueryChanges(existing, reconciled)
+		if len(changes) == 0 {
+			return nil
+		}
+		org, repo := splitOrgRepo(filepath.ToSlash(filepath.Dir(path)))
+		plan := repoPlan(org, repo)
+		plan.TideQueries = append(plan.TideQueries, changes...)
+		return nil
+	})
+}
+
+func readTideQueries(raw []byte, err error) (prowconfig.TideQueries, error) {
+	if err != nil {
+		return nil, err
+	}
+	var shard shardprowconfig.ProwConfigWithPointers
+	if err := yaml.Unmarshal(raw, &shard); err != nil {
+		return nil, err
+	}
+	if shard.Tide == nil {
+		return nil, nil
+	}
+	return shard.Tide.Queries, nil
+}
+
+func tideQueryChanges(existing, reconciled prowconfig.TideQueries) []TideQueryChange {
+	contains := func(queries prowconfig.TideQueries, query prowconfig.TideQuery) bool {
+		for _, q := range queries {
+			if reflect.DeepEqual(normalizeQuery(q), normalizeQuery(query)) {
+				return true
+			}
+		}
+		return false
+	}
+	var changes []TideQueryChange
+	for _, query := range existing {
+		if !contains(reconciled, query) {
+			changes = append(changes, TideQueryChange{Action: ActionRemove, Query: query})
+		}
+	}
+	for _, query := range reconciled {
+		if !contains(existing, query) {
+			changes = append(changes, TideQueryChange{Action: ActionCreate, Query: query})
+		}
+	}
+	return changes
+}
+
+// normalizeQuery sorts the fields of a query that the config manager sorts
+// and treats empty fields as unset, so that only real changes are reported
+func normalizeQuery(query prowconfig.TideQuery) prowconfig.TideQuery {
+	sorted := func(values []string) []string {
+		if len(values) == 0 {
+			return nil
+		}
+		return sets.NewString(values...).List()
+	}
+	query.Labels = sorted(query.Labels)
+	query.MissingLabels = sorted(query.MissingLabels)
+	query.IncludedBranches = sorted(query.IncludedBranches)
+	query.ExcludedBranches = sorted(query.ExcludedBranches)
+	return query
+}
+
+// planBugzilla determines the default Bugzilla branch settings the
+// bugzilla-config-manager creates or changes
+func (p *Planner) planBugzilla() ([]BugzillaChange, error) {
+	pluginConfigDir := filepath.Dir(filepath.Join(p.ReleaseRepo, config.PluginConfigInRepoPath))
+	agent := plugins.ConfigAgent{}
+	if err := agent.Load(filepath.Join(p.ReleaseRepo, config.PluginConfigInRepoPath), []string{pluginConfigDir}, config.SupplementalPluginConfigFileName, false, true); err != nil {
+		return nil, fmt.Errorf("failed to load Prow plugin configuration: %w", err)
+	}
+	current := agent.Config().Bugzilla
+	// the config manager updates the defaults in place
+	defaults := map[string]plugins.BugzillaBranchOptions{}
+	for branch, options := range current.Default {
+		defaults[branch] = options
+	}
+	reconciled := current
+	reconciled.Default = defaults
+	updated, err := bugzilla.Reconcile(p.Lifecycle, reconciled, p.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []BugzillaChange
+	for _, branch := range sets.StringKeySet(updated.Default).List() {
+		after := updated.Default[branch]
+		before, ok := current.Default[branch]
+		switch {
+		case !ok:
+			changes = append(changes, BugzillaChange{Branch: branch, Action: ActionCreate, After: &after})
+		case !reflect.DeepEqual(before, after):
+			changes = append(changes, BugzillaChange{Branch: branch, Action: ActionChange, Before: &before, After: &after})
+		}
+	}
+	return changes, nil
+}
+
+// planFastForwarding determines the changes of the fast-forwarding-config-manager
+// to the fast-forwarding job
+func (p *Planner) planFastForwarding() (*FastForwardingChange, error) {
+	jobConfig, err := prowconfig.ReadJobConfig(filepath.Join(p.ReleaseRepo, InfraPeriodicsInRepoPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job configuration: %w", err)
+	}
+	job, err := fastforwarding.GetPeriodicJob(fastforwarding.JobName, jobConfig.Periodics)
+	if err != nil {
+		return nil, err
+	}
+	if job.Spec == nil || len(job.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("job %s has no containers", job.Name)
+	}
+	before := job.Spec.Containers[0].Args
+	// the config manager updates the job arguments in place
+	job.Spec = job.Spec.DeepCopy()
+	if err := fastforwarding.Reconcile(p.Lifecycle, p.Now, job); err != nil {
+		return nil, err
+	}
+	after := job.Spec.Containers[0].Args
+	if reflect.DeepEqual(before, after) {
+		return nil, nil
+	}
+	return &FastForwardingChange{Job: job.Name, Before: before, After: after}, nil
+}
+
+// differingFields returns the top-level fields that differ between the
+// serialized objects
+func differingFields(a, b interface{}) ([]string, error) {
+	fields := func(o interface{}) (map[string]json.RawMessage, error) {
+		raw, err := json.Marshal(o)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		return fields, json.Unmarshal(raw, &fields)
+	}
+	aFields, err := fields(a)
+	if err != nil {
+		return nil, err
+	}
+	bFields, err := fields(b)
+	if err != nil {
+		return nil, err
+	}
+	differing := sets.NewString()
+	for field := range aFields {
+		if string(aFields[field]) != string(bFields[field]) {
+			differing.Insert(field)
+		}
+	}
+	for field := range bFields {
+		if _, ok := aFields[field]; !ok {
+			differing.Insert(field)
+		}
+	}
+	return differing.List(), nil
+}
+
+func splitOrgRepo(orgRepo string) (string, string) {
+	org, repo, _ := strings.Cut(orgRepo, "/")
+	return org, repo
+}
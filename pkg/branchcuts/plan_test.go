@@ -0,0 +1,255 @@
+package branchcuts
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	prowconfig "k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/flagutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api/ocplifecycle"
+	"github.com/openshift/ci-tools/pkg/config"
+	jc "github.com/openshift/ci-tools/pkg/jobconfig"
+	"github.com/openshift/ci-tools/pkg/promotion"
+	"github.com/openshift/ci-tools/pkg/prowgen"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+const (
+	masterConfig = `build_root:
+  image_stream_tag:
+    name: release
+    namespace: openshift
+    tag: golang-1.17
+promotion:
+  name: "4.10"
+  namespace: ocp
+releases:
+  latest:
+    integration:
+      name: "4.10"
+      namespace: ocp
+resources:
+  '*':
+    requests:
+      cpu: 100m
+tests:
+- as: unit
+  commands: make test
+  container:
+    from: src
+zz_generated_metadata:
+  branch: master
+  org: org
+  repo: repo
+`
+	// releaseConfig is the configuration for the release branch of the
+	// current release, which does not promote before the branch cut, with a
+	// test that is not in the dev branch
+	releaseConfig = `build_root:
+  image_stream_tag:
+    name: release
+    namespace: openshift
+    tag: golang-1.17
+promotion:
+  disabled: true
+  name: "4.10"
+  namespace: ocp
+releases:
+  latest:
+    integration:
+      name: "4.10"
+      namespace: ocp
+resources:
+  '*':
+    requests:
+      cpu: 100m
+tests:
+- as: unit
+  commands: make test
+  container:
+    from: src
+- as: lint
+  commands: make lint
+  container:
+    from: src
+zz_generated_metadata:
+  branch: release-4.10
+  org: org
+  repo: repo
+`
+	featureBranchConfig = `build_root:
+  image_stream_tag:
+    name: release
+    namespace: openshift
+    tag: golang-1.17
+promotion:
+  name: "4.10"
+  namespace: ocp
+resources:
+  '*':
+    requests:
+      cpu: 100m
+tests:
+- as: unit
+  commands: make test
+  container:
+    from: src
+zz_generated_metadata:
+  branch: feature
+  org: org
+  repo: other
+`
+	prowConfig = `tide:
+  sync_period: 1m
+`
+	repoProwConfig = `tide:
+  queries:
+  - repos:
+    - org/repo
+    labels:
+    - staff-eng-approved
+    includedBranches:
+    - release-4.10
+  - repos:
+    - org/repo
+    labels:
+    - cherry-pick-approved
+    includedBranches:
+    - release-4.9
+  - repos:
+    - org/repo
+    labels:
+    - lgtm
+    includedBranches:
+    - master
+`
+	pluginConfig = `bugzilla:
+  default:
+    master:
+      target_release: 4.10.0
+    release-4.10:
+      target_release: 4.10.0
+      validate_by_default: true
+`
+	infraPeriodics = `periodics:
+- name: periodic-openshift-release-fast-forward
+  cron: '@daily'
+  spec:
+    containers:
+    - args:
+      - --current-release=4.10
+      - --future-release=4.10
+      - --future-release=4.11
+      command:
+      - repo-brancher
+      image: repo-brancher:latest
+`
+	lifecycleConfig = `ocp:
+  "4.10":
+  - event: generally-available
+    when: "2022-03-10T00:00:00Z"
+  - event: code-freeze
+    when: "2022-02-01T00:00:00Z"
+  - event: feature-freeze
+    when: "2022-01-05T00:00:00Z"
+  - event: open
+    when: "2021-09-01T00:00:00Z"
+  "4.11":
+  - event: generally-available
+  - event: code-freeze
+  - event: feature-freeze
+    when: "2022-05-01T00:00:00Z"
+  - event: open
+    when: "2022-01-10T00:00:00Z"
+`
+)
+
+func TestPlan(t *testing.T) {
+	file := func(content string) fstest.MapFile {
+		return fstest.MapFile{Data: []byte(content), Mode: 0644}
+	}
+	releaseRepo, err := testhelper.TmpDir(t, map[string]fstest.MapFile{
+		"ci-operator/config/org/repo/org-repo-master.yaml":       file(masterConfig),
+		"ci-operator/config/org/repo/org-repo-release-4.10.yaml": file(releaseConfig),
+		"ci-operator/config/org/other/org-other-feature.yaml":    file(featureBranchConfig),
+		"ci-operator/jobs/infra-periodics.yaml":                  file(infraPeriodics),
+		"core-services/prow/02_config/_config.yaml":              file(prowConfig),
+		"core-services/prow/02_config/org/repo/_prowconfig.yaml": file(repoProwConfig),
+		"core-services/prow/02_config/_plugins.yaml":             file(pluginConfig),
+	})
+	if err != nil {
+		t.Fatalf("failed to create the release repo: %v", err)
+	}
+
+	// the jobs of the dev branch are up to date, so bumping its configuration
+	// does not change them
+	configs, err := config.LoadDataByFilename(filepath.Join(releaseRepo, config.CiopConfigInRepoPath))
+	if err != nil {
+		t.Fatalf("failed to load configurations: %v", err)
+	}
+	master := configs["org-repo-master.yaml"]
+	jobs, err := prowgen.GenerateJobs(&master.Configuration, &prowgen.ProwgenInfo{Metadata: master.Info.Metadata})
+	if err != nil {
+		t.Fatalf("failed to generate jobs: %v", err)
+	}
+	if err := jc.WriteToDir(filepath.Join(releaseRepo, config.JobConfigInRepoPath), "org", "repo", jobs, prowgen.Generator, nil); err != nil {
+		t.Fatalf("failed to write jobs: %v", err)
+	}
+
+	var lifecycle ocplifecycle.Config
+	if err := yaml.Unmarshal([]byte(lifecycleConfig), &lifecycle); err != nil {
+		t.Fatalf("failed to load the lifecycle configuration: %v", err)
+	}
+	futureReleases := flagutil.NewStrings("4.10", "4.11")
+	planner := Planner{
+		ReleaseRepo: releaseRepo,
+		Options: promotion.FutureOptions{
+			Options:        promotion.Options{CurrentRelease: "4.10"},
+			FutureReleases: futureReleases,
+		},
+		BumpRelease: "4.11",
+		BranchExists: func(org, repo, branch string) (bool, error) {
+			return branch == "release-4.10", nil
+		},
+		Lifecycle: lifecycle,
+		Now:       time.Date(2022, time.March, 10, 12, 0, 0, 0, time.UTC),
+	}
+	plan, err := planner.Plan()
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	testhelper.CompareWithFixture(t, plan)
+
+	// planning does not change the release repo
+	after, err := config.LoadDataByFilename(filepath.Join(releaseRepo, config.CiopConfigInRepoPath))
+	if err != nil {
+		t.Fatalf("failed to load configurations: %v", err)
+	}
+	testhelper.Diff(t, "configurations", after, configs)
+	infra, err := prowconfig.ReadJobConfig(filepath.Join(releaseRepo, InfraPeriodicsInRepoPath))
+	if err != nil {
+		t.Fatalf("failed to read the infra periodics: %v", err)
+	}
+	if args := infra.Periodics[0].Spec.Containers[0].Args; len(args) != 3 {
+		t.Errorf("expected the fast-forwarding job not to change, got arguments %v", args)
+	}
+}
+
+func TestTideQueryChanges(t *testing.T) {
+	existing := prowconfig.TideQueries{
+		{Repos: []string{"org/repo"}, Labels: []string{"lgtm", "approved"}, IncludedBranches: []string{"master"}},
+		{Repos: []string{"org/repo"}, Labels: []string{"staff-eng-approved"}, IncludedBranches: []string{"release-4.10"}},
+	}
+	reconciled := prowconfig.TideQueries{
+		{Repos: []string{"org/repo"}, Labels: []string{"approved", "lgtm"}, IncludedBranches: []string{"master"}, ExcludedBranches: []string{}},
+		{Repos: []string{"org/repo"}, Labels: []string{"staff-eng-approved"}, IncludedBranches: []string{"release-4.11"}},
+	}
+	testhelper.Diff(t, "changes", tideQueryChanges(existing, reconciled), []TideQueryChange{
+		{Action: ActionRemove, Query: existing[1]},
+		{Action: ActionCreate, Query: reconciled[1]},
+	})
+}
@@ -0,0 +1,351 @@
+package tide
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api/ocplifecycle"
+	"github.com/openshift/ci-tools/pkg/api/shardprowconfig"
+)
+
+const (
+	staffEngApproved   = "staff-eng-approved"
+	cherryPickApproved = "cherry-pick-approved"
+	qeApproved         = "qe-approved"
+	docsApproved       = "docs-approved"
+	pxApproved         = "px-approved"
+	validBug           = "bugzilla/valid-bug"
+	release            = "release-"
+	openshift          = "openshift-"
+	mainBranch         = "main"
+	masterBranch       = "master"
+	openshiftPriv      = "openshift-priv"
+	ocpProductName     = "ocp"
+)
+
+type sharedDataDelegate struct {
+	excludedLabels sets.String
+	mainMaster     sets.String
+	validBug       sets.String
+}
+
+func newSharedDataDelegate() *sharedDataDelegate {
+	return &sharedDataDelegate{
+		excludedLabels: sets.NewString(qeApproved, docsApproved, pxApproved),
+		mainMaster:     sets.NewString(mainBranch, masterBranch),
+		validBug:       sets.NewString(validBug),
+	}
+
+}
+
+type featureFreezeEvent struct {
+	excludedOrgs                  []string
+	repos                         sets.String
+	openshiftReleaseBranches      sets.String
+	openshiftReleaseBranchesPlus1 sets.String
+	*sharedDataDelegate
+}
+
+func newFeatureFreezeEvent(current, future string, delegate *sharedDataDelegate) featureFreezeEvent {
+	return featureFreezeEvent{
+		excludedOrgs:                  []string{openshiftPriv},
+		repos:                         sets.NewString(),
+		openshiftReleaseBranches:      sets.NewString(release+current, openshift+current),
+		openshiftReleaseBranchesPlus1: sets.NewString(release+future, openshift+future),
+		sharedDataDelegate:            delegate,
+	}
+}
+
+func (ffe featureFreezeEvent) ModifyQuery(q *prowconfig.TideQuery, repo string) {
+	ffe.ensureFeatureFreezeApprovals(q)
+	if ffe.repos.Has(repo) {
+		ffe.ensureFeatureFreezeBugs(q)
+	}
+}
+
+func (ffe featureFreezeEvent) GetDataFromProwConfig(pc *prowconfig.ProwConfig) {
+	for _, query := range pc.Tide.Queries {
+		branches := sets.NewString(query.IncludedBranches...)
+		labels := sets.NewString(query.Labels...)
+		ffe.rewriteReposToExcludeOrgs(&query)
+		if branches.Intersection(ffe.openshiftReleaseBranchesPlus1).Len() > 0 && labels.Has(staffEngApproved) {
+			ffe.repos.Insert(query.Repos...)
+			continue
+		}
+		if branches.Intersection(ffe.openshiftReleaseBranches).Len() > 0 && labels.Has(cherryPickApproved) {
+			ffe.repos.Insert(query.Repos...)
+		}
+	}
+}
+
+func (ffe *featureFreezeEvent) rewriteReposToExcludeOrgs(q *prowconfig.TideQuery) {
+	repos := []string{}
+	for _, repo := range q.Repos {
+		for _, org := range ffe.excludedOrgs {
+			if strings.Contains(repo, org) {
+				continue
+			}
+			repos = append(repos, repo)
+		}
+	}
+	q.Repos = repos
+}
+
+func (ffe *featureFreezeEvent) ensureFeatureFreezeBugs(q *prowconfig.TideQuery) {
+	requiredLabels := sets.NewString(q.Labels...)
+	branches := sets.NewString(q.IncludedBranches...)
+	if branches.Intersection(ffe.mainMaster).Len() == 0 {
+		return
+	}
+	if requiredLabels.Intersection(ffe.excludedLabels).Len() > 0 {
+		return
+	}
+	requiredLabels = requiredLabels.Union(ffe.validBug)
+	q.Labels = requiredLabels.List()
+}
+
+func (ffe *featureFreezeEvent) ensureFeatureFreezeApprovals(q *prowconfig.TideQuery) {
+	requiredLabels := sets.NewString(q.Labels...)
+	branches := sets.NewString(q.IncludedBranches...)
+	if branches.Intersection(ffe.mainMaster).Len() == 0 {
+		return
+	}
+	if requiredLabels.Intersection(ffe.excludedLabels).Len() == 0 {
+		return
+	}
+	requiredLabels = requiredLabels.Union(ffe.excludedLabels)
+	requiredLabels = requiredLabels.Difference(ffe.validBug)
+	q.Labels = requiredLabels.List()
+}
+
+type codeFreezeEvent struct {
+	repos                          sets.String
+	noFeatureFreezeRepos           sets.String
+	bugzillaLabelOnMainMasterRepos sets.String
+	*sharedDataDelegate
+}
+
+func newCodeFreezeEvent(delegate *sharedDataDelegate) codeFreezeEvent {
+	return codeFreezeEvent{
+		repos:                          sets.NewString(),
+		noFeatureFreezeRepos:           sets.NewString(),
+		bugzillaLabelOnMainMasterRepos: sets.NewString(),
+		sharedDataDelegate:             delegate,
+	}
+}
+
+func (cfe codeFreezeEvent) ModifyQuery(q *prowconfig.TideQuery, repo string) {
+	if cfe.repos.Has(repo) {
+		branches := sets.NewString(q.IncludedBranches...)
+		if len(branches.Intersection(cfe.mainMaster)) == 0 {
+			return
+		}
+		q.Labels = sets.NewString(q.Labels...).Difference(cfe.validBug).List()
+	}
+}
+
+func (cfe codeFreezeEvent) GetDataFromProwConfig(pc *prowconfig.ProwConfig) {
+	for _, query := range pc.Tide.Queries {
+		branches := sets.NewString(query.IncludedBranches...)
+		if len(branches.Intersection(cfe.mainMaster)) == 0 {
+			continue
+		}
+		labels := sets.NewString(query.Labels...)
+		if len(labels.Intersection(cfe.excludedLabels)) > 0 {
+			for _, repo := range query.Repos {
+				cfe.noFeatureFreezeRepos.Insert(repo)
+			}
+		}
+		if len(labels.Intersection(cfe.validBug)) > 0 {
+			for _, repo := range query.Repos {
+				cfe.bugzillaLabelOnMainMasterRepos.Insert(repo)
+			}
+		}
+	}
+	for repo := range cfe.bugzillaLabelOnMainMasterRepos.Difference(cfe.noFeatureFreezeRepos) {
+		cfe.repos.Insert(repo)
+	}
+}
+
+// ExcludedRepos lists the repositories that the GA reconciliation does not
+// warn about when their queries do not include the future release branches.
+type ExcludedRepos struct {
+	NoXYAllowList     []string `yaml:"NoXYAllowList,flow"`
+	ExcludedAllowList []string `yaml:"ExcludedAllowList,flow"`
+}
+
+// LoadExcludedReposConfig loads the excluded repositories from a file.
+func (er *ExcludedRepos) LoadExcludedReposConfig(path string) error {
+	cfgBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read excluded repos config from path %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(cfgBytes, er); err != nil {
+		return fmt.Errorf("failed to deserialize the excluded repos config: %w", err)
+	}
+	return nil
+}
+
+type generalAvailabilityEvent struct {
+	repos                         sets.String
+	excludedAllowList             sets.String
+	noXYAllowList                 sets.String
+	openshiftReleaseBranches      sets.String
+	openshiftReleaseBranchesPlus1 sets.String
+	releasePast                   string
+	openshiftPast                 string
+	releaseCurrent                string
+	openshiftCurrent              string
+	releaseFuture                 string
+	openshiftFuture               string
+	past                          string
+	current                       string
+	future                        string
+}
+
+func newGeneralAvailabilityEvent(past, current, future string, repos ExcludedRepos) generalAvailabilityEvent {
+	noXYAllowList := sets.NewString(repos.NoXYAllowList...)
+	excludedAllowList := sets.NewString(repos.ExcludedAllowList...).Union(noXYAllowList)
+
+	return generalAvailabilityEvent{
+		repos:                         sets.NewString(),
+		excludedAllowList:             excludedAllowList,
+		noXYAllowList:                 noXYAllowList,
+		openshiftReleaseBranches:      sets.NewString(release+current, openshift+current),
+		openshiftReleaseBranchesPlus1: sets.NewString(release+future, openshift+future),
+		releasePast:                   release + past,
+		releaseCurrent:                release + current,
+		releaseFuture:                 release + future,
+		openshiftPast:                 openshift + past,
+		openshiftCurrent:              openshift + current,
+		openshiftFuture:               openshift + future,
+		past:                          past,
+		current:                       current,
+		future:                        future,
+	}
+}
+
+func (gae generalAvailabilityEvent) ModifyQuery(q *prowconfig.TideQuery, repo string) {
+	gae.ensureStaffEngApprovedLabel(q)
+	gae.ensureCherryPickApprovedLabel(q)
+	gae.overrideExcludedBranches(q)
+}
+
+func (gae generalAvailabilityEvent) GetDataFromProwConfig(*prowconfig.ProwConfig) {}
+
+func (gae *generalAvailabilityEvent) ensureCherryPickApprovedLabel(q *prowconfig.TideQuery) {
+	reqLabels := sets.NewString(q.Labels...)
+	branches := sets.NewString(q.IncludedBranches...)
+	if reqLabels.Has(cherryPickApproved) {
+		if branches.Has(gae.releasePast) {
+			branches.Insert(gae.releaseCurrent)
+		}
+		if branches.Has(gae.openshiftPast) {
+			branches.Insert(gae.openshiftCurrent)
+		}
+		if branches.Intersection(gae.openshiftReleaseBranches).Len() == 0 && !gae.noXYAllowList.Has(q.Repos[0]) {
+			logrus.Warnf("Suspicious cherry-pick-approved query (without %s): %s", gae.current, q.Repos)
+		}
+		if branches.Intersection(gae.openshiftReleaseBranchesPlus1).Len() != 0 {
+			logrus.Warnf("Suspicious cherry-pick-approved query (with %s): %s", gae.future, q.Repos)
+		}
+	}
+	q.IncludedBranches = branches.List()
+}
+
+func (gae *generalAvailabilityEvent) ensureStaffEngApprovedLabel(q *prowconfig.TideQuery) {
+	reqLabels := sets.NewString(q.Labels...)
+	branches := sets.NewString(q.IncludedBranches...)
+
+	if reqLabels.Has(staffEngApproved) {
+		if branches.Has(gae.releaseCurrent) {
+			branches.Delete(gae.releaseCurrent)
+			branches.Insert(gae.releaseFuture)
+		}
+		if branches.Has(gae.openshiftCurrent) {
+			branches.Delete(gae.openshiftCurrent)
+			branches.Insert(gae.openshiftFuture)
+		}
+
+		if !(branches.Equal(sets.NewString(gae.releaseFuture)) || branches.Equal(sets.NewString(gae.openshiftFuture)) || branches.Equal(gae.openshiftReleaseBranchesPlus1)) {
+			logrus.Warnf("Suspicious staff-eng-approved query: %s", q.Repos)
+		}
+	}
+	q.IncludedBranches = branches.List()
+}
+
+func (gae *generalAvailabilityEvent) overrideExcludedBranches(q *prowconfig.TideQuery) {
+	branches := sets.NewString(q.ExcludedBranches...)
+	if branches.Has(gae.releasePast) {
+		branches.Insert(gae.releaseCurrent)
+		branches.Insert(gae.releaseFuture)
+	}
+	if branches.Has(gae.openshiftPast) {
+		branches.Insert(gae.openshiftCurrent)
+		branches.Insert(gae.openshiftFuture)
+	}
+	if branches.Len() > 0 {
+		if branches.Intersection(gae.openshiftReleaseBranchesPlus1).Len() == 0 && !gae.excludedAllowList.Has(q.Repos[0]) {
+			logrus.Warnf("Suspicious complement query (without %s): %s", gae.future, q.Repos)
+		}
+	}
+	q.ExcludedBranches = branches.List()
+}
+
+// EventAt determines the lifecycle event of the current release that the Tide
+// queries are reconciled for at the given time, if any.
+func EventAt(lifecycleConfig ocplifecycle.Config, now time.Time) *ocplifecycle.Event {
+	timelineOpts := ocplifecycle.TimelineOptions{
+		OnlyEvents: sets.NewString([]string{
+			string(ocplifecycle.LifecycleEventFeatureFreeze),
+			string(ocplifecycle.LifecycleEventCodeFreeze),
+			string(ocplifecycle.LifecycleEventGenerallyAvailable),
+		}...),
+	}
+
+	timeline := lifecycleConfig.GetTimeline(ocpProductName, timelineOpts)
+	return timeline.GetExactLifecyclePhase(now)
+}
+
+// Reconcile shards the Prow configuration into the target, with the Tide
+// queries updated for the lifecycle event of the current release.
+func Reconcile(event *ocplifecycle.Event, config *prowconfig.ProwConfig, target afero.Fs, repos ExcludedRepos) error {
+	delegate := newSharedDataDelegate()
+	currentVersion, err := ocplifecycle.ParseMajorMinor(event.ProductVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s as majorMinor version: %w", event.ProductVersion, err)
+	}
+	if event.LifecyclePhase.Event == ocplifecycle.LifecycleEventFeatureFreeze {
+		_, err = shardprowconfig.ShardProwConfig(config, target,
+			newFeatureFreezeEvent(
+				currentVersion.GetVersion(),
+				currentVersion.GetFutureVersion(),
+				delegate),
+		)
+	}
+	if event.LifecyclePhase.Event == ocplifecycle.LifecycleEventCodeFreeze {
+		_, err = shardprowconfig.ShardProwConfig(config, target, newCodeFreezeEvent(delegate))
+	}
+	if event.LifecyclePhase.Event == ocplifecycle.LifecycleEventGenerallyAvailable {
+		_, err = shardprowconfig.ShardProwConfig(config, target,
+			newGeneralAvailabilityEvent(
+				currentVersion.GetPastVersion(),
+				currentVersion.GetVersion(),
+				currentVersion.GetFutureVersion(),
+				repos),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to shard the prow config: %w", err)
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-package main
+package tide
 
 import (
 	"io/fs"
@@ -276,7 +276,7 @@ func TestReconcile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := reconcile(tt.args.event, tt.args.config, tt.args.target, excludedRepos{}); (err != nil) != tt.wantErr {
+			if err := Reconcile(tt.args.event, tt.args.config, tt.args.target, ExcludedRepos{}); (err != nil) != tt.wantErr {
 				t.Errorf("reconcile() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			shardedConfigFiles := map[string]string{}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/ghodss/yaml"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/util/gzip"
+)
+
+// extendsField is the field of a ci-operator configuration file that names
+// the configuration it inherits from, as ORG/REPO@BRANCH. The rest of the file
+// is a JSON merge patch (RFC 7386) for the inherited configuration: objects
+// are merged field by field, lists and other values replace the inherited
+// ones and null removes an inherited field.
+const extendsField = "extends"
+
+// ParseExtends parses a reference to the configuration that another one
+// extends, ORG/REPO@BRANCH
+func ParseExtends(ref string) (*cioperatorapi.Metadata, error) {
+	orgRepo, branch, ok := strings.Cut(ref, "@")
+	if !ok || branch == "" {
+		return nil, fmt.Errorf("%q is not a reference to a configuration, expected ORG/REPO@BRANCH", ref)
+	}
+	org, repo, ok := strings.Cut(orgRepo, "/")
+	if !ok || org == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("%q is not a reference to a configuration, expected ORG/REPO@BRANCH", ref)
+	}
+	return &cioperatorapi.Metadata{Org: org, Repo: repo, Branch: branch}, nil
+}
+
+// extendsRef formats the reference to a configuration that others extend
+func extendsRef(metadata cioperatorapi.Metadata) string {
+	return fmt.Sprintf("%s/%s@%s", metadata.Org, metadata.Repo, metadata.Branch)
+}
+
+// ExtendsOf returns the reference to the configuration that a raw configuration
+// extends, or an empty string if it does not extend any
+func ExtendsOf(data []byte) (string, error) {
+	var fields struct {
+		Extends string `json:"extends"`
+	}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("failed to load ci-operator config (%w)", err)
+	}
+	return fields.Extends, nil
+}
+
+// ReadConfigFile reads a ci-operator configuration file. When it extends other
+// configurations, it is returned as JSON with them merged in. These are read
+// from the configuration directory the file is in, so a file that extends
+// another configuration must be laid out like .../ORG/REPO/ORG-REPO-BRANCH.yaml
+// in it.
+func ReadConfigFile(configFilePath string) ([]byte, error) {
+	data, err := gzip.ReadFileMaybeGZIP(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ci-operator config (%w)", err)
+	}
+	ref, err := ExtendsOf(data)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		return data, nil
+	}
+	info, err := InfoFromPath(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: configurations that extend %s must be in a configuration directory: %w", extendsField, ref, err)
+	}
+	raw, _, err := readFlattenedConfig(configFilePath, *info)
+	if err != nil {
+		return nil, err
+	}
+	// the metadata must not be inherited
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("failed to load ci-operator config (%w)", err)
+	}
+	metadata, err := json.Marshal(info.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	fields["zz_generated_metadata"] = metadata
+	return json.Marshal(fields)
+}
+
+// readFlattenedConfig reads a configuration file as JSON with the
+// configurations it extends merged in. The configurations it extends are read
+// from the same configuration directory. It returns the configuration the file
+// extends directly, if any.
+func readFlattenedConfig(configFilePath string, info Info) ([]byte, *cioperatorapi.Metadata, error) {
+	return flatten(configFilePath, info, sets.NewString())
+}
+
+func flatten(configFilePath string, info Info, seen sets.String) ([]byte, *cioperatorapi.Metadata, error) {
+	data, err := gzip.ReadFileMaybeGZIP(configFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ci-operator config (%w)", err)
+	}
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load ci-operator config (%w)", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, nil, fmt.Errorf("failed to load ci-operator config (%w)", err)
+	}
+	rawRef, ok := fields[extendsField]
+	if !ok {
+		return raw, nil, nil
+	}
+	var ref string
+	if err := json.Unmarshal(rawRef, &ref); err != nil {
+		return nil, nil, fmt.Errorf("%s: must be a string: %w", extendsField, err)
+	}
+	parent, err := ParseExtends(ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", extendsField, err)
+	}
+
+	seen.Insert(info.RelativePath())
+	if seen.Has(parent.RelativePath()) {
+		return nil, nil, fmt.Errorf("%s: %s extends itself through %s", extendsField, info.RelativePath(), strings.Join(seen.List(), ", "))
+	}
+	parentPath := filepath.Join(filepath.Dir(info.OrgPath), parent.RelativePath())
+	parentInfo, err := InfoFromPath(parentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	parentRaw, _, err := flatten(parentPath, *parentInfo, seen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: failed to load %s: %w", extendsField, ref, err)
+	}
+
+	delete(fields, extendsField)
+	overrides, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal the overrides of %s: %w", ref, err)
+	}
+	merged, err := jsonpatch.MergePatch(parentRaw, overrides)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply the overrides of %s: %w", ref, err)
+	}
+	return merged, parent, nil
+}
+
+// overridesFor renders a configuration that extends another one as the
+// reference to the configuration it extends and the fields that differ from it
+func overridesFor(configuration cioperatorapi.ReleaseBuildConfiguration, configDir string, extends cioperatorapi.Metadata) ([]byte, error) {
+	parentPath := filepath.Join(configDir, extends.RelativePath())
+	parentInfo, err := InfoFromPath(parentPath)
+	if err != nil {
+		return nil, err
+	}
+	parentRaw, _, err := readFlattenedConfig(parentPath, *parentInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", extendsRef(extends), err)
+	}
+	// round-trip the inherited configuration so that it is serialized the
+	// same way as the configuration and only real differences are kept
+	var parent cioperatorapi.ReleaseBuildConfiguration
+	if err := json.Unmarshal(parentRaw, &parent); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", extendsRef(extends), err)
+	}
+	parentJSON, err := json.Marshal(parent)
+	if err != nil {
+		return nil, err
+	}
+	childJSON, err := json.Marshal(configuration)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.CreateMergePatch(parentJSON, childJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the overrides of %s: %w", extendsRef(extends), err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return nil, err
+	}
+	fields[extendsField] = extendsRef(extends)
+	return yaml.Marshal(fields)
+}
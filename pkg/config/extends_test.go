@@ -0,0 +1,246 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+
+	"sigs.k8s.io/yaml"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+const masterConfig = `binary_build_commands: make build
+build_root:
+  image_stream_tag:
+    name: release
+    namespace: openshift
+    tag: golang-1.17
+promotion:
+  name: "4.11"
+  namespace: ocp
+resources:
+  '*':
+    requests:
+      cpu: 100m
+tests:
+- as: unit
+  commands: make test
+  container:
+    from: src
+zz_generated_metadata:
+  branch: master
+  org: org
+  repo: repo
+`
+
+func TestParseExtends(t *testing.T) {
+	var testCases = []struct {
+		ref         string
+		expected    *cioperatorapi.Metadata
+		expectedErr bool
+	}{
+		{ref: "org/repo@master", expected: &cioperatorapi.Metadata{Org: "org", Repo: "repo", Branch: "master"}},
+		{ref: "org/repo@release-4.10", expected: &cioperatorapi.Metadata{Org: "org", Repo: "repo", Branch: "release-4.10"}},
+		{ref: "org/repo", expectedErr: true},
+		{ref: "org@master", expectedErr: true},
+		{ref: "org/repo/sub@master", expectedErr: true},
+		{ref: "org/repo@", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		actual, err := ParseExtends(tc.ref)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%s: expected error %v, got %v", tc.ref, tc.expectedErr, err)
+		}
+		if diff := cmp.Diff(tc.expected, actual); diff != "" {
+			t.Errorf("%s: metadata differs from expected: %s", tc.ref, diff)
+		}
+	}
+}
+
+func TestLoadExtendingConfigurations(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		files       map[string]string
+		verify      func(t *testing.T, configs DataByFilename)
+		expectedErr bool
+	}{
+		{
+			name: "overrides are merged into the inherited configuration",
+			files: map[string]string{
+				"org/repo/org-repo-master.yaml": masterConfig,
+				"org/repo/org-repo-release-4.10.yaml": `extends: org/repo@master
+promotion:
+  name: "4.10"
+binary_build_commands: null
+`,
+				"org/repo/org-repo-release-4.9.yaml": `extends: org/repo@release-4.10
+promotion:
+  name: "4.9"
+resources:
+  '*':
+    requests:
+      cpu: 200m
+`,
+			},
+			verify: func(t *testing.T, configs DataByFilename) {
+				release := configs["org-repo-release-4.10.yaml"]
+				if diff := cmp.Diff(&cioperatorapi.Metadata{Org: "org", Repo: "repo", Branch: "master"}, release.Info.Extends); diff != "" {
+					t.Errorf("extended configuration differs from expected: %s", diff)
+				}
+				if release.Configuration.Metadata.Branch != "release-4.10" {
+					t.Errorf("expected the metadata not to be inherited, got %v", release.Configuration.Metadata)
+				}
+				if promotion := release.Configuration.PromotionConfiguration; promotion.Name != "4.10" || promotion.Namespace != "ocp" {
+					t.Errorf("expected the promotion name to be overridden and the namespace inherited, got %v", promotion)
+				}
+				if release.Configuration.BinaryBuildCommands != "" {
+					t.Errorf("expected the binary build commands to be removed, got %q", release.Configuration.BinaryBuildCommands)
+				}
+				if len(release.Configuration.Tests) != 1 || release.Configuration.Tests[0].As != "unit" {
+					t.Errorf("expected the tests to be inherited, got %v", release.Configuration.Tests)
+				}
+				older := configs["org-repo-release-4.9.yaml"].Configuration
+				if older.PromotionConfiguration.Name != "4.9" || older.Resources["*"].Requests["cpu"] != "200m" || older.BuildRootImage == nil {
+					t.Errorf("expected the configuration to be inherited through release-4.10, got %v", older)
+				}
+			},
+		},
+		{
+			name: "configuration that extends a missing configuration",
+			files: map[string]string{
+				"org/repo/org-repo-release-4.10.yaml": "extends: org/repo@master\n",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "configurations that extend each other",
+			files: map[string]string{
+				"org/repo/org-repo-master.yaml":       "extends: org/repo@release-4.10\n",
+				"org/repo/org-repo-release-4.10.yaml": "extends: org/repo@master\n",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid reference",
+			files: map[string]string{
+				"org/repo/org-repo-master.yaml":       masterConfig,
+				"org/repo/org-repo-release-4.10.yaml": "extends: master\n",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files := map[string]fstest.MapFile{}
+			for path, content := range tc.files {
+				files[path] = fstest.MapFile{Data: []byte(content), Mode: 0644}
+			}
+			dir, err := testhelper.TmpDir(t, files)
+			if err != nil {
+				t.Fatalf("failed to create configurations: %v", err)
+			}
+			configs, err := LoadDataByFilename(dir)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if tc.verify != nil {
+				tc.verify(t, configs)
+			}
+		})
+	}
+}
+
+func TestCommitToWritesOverrides(t *testing.T) {
+	dir, err := testhelper.TmpDir(t, map[string]fstest.MapFile{
+		"org/repo/org-repo-master.yaml": {Data: []byte(masterConfig), Mode: 0644},
+		"org/repo/org-repo-release-4.10.yaml": {Data: []byte(`extends: org/repo@master
+promotion:
+  name: "4.10"
+`), Mode: 0644},
+	})
+	if err != nil {
+		t.Fatalf("failed to create configurations: %v", err)
+	}
+	configs, err := LoadDataByFilename(dir)
+	if err != nil {
+		t.Fatalf("failed to load configurations: %v", err)
+	}
+	release := configs["org-repo-release-4.10.yaml"]
+	release.Configuration.Tests = append(release.Configuration.Tests, cioperatorapi.TestStepConfiguration{As: "lint", Commands: "make lint", ContainerTestConfiguration: &cioperatorapi.ContainerTestConfiguration{From: "src"}})
+	if err := release.CommitTo(dir); err != nil {
+		t.Fatalf("failed to commit configuration: %v", err)
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(dir, "org/repo/org-repo-release-4.10.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read configuration: %v", err)
+	}
+	expected := `extends: org/repo@master
+promotion:
+  name: "4.10"
+tests:
+- as: unit
+  commands: make test
+  container:
+    from: src
+- as: lint
+  commands: make lint
+  container:
+    from: src
+zz_generated_metadata:
+  branch: release-4.10
+`
+	if diff := cmp.Diff(expected, string(raw)); diff != "" {
+		t.Errorf("committed configuration differs from expected: %s", diff)
+	}
+
+	reloaded, err := LoadDataByFilename(dir)
+	if err != nil {
+		t.Fatalf("failed to reload configurations: %v", err)
+	}
+	if diff := cmp.Diff(release.Configuration, reloaded["org-repo-release-4.10.yaml"].Configuration); diff != "" {
+		t.Errorf("reloaded configuration differs from the committed one: %s", diff)
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir, err := testhelper.TmpDir(t, map[string]fstest.MapFile{
+		"org/repo/org-repo-master.yaml": {Data: []byte(masterConfig), Mode: 0644},
+		"org/repo/org-repo-release-4.10.yaml": {Data: []byte(`extends: org/repo@master
+promotion:
+  name: "4.10"
+`), Mode: 0644},
+	})
+	if err != nil {
+		t.Fatalf("failed to create configurations: %v", err)
+	}
+	raw, err := ReadConfigFile(filepath.Join(dir, "org/repo/org-repo-release-4.10.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read configuration: %v", err)
+	}
+	var configuration cioperatorapi.ReleaseBuildConfiguration
+	if err := yaml.UnmarshalStrict(raw, &configuration); err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	if diff := cmp.Diff(cioperatorapi.Metadata{Org: "org", Repo: "repo", Branch: "release-4.10"}, configuration.Metadata); diff != "" {
+		t.Errorf("metadata differs from expected: %s", diff)
+	}
+	if configuration.PromotionConfiguration == nil || configuration.PromotionConfiguration.Name != "4.10" || configuration.PromotionConfiguration.Namespace != "ocp" {
+		t.Errorf("expected the promotion to be merged into the inherited one, got %#v", configuration.PromotionConfiguration)
+	}
+	if len(configuration.Tests) != 1 || configuration.Tests[0].As != "unit" {
+		t.Errorf("expected the tests to be inherited, got %#v", configuration.Tests)
+	}
+
+	outside := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(outside, []byte("extends: org/repo@master\n"), 0644); err != nil {
+		t.Fatalf("failed to write configuration: %v", err)
+	}
+	if _, err := ReadConfigFile(outside); err == nil {
+		t.Error("expected an error for a configuration extending one that cannot be found")
+	}
+}
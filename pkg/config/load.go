@@ -1,23 +1,26 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/sirupsen/logrus"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/jobconfig"
 	"github.com/openshift/ci-tools/pkg/util"
-	"github.com/openshift/ci-tools/pkg/util/gzip"
 	"github.com/openshift/ci-tools/pkg/validation"
 )
 
@@ -38,6 +41,9 @@ type Prowgen struct {
 	Rehearsals Rehearsals `json:"rehearsals,omitempty"`
 	// Set which architecture should the images be promoted from
 	AdditionalArchitectures []api.Architecture `json:"additional_architectures"`
+	// Overlays declare customizations of the generated jobs, applied in order
+	// after the jobs are generated
+	Overlays []JobOverlay `json:"overlays,omitempty"`
 }
 
 func (p *Prowgen) Validate() error {
@@ -53,6 +59,72 @@ func (p *Prowgen) Validate() error {
 			strings.Join(invalidArchs, ", "), strings.Join(api.GetAvailableArchitectures(), ", "))
 		errs = append(errs, e)
 	}
+	for i, overlay := range p.Overlays {
+		if err := overlay.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("overlays[%d]: %w", i, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// JobOverlay customizes the generated jobs it matches. A job matches when it
+// runs one of the listed tests and is of one of the listed job types, and is
+// generated for one of the listed branches. Empty lists match anything.
+type JobOverlay struct {
+	// Tests are the names of the tests from the ci-operator configuration whose
+	// jobs are customized, like `images` or `e2e-aws`
+	Tests []string `json:"tests,omitempty"`
+	// JobTypes are the types of jobs that are customized
+	JobTypes []prowv1.ProwJobType `json:"job_types,omitempty"`
+	// Branches limit the overlay to the ci-operator configurations of these
+	// branches, so that tests that only exist on some branches can be named
+	Branches []string `json:"branches,omitempty"`
+
+	// Labels are added to the jobs, replacing generated labels with the same key
+	Labels map[string]string `json:"labels,omitempty"`
+	// MaxConcurrency limits how many instances of the jobs can run at once
+	MaxConcurrency *int `json:"max_concurrency,omitempty"`
+	// ReporterConfig configures how the results of the jobs are reported
+	ReporterConfig *prowv1.ReporterConfig `json:"reporter_config,omitempty"`
+	// AlwaysRun overrides whether presubmits and postsubmits are triggered for
+	// every change. Setting it to true removes any run_if_changed or
+	// skip_if_only_changed condition.
+	AlwaysRun *bool `json:"always_run,omitempty"`
+	// Timeout is how long the jobs may run before they are aborted
+	Timeout *prowv1.Duration `json:"timeout,omitempty"`
+	// GracePeriod is how long the jobs have to finish after they are aborted
+	GracePeriod *prowv1.Duration `json:"grace_period,omitempty"`
+}
+
+func (o *JobOverlay) validate() error {
+	var errs []error
+	onlyPeriodics := len(o.JobTypes) > 0
+	for _, jobType := range o.JobTypes {
+		switch jobType {
+		case prowv1.PresubmitJob, prowv1.PostsubmitJob:
+			onlyPeriodics = false
+		case prowv1.PeriodicJob:
+		default:
+			errs = append(errs, fmt.Errorf("job type %q is not valid, available ones are: %s, %s, %s", jobType, prowv1.PresubmitJob, prowv1.PostsubmitJob, prowv1.PeriodicJob))
+		}
+	}
+	if o.AlwaysRun != nil && onlyPeriodics {
+		errs = append(errs, fmt.Errorf("always_run cannot be set for periodics"))
+	}
+	if o.MaxConcurrency != nil && *o.MaxConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("max_concurrency must not be negative"))
+	}
+	for _, field := range []struct {
+		name     string
+		duration *prowv1.Duration
+	}{{name: "timeout", duration: o.Timeout}, {name: "grace_period", duration: o.GracePeriod}} {
+		if field.duration != nil && field.duration.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", field.name))
+		}
+	}
+	if len(o.Labels) == 0 && o.MaxConcurrency == nil && o.ReporterConfig == nil && o.AlwaysRun == nil && o.Timeout == nil && o.GracePeriod == nil {
+		errs = append(errs, fmt.Errorf("overlay does not customize anything"))
+	}
 	return utilerrors.NewAggregate(errs)
 }
 
@@ -65,16 +137,21 @@ type Rehearsals struct {
 	DisabledRehearsals []string `json:"disabled_rehearsals,omitempty"`
 }
 
-func readCiOperatorConfig(configFilePath string, info Info) (*cioperatorapi.ReleaseBuildConfiguration, error) {
-	data, err := gzip.ReadFileMaybeGZIP(configFilePath)
+func readCiOperatorConfig(configFilePath string, info *Info) (*cioperatorapi.ReleaseBuildConfiguration, error) {
+	data, extends, err := readFlattenedConfig(configFilePath, *info)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read ci-operator config (%w)", err)
+		return nil, err
 	}
 
 	var configSpec cioperatorapi.ReleaseBuildConfiguration
-	if err := yaml.Unmarshal(data, &configSpec); err != nil {
+	if err := json.Unmarshal(data, &configSpec); err != nil {
 		return nil, fmt.Errorf("failed to load ci-operator config (%w)", err)
 	}
+	if extends != nil {
+		// the metadata must not be inherited
+		configSpec.Metadata = info.Metadata
+		info.Extends = extends
+	}
 
 	if err := validation.IsValidConfiguration(&configSpec, info.Org, info.Repo); err != nil {
 		return nil, fmt.Errorf("invalid ci-operator config: %w", err)
@@ -93,6 +170,8 @@ type Info struct {
 	OrgPath string
 	// RepoPath is the full path to the directory containing config for the repo
 	RepoPath string
+	// Extends is the configuration this one inherits from, if any
+	Extends *cioperatorapi.Metadata
 }
 
 // We use the directory/file naming convention to encode useful information
@@ -174,7 +253,7 @@ func OperateOnCIOperatorConfig(path string, callback func(*cioperatorapi.Release
 		logrus.WithField("source-file", path).WithError(err).Error("Failed to resolve info from CI Operator configuration path")
 		return err
 	}
-	jobConfig, err := readCiOperatorConfig(path, *info)
+	jobConfig, err := readCiOperatorConfig(path, info)
 	if err != nil {
 		logrus.WithField("source-file", path).WithError(err).Error("Failed to load CI Operator configuration")
 		return err
@@ -231,7 +310,7 @@ func OperateOnCIOperatorConfigSubdir(configDir, subDir string, callback func(*ci
 				errCh <- err
 				continue
 			}
-			config, err := readCiOperatorConfig(path, *info)
+			config, err := readCiOperatorConfig(path, info)
 			if err != nil {
 				logrus.WithField("source-file", path).WithError(err).Error("Failed to load CI Operator configuration")
 				errCh <- err
@@ -271,8 +350,16 @@ func (i *DataWithInfo) Logger() *logrus.Entry {
 	return LoggerForInfo(i.Info)
 }
 
+// CommitTo writes the configuration to its file in the directory. Configurations
+// that extend another one are written as the fields that differ from it.
 func (i *DataWithInfo) CommitTo(dir string) error {
-	raw, err := yaml.Marshal(i.Configuration)
+	var raw []byte
+	var err error
+	if i.Info.Extends != nil {
+		raw, err = overridesFor(i.Configuration, dir, *i.Info.Extends)
+	} else {
+		raw, err = yaml.Marshal(i.Configuration)
+	}
 	if err != nil {
 		i.Logger().WithError(err).Error("failed to marshal output CI Operator configuration")
 		return err
@@ -339,6 +426,41 @@ func (all ByOrgRepo) add(c *cioperatorapi.ReleaseBuildConfiguration, i *Info) er
 	return nil
 }
 
+// GetMatchingConfig returns the configuration that matches the metadata,
+// allowing for regex matching on branch names.
+func (all ByOrgRepo) GetMatchingConfig(metadata cioperatorapi.Metadata) (cioperatorapi.ReleaseBuildConfiguration, error) {
+	orgConfigs, exist := all[metadata.Org]
+	if !exist {
+		return cioperatorapi.ReleaseBuildConfiguration{}, fmt.Errorf("could not find any config for org %s", metadata.Org)
+	}
+	repoConfigs, exist := orgConfigs[metadata.Repo]
+	if !exist {
+		return cioperatorapi.ReleaseBuildConfiguration{}, fmt.Errorf("could not find any config for repo %s/%s", metadata.Org, metadata.Repo)
+	}
+	var matchingConfigs []cioperatorapi.ReleaseBuildConfiguration
+	for _, config := range repoConfigs {
+		for _, f := range []func(string) string{jobconfig.ExactlyBranch, jobconfig.FeatureBranch} {
+			r, err := regexp.Compile(f(config.Metadata.Branch))
+			if err != nil {
+				return cioperatorapi.ReleaseBuildConfiguration{}, fmt.Errorf("could not compile regex for %s/%s@%s: %w", metadata.Org, metadata.Repo, config.Metadata.Branch, err)
+			}
+			if r.MatchString(metadata.Branch) && config.Metadata.Variant == metadata.Variant {
+				matchingConfigs = append(matchingConfigs, config)
+				break
+			}
+		}
+
+	}
+	switch len(matchingConfigs) {
+	case 0:
+		return cioperatorapi.ReleaseBuildConfiguration{}, fmt.Errorf("could not find any config for branch %s on repo %s/%s", metadata.Branch, metadata.Org, metadata.Repo)
+	case 1:
+		return matchingConfigs[0], nil
+	default:
+		return cioperatorapi.ReleaseBuildConfiguration{}, fmt.Errorf("found more than one matching config for branch %s on repo %s/%s", metadata.Branch, metadata.Org, metadata.Repo)
+	}
+}
+
 func LoadByOrgRepo(path string) (ByOrgRepo, error) {
 	config := ByOrgRepo{}
 	if err := OperateOnCIOperatorConfigDir(path, config.add); err != nil {
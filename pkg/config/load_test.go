@@ -3,8 +3,10 @@ package config
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/diff"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
 )
@@ -92,3 +94,51 @@ func TestExtractRepoElementsFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestProwgenValidateOverlays(t *testing.T) {
+	maxConcurrency := 1
+	negative := -1
+	alwaysRun := true
+	var testCases = []struct {
+		name        string
+		overlay     JobOverlay
+		expectedErr string
+	}{
+		{
+			name:    "valid overlay",
+			overlay: JobOverlay{Tests: []string{"e2e"}, JobTypes: []prowv1.ProwJobType{prowv1.PresubmitJob}, AlwaysRun: &alwaysRun, MaxConcurrency: &maxConcurrency},
+		},
+		{
+			name:        "unknown job type",
+			overlay:     JobOverlay{JobTypes: []prowv1.ProwJobType{"batch"}, MaxConcurrency: &maxConcurrency},
+			expectedErr: `overlays[0]: job type "batch" is not valid, available ones are: presubmit, postsubmit, periodic`,
+		},
+		{
+			name:        "always_run for periodics",
+			overlay:     JobOverlay{JobTypes: []prowv1.ProwJobType{prowv1.PeriodicJob}, AlwaysRun: &alwaysRun},
+			expectedErr: "overlays[0]: always_run cannot be set for periodics",
+		},
+		{
+			name:        "negative max_concurrency and timeout",
+			overlay:     JobOverlay{MaxConcurrency: &negative, Timeout: &prowv1.Duration{Duration: -time.Hour}},
+			expectedErr: "overlays[0]: [max_concurrency must not be negative, timeout must be positive]",
+		},
+		{
+			name:        "nothing customized",
+			overlay:     JobOverlay{Tests: []string{"e2e"}},
+			expectedErr: "overlays[0]: overlay does not customize anything",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&Prowgen{Overlays: []JobOverlay{tc.overlay}}).Validate()
+			var actual string
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.expectedErr {
+				t.Errorf("expected error %q, got %q", tc.expectedErr, actual)
+			}
+		})
+	}
+}
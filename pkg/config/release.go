@@ -146,28 +146,56 @@ func GetAllConfigs(releaseRepoPath string, logger *logrus.Entry) *ReleaseRepoCon
 // manipulations are propagated in the error return value. Errors occurred during the actual config loading are not
 // propagated, but the returned struct field will have a nil value in the appropriate field. The error is only logged.
 func GetAllConfigsFromSHA(releaseRepoPath, sha string, logger *logrus.Entry) (*ReleaseRepoConfig, error) {
+	var config *ReleaseRepoConfig
+	err := atRevision(releaseRepoPath, sha, func() error {
+		config = GetAllConfigs(releaseRepoPath, logger)
+		return nil
+	})
+	return config, err
+}
+
+// GetRegistryFromSHA loads the step registry from the given revision of the release repo
+func GetRegistryFromSHA(releaseRepoPath, sha string) (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, registry.ObserverByName, error) {
+	var references registry.ReferenceByName
+	var chains registry.ChainByName
+	var workflows registry.WorkflowByName
+	var observers registry.ObserverByName
+	err := atRevision(releaseRepoPath, sha, func() error {
+		var err error
+		references, chains, workflows, _, _, observers, _, err = load.Registry(filepath.Join(releaseRepoPath, RegistryPath), load.RegistryFlag(0))
+		if err != nil {
+			return fmt.Errorf("could not load step registry: %w", err)
+		}
+		return nil
+	})
+	return references, chains, workflows, observers, err
+}
+
+// atRevision checks out the given revision of the repo, runs the function and
+// checks out the previously checked out revision again
+func atRevision(releaseRepoPath, sha string, f func() error) error {
 	currentSHA, err := revParse(releaseRepoPath, "HEAD")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get SHA of current HEAD: %w", err)
+		return fmt.Errorf("failed to get SHA of current HEAD: %w", err)
 	}
 	restoreRev, err := revParse(releaseRepoPath, "--abbrev-ref", "HEAD")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current branch: %w", err)
+		return fmt.Errorf("failed to get current branch: %w", err)
 	}
 	if restoreRev == "HEAD" {
 		restoreRev = currentSHA
 	}
 	if err := gitCheckout(releaseRepoPath, sha); err != nil {
-		return nil, fmt.Errorf("could not checkout worktree: %w", err)
+		return fmt.Errorf("could not checkout worktree: %w", err)
 	}
 
-	config := GetAllConfigs(releaseRepoPath, logger)
+	fErr := f()
 
 	if err := gitCheckout(releaseRepoPath, restoreRev); err != nil {
-		return config, fmt.Errorf("failed to check out tested revision back: %w", err)
+		return fmt.Errorf("failed to check out tested revision back: %w", err)
 	}
 
-	return config, nil
+	return fErr
 }
 
 func GetChangedTemplates(path, baseRev string) ([]string, error) {
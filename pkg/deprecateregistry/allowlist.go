@@ -0,0 +1,176 @@
+package deprecateregistry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/util/gzip"
+)
+
+const ownerColTotal = "total"
+
+type deprecatedComponent struct {
+	// unexported fields so they are never serialized and they are empty on
+	// read. We record the usages we see when we process configs in `current`
+	// so that we can recognize nonexistent usages later and remove them, and
+	// the ones that were not in the allowlist before in `newlyAdded`
+	current    sets.String
+	newlyAdded sets.String
+
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Replacement string `json:"replacement,omitempty"`
+	Deadline    string `json:"deadline,omitempty"`
+	// Usages maps owners (org/repo) to the tests that use the component
+	Usages map[string][]string `json:"usages,omitempty"`
+}
+
+func (d *deprecatedComponent) has(owner, usage string) bool {
+	for _, item := range d.Usages[owner] {
+		if item == usage {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *deprecatedComponent) insert(owner, usage string) {
+	if d.current == nil {
+		d.current = sets.NewString()
+	}
+	d.current.Insert(componentUsage(owner, usage))
+	if d.has(owner, usage) {
+		return
+	}
+	if d.newlyAdded == nil {
+		d.newlyAdded = sets.NewString()
+	}
+	d.newlyAdded.Insert(usage)
+	if d.Usages == nil {
+		d.Usages = map[string][]string{}
+	}
+	d.Usages[owner] = append(d.Usages[owner], usage)
+	sort.Strings(d.Usages[owner])
+}
+
+// prune removes all usages that were not inserted into the allowlist by this
+// execution
+func (d *deprecatedComponent) prune() {
+	for owner, usages := range d.Usages {
+		var kept []string
+		for _, usage := range usages {
+			if d.current.Has(componentUsage(owner, usage)) {
+				kept = append(kept, usage)
+			}
+		}
+		if len(kept) == 0 {
+			delete(d.Usages, owner)
+			continue
+		}
+		d.Usages[owner] = kept
+	}
+	if len(d.Usages) == 0 {
+		d.Usages = nil
+	}
+}
+
+func componentUsage(owner, usage string) string {
+	return owner + " " + usage
+}
+
+type statsLine struct {
+	componentType string
+	component     string
+	owner         string
+	usages        int
+	replacement   string
+	deadline      string
+}
+
+func (d *deprecatedComponent) Stats() (total statsLine, owners []statsLine) {
+	total = statsLine{
+		componentType: d.Type,
+		component:     d.Name,
+		owner:         ownerColTotal,
+		replacement:   d.Replacement,
+		deadline:      d.Deadline,
+	}
+	for owner, usages := range d.Usages {
+		line := total
+		line.owner = owner
+		line.usages = len(usages)
+		owners = append(owners, line)
+		total.usages += len(usages)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i].owner < owners[j].owner
+	})
+	return total, owners
+}
+
+type allowlist struct {
+	// Components maps type/name of deprecated components to their usages
+	Components map[string]*deprecatedComponent `json:"components,omitempty"`
+}
+
+func componentKey(componentType, name string) string {
+	return fmt.Sprintf("%s/%s", componentType, name)
+}
+
+func (a *allowlist) get(componentType, name string) *deprecatedComponent {
+	if a.Components == nil {
+		a.Components = map[string]*deprecatedComponent{}
+	}
+	key := componentKey(componentType, name)
+	if _, ok := a.Components[key]; !ok {
+		a.Components[key] = &deprecatedComponent{Type: componentType, Name: name}
+	}
+	return a.Components[key]
+}
+
+// Prune removes usages that no longer exist and components that are no longer
+// deprecated
+func (a *allowlist) Prune(deprecated sets.String) {
+	for key, component := range a.Components {
+		if !deprecated.Has(key) {
+			delete(a.Components, key)
+			continue
+		}
+		component.prune()
+		if len(component.Usages) == 0 {
+			delete(a.Components, key)
+		}
+	}
+}
+
+func loadAllowlist(allowlistPath string) (*allowlist, error) {
+	var allowlist allowlist
+
+	raw, err := gzip.ReadFileMaybeGZIP(allowlistPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		return &allowlist, yaml.Unmarshal(raw, &allowlist)
+	}
+
+	logrus.Warn("registry deprecation allowlist does not exist, will populate a new one")
+	return &allowlist, nil
+}
+
+func (a allowlist) Save(path string) error {
+	raw, err := yaml.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, raw, 0644)
+}
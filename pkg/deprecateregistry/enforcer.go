@@ -0,0 +1,199 @@
+package deprecateregistry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// Enforcer manages all necessary data to decide if the state of
+// openshift/release is valid, which means that no new usages of deprecated
+// step registry components were added to it
+type Enforcer struct {
+	chains       registry.ChainByName
+	workflows    registry.WorkflowByName
+	deprecations registry.Deprecations
+	allowlist    *allowlist
+}
+
+// NewEnforcer initializes a new enforcer instance. The enforcer will be
+// initialized with an allowlist from the given location. If the allowlist
+// does not exist, the enforcer will have an empty allowlist.
+func NewEnforcer(allowlistPath string) (*Enforcer, error) {
+	allowlist, err := loadAllowlist(allowlistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry deprecation allowlist from %q: %w", allowlistPath, err)
+	}
+	return &Enforcer{allowlist: allowlist}, nil
+}
+
+// LoadRegistry sets the registry components used to find the deprecated
+// components used by tests
+func (e *Enforcer) LoadRegistry(chains registry.ChainByName, workflows registry.WorkflowByName, deprecations registry.Deprecations) {
+	e.chains = chains
+	e.workflows = workflows
+	e.deprecations = deprecations
+}
+
+// ProcessConfig records all usages of deprecated components by the tests in
+// the ci-operator configuration. It has the signature expected by
+// config.OperateOnCIOperatorConfigDir.
+func (e *Enforcer) ProcessConfig(configuration *api.ReleaseBuildConfiguration, info *config.Info) error {
+	owner := fmt.Sprintf("%s/%s", info.Org, info.Repo)
+	for _, test := range configuration.Tests {
+		if test.MultiStageTestConfiguration == nil {
+			continue
+		}
+		usage := fmt.Sprintf("%s@%s:%s", owner, info.Branch, info.TestName(test.As))
+		for _, component := range registry.DeprecatedComponents(*test.MultiStageTestConfiguration, e.chains, e.workflows, e.deprecations) {
+			record := e.allowlist.get(component.Type.String(), component.Name)
+			record.Replacement = component.Deprecation.Replacement
+			record.Deadline = component.Deprecation.Deadline
+			record.insert(owner, usage)
+		}
+	}
+	return nil
+}
+
+// SaveAllowlist dumps the allowlist to the given location
+func (e *Enforcer) SaveAllowlist(path string) error {
+	return e.allowlist.Save(path)
+}
+
+// Prune removes from the allowlist all usages that no longer exist and all
+// components that are no longer deprecated
+func (e *Enforcer) Prune() {
+	deprecated := sets.NewString()
+	for t, byName := range map[registry.Type]registry.DeprecationByName{
+		registry.Reference: e.deprecations.References,
+		registry.Chain:     e.deprecations.Chains,
+		registry.Workflow:  e.deprecations.Workflows,
+	} {
+		for name := range byName {
+			deprecated.Insert(componentKey(t.String(), name))
+		}
+	}
+	e.allowlist.Prune(deprecated)
+}
+
+func asLine(stats statsLine) []string {
+	return []string{
+		stats.componentType,
+		stats.component,
+		stats.owner,
+		strconv.Itoa(stats.usages),
+		stats.replacement,
+		stats.deadline,
+	}
+}
+
+func ownerSortKey(owner string) string {
+	// keep the `total` line of each component below the lines of its owners
+	if owner == ownerColTotal {
+		return "\xff"
+	}
+	return owner
+}
+
+// Stats returns a table with the usages of every deprecated component by
+// every owner
+func (e *Enforcer) Stats(hideTotals bool) (header, footer []string, lines [][]string) {
+	header = []string{"Type", "Component", "Owner", "Usages", "Replacement", "Deadline"}
+	var data []statsLine
+	var sumUsages int
+	owners := sets.NewString()
+
+	totals := map[string]int{}
+	for key, component := range e.allowlist.Components {
+		total, byOwner := component.Stats()
+		totals[key] = total.usages
+		if !hideTotals {
+			data = append(data, total)
+		}
+		data = append(data, byOwner...)
+		sumUsages += total.usages
+		for _, line := range byOwner {
+			owners.Insert(line.owner)
+		}
+	}
+
+	sort.Slice(data, func(i, j int) bool {
+		keyI, keyJ := componentKey(data[i].componentType, data[i].component), componentKey(data[j].componentType, data[j].component)
+		switch {
+		// Primary sort by total usages of the component
+		case totals[keyI] != totals[keyJ]:
+			return totals[keyI] < totals[keyJ]
+		// Secondary sort by the component
+		case keyI != keyJ:
+			return keyI < keyJ
+		// Tertiary sort by usages by the owner
+		case data[i].usages != data[j].usages:
+			return data[i].usages < data[j].usages
+		}
+		// Last sort by owner name
+		return ownerSortKey(data[i].owner) < ownerSortKey(data[j].owner)
+	})
+
+	for _, item := range data {
+		lines = append(lines, asLine(item))
+	}
+
+	footer = []string{
+		"Total",
+		fmt.Sprintf("%d components", len(e.allowlist.Components)),
+		fmt.Sprintf("%d owners", owners.Len()),
+		strconv.Itoa(sumUsages),
+		"",
+		"",
+	}
+	return header, footer, lines
+}
+
+type enforcingFunc func() []error
+
+func (e *Enforcer) noNewUsages() []error {
+	var keys []string
+	for key, component := range e.allowlist.Components {
+		if component.newlyAdded.Len() > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		component := e.allowlist.Components[key]
+		lines := []string{fmt.Sprintf("The deprecated %s '%s' must not be used by new tests, but it was added to:", component.Type, component.Name)}
+		for _, usage := range component.newlyAdded.List() {
+			lines = append(lines, fmt.Sprintf("- %s", usage))
+		}
+		if component.Replacement != "" {
+			lines = append(lines, "", fmt.Sprintf("Use the '%s' %s instead.", component.Replacement, component.Type))
+		}
+		errs = append(errs, errors.New(strings.Join(lines, "\n")))
+	}
+	return errs
+}
+
+// Validate returns all violations of the desired state of the repository
+func (e *Enforcer) Validate() []string {
+	checks := []enforcingFunc{
+		e.noNewUsages,
+	}
+	var violations []string
+	for _, check := range checks {
+		for _, err := range check() {
+			violations = append(violations, err.Error())
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,107 @@
+package deprecateregistry
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+func newTestEnforcer(t *testing.T, allowlistPath string) *Enforcer {
+	chain := "old-chain"
+	enforcer, err := NewEnforcer(allowlistPath)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	enforcer.LoadRegistry(
+		registry.ChainByName{chain: {As: chain, Steps: []api.TestStep{{Reference: &[]string{"old-install"}[0]}}}},
+		registry.WorkflowByName{"old-workflow": {Pre: []api.TestStep{{Chain: &chain}}}},
+		registry.Deprecations{
+			References: registry.DeprecationByName{"old-install": {Reason: "unmaintained", Replacement: "install", Deadline: "2022-01-31"}},
+			Workflows:  registry.DeprecationByName{"old-workflow": {Reason: "unmaintained"}},
+		},
+	)
+	return enforcer
+}
+
+func process(t *testing.T, enforcer *Enforcer, org, repo string, tests map[string]*api.MultiStageTestConfiguration) {
+	configuration := &api.ReleaseBuildConfiguration{}
+	for as, test := range tests {
+		configuration.Tests = append(configuration.Tests, api.TestStepConfiguration{As: as, MultiStageTestConfiguration: test})
+	}
+	info := &config.Info{Metadata: api.Metadata{Org: org, Repo: repo, Branch: "master"}}
+	if err := enforcer.ProcessConfig(configuration, info); err != nil {
+		t.Fatalf("failed to process config: %v", err)
+	}
+}
+
+func TestEnforcer(t *testing.T) {
+	workflow, install := "old-workflow", "old-install"
+	usesWorkflow := &api.MultiStageTestConfiguration{Workflow: &workflow}
+	usesStep := &api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: &install}}}
+	allowlistPath := filepath.Join(t.TempDir(), "allowlist.yaml")
+
+	enforcer := newTestEnforcer(t, allowlistPath)
+	process(t, enforcer, "org", "repo", map[string]*api.MultiStageTestConfiguration{"e2e": usesWorkflow, "install": usesStep, "unit": nil})
+	process(t, enforcer, "org", "other", map[string]*api.MultiStageTestConfiguration{"e2e": usesWorkflow})
+
+	violations := enforcer.Validate()
+	if len(violations) != 2 {
+		t.Fatalf("expected a violation for each deprecated component, got %v", violations)
+	}
+	expected := "The deprecated reference 'old-install' must not be used by new tests, but it was added to:\n- org/other@master:e2e\n- org/repo@master:e2e\n- org/repo@master:install\n\nUse the 'install' reference instead."
+	if diff := cmp.Diff(expected, violations[0]); diff != "" {
+		t.Errorf("unexpected violation: %s", diff)
+	}
+
+	header, footer, lines := enforcer.Stats(false)
+	if diff := cmp.Diff([]string{"Type", "Component", "Owner", "Usages", "Replacement", "Deadline"}, header); diff != "" {
+		t.Errorf("unexpected header: %s", diff)
+	}
+	expectedLines := [][]string{
+		{"workflow", "old-workflow", "org/other", "1", "", ""},
+		{"workflow", "old-workflow", "org/repo", "1", "", ""},
+		{"workflow", "old-workflow", "total", "2", "", ""},
+		{"reference", "old-install", "org/other", "1", "install", "2022-01-31"},
+		{"reference", "old-install", "org/repo", "2", "install", "2022-01-31"},
+		{"reference", "old-install", "total", "3", "install", "2022-01-31"},
+	}
+	if diff := cmp.Diff(expectedLines, lines); diff != "" {
+		t.Errorf("unexpected stats: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"Total", "2 components", "2 owners", "5", "", ""}, footer); diff != "" {
+		t.Errorf("unexpected footer: %s", diff)
+	}
+
+	if err := enforcer.SaveAllowlist(allowlistPath); err != nil {
+		t.Fatalf("failed to save allowlist: %v", err)
+	}
+
+	// known usages are allowed, new ones are not, and removed ones are pruned
+	enforcer = newTestEnforcer(t, allowlistPath)
+	process(t, enforcer, "org", "repo", map[string]*api.MultiStageTestConfiguration{"e2e": usesWorkflow, "upgrade": usesStep})
+	process(t, enforcer, "org", "other", map[string]*api.MultiStageTestConfiguration{"e2e": usesWorkflow})
+	violations = enforcer.Validate()
+	if len(violations) != 1 || !strings.Contains(violations[0], "- org/repo@master:upgrade\n") {
+		t.Errorf("expected a violation for the new usage only, got %v", violations)
+	}
+	enforcer.Prune()
+	if diff := cmp.Diff(map[string][]string{
+		"org/other": {"org/other@master:e2e"},
+		"org/repo":  {"org/repo@master:e2e", "org/repo@master:upgrade"},
+	}, enforcer.allowlist.Components["reference/old-install"].Usages); diff != "" {
+		t.Errorf("unexpected usages after pruning: %s", diff)
+	}
+
+	// components that are no longer deprecated are pruned
+	enforcer.LoadRegistry(nil, nil, registry.Deprecations{})
+	enforcer.Prune()
+	if len(enforcer.allowlist.Components) != 0 {
+		t.Errorf("expected all components to be pruned, got %v", enforcer.allowlist.Components)
+	}
+}
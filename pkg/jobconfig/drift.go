@@ -0,0 +1,292 @@
+package jobconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+)
+
+// DriftKind categorizes how a job in the job configuration files differs
+// from the job a generator produces
+type DriftKind string
+
+const (
+	// DriftOrphaned is a generated job for which no job is generated anymore
+	DriftOrphaned DriftKind = "orphaned"
+	// DriftHandModified is a generated job with manual changes to fields that
+	// the generator does not preserve
+	DriftHandModified DriftKind = "hand-modified"
+	// DriftMissing is a job the generator produces that is not in the files
+	DriftMissing DriftKind = "missing"
+	// DriftStaleLabels is a generated job whose labels differ from the generated ones
+	DriftStaleLabels DriftKind = "stale labels"
+)
+
+// DriftKinds lists the kinds of drift in the order they are reported
+var DriftKinds = []DriftKind{DriftOrphaned, DriftHandModified, DriftMissing, DriftStaleLabels}
+
+// Drift is a single difference between the job configuration files and the
+// generated jobs
+type Drift struct {
+	Kind    DriftKind
+	OrgRepo string
+	JobType prowv1.ProwJobType
+	Job     string
+	// Fields holds the names of the fields that differ, for hand-modified jobs
+	// the serialized job fields and for stale labels the label keys
+	Fields []string
+}
+
+// DetectDrift compares the jobs in the job configuration files with the jobs
+// the generator produces, both keyed by org/repo. Jobs are compared the way
+// WriteToDir would merge them, so manual changes that are preserved on merge
+// are not reported. Jobs in the files that were not generated are ignored
+// unless a generated job has the same name.
+func DetectDrift(expected, actual map[string]*prowconfig.JobConfig, generator Generator) ([]Drift, error) {
+	var drifts []Drift
+	for _, orgRepo := range sets.StringKeySet(expected).Union(sets.StringKeySet(actual)).List() {
+		generated, existing := expected[orgRepo], actual[orgRepo]
+		if generated == nil {
+			generated = &prowconfig.JobConfig{}
+		}
+		if existing == nil {
+			existing = &prowconfig.JobConfig{}
+		}
+		sortConfigFields(generated)
+		sortConfigFields(existing)
+		withGeneratorLabel(generated, generator)
+
+		repoDrifts, err := detectRepoDrift(orgRepo, generated, existing, generator)
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, repoDrifts...)
+	}
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].OrgRepo != drifts[j].OrgRepo {
+			return drifts[i].OrgRepo < drifts[j].OrgRepo
+		}
+		return drifts[i].Job < drifts[j].Job
+	})
+	return drifts, nil
+}
+
+// withGeneratorLabel labels the generated jobs the way WriteToDir does
+func withGeneratorLabel(jobConfig *prowconfig.JobConfig, generator Generator) {
+	label := func(job *prowconfig.JobBase) {
+		if job.Labels == nil {
+			job.Labels = map[string]string{}
+		}
+		job.Labels[LabelGenerator] = string(generator)
+	}
+	for repo := range jobConfig.PresubmitsStatic {
+		for i := range jobConfig.PresubmitsStatic[repo] {
+			label(&jobConfig.PresubmitsStatic[repo][i].JobBase)
+		}
+	}
+	for repo := range jobConfig.PostsubmitsStatic {
+		for i := range jobConfig.PostsubmitsStatic[repo] {
+			label(&jobConfig.PostsubmitsStatic[repo][i].JobBase)
+		}
+	}
+	for i := range jobConfig.Periodics {
+		label(&jobConfig.Periodics[i].JobBase)
+	}
+}
+
+func detectRepoDrift(orgRepo string, generated, existing *prowconfig.JobConfig, generator Generator) ([]Drift, error) {
+	var drifts []Drift
+	compare := func(jobType prowv1.ProwJobType, name string, generated, existing, merged interface{}, existingBase *prowconfig.JobBase) error {
+		switch {
+		case existing == nil:
+			drifts = append(drifts, Drift{Kind: DriftMissing, OrgRepo: orgRepo, JobType: jobType, Job: name})
+			return nil
+		case generated == nil:
+			isGenerated, err := IsGenerated(*existingBase, generator)
+			if err != nil {
+				return err
+			}
+			if isGenerated {
+				drifts = append(drifts, Drift{Kind: DriftOrphaned, OrgRepo: orgRepo, JobType: jobType, Job: name})
+			}
+			return nil
+		}
+		fields, err := differingFields(existing, merged)
+		if err != nil {
+			return fmt.Errorf("failed to compare job %s: %w", name, err)
+		}
+		if fields.Has("labels") {
+			fields.Delete("labels")
+			var mergedLabels map[string]string
+			switch job := merged.(type) {
+			case prowconfig.Presubmit:
+				mergedLabels = job.Labels
+			case prowconfig.Postsubmit:
+				mergedLabels = job.Labels
+			case prowconfig.Periodic:
+				mergedLabels = job.Labels
+			}
+			drifts = append(drifts, Drift{Kind: DriftStaleLabels, OrgRepo: orgRepo, JobType: jobType, Job: name, Fields: differingLabels(existingBase.Labels, mergedLabels)})
+		}
+		if fields.Len() > 0 {
+			drifts = append(drifts, Drift{Kind: DriftHandModified, OrgRepo: orgRepo, JobType: jobType, Job: name, Fields: fields.List()})
+		}
+		return nil
+	}
+
+	generatedPresubmits, existingPresubmits := map[string]prowconfig.Presubmit{}, map[string]prowconfig.Presubmit{}
+	for _, jobs := range generated.PresubmitsStatic {
+		for _, job := range jobs {
+			generatedPresubmits[job.Name] = job
+		}
+	}
+	for _, jobs := range existing.PresubmitsStatic {
+		for _, job := range jobs {
+			existingPresubmits[job.Name] = job
+		}
+	}
+	for _, name := range sets.StringKeySet(generatedPresubmits).Union(sets.StringKeySet(existingPresubmits)).List() {
+		newJob, isNew := generatedPresubmits[name]
+		oldJob, isOld := existingPresubmits[name]
+		var err error
+		switch {
+		case !isOld:
+			err = compare(prowv1.PresubmitJob, name, newJob, nil, nil, nil)
+		case !isNew:
+			err = compare(prowv1.PresubmitJob, name, nil, oldJob, nil, &oldJob.JobBase)
+		default:
+			err = compare(prowv1.PresubmitJob, name, newJob, oldJob, mergePresubmits(&oldJob, &newJob), &oldJob.JobBase)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	generatedPostsubmits, existingPostsubmits := map[string]prowconfig.Postsubmit{}, map[string]prowconfig.Postsubmit{}
+	for _, jobs := range generated.PostsubmitsStatic {
+		for _, job := range jobs {
+			generatedPostsubmits[job.Name] = job
+		}
+	}
+	for _, jobs := range existing.PostsubmitsStatic {
+		for _, job := range jobs {
+			existingPostsubmits[job.Name] = job
+		}
+	}
+	for _, name := range sets.StringKeySet(generatedPostsubmits).Union(sets.StringKeySet(existingPostsubmits)).List() {
+		newJob, isNew := generatedPostsubmits[name]
+		oldJob, isOld := existingPostsubmits[name]
+		var err error
+		switch {
+		case !isOld:
+			err = compare(prowv1.PostsubmitJob, name, newJob, nil, nil, nil)
+		case !isNew:
+			err = compare(prowv1.PostsubmitJob, name, nil, oldJob, nil, &oldJob.JobBase)
+		default:
+			err = compare(prowv1.PostsubmitJob, name, newJob, oldJob, mergePostsubmits(&oldJob, &newJob), &oldJob.JobBase)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	generatedPeriodics, existingPeriodics := map[string]prowconfig.Periodic{}, map[string]prowconfig.Periodic{}
+	for _, job := range generated.Periodics {
+		generatedPeriodics[job.Name] = job
+	}
+	for _, job := range existing.Periodics {
+		existingPeriodics[job.Name] = job
+	}
+	for _, name := range sets.StringKeySet(generatedPeriodics).Union(sets.StringKeySet(existingPeriodics)).List() {
+		newJob, isNew := generatedPeriodics[name]
+		oldJob, isOld := existingPeriodics[name]
+		var err error
+		switch {
+		case !isOld:
+			err = compare(prowv1.PeriodicJob, name, newJob, nil, nil, nil)
+		case !isNew:
+			err = compare(prowv1.PeriodicJob, name, nil, oldJob, nil, &oldJob.JobBase)
+		default:
+			err = compare(prowv1.PeriodicJob, name, newJob, oldJob, mergePeriodics(&oldJob, &newJob), &oldJob.JobBase)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return drifts, nil
+}
+
+// differingFields compares the serialized forms of two jobs and returns the
+// names of the top-level fields that differ
+func differingFields(a, b interface{}) (sets.String, error) {
+	toMap := func(job interface{}) (map[string]interface{}, error) {
+		raw, err := json.Marshal(job)
+		if err != nil {
+			return nil, err
+		}
+		fields := map[string]interface{}{}
+		return fields, json.Unmarshal(raw, &fields)
+	}
+	aFields, err := toMap(a)
+	if err != nil {
+		return nil, err
+	}
+	bFields, err := toMap(b)
+	if err != nil {
+		return nil, err
+	}
+	differing := sets.NewString()
+	for _, field := range sets.StringKeySet(aFields).Union(sets.StringKeySet(bFields)).List() {
+		if !reflect.DeepEqual(aFields[field], bFields[field]) {
+			differing.Insert(field)
+		}
+	}
+	return differing, nil
+}
+
+func differingLabels(a, b map[string]string) []string {
+	differing := sets.NewString()
+	for key := range a {
+		if value, ok := b[key]; !ok || value != a[key] {
+			differing.Insert(key)
+		}
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			differing.Insert(key)
+		}
+	}
+	return differing.List()
+}
+
+// DriftReport renders the drift grouped by its kind
+func DriftReport(drifts []Drift) string {
+	if len(drifts) == 0 {
+		return "No drift between the job configuration and the generated jobs.\n"
+	}
+	byKind := map[DriftKind][]Drift{}
+	for _, drift := range drifts {
+		byKind[drift.Kind] = append(byKind[drift.Kind], drift)
+	}
+	var report strings.Builder
+	for _, kind := range DriftKinds {
+		if len(byKind[kind]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&report, "%s (%d):\n", kind, len(byKind[kind]))
+		for _, drift := range byKind[kind] {
+			fmt.Fprintf(&report, "  %s %s %s", drift.OrgRepo, drift.JobType, drift.Job)
+			if len(drift.Fields) > 0 {
+				fmt.Fprintf(&report, ": %s", strings.Join(drift.Fields, ", "))
+			}
+			report.WriteString("\n")
+		}
+	}
+	return report.String()
+}
@@ -0,0 +1,91 @@
+package jobconfig
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	v1 "k8s.io/api/core/v1"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+)
+
+func TestDetectDrift(t *testing.T) {
+	presubmit := func(name string, labels map[string]string, args ...string) prowconfig.Presubmit {
+		return prowconfig.Presubmit{
+			JobBase: prowconfig.JobBase{
+				Name:   name,
+				Labels: labels,
+				Spec:   &v1.PodSpec{Containers: []v1.Container{{Command: []string{"ci-operator"}, Args: args}}},
+			},
+			Brancher: prowconfig.Brancher{Branches: []string{"^master$"}},
+		}
+	}
+	generated := func() map[string]string { return map[string]string{LabelGenerator: "prowgen"} }
+	expected := map[string]*prowconfig.JobConfig{
+		"org/repo": {
+			PresubmitsStatic: map[string][]prowconfig.Presubmit{"org/repo": {
+				presubmit("pull-ci-org-repo-master-unit", map[string]string{}, "--target=unit"),
+				presubmit("pull-ci-org-repo-master-e2e", map[string]string{}, "--target=e2e"),
+				presubmit("pull-ci-org-repo-master-lint", map[string]string{"team": "a"}, "--target=lint"),
+				presubmit("pull-ci-org-repo-master-images", map[string]string{}, "--target=[images]"),
+			}},
+			Periodics: []prowconfig.Periodic{{JobBase: prowconfig.JobBase{Name: "periodic-ci-org-repo-master-nightly"}, Cron: "@daily"}},
+		},
+	}
+	manualChange := presubmit("pull-ci-org-repo-master-unit", generated(), "--target=unit")
+	manualChange.AlwaysRun = true
+	manualChange.Cluster = "build01"
+	staleLabels := presubmit("pull-ci-org-repo-master-lint", map[string]string{LabelGenerator: "prowgen", "team": "b", "old": "true"}, "--target=lint")
+	actual := map[string]*prowconfig.JobConfig{
+		"org/repo": {
+			PresubmitsStatic: map[string][]prowconfig.Presubmit{"org/repo": {
+				manualChange,
+				presubmit("pull-ci-org-repo-master-e2e", generated(), "--target=e2e", "--hand-edited"),
+				staleLabels,
+				presubmit("pull-ci-org-repo-master-removed", generated(), "--target=removed"),
+				presubmit("pull-ci-org-repo-master-manual", map[string]string{}, "--target=manual"),
+			}},
+			Periodics: []prowconfig.Periodic{{JobBase: prowconfig.JobBase{Name: "periodic-ci-org-repo-master-nightly", Labels: generated()}, Cron: "@daily"}},
+		},
+		"org/gone": {
+			PostsubmitsStatic: map[string][]prowconfig.Postsubmit{"org/gone": {{JobBase: prowconfig.JobBase{Name: "branch-ci-org-gone-master-images", Labels: generated()}}}},
+		},
+	}
+
+	drifts, err := DetectDrift(expected, actual, "prowgen")
+	if err != nil {
+		t.Fatalf("failed to detect drift: %v", err)
+	}
+	expectedDrifts := []Drift{
+		{Kind: DriftOrphaned, OrgRepo: "org/gone", JobType: prowv1.PostsubmitJob, Job: "branch-ci-org-gone-master-images"},
+		{Kind: DriftHandModified, OrgRepo: "org/repo", JobType: prowv1.PresubmitJob, Job: "pull-ci-org-repo-master-e2e", Fields: []string{"spec"}},
+		{Kind: DriftMissing, OrgRepo: "org/repo", JobType: prowv1.PresubmitJob, Job: "pull-ci-org-repo-master-images"},
+		{Kind: DriftStaleLabels, OrgRepo: "org/repo", JobType: prowv1.PresubmitJob, Job: "pull-ci-org-repo-master-lint", Fields: []string{"old", "team"}},
+		{Kind: DriftOrphaned, OrgRepo: "org/repo", JobType: prowv1.PresubmitJob, Job: "pull-ci-org-repo-master-removed"},
+	}
+	if diff := cmp.Diff(expectedDrifts, drifts); diff != "" {
+		t.Errorf("drift differs from expected: %s", diff)
+	}
+}
+
+func TestDriftReport(t *testing.T) {
+	if actual, expected := DriftReport(nil), "No drift between the job configuration and the generated jobs.\n"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	report := DriftReport([]Drift{
+		{Kind: DriftMissing, OrgRepo: "org/repo", JobType: prowv1.PresubmitJob, Job: "pull-ci-org-repo-master-images"},
+		{Kind: DriftOrphaned, OrgRepo: "org/gone", JobType: prowv1.PostsubmitJob, Job: "branch-ci-org-gone-master-images"},
+		{Kind: DriftHandModified, OrgRepo: "org/repo", JobType: prowv1.PresubmitJob, Job: "pull-ci-org-repo-master-e2e", Fields: []string{"decorate", "spec"}},
+	})
+	expected := `orphaned (1):
+  org/gone postsubmit branch-ci-org-gone-master-images
+hand-modified (1):
+  org/repo presubmit pull-ci-org-repo-master-e2e: decorate, spec
+missing (1):
+  org/repo presubmit pull-ci-org-repo-master-images
+`
+	if diff := cmp.Diff(expected, report); diff != "" {
+		t.Errorf("report differs from expected: %s", diff)
+	}
+}
@@ -41,8 +41,33 @@ const (
 	PostsubmitPrefix             = "branch"
 	PeriodicPrefix               = "periodic"
 	newlyGenerated         label = "newly-generated"
+
+	// OverlaidFieldsAnnotation lists the fields of a generated job that were
+	// set by an overlay from the prowgen configuration, which take precedence
+	// over manual changes to these fields when jobs are merged
+	OverlaidFieldsAnnotation = "ci-operator.openshift.io/prowgen-overlaid-fields"
+	OverlaidAlwaysRun        = "always_run"
+	OverlaidMaxConcurrency   = "max_concurrency"
+	OverlaidReporterConfig   = "reporter_config"
 )
 
+// overlaid determines whether a field of a generated job was set by an overlay
+func overlaid(job prowconfig.JobBase, field string) bool {
+	for _, overlaid := range strings.Split(job.Annotations[OverlaidFieldsAnnotation], ",") {
+		if overlaid == field {
+			return true
+		}
+	}
+	return false
+}
+
+// generated determines whether the generated value of a field takes precedence
+// when merging jobs: either an overlay sets it now, or an overlay set it before
+// and was removed, so the old value is not a manual change to keep
+func generated(old, new prowconfig.JobBase, field string) bool {
+	return overlaid(new, field) || overlaid(old, field)
+}
+
 // SimpleBranchRegexp matches a branch name that does not appear to be a regex (lacks wildcard,
 // group, or other modifiers). For instance, `master` is considered simple, `master-.*` would
 // not.
@@ -471,12 +496,14 @@ func mergePresubmits(old, new *prowconfig.Presubmit) prowconfig.Presubmit {
 	merged.AlwaysRun = old.AlwaysRun
 	merged.RunIfChanged = old.RunIfChanged
 	merged.SkipIfOnlyChanged = old.SkipIfOnlyChanged
-	merged.MaxConcurrency = old.MaxConcurrency
+	if !generated(old.JobBase, new.JobBase, OverlaidMaxConcurrency) {
+		merged.MaxConcurrency = old.MaxConcurrency
+	}
 	merged.SkipReport = old.SkipReport
 	if old.Cluster != "" {
 		merged.Cluster = old.Cluster
 	}
-	if new.RunIfChanged != "" || new.SkipIfOnlyChanged != "" {
+	if new.RunIfChanged != "" || new.SkipIfOnlyChanged != "" || generated(old.JobBase, new.JobBase, OverlaidAlwaysRun) {
 		merged.RunIfChanged = new.RunIfChanged
 		merged.SkipIfOnlyChanged = new.SkipIfOnlyChanged
 		merged.AlwaysRun = new.AlwaysRun
@@ -501,7 +528,7 @@ func mergePresubmits(old, new *prowconfig.Presubmit) prowconfig.Presubmit {
 func mergePostsubmits(old, new *prowconfig.Postsubmit) prowconfig.Postsubmit {
 	merged := *new
 
-	if _, ok := merged.Labels[cioperatorapi.PromotionJobLabelKey]; !ok {
+	if _, ok := merged.Labels[cioperatorapi.PromotionJobLabelKey]; !ok && !generated(old.JobBase, new.JobBase, OverlaidMaxConcurrency) {
 		merged.MaxConcurrency = old.MaxConcurrency
 	}
 	if old.Cluster != "" {
@@ -517,8 +544,12 @@ func mergePostsubmits(old, new *prowconfig.Postsubmit) prowconfig.Postsubmit {
 func mergePeriodics(old, new *prowconfig.Periodic) prowconfig.Periodic {
 	merged := *new
 
-	merged.MaxConcurrency = old.MaxConcurrency
-	merged.ReporterConfig = old.ReporterConfig
+	if !generated(old.JobBase, new.JobBase, OverlaidMaxConcurrency) {
+		merged.MaxConcurrency = old.MaxConcurrency
+	}
+	if !generated(old.JobBase, new.JobBase, OverlaidReporterConfig) {
+		merged.ReporterConfig = old.ReporterConfig
+	}
 	if old.Cluster != "" {
 		merged.Cluster = old.Cluster
 	}
@@ -9,6 +9,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowconfig "k8s.io/test-infra/prow/config"
 )
 
@@ -428,6 +429,43 @@ func TestMergePresubmits(t *testing.T) {
 			new:      &prowconfig.Presubmit{RegexpChangeMatcher: prowconfig.RegexpChangeMatcher{SkipIfOnlyChanged: "new"}},
 			expected: prowconfig.Presubmit{RegexpChangeMatcher: prowconfig.RegexpChangeMatcher{SkipIfOnlyChanged: "new"}},
 		},
+		{
+			name: "fields set by overlays in new take precedence",
+			old: &prowconfig.Presubmit{
+				JobBase:             prowconfig.JobBase{MaxConcurrency: 10},
+				RegexpChangeMatcher: prowconfig.RegexpChangeMatcher{RunIfChanged: "old"},
+			},
+			new: &prowconfig.Presubmit{
+				JobBase:   prowconfig.JobBase{MaxConcurrency: 1, Annotations: map[string]string{OverlaidFieldsAnnotation: "always_run,max_concurrency"}},
+				AlwaysRun: true,
+			},
+			expected: prowconfig.Presubmit{
+				JobBase:   prowconfig.JobBase{MaxConcurrency: 1, Annotations: map[string]string{OverlaidFieldsAnnotation: "always_run,max_concurrency"}},
+				AlwaysRun: true,
+			},
+		},
+		{
+			name: "fields set by removed overlays in old are generated again",
+			old: &prowconfig.Presubmit{
+				JobBase:   prowconfig.JobBase{MaxConcurrency: 1, Annotations: map[string]string{OverlaidFieldsAnnotation: "always_run,max_concurrency"}},
+				AlwaysRun: true,
+			},
+			new: &prowconfig.Presubmit{
+				RegexpChangeMatcher: prowconfig.RegexpChangeMatcher{RunIfChanged: "new"},
+			},
+			expected: prowconfig.Presubmit{
+				RegexpChangeMatcher: prowconfig.RegexpChangeMatcher{RunIfChanged: "new"},
+			},
+		},
+		{
+			name: "always_run set by a removed overlay in old is generated again",
+			old: &prowconfig.Presubmit{
+				JobBase:   prowconfig.JobBase{Annotations: map[string]string{OverlaidFieldsAnnotation: "always_run"}},
+				AlwaysRun: true,
+			},
+			new:      &prowconfig.Presubmit{},
+			expected: prowconfig.Presubmit{},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -587,6 +625,22 @@ func TestMergePostsubmits(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "max_concurrency set by a removed overlay in old is generated again",
+			old: &prowconfig.Postsubmit{
+				JobBase: prowconfig.JobBase{
+					Name:           "branch-ci-super-duper",
+					MaxConcurrency: 3,
+					Annotations:    map[string]string{OverlaidFieldsAnnotation: "max_concurrency"},
+				},
+			},
+			new: &prowconfig.Postsubmit{
+				JobBase: prowconfig.JobBase{Name: "branch-ci-super-duper"},
+			},
+			expected: prowconfig.Postsubmit{
+				JobBase: prowconfig.JobBase{Name: "branch-ci-super-duper"},
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -598,6 +652,55 @@ func TestMergePostsubmits(t *testing.T) {
 	}
 }
 
+func TestMergePeriodics(t *testing.T) {
+	reporterConfig := &prowv1.ReporterConfig{Slack: &prowv1.SlackReporterConfig{Channel: "#alerts"}}
+	var testCases = []struct {
+		name     string
+		old      *prowconfig.Periodic
+		new      *prowconfig.Periodic
+		expected prowconfig.Periodic
+	}{
+		{
+			name:     "manual changes are kept",
+			old:      &prowconfig.Periodic{JobBase: prowconfig.JobBase{MaxConcurrency: 3, ReporterConfig: reporterConfig, Cluster: "somewhere"}},
+			new:      &prowconfig.Periodic{},
+			expected: prowconfig.Periodic{JobBase: prowconfig.JobBase{MaxConcurrency: 3, ReporterConfig: reporterConfig, Cluster: "somewhere"}},
+		},
+		{
+			name: "fields set by overlays in new take precedence",
+			old:  &prowconfig.Periodic{JobBase: prowconfig.JobBase{MaxConcurrency: 3}},
+			new: &prowconfig.Periodic{JobBase: prowconfig.JobBase{
+				MaxConcurrency: 1,
+				ReporterConfig: reporterConfig,
+				Annotations:    map[string]string{OverlaidFieldsAnnotation: "max_concurrency,reporter_config"},
+			}},
+			expected: prowconfig.Periodic{JobBase: prowconfig.JobBase{
+				MaxConcurrency: 1,
+				ReporterConfig: reporterConfig,
+				Annotations:    map[string]string{OverlaidFieldsAnnotation: "max_concurrency,reporter_config"},
+			}},
+		},
+		{
+			name: "fields set by removed overlays in old are generated again",
+			old: &prowconfig.Periodic{JobBase: prowconfig.JobBase{
+				MaxConcurrency: 1,
+				ReporterConfig: reporterConfig,
+				Annotations:    map[string]string{OverlaidFieldsAnnotation: "max_concurrency,reporter_config"},
+			}},
+			new:      &prowconfig.Periodic{},
+			expected: prowconfig.Periodic{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := mergePeriodics(testCase.old, testCase.new)
+			if diff := cmp.Diff(testCase.expected, result, unexportedFields...); diff != "" {
+				t.Errorf("did not get expected merged periodic config: %s", diff)
+			}
+		})
+	}
+}
+
 func TestExtractRepoElementsFromPath(t *testing.T) {
 	var testCases = []struct {
 		name          string
@@ -3,7 +3,6 @@ package agents
 import (
 	"fmt"
 	"reflect"
-	"regexp"
 	"sync"
 	"time"
 
@@ -12,7 +11,6 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/config"
-	"github.com/openshift/ci-tools/pkg/jobconfig"
 )
 
 type IndexDelta struct {
@@ -126,36 +124,7 @@ func NewConfigAgent(configPath string, opts ...ConfigAgentOption) (ConfigAgent,
 func (a *configAgent) GetMatchingConfig(metadata api.Metadata) (api.ReleaseBuildConfiguration, error) {
 	a.lock.RLock()
 	defer a.lock.RUnlock()
-	orgConfigs, exist := a.configs[metadata.Org]
-	if !exist {
-		return api.ReleaseBuildConfiguration{}, fmt.Errorf("could not find any config for org %s", metadata.Org)
-	}
-	repoConfigs, exist := orgConfigs[metadata.Repo]
-	if !exist {
-		return api.ReleaseBuildConfiguration{}, fmt.Errorf("could not find any config for repo %s/%s", metadata.Org, metadata.Repo)
-	}
-	var matchingConfigs []api.ReleaseBuildConfiguration
-	for _, config := range repoConfigs {
-		for _, f := range []func(string) string{jobconfig.ExactlyBranch, jobconfig.FeatureBranch} {
-			r, err := regexp.Compile(f(config.Metadata.Branch))
-			if err != nil {
-				return api.ReleaseBuildConfiguration{}, fmt.Errorf("could not compile regex for %s/%s@%s: %w", metadata.Org, metadata.Repo, config.Metadata.Branch, err)
-			}
-			if r.MatchString(metadata.Branch) && config.Metadata.Variant == metadata.Variant {
-				matchingConfigs = append(matchingConfigs, config)
-				break
-			}
-		}
-
-	}
-	switch len(matchingConfigs) {
-	case 0:
-		return api.ReleaseBuildConfiguration{}, fmt.Errorf("could not find any config for branch %s on repo %s/%s", metadata.Branch, metadata.Org, metadata.Repo)
-	case 1:
-		return matchingConfigs[0], nil
-	default:
-		return api.ReleaseBuildConfiguration{}, fmt.Errorf("found more than one matching config for branch %s on repo %s/%s", metadata.Branch, metadata.Org, metadata.Repo)
-	}
+	return a.configs.GetMatchingConfig(metadata)
 }
 
 func (a *configAgent) GetAll() config.ByOrgRepo {
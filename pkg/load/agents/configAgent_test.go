@@ -5,11 +5,13 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"testing/fstest"
 
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
 func TestGetFromIndex(t *testing.T) {
@@ -565,3 +567,48 @@ func TestBuildIndexDelta(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigAgentResolvesExtends(t *testing.T) {
+	dir, err := testhelper.TmpDir(t, map[string]fstest.MapFile{
+		"org/repo/org-repo-master.yaml": {Data: []byte(`build_root:
+  image_stream_tag:
+    name: release
+    namespace: openshift
+    tag: golang-1.17
+promotion:
+  name: "4.11"
+  namespace: ocp
+resources:
+  '*':
+    requests:
+      cpu: 100m
+tests:
+- as: unit
+  commands: make test
+  container:
+    from: src
+zz_generated_metadata:
+  branch: master
+  org: org
+  repo: repo
+`), Mode: 0644},
+		"org/repo/org-repo-release-4.10.yaml": {Data: []byte(`extends: org/repo@master
+promotion:
+  name: "4.10"
+`), Mode: 0644},
+	})
+	if err != nil {
+		t.Fatalf("failed to create configurations: %v", err)
+	}
+	agent, err := NewConfigAgent(dir)
+	if err != nil {
+		t.Fatalf("failed to create config agent: %v", err)
+	}
+	configuration, err := agent.GetMatchingConfig(api.Metadata{Org: "org", Repo: "repo", Branch: "release-4.10"})
+	if err != nil {
+		t.Fatalf("failed to get configuration: %v", err)
+	}
+	if configuration.Metadata.Branch != "release-4.10" || configuration.PromotionConfiguration.Name != "4.10" || len(configuration.Tests) != 1 {
+		t.Errorf("expected the configuration to be resolved from the one it extends, got %v", configuration)
+	}
+}
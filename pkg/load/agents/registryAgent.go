@@ -21,9 +21,21 @@ type RegistryAgent interface {
 	ResolveConfig(config api.ReleaseBuildConfiguration) (api.ReleaseBuildConfiguration, error)
 	GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata)
 	GetGeneration() int
+	// GetResolverForGeneration returns the resolver for a recent generation of
+	// the registry, as long as it is still kept in the history of the agent
+	GetResolverForGeneration(generation int) (registry.Resolver, error)
+	// GetRegistrySnapshot returns the current generation of the registry together
+	// with all of its components, which are consistent with that generation
+	GetRegistrySnapshot() (int, registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, registry.ObserverByName, registry.Deprecations)
+	// GetDeprecations returns the deprecations of registry components
+	GetDeprecations() registry.Deprecations
 	registry.Resolver
 }
 
+// defaultGenerationHistory is the number of registry generations whose
+// resolvers are kept in memory by default
+const defaultGenerationHistory = 10
+
 type registryAgent struct {
 	lock          *sync.RWMutex
 	resolver      registry.Resolver
@@ -34,8 +46,19 @@ type registryAgent struct {
 	references    registry.ReferenceByName
 	chains        registry.ChainByName
 	workflows     registry.WorkflowByName
+	observers     registry.ObserverByName
+	deprecations  registry.Deprecations
 	documentation map[string]string
 	metadata      api.RegistryMetadata
+
+	// history holds the resolvers of the most recent generations, oldest first
+	history        []generationResolver
+	historyMaxSize int
+}
+
+type generationResolver struct {
+	generation int
+	resolver   registry.Resolver
 }
 
 var registryReloadTimeMetric = prometheus.NewHistogram(
@@ -58,6 +81,9 @@ type RegistryAgentOptions struct {
 	// from the filepath. Defaults to true.
 	FlatRegistry            *bool
 	UniversalSymlinkWatcher *UniversalSymlinkWatcher
+	// GenerationHistory is the number of registry generations whose resolvers
+	// are kept to compare resolved configurations between them. Defaults to 10.
+	GenerationHistory int
 }
 
 type RegistryAgentOption func(*RegistryAgentOptions)
@@ -74,6 +100,12 @@ func WithRegistryFlat(v bool) RegistryAgentOption {
 	}
 }
 
+func WithRegistryGenerationHistory(size int) RegistryAgentOption {
+	return func(o *RegistryAgentOptions) {
+		o.GenerationHistory = size
+	}
+}
+
 // NewRegistryAgent returns a RegistryAgent interface that automatically reloads when
 // the registry is changed on disk.
 func NewRegistryAgent(registryPath string, opts ...RegistryAgentOption) (RegistryAgent, error) {
@@ -87,15 +119,19 @@ func NewRegistryAgent(registryPath string, opts ...RegistryAgentOption) (Registr
 	if opt.FlatRegistry == nil {
 		opt.FlatRegistry = utilpointer.BoolPtr(true)
 	}
+	if opt.GenerationHistory <= 0 {
+		opt.GenerationHistory = defaultGenerationHistory
+	}
 	flags := load.RegistryMetadata | load.RegistryDocumentation
 	if *opt.FlatRegistry {
 		flags |= load.RegistryFlat
 	}
 	a := &registryAgent{
-		registryPath: registryPath,
-		lock:         &sync.RWMutex{},
-		errorMetrics: opt.ErrorMetric,
-		flags:        flags,
+		registryPath:   registryPath,
+		lock:           &sync.RWMutex{},
+		errorMetrics:   opt.ErrorMetric,
+		flags:          flags,
+		historyMaxSize: opt.GenerationHistory,
 	}
 	// Load config once so we fail early if that doesn't work and are ready as soon as we return
 	if err := a.loadRegistry(); err != nil {
@@ -122,17 +158,43 @@ func (a *registryAgent) GetGeneration() int {
 	return a.generation
 }
 
+func (a *registryAgent) GetResolverForGeneration(generation int) (registry.Resolver, error) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	for _, item := range a.history {
+		if item.generation == generation {
+			return item.resolver, nil
+		}
+	}
+	if len(a.history) == 0 {
+		return nil, fmt.Errorf("registry generation %d is not available", generation)
+	}
+	return nil, fmt.Errorf("registry generation %d is not available, only generations %d to %d are kept", generation, a.history[0].generation, a.history[len(a.history)-1].generation)
+}
+
 func (a *registryAgent) GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata) {
 	return a.references, a.chains, a.workflows, a.documentation, a.metadata
 }
 
+func (a *registryAgent) GetRegistrySnapshot() (int, registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, registry.ObserverByName, registry.Deprecations) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.generation, a.references, a.chains, a.workflows, a.observers, a.deprecations
+}
+
+func (a *registryAgent) GetDeprecations() registry.Deprecations {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.deprecations
+}
+
 func (a *registryAgent) loadRegistry() error {
 	logrus.Debug("Reloading registry")
 	duration, err := func() (time.Duration, error) {
 		a.lock.Lock()
 		defer a.lock.Unlock()
 		startTime := time.Now()
-		references, chains, workflows, documentation, metadata, observers, err := load.Registry(a.registryPath, a.flags)
+		references, chains, workflows, documentation, metadata, observers, deprecations, err := load.Registry(a.registryPath, a.flags)
 		if err != nil {
 			recordErrorForMetric(a.errorMetrics, "failed to load ci-operator registry")
 			return time.Duration(0), fmt.Errorf("failed to load ci-operator registry (%w)", err)
@@ -140,10 +202,16 @@ func (a *registryAgent) loadRegistry() error {
 		a.references = references
 		a.chains = chains
 		a.workflows = workflows
+		a.observers = observers
+		a.deprecations = deprecations
 		a.documentation = documentation
 		a.metadata = metadata
-		a.resolver = registry.NewResolver(references, chains, workflows, observers)
+		a.resolver = registry.NewResolver(references, chains, workflows, observers, registry.WithDeprecations(deprecations))
 		a.generation++
+		a.history = append(a.history, generationResolver{generation: a.generation, resolver: a.resolver})
+		if len(a.history) > a.historyMaxSize {
+			a.history = a.history[len(a.history)-a.historyMaxSize:]
+		}
 		return time.Since(startTime), nil
 	}()
 	if err != nil {
@@ -40,12 +40,17 @@ const (
 
 // Registry takes the path to a registry config directory and returns the full set of references, chains,
 // and workflows that the registry's Resolver needs to resolve a user's MultiStageTestConfiguration
-func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata, registry.ObserverByName, error) {
+func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata, registry.ObserverByName, registry.Deprecations, error) {
 	flat := flags&RegistryFlat != 0
 	references := registry.ReferenceByName{}
 	chains := registry.ChainByName{}
 	workflows := registry.WorkflowByName{}
 	observers := registry.ObserverByName{}
+	deprecations := registry.Deprecations{
+		References: registry.DeprecationByName{},
+		Chains:     registry.DeprecationByName{},
+		Workflows:  registry.DeprecationByName{},
+	}
 	var documentation map[string]string
 	var metadata api.RegistryMetadata
 	if flags&RegistryDocumentation != 0 {
@@ -92,7 +97,7 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 			}
 		}
 		if strings.HasSuffix(path, RefSuffix) {
-			name, doc, ref, err := loadReference(raw, dir, prefix, flat)
+			name, doc, deprecation, ref, err := loadReference(raw, dir, prefix, flat)
 			if err != nil {
 				return fmt.Errorf("failed to load registry file %s: %w", path, err)
 			}
@@ -106,6 +111,9 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 			if documentation != nil {
 				documentation[name] = doc
 			}
+			if deprecation != nil {
+				deprecations.References[name] = *deprecation
+			}
 		} else if strings.HasSuffix(path, ChainSuffix) {
 			var chain api.RegistryChainConfig
 			err := yaml.UnmarshalStrict(raw, &chain)
@@ -122,9 +130,13 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 				documentation[chain.Chain.As] = chain.Chain.Documentation
 			}
 			chain.Chain.Documentation = ""
+			if chain.Chain.Deprecation != nil {
+				deprecations.Chains[chain.Chain.As] = *chain.Chain.Deprecation
+			}
+			chain.Chain.Deprecation = nil
 			chains[chain.Chain.As] = chain.Chain
 		} else if strings.HasSuffix(path, WorkflowSuffix) {
-			name, doc, workflow, err := loadWorkflow(raw)
+			name, doc, deprecation, workflow, err := loadWorkflow(raw)
 			if err != nil {
 				return fmt.Errorf("failed to load registry file %s: %w", path, err)
 			}
@@ -138,6 +150,9 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 			if documentation != nil {
 				documentation[name] = doc
 			}
+			if deprecation != nil {
+				deprecations.Workflows[name] = *deprecation
+			}
 		} else if strings.HasSuffix(path, MetadataSuffix) {
 			if metadata == nil {
 				return nil
@@ -185,15 +200,18 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 		return nil
 	})
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, registry.Deprecations{}, err
 	}
 	// create graph to verify that there are no cycles
 	if _, err = registry.NewGraph(references, chains, workflows, observers); err != nil {
-		return nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, registry.Deprecations{}, err
 	}
 	err = registry.Validate(references, chains, workflows, observers)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, registry.Deprecations{}, err
+	}
+	if err := registry.ValidateDeprecations(deprecations, references, chains, workflows); err != nil {
+		return nil, nil, nil, nil, nil, nil, registry.Deprecations{}, err
 	}
 	// validate the integrity of each reference
 	v := validation.NewValidator()
@@ -204,36 +222,36 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 		}
 	}
 	if len(validationErrors) > 0 {
-		return nil, nil, nil, nil, nil, nil, utilerrors.NewAggregate(validationErrors)
+		return nil, nil, nil, nil, nil, nil, registry.Deprecations{}, utilerrors.NewAggregate(validationErrors)
 	}
-	return references, chains, workflows, documentation, metadata, observers, nil
+	return references, chains, workflows, documentation, metadata, observers, deprecations, nil
 }
 
-func loadReference(bytes []byte, baseDir, prefix string, flat bool) (string, string, api.LiteralTestStep, error) {
+func loadReference(bytes []byte, baseDir, prefix string, flat bool) (string, string, *api.RegistryDeprecation, api.LiteralTestStep, error) {
 	step := api.RegistryReferenceConfig{}
 	err := yaml.UnmarshalStrict(bytes, &step)
 	if err != nil {
-		return "", "", api.LiteralTestStep{}, err
+		return "", "", nil, api.LiteralTestStep{}, err
 	}
 	if !flat && step.Reference.Commands != fmt.Sprintf("%s%s%s", prefix, CommandsSuffix, filepath.Ext(step.Reference.Commands)) {
-		return "", "", api.LiteralTestStep{}, fmt.Errorf("reference %s has invalid command file path; command should be set to %s (with an optional extension like .sh)", step.Reference.As, fmt.Sprintf("%s%s", prefix, CommandsSuffix))
+		return "", "", nil, api.LiteralTestStep{}, fmt.Errorf("reference %s has invalid command file path; command should be set to %s (with an optional extension like .sh)", step.Reference.As, fmt.Sprintf("%s%s", prefix, CommandsSuffix))
 	}
 	command, err := gzip.ReadFileMaybeGZIP(filepath.Join(baseDir, step.Reference.Commands))
 	if err != nil {
-		return "", "", api.LiteralTestStep{}, err
+		return "", "", nil, api.LiteralTestStep{}, err
 	}
 	step.Reference.Commands = string(command)
-	return step.Reference.As, step.Reference.Documentation, step.Reference.LiteralTestStep, nil
+	return step.Reference.As, step.Reference.Documentation, step.Reference.Deprecation, step.Reference.LiteralTestStep, nil
 }
 
-func loadWorkflow(bytes []byte) (string, string, api.MultiStageTestConfiguration, error) {
+func loadWorkflow(bytes []byte) (string, string, *api.RegistryDeprecation, api.MultiStageTestConfiguration, error) {
 	workflow := api.RegistryWorkflowConfig{}
 	err := yaml.UnmarshalStrict(bytes, &workflow)
 	if err != nil {
-		return "", "", api.MultiStageTestConfiguration{}, err
+		return "", "", nil, api.MultiStageTestConfiguration{}, err
 	}
 	if workflow.Workflow.Steps.Workflow != nil {
-		return "", "", api.MultiStageTestConfiguration{}, errors.New("workflows cannot contain other workflows")
+		return "", "", nil, api.MultiStageTestConfiguration{}, errors.New("workflows cannot contain other workflows")
 	}
-	return workflow.Workflow.As, workflow.Workflow.Documentation, workflow.Workflow.Steps, nil
+	return workflow.Workflow.As, workflow.Workflow.Documentation, workflow.Workflow.Deprecation, workflow.Workflow.Steps, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 
+	corev1 "k8s.io/api/core/v1"
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/kube"
 
@@ -65,8 +67,16 @@ type CachedQuery struct {
 	// The list of fingerprints is guaranteed to be unique for any set of labels
 	// and will never contain more than fifty items.
 	DataByMetaData map[FullMetadata][]model.Fingerprint `json:"data_by_meta_data"`
+	// DataByDay holds the same data as Data, but split by the UTC day on which it
+	// was recorded, in order to allow for older data to weigh less than recent data
+	// and to be removed once it ages out. Data recorded before we started to split
+	// it by day will only exist in Data.
+	DataByDay map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups `json:"data_by_day,omitempty"`
 }
 
+// DayFormat is the format of the days indexing per-day data.
+const DayFormat = "2006-01-02"
+
 // Record adds the data in the matrix to the cache and records that the given cluster has
 // successfully had this time range queried.
 func (q *CachedQuery) Record(clusterName string, r TimeRange, matrix model.Matrix, logger *logrus.Entry) {
@@ -87,6 +97,13 @@ func (q *CachedQuery) Record(clusterName string, r TimeRange, matrix model.Matri
 		} else {
 			hist = circonusllhist.New(circonusllhist.NoLookup())
 		}
+		if q.DataByDay == nil {
+			q.DataByDay = map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{}
+		}
+		if _, exists := q.DataByDay[fingerprint]; !exists {
+			q.DataByDay[fingerprint] = map[string]*circonusllhist.HistogramWithoutLookups{}
+		}
+		byDay := map[string]*circonusllhist.Histogram{}
 		for _, value := range stream.Values {
 			if math.IsNaN(float64(value.Value)) {
 				continue
@@ -95,8 +112,22 @@ func (q *CachedQuery) Record(clusterName string, r TimeRange, matrix model.Matri
 			if err != nil {
 				logger.WithError(err).Warn("Failed to insert data into histogram. This should never happen.")
 			}
+			day := value.Timestamp.Time().UTC().Format(DayFormat)
+			if _, exists := byDay[day]; !exists {
+				if existing, exists := q.DataByDay[fingerprint][day]; exists {
+					byDay[day] = existing.Histogram()
+				} else {
+					byDay[day] = circonusllhist.New(circonusllhist.NoLookup())
+				}
+			}
+			if err := byDay[day].RecordValue(float64(value.Value)); err != nil {
+				logger.WithError(err).Warn("Failed to insert data into daily histogram. This should never happen.")
+			}
 		}
 		q.Data[fingerprint] = circonusllhist.NewHistogramWithoutLookups(hist)
+		for day, dayHist := range byDay {
+			q.DataByDay[fingerprint][day] = circonusllhist.NewHistogramWithoutLookups(dayHist)
+		}
 		if !seen {
 			q.DataByMetaData[meta] = append(q.DataByMetaData[meta], fingerprint)
 		}
@@ -330,8 +361,162 @@ func (q *CachedQuery) Prune() {
 		}
 		for _, item := range toRemove {
 			delete(q.Data, item)
+			delete(q.DataByDay, item)
+		}
+	}
+}
+
+// AgeOut removes all data recorded on days before the cutoff. Fingerprints for
+// which no data is left are removed entirely, while the others have their overall
+// histogram rebuilt from the days that remain. Data that was not recorded by day
+// is left untouched, as we have no way of knowing how old it is.
+func (q *CachedQuery) AgeOut(cutoff time.Time) {
+	cutoffDay := cutoff.UTC().Format(DayFormat)
+	removed := map[model.Fingerprint]bool{}
+	for fingerprint, byDay := range q.DataByDay {
+		changed := false
+		for day := range byDay {
+			// days sort lexically in chronological order
+			if day < cutoffDay {
+				delete(byDay, day)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if len(byDay) == 0 {
+			delete(q.DataByDay, fingerprint)
+			delete(q.Data, fingerprint)
+			removed[fingerprint] = true
+			continue
+		}
+		overall := circonusllhist.New(circonusllhist.NoLookup())
+		for _, hist := range byDay {
+			overall.Merge(hist.Histogram())
 		}
+		q.Data[fingerprint] = circonusllhist.NewHistogramWithoutLookups(overall)
+	}
+	if len(removed) == 0 {
+		return
+	}
+	for meta, fingerprints := range q.DataByMetaData {
+		var kept []model.Fingerprint
+		for _, fingerprint := range fingerprints {
+			if !removed[fingerprint] {
+				kept = append(kept, fingerprint)
+			}
+		}
+		if len(kept) == 0 {
+			delete(q.DataByMetaData, meta)
+			continue
+		}
+		q.DataByMetaData[meta] = kept
+	}
+}
+
+// Decay describes how the weight of data decreases with its age.
+type Decay struct {
+	// Now is the point in time relative to which the age of data is determined.
+	Now time.Time
+	// HalfLife is the age at which data weighs half as much as data from today.
+	HalfLife time.Duration
+	// LegacyAge is the age assumed for data that was not recorded by day.
+	LegacyAge time.Duration
+}
+
+// decayScale is the factor by which counts in histograms are scaled before
+// being weighed, so that we retain precision when weighing down small counts.
+const decayScale = 1000
+
+// weight determines the weight of data of the given age.
+func (d Decay) weight(age time.Duration) float64 {
+	if d.HalfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(d.HalfLife))
+}
+
+// age determines the age of data recorded on the given day.
+func (d Decay) age(day string) (time.Duration, error) {
+	recorded, err := time.Parse(DayFormat, day)
+	if err != nil {
+		return 0, fmt.Errorf("invalid day %q: %w", day, err)
+	}
+	today, err := time.Parse(DayFormat, d.Now.UTC().Format(DayFormat))
+	if err != nil {
+		return 0, err
 	}
+	return today.Sub(recorded), nil
+}
+
+// WeightedHistogram merges the data for all fingerprints into one histogram, in
+// which each day of data is weighed by its age, so recent data affects quantiles
+// more than old data does.
+func (q *CachedQuery) WeightedHistogram(fingerprints []model.Fingerprint, decay Decay) (*circonusllhist.Histogram, error) {
+	overall := circonusllhist.New()
+	for _, fingerprint := range fingerprints {
+		byDay, recordedByDay := q.DataByDay[fingerprint]
+		if !recordedByDay || len(byDay) == 0 {
+			data, exists := q.Data[fingerprint]
+			if !exists {
+				continue
+			}
+			weighted, err := weigh(data.Histogram(), decay.weight(decay.LegacyAge))
+			if err != nil {
+				return nil, err
+			}
+			overall.Merge(weighted)
+			continue
+		}
+		for day, data := range byDay {
+			age, err := decay.age(day)
+			if err != nil {
+				return nil, err
+			}
+			weighted, err := weigh(data.Histogram(), decay.weight(age))
+			if err != nil {
+				return nil, err
+			}
+			overall.Merge(weighted)
+		}
+	}
+	return overall, nil
+}
+
+// weigh scales the counts in every bin of the histogram by the weight.
+func weigh(hist *circonusllhist.Histogram, weight float64) (*circonusllhist.Histogram, error) {
+	var bins []string
+	for _, bin := range hist.DecStrings() {
+		idx := strings.LastIndex(bin, "=")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid histogram bin %q", bin)
+		}
+		count, err := strconv.ParseUint(bin[idx+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count in histogram bin %q: %w", bin, err)
+		}
+		weighted := uint64(math.Round(float64(count) * decayScale * weight))
+		if weighted == 0 {
+			continue
+		}
+		bins = append(bins, fmt.Sprintf("%s=%d", bin[:idx], weighted))
+	}
+	return circonusllhist.NewFromStrings(bins, false)
+}
+
+// HistogramsByDay merges the data for all fingerprints recorded on the same day.
+func (q *CachedQuery) HistogramsByDay(fingerprints []model.Fingerprint) map[string]*circonusllhist.Histogram {
+	byDay := map[string]*circonusllhist.Histogram{}
+	for _, fingerprint := range fingerprints {
+		for day, data := range q.DataByDay[fingerprint] {
+			if _, exists := byDay[day]; !exists {
+				byDay[day] = circonusllhist.New()
+			}
+			byDay[day].Merge(data.Histogram())
+		}
+	}
+	return byDay
 }
 
 // TimeRange describes a range of time, inclusive.
@@ -357,6 +542,32 @@ type FullMetadata struct {
 	Container string `json:"container"`
 }
 
+// Recommendation holds the resource requests we recommend for a workload.
+type Recommendation struct {
+	Metadata FullMetadata        `json:"metadata"`
+	Requests corev1.ResourceList `json:"requests"`
+	// Configured holds the requests and limits configured for the workload in
+	// its ci-operator configuration, when they are known.
+	Configured *api.ResourceRequirements `json:"configured,omitempty"`
+}
+
+// ConfiguredName determines the name under which the resources of a workload
+// are configured in the ci-operator configuration. Only builds and container
+// tests use the configured resources, as multi-stage steps configure their
+// requests in the step itself.
+func ConfiguredName(meta FullMetadata) (string, bool) {
+	switch {
+	case meta.Step != "":
+		return "", false
+	case meta.Target == "" && strings.HasSuffix(meta.Pod, "-build") && meta.Container == "docker":
+		return strings.TrimSuffix(meta.Pod, "-build"), true
+	case meta.Target != "" && meta.Pod == meta.Target && meta.Container == "test":
+		return meta.Target, true
+	default:
+		return "", false
+	}
+}
+
 func (m FullMetadata) LogFields() logrus.Fields {
 	fields := api.LogFieldsFor(m.Metadata)
 	fields["target"] = m.Target
@@ -1,6 +1,7 @@
 package pod_scaler
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -223,8 +224,8 @@ func TestMetadataFromMetric(t *testing.T) {
 				model.LabelName("label_ci_openshift_io_metadata_variant"): "variant",
 				model.LabelName("label_ci_openshift_io_metadata_target"):  "target",
 				model.LabelName("label_ci_openshift_io_metadata_step"):    "step",
-				model.LabelName("pod"):                                    "pod",
-				model.LabelName("container"):                              "container",
+				model.LabelName("pod"):       "pod",
+				model.LabelName("container"): "container",
 			},
 			meta: FullMetadata{
 				Metadata: api.Metadata{
@@ -507,9 +508,9 @@ func TestCachedQuery_Record(t *testing.T) {
 				model.LabelName("label_ci_openshift_io_metadata_variant"): "variant",
 				model.LabelName("label_ci_openshift_io_metadata_target"):  "target",
 				model.LabelName("label_ci_openshift_io_metadata_step"):    "step",
-				model.LabelName("pod"):                                    "pod",
-				model.LabelName("container"):                              "container",
-				model.LabelName("namespace"):                              "namespace",
+				model.LabelName("pod"):       "pod",
+				model.LabelName("container"): "container",
+				model.LabelName("namespace"): "namespace",
 			},
 			meta: FullMetadata{
 				Metadata: api.Metadata{
@@ -532,9 +533,9 @@ func TestCachedQuery_Record(t *testing.T) {
 				model.LabelName("label_ci_openshift_io_metadata_variant"): "VARIANT",
 				model.LabelName("label_ci_openshift_io_metadata_target"):  "TARGET",
 				model.LabelName("label_ci_openshift_io_metadata_step"):    "STEP",
-				model.LabelName("pod"):                                    "POD",
-				model.LabelName("container"):                              "CONTAINER",
-				model.LabelName("namespace"):                              "NAMESPACE",
+				model.LabelName("pod"):       "POD",
+				model.LabelName("container"): "CONTAINER",
+				model.LabelName("namespace"): "NAMESPACE",
 			},
 			meta: FullMetadata{
 				Metadata: api.Metadata{
@@ -557,9 +558,9 @@ func TestCachedQuery_Record(t *testing.T) {
 				model.LabelName("label_ci_openshift_io_metadata_variant"): "VARIANT",
 				model.LabelName("label_ci_openshift_io_metadata_target"):  "TARGET",
 				model.LabelName("label_ci_openshift_io_metadata_step"):    "STEP",
-				model.LabelName("pod"):                                    "POD",
-				model.LabelName("container"):                              "CONTAINER",
-				model.LabelName("namespace"):                              "OTHER_NAMESPACE",
+				model.LabelName("pod"):       "POD",
+				model.LabelName("container"): "CONTAINER",
+				model.LabelName("namespace"): "OTHER_NAMESPACE",
 			},
 			meta: FullMetadata{
 				Metadata: api.Metadata{
@@ -586,6 +587,8 @@ func TestCachedQuery_Record(t *testing.T) {
 	}
 
 	logger := logrus.WithField("test", "TestCachedQuery_Record")
+	// all samples below are recorded milliseconds after the epoch
+	epochDay := "1970-01-01"
 
 	// insert into an empty data structure, should update ranges and make new hist
 	q.Record("cluster", TimeRange{Start: year(1), End: year(20)}, model.Matrix{{
@@ -616,6 +619,9 @@ func TestCachedQuery_Record(t *testing.T) {
 		DataByMetaData: map[FullMetadata][]model.Fingerprint{
 			metrics[0].meta: {metrics[0].metric.Fingerprint()},
 		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			metrics[0].metric.Fingerprint(): {epochDay: expectedHist},
+		},
 	}
 	if diff := cmp.Diff(expected, q, dataComparer); diff != "" {
 		t.Errorf("got incorrect state after first insertion: %v", diff)
@@ -645,6 +651,10 @@ func TestCachedQuery_Record(t *testing.T) {
 			metrics[0].meta: {metrics[0].metric.Fingerprint()},
 			metrics[1].meta: {metrics[1].metric.Fingerprint()},
 		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			metrics[0].metric.Fingerprint(): {epochDay: expectedHist},
+			metrics[1].metric.Fingerprint(): {epochDay: expectedHist},
+		},
 	}
 	if diff := cmp.Diff(expected, q, dataComparer); diff != "" {
 		t.Errorf("got incorrect state after second insertion: %v", diff)
@@ -681,6 +691,10 @@ func TestCachedQuery_Record(t *testing.T) {
 			metrics[0].meta: {metrics[0].metric.Fingerprint()},
 			metrics[1].meta: {metrics[1].metric.Fingerprint()},
 		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			metrics[0].metric.Fingerprint(): {epochDay: expectedHist},
+			metrics[1].metric.Fingerprint(): {epochDay: biggerHist},
+		},
 	}
 	if diff := cmp.Diff(expected, q, dataComparer); diff != "" {
 		t.Errorf("got incorrect state after third insertion: %v", diff)
@@ -718,6 +732,11 @@ func TestCachedQuery_Record(t *testing.T) {
 			metrics[0].meta: {metrics[0].metric.Fingerprint()},
 			metrics[1].meta: {metrics[1].metric.Fingerprint(), metrics[2].metric.Fingerprint()},
 		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			metrics[0].metric.Fingerprint(): {epochDay: expectedHist},
+			metrics[1].metric.Fingerprint(): {epochDay: biggerHist},
+			metrics[2].metric.Fingerprint(): {epochDay: otherHist},
+		},
 	}
 	if diff := cmp.Diff(expected, q, dataComparer); diff != "" {
 		t.Errorf("got incorrect state after fourth insertion: %v", diff)
@@ -773,6 +792,121 @@ func TestCachedQuery_Prune(t *testing.T) {
 	}
 }
 
+func histogramOf(t *testing.T, values ...float64) *circonusllhist.HistogramWithoutLookups {
+	hist := circonusllhist.New(circonusllhist.NoLookup())
+	for _, value := range values {
+		if err := hist.RecordValue(value); err != nil {
+			t.Fatalf("failed to insert value into histogram, this should never happen: %v", err)
+		}
+	}
+	return circonusllhist.NewHistogramWithoutLookups(hist)
+}
+
+func TestCachedQuery_AgeOut(t *testing.T) {
+	q := CachedQuery{
+		Data: map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{
+			1: histogramOf(t, 1, 2),
+			2: histogramOf(t, 3, 4),
+			3: histogramOf(t, 5),
+		},
+		DataByMetaData: map[FullMetadata][]model.Fingerprint{
+			{Step: "partially-aged"}: {1},
+			{Step: "mixed"}:          {2, 3},
+		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			1: {"2021-01-01": histogramOf(t, 1), "2021-03-01": histogramOf(t, 2)},
+			2: {"2021-01-01": histogramOf(t, 3), "2021-01-02": histogramOf(t, 4)},
+		},
+	}
+	q.AgeOut(time.Date(2021, 2, 1, 12, 0, 0, 0, time.UTC))
+
+	expected := CachedQuery{
+		Data: map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{
+			1: histogramOf(t, 2),
+			3: histogramOf(t, 5),
+		},
+		DataByMetaData: map[FullMetadata][]model.Fingerprint{
+			{Step: "partially-aged"}: {1},
+			{Step: "mixed"}:          {3},
+		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			1: {"2021-03-01": histogramOf(t, 2)},
+		},
+	}
+	if diff := cmp.Diff(expected, q, dataComparer); diff != "" {
+		t.Errorf("got incorrect state after aging out: %v", diff)
+	}
+}
+
+func TestCachedQuery_WeightedHistogram(t *testing.T) {
+	var older, newer []float64
+	for i := 0; i < 100; i++ {
+		older = append(older, 100)
+		newer = append(newer, 10)
+	}
+	q := CachedQuery{
+		Data: map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{
+			1: histogramOf(t, older...),
+			2: histogramOf(t, newer...),
+			3: histogramOf(t, older...),
+		},
+		DataByDay: map[model.Fingerprint]map[string]*circonusllhist.HistogramWithoutLookups{
+			1: {"2021-01-01": histogramOf(t, older...)},
+			2: {"2021-01-29": histogramOf(t, newer...)},
+		},
+	}
+	now := time.Date(2021, 1, 29, 12, 0, 0, 0, time.UTC)
+
+	var testCases = []struct {
+		name         string
+		fingerprints []model.Fingerprint
+		decay        Decay
+		quantile     float64
+		expected     float64
+	}{
+		{
+			name:         "without decay, data weighs the same",
+			fingerprints: []model.Fingerprint{1, 2},
+			decay:        Decay{Now: now},
+			quantile:     0.6,
+			expected:     100,
+		},
+		{
+			name:         "with decay, the older data weighs less",
+			fingerprints: []model.Fingerprint{1, 2},
+			decay:        Decay{Now: now, HalfLife: 14 * 24 * time.Hour},
+			quantile:     0.6,
+			expected:     10,
+		},
+		{
+			name:         "with decay, the older data still shows in high quantiles",
+			fingerprints: []model.Fingerprint{1, 2},
+			decay:        Decay{Now: now, HalfLife: 14 * 24 * time.Hour},
+			quantile:     0.9,
+			expected:     100,
+		},
+		{
+			name:         "data without days weighs as much as data of the legacy age",
+			fingerprints: []model.Fingerprint{2, 3},
+			decay:        Decay{Now: now, HalfLife: 14 * 24 * time.Hour, LegacyAge: 28 * 24 * time.Hour},
+			quantile:     0.6,
+			expected:     10,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			hist, err := q.WeightedHistogram(testCase.fingerprints, testCase.decay)
+			if err != nil {
+				t.Fatalf("failed to weigh histograms: %v", err)
+			}
+			// histograms store values in bins, so check the value with their precision
+			if actual := hist.ValueAtQuantile(testCase.quantile); math.Abs(actual-testCase.expected) > testCase.expected/10 {
+				t.Errorf("expected value at quantile %v to be about %v, got %v", testCase.quantile, testCase.expected, actual)
+			}
+		})
+	}
+}
+
 func TestMetadataFor(t *testing.T) {
 	var testCases = []struct {
 		name           string
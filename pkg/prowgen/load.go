@@ -0,0 +1,90 @@
+package prowgen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	jc "github.com/openshift/ci-tools/pkg/jobconfig"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// ReadProwgenConfig loads and validates the prowgen configuration file at
+// the path. A missing file is not an error and results in a nil configuration.
+func ReadProwgenConfig(path string) (*config.Prowgen, error) {
+	var pConfig *config.Prowgen
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("prowgen config found in path %s but couldn't read the file: %w", path, err)
+	}
+
+	if err == nil {
+		if err := yaml.Unmarshal(b, &pConfig); err != nil {
+			return nil, fmt.Errorf("prowgen config found in path %sbut couldn't unmarshal it: %w", path, err)
+		}
+		if err := pConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("prowgen config found in path %s is not valid: %w", path, err)
+		}
+	}
+
+	return pConfig, nil
+}
+
+// GenerateJobsInto returns a callback for walking a ci-operator configuration
+// directory that generates the jobs for every configuration into the output,
+// keyed by org/repo. Prowgen configuration files are read next to the
+// configurations and cached by org and org/repo.
+func GenerateJobsInto(resolver registry.Resolver, cache map[string]*config.Prowgen, output map[string]*prowconfig.JobConfig) func(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *config.Info) error {
+	return func(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *config.Info) error {
+		orgRepo := fmt.Sprintf("%s/%s", info.Org, info.Repo)
+		pInfo := &ProwgenInfo{Metadata: info.Metadata, Config: config.Prowgen{Private: false, Expose: false}}
+		var ok bool
+		var err error
+		var orgConfig, repoConfig *config.Prowgen
+
+		if orgConfig, ok = cache[info.Org]; !ok {
+			if cache[info.Org], err = ReadProwgenConfig(filepath.Join(info.OrgPath, config.ProwgenFile)); err != nil {
+				return err
+			}
+			orgConfig = cache[info.Org]
+		}
+
+		if repoConfig, ok = cache[orgRepo]; !ok {
+			if cache[orgRepo], err = ReadProwgenConfig(filepath.Join(info.RepoPath, config.ProwgenFile)); err != nil {
+				return err
+			}
+			repoConfig = cache[orgRepo]
+		}
+
+		switch {
+		case orgConfig != nil:
+			pInfo.Config = *orgConfig
+		case repoConfig != nil:
+			pInfo.Config = *repoConfig
+		}
+		if resolver != nil {
+			resolved, err := registry.ResolveConfig(resolver, *configSpec)
+			if err != nil {
+				return fmt.Errorf("failed to resolve configuration: %w", err)
+			}
+			configSpec = &resolved
+		}
+		generated, err := GenerateJobs(configSpec, pInfo)
+		if err != nil {
+			return err
+		}
+		if o, ok := output[orgRepo]; ok {
+			jc.Append(o, generated)
+		} else {
+			output[orgRepo] = generated
+		}
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package prowgen
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/config"
+	jc "github.com/openshift/ci-tools/pkg/jobconfig"
+)
+
+// applyOverlays customizes the generated jobs with the overlays from the
+// prowgen configuration. Overlays may only name tests that jobs were generated
+// for, so that typos and removed tests do not go unnoticed.
+func applyOverlays(jobConfig *prowconfig.JobConfig, info *ProwgenInfo) error {
+	if len(info.Config.Overlays) == 0 {
+		return nil
+	}
+
+	type job struct {
+		jobType prowv1.ProwJobType
+		test    string
+		base    *prowconfig.JobBase
+		// alwaysRun and changeMatcher are only set for jobs triggered by changes
+		alwaysRun     func(bool)
+		changeMatcher *prowconfig.RegexpChangeMatcher
+	}
+	var jobs []job
+	for repo := range jobConfig.PresubmitsStatic {
+		for i := range jobConfig.PresubmitsStatic[repo] {
+			presubmit := &jobConfig.PresubmitsStatic[repo][i]
+			jobs = append(jobs, job{
+				jobType:       prowv1.PresubmitJob,
+				test:          info.TestNameFromJobName(presubmit.Name, jc.PresubmitPrefix),
+				base:          &presubmit.JobBase,
+				alwaysRun:     func(alwaysRun bool) { presubmit.AlwaysRun = alwaysRun },
+				changeMatcher: &presubmit.RegexpChangeMatcher,
+			})
+		}
+	}
+	for repo := range jobConfig.PostsubmitsStatic {
+		for i := range jobConfig.PostsubmitsStatic[repo] {
+			postsubmit := &jobConfig.PostsubmitsStatic[repo][i]
+			jobs = append(jobs, job{
+				jobType:       prowv1.PostsubmitJob,
+				test:          info.TestNameFromJobName(postsubmit.Name, jc.PostsubmitPrefix),
+				base:          &postsubmit.JobBase,
+				alwaysRun:     func(alwaysRun bool) { postsubmit.AlwaysRun = &alwaysRun },
+				changeMatcher: &postsubmit.RegexpChangeMatcher,
+			})
+		}
+	}
+	for i := range jobConfig.Periodics {
+		periodic := &jobConfig.Periodics[i]
+		jobs = append(jobs, job{
+			jobType: prowv1.PeriodicJob,
+			test:    info.TestNameFromJobName(periodic.Name, jc.PeriodicPrefix),
+			base:    &periodic.JobBase,
+		})
+	}
+
+	tests := sets.NewString()
+	for _, job := range jobs {
+		tests.Insert(job.test)
+	}
+	for i, overlay := range info.Config.Overlays {
+		if len(overlay.Branches) > 0 && !sets.NewString(overlay.Branches...).Has(info.Branch) {
+			continue
+		}
+		if unknown := sets.NewString(overlay.Tests...).Difference(tests); unknown.Len() > 0 {
+			return fmt.Errorf("overlays[%d] refers to tests that are not in the ci-operator configuration %s: %s", i, info.Basename(), strings.Join(unknown.List(), ", "))
+		}
+		selectedTests := sets.NewString(overlay.Tests...)
+		jobTypes := sets.NewString()
+		for _, jobType := range overlay.JobTypes {
+			jobTypes.Insert(string(jobType))
+		}
+		for _, job := range jobs {
+			if (selectedTests.Len() > 0 && !selectedTests.Has(job.test)) || (jobTypes.Len() > 0 && !jobTypes.Has(string(job.jobType))) {
+				continue
+			}
+			applyOverlay(overlay, job.base, job.alwaysRun, job.changeMatcher)
+		}
+	}
+	return nil
+}
+
+func applyOverlay(overlay config.JobOverlay, base *prowconfig.JobBase, alwaysRun func(bool), changeMatcher *prowconfig.RegexpChangeMatcher) {
+	var overlaid []string
+	for key, value := range overlay.Labels {
+		if base.Labels == nil {
+			base.Labels = map[string]string{}
+		}
+		base.Labels[key] = value
+	}
+	if overlay.MaxConcurrency != nil {
+		base.MaxConcurrency = *overlay.MaxConcurrency
+		overlaid = append(overlaid, jc.OverlaidMaxConcurrency)
+	}
+	if overlay.ReporterConfig != nil {
+		base.ReporterConfig = overlay.ReporterConfig.DeepCopy()
+		overlaid = append(overlaid, jc.OverlaidReporterConfig)
+	}
+	if overlay.AlwaysRun != nil && alwaysRun != nil {
+		alwaysRun(*overlay.AlwaysRun)
+		if *overlay.AlwaysRun {
+			*changeMatcher = prowconfig.RegexpChangeMatcher{}
+		}
+		overlaid = append(overlaid, jc.OverlaidAlwaysRun)
+	}
+	if overlay.Timeout != nil || overlay.GracePeriod != nil {
+		if base.DecorationConfig == nil {
+			base.DecorationConfig = &prowv1.DecorationConfig{}
+		}
+		if overlay.Timeout != nil {
+			base.DecorationConfig.Timeout = &prowv1.Duration{Duration: overlay.Timeout.Duration}
+		}
+		if overlay.GracePeriod != nil {
+			base.DecorationConfig.GracePeriod = &prowv1.Duration{Duration: overlay.GracePeriod.Duration}
+		}
+	}
+	if len(overlaid) == 0 {
+		return
+	}
+	// the annotation tells jobconfig to keep the overlaid fields instead
+	// of preserving manual changes to them when the jobs are merged
+	if base.Annotations == nil {
+		base.Annotations = map[string]string{}
+	}
+	fields := sets.NewString(overlaid...)
+	if existing := base.Annotations[jc.OverlaidFieldsAnnotation]; existing != "" {
+		fields.Insert(strings.Split(existing, ",")...)
+	}
+	base.Annotations[jc.OverlaidFieldsAnnotation] = strings.Join(fields.List(), ",")
+}
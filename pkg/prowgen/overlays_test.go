@@ -0,0 +1,129 @@
+package prowgen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+	utilpointer "k8s.io/utils/pointer"
+
+	ciop "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	jc "github.com/openshift/ci-tools/pkg/jobconfig"
+)
+
+func TestApplyOverlays(t *testing.T) {
+	configSpec := &ciop.ReleaseBuildConfiguration{
+		Tests: []ciop.TestStepConfiguration{
+			{As: "unit", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}},
+			{As: "e2e", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}, RunIfChanged: "^pkg/"},
+			{As: "nightly", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}, Cron: utilpointer.StringPtr("@daily")},
+		},
+		Images:                 []ciop.ProjectDirectoryImageBuildStepConfiguration{{To: "component"}},
+		PromotionConfiguration: &ciop.PromotionConfiguration{Namespace: "ci"},
+	}
+	metadata := ciop.Metadata{Org: "org", Repo: "repo", Branch: "master"}
+
+	var testCases = []struct {
+		name        string
+		overlays    []config.JobOverlay
+		expectedErr string
+		verify      func(t *testing.T, jobs *prowconfig.JobConfig)
+	}{
+		{
+			name: "overlay for a test applies to its jobs only",
+			overlays: []config.JobOverlay{{
+				Tests:          []string{"e2e"},
+				Labels:         map[string]string{"team": "storage"},
+				MaxConcurrency: utilpointer.IntPtr(2),
+				AlwaysRun:      utilpointer.BoolPtr(true),
+				Timeout:        &prowv1.Duration{Duration: 6 * time.Hour},
+			}},
+			verify: func(t *testing.T, jobs *prowconfig.JobConfig) {
+				for _, presubmit := range jobs.PresubmitsStatic["org/repo"] {
+					if presubmit.Name != "pull-ci-org-repo-master-e2e" {
+						if presubmit.Labels["team"] != "" || presubmit.MaxConcurrency != 0 || presubmit.Annotations != nil {
+							t.Errorf("expected %s not to be customized", presubmit.Name)
+						}
+						continue
+					}
+					if presubmit.Labels["team"] != "storage" || presubmit.MaxConcurrency != 2 {
+						t.Errorf("expected label and max_concurrency to be set, got %v and %d", presubmit.Labels, presubmit.MaxConcurrency)
+					}
+					if !presubmit.AlwaysRun || presubmit.RunIfChanged != "" {
+						t.Errorf("expected the job to always run, got always_run %v and run_if_changed %q", presubmit.AlwaysRun, presubmit.RunIfChanged)
+					}
+					if presubmit.DecorationConfig == nil || presubmit.DecorationConfig.Timeout.Duration != 6*time.Hour {
+						t.Errorf("expected the timeout to be set, got %v", presubmit.DecorationConfig)
+					}
+					if diff := cmp.Diff(map[string]string{jc.OverlaidFieldsAnnotation: "always_run,max_concurrency"}, presubmit.Annotations); diff != "" {
+						t.Errorf("annotations differ from expected: %s", diff)
+					}
+				}
+			},
+		},
+		{
+			name: "overlay for a job type applies to all its jobs",
+			overlays: []config.JobOverlay{{
+				JobTypes:       []prowv1.ProwJobType{prowv1.PeriodicJob},
+				ReporterConfig: &prowv1.ReporterConfig{Slack: &prowv1.SlackReporterConfig{Channel: "#alerts"}},
+			}},
+			verify: func(t *testing.T, jobs *prowconfig.JobConfig) {
+				if len(jobs.Periodics) != 1 || jobs.Periodics[0].ReporterConfig == nil || jobs.Periodics[0].ReporterConfig.Slack.Channel != "#alerts" {
+					t.Errorf("expected the periodic to report to Slack, got %v", jobs.Periodics)
+				}
+				for _, presubmit := range jobs.PresubmitsStatic["org/repo"] {
+					if presubmit.ReporterConfig != nil {
+						t.Errorf("expected presubmit %s not to be customized", presubmit.Name)
+					}
+				}
+			},
+		},
+		{
+			name:     "later overlays win",
+			overlays: []config.JobOverlay{{MaxConcurrency: utilpointer.IntPtr(1)}, {Tests: []string{"images"}, JobTypes: []prowv1.ProwJobType{prowv1.PostsubmitJob}, MaxConcurrency: utilpointer.IntPtr(3)}},
+			verify: func(t *testing.T, jobs *prowconfig.JobConfig) {
+				if postsubmit := jobs.PostsubmitsStatic["org/repo"][0]; postsubmit.MaxConcurrency != 3 {
+					t.Errorf("expected the images postsubmit to have max_concurrency 3, got %d", postsubmit.MaxConcurrency)
+				}
+				if periodic := jobs.Periodics[0]; periodic.MaxConcurrency != 1 {
+					t.Errorf("expected the periodic to have max_concurrency 1, got %d", periodic.MaxConcurrency)
+				}
+			},
+		},
+		{
+			name:     "overlays for other branches are ignored",
+			overlays: []config.JobOverlay{{Tests: []string{"removed"}, Branches: []string{"release-4.1"}, MaxConcurrency: utilpointer.IntPtr(1)}},
+			verify: func(t *testing.T, jobs *prowconfig.JobConfig) {
+				for _, presubmit := range jobs.PresubmitsStatic["org/repo"] {
+					if presubmit.MaxConcurrency != 0 {
+						t.Errorf("expected %s not to be customized", presubmit.Name)
+					}
+				}
+			},
+		},
+		{
+			name:        "overlay for a test that is not configured",
+			overlays:    []config.JobOverlay{{Tests: []string{"e2e", "removed"}, MaxConcurrency: utilpointer.IntPtr(1)}},
+			expectedErr: "overlays[0] refers to tests that are not in the ci-operator configuration org-repo-master.yaml: removed",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jobs, err := GenerateJobs(configSpec, &ProwgenInfo{Metadata: metadata, Config: config.Prowgen{Overlays: tc.overlays}})
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to generate jobs: %v", err)
+			}
+			tc.verify(t, jobs)
+		})
+	}
+}
@@ -34,6 +34,7 @@ type ProwgenInfo struct {
 //     presubmit and postsubmit that has `--target=[images]`. This postsubmit
 //     will additionally pass `--promote` to ci-operator
 //
+// Overlays from the prowgen configuration are applied to the generated jobs.
 // All these generated jobs will be labeled as "newly generated". After all
 // new jobs are generated with GenerateJobs, the call site should also use
 // Prune() function to remove all stale jobs and label the jobs as simply
@@ -140,11 +141,15 @@ func GenerateJobs(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *Pro
 		}
 	}
 
-	return &prowconfig.JobConfig{
+	jobConfig := &prowconfig.JobConfig{
 		PresubmitsStatic:  presubmits,
 		PostsubmitsStatic: postsubmits,
 		Periodics:         periodics,
-	}, nil
+	}
+	if err := applyOverlays(jobConfig, info); err != nil {
+		return nil, err
+	}
+	return jobConfig, nil
 }
 
 func testContainsLease(test *cioperatorapi.TestStepConfiguration) bool {
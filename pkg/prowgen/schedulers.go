@@ -0,0 +1,411 @@
+package prowgen
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	jc "github.com/openshift/ci-tools/pkg/jobconfig"
+)
+
+// Scheduler is a system that runs the jobs generated from ci-operator configurations
+type Scheduler string
+
+const (
+	SchedulerProw    Scheduler = "prow"
+	SchedulerTekton  Scheduler = "tekton"
+	SchedulerCronJob Scheduler = "cronjob"
+
+	// JobNameAnnotation holds the name of the Prow job an object was generated
+	// from, as object names may need to be shortened
+	JobNameAnnotation = "ci.openshift.io/job"
+	// gitRefParam is the parameter of generated TriggerTemplates and PipelineRuns
+	// that determines the revision ci-operator tests
+	gitRefParam = "git-ref"
+
+	triggersAPIVersion = "triggers.tekton.dev/v1beta1"
+	// triggersServiceAccount is the service account EventListeners run as, which
+	// OpenShift Pipelines creates with the necessary permissions in every namespace
+	triggersServiceAccount = "pipeline"
+)
+
+// Schedulers are all the supported schedulers
+var Schedulers = []Scheduler{SchedulerProw, SchedulerTekton, SchedulerCronJob}
+
+// JobGenerator turns the Prow jobs generated for a repository into objects
+// another scheduler runs. The pod specs of all schedulers are the ones built by
+// the CiOperatorPodSpecGenerator mutators for Prow; generators only replace
+// what Prow provides around them: ci-operator is pointed at the tested revision
+// with --git-ref and at the configuration of the job with --org, --repo and
+// --branch instead of the JOB_SPEC from Prow, and scheduling is delegated to
+// the scheduler. Secrets the pod specs mount must exist where the objects run.
+type JobGenerator interface {
+	Generate(jobs *prowconfig.JobConfig) ([]ctrlruntimeclient.Object, error)
+}
+
+// JobGeneratorFor returns the generator for a scheduler other than Prow, whose
+// objects are created in the given namespace
+func JobGeneratorFor(scheduler Scheduler, namespace string) (JobGenerator, error) {
+	switch scheduler {
+	case SchedulerTekton:
+		return &tektonGenerator{namespace: namespace}, nil
+	case SchedulerCronJob:
+		return &cronJobGenerator{namespace: namespace}, nil
+	default:
+		return nil, fmt.Errorf("no job generator for scheduler %q, available ones are: %s, %s", scheduler, SchedulerTekton, SchedulerCronJob)
+	}
+}
+
+// gitRef formats a ref in the form ci-operator's --git-ref expects
+func gitRef(org, repo, branch string) string {
+	return fmt.Sprintf("%s/%s@%s", org, repo, branch)
+}
+
+// ciOperatorPodSpec copies the pod spec of a generated job and points its
+// ci-operator container at the given revision. The configuration is pinned to
+// the one the job was generated for, as ci-operator would otherwise look up the
+// configuration for the ref, which is not a branch for pull requests or pushes.
+// Volumes that Prow decoration
+// adds to the pod, like the GCS credentials, do not exist elsewhere, so their
+// mounts are removed along with the arguments that refer to files in them.
+func ciOperatorPodSpec(job prowconfig.JobBase, org, repo, branch, ref string) (*corev1.PodSpec, error) {
+	if job.Spec == nil || len(job.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("job %s has no containers", job.Name)
+	}
+	spec := job.Spec.DeepCopy()
+	container := &spec.Containers[0]
+	if len(container.Command) == 0 || container.Command[0] != "ci-operator" {
+		return nil, fmt.Errorf("job %s does not run ci-operator", job.Name)
+	}
+	if container.Name == "" {
+		container.Name = "test"
+	}
+
+	volumes := sets.NewString()
+	for _, volume := range spec.Volumes {
+		volumes.Insert(volume.Name)
+	}
+	var mounts []corev1.VolumeMount
+	var decorationPaths []string
+	for _, mount := range container.VolumeMounts {
+		if volumes.Has(mount.Name) {
+			mounts = append(mounts, mount)
+		} else {
+			decorationPaths = append(decorationPaths, mount.MountPath+"/")
+		}
+	}
+	container.VolumeMounts = mounts
+	var args []string
+	for _, arg := range container.Args {
+		if !refersTo(arg, decorationPaths) {
+			args = append(args, arg)
+		}
+	}
+	container.Args = args
+
+	addUniqueParameter(container, fmt.Sprintf("--git-ref=%s", ref))
+	for _, flag := range [][2]string{{"org", org}, {"repo", repo}, {"branch", branch}} {
+		if flag[1] != "" {
+			addUniqueParameter(container, fmt.Sprintf("--%s=%s", flag[0], flag[1]))
+		}
+	}
+	return spec, nil
+}
+
+// refersTo determines whether the value of an argument is a file in one of the paths
+func refersTo(arg string, paths []string) bool {
+	_, value, ok := strings.Cut(arg, "=")
+	if !ok {
+		return false
+	}
+	for _, path := range paths {
+		if strings.HasPrefix(value, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectName returns a name for an object generated from a job that is not
+// longer than the limit, replacing the end of long names with a hash
+func objectName(job string, limit int) string {
+	if len(job) <= limit {
+		return job
+	}
+	h := fnv.New32a()
+	// hash writes never return errors
+	_, _ = h.Write([]byte(job))
+	suffix := fmt.Sprintf("-%08x", h.Sum32())
+	return strings.TrimRight(job[:limit-len(suffix)], "-") + suffix
+}
+
+// objectMeta identifies the job an object was generated from. The labels of
+// the job are not copied, as they are meant for Prow and tools that act on
+// Prow jobs.
+func objectMeta(job prowconfig.JobBase, name, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Annotations: map[string]string{JobNameAnnotation: job.Name},
+	}
+}
+
+// labelName turns a name into one that is valid as a DNS label
+func labelName(name string, limit int) string {
+	return objectName(strings.ToLower(strings.NewReplacer(".", "-", "_", "-").Replace(name)), limit)
+}
+
+// tektonGenerator generates a TriggerTemplate for every job, which creates a
+// PipelineRun named after the job for the revision it is given as a parameter,
+// and an EventListener for every repository, which triggers the templates on
+// GitHub events: presubmits that always run test the head of pull requests
+// against their branch as `org/repo@refs/pull/N/head`, without merging it into
+// the branch like Prow does, and postsubmits test pushes to their branch. Jobs that run conditionally and periodics are not triggered by any
+// event; the templates of postsubmits and periodics default to their branch, so
+// they can be triggered manually or on a schedule.
+type tektonGenerator struct {
+	namespace string
+}
+
+func (g *tektonGenerator) Generate(jobs *prowconfig.JobConfig) ([]ctrlruntimeclient.Object, error) {
+	var objects []ctrlruntimeclient.Object
+	add := func(job prowconfig.JobBase, org, repo, branch, defaultRef string) (string, error) {
+		spec, err := ciOperatorPodSpec(job, org, repo, branch, fmt.Sprintf("$(params.%s)", gitRefParam))
+		if err != nil {
+			return "", err
+		}
+		template, err := triggerTemplateFor(job, spec, defaultRef, g.namespace)
+		if err != nil {
+			return "", err
+		}
+		objects = append(objects, template)
+		return template.GetName(), nil
+	}
+
+	for _, repo := range sets.StringKeySet(jobs.PresubmitsStatic).Union(sets.StringKeySet(jobs.PostsubmitsStatic)).List() {
+		org, name := splitOrgRepo(repo)
+		var triggers []interface{}
+		for _, presubmit := range jobs.PresubmitsStatic[repo] {
+			branch := branchOf(presubmit.Branches)
+			template, err := add(presubmit.JobBase, org, name, branch, "")
+			if err != nil {
+				return nil, err
+			}
+			if !presubmit.AlwaysRun || branch == "" {
+				continue
+			}
+			triggers = append(triggers, trigger(template, "pull_request",
+				fmt.Sprintf("body.action in ['opened', 'synchronize', 'reopened'] && body.pull_request.base.ref == '%s'", branch),
+				"$(body.repository.full_name)@refs/pull/$(body.number)/head",
+			))
+		}
+		for _, postsubmit := range jobs.PostsubmitsStatic[repo] {
+			branch := branchOf(postsubmit.Branches)
+			template, err := add(postsubmit.JobBase, org, name, branch, gitRef(org, name, branch))
+			if err != nil {
+				return nil, err
+			}
+			if (postsubmit.AlwaysRun != nil && !*postsubmit.AlwaysRun) || postsubmit.RunIfChanged != "" || postsubmit.SkipIfOnlyChanged != "" || branch == "" {
+				continue
+			}
+			triggers = append(triggers, trigger(template, "push",
+				fmt.Sprintf("body.ref == 'refs/heads/%s'", branch),
+				"$(body.repository.full_name)@$(body.ref)",
+			))
+		}
+		if len(triggers) == 0 {
+			continue
+		}
+		listener := triggersObject("EventListener", labelName(fmt.Sprintf("%s-%s", org, name), 60), g.namespace)
+		listener.Object["spec"] = map[string]interface{}{
+			"serviceAccountName": triggersServiceAccount,
+			"triggers":           triggers,
+		}
+		objects = append(objects, listener)
+	}
+	for _, periodic := range jobs.Periodics {
+		if len(periodic.ExtraRefs) == 0 {
+			return nil, fmt.Errorf("periodic %s does not test any repository", periodic.Name)
+		}
+		ref := periodic.ExtraRefs[0]
+		if _, err := add(periodic.JobBase, ref.Org, ref.Repo, ref.BaseRef, gitRef(ref.Org, ref.Repo, ref.BaseRef)); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+func triggersObject(kind, name, namespace string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	object.SetAPIVersion(triggersAPIVersion)
+	object.SetKind(kind)
+	object.SetName(name)
+	object.SetNamespace(namespace)
+	return object
+}
+
+// trigger triggers the template on GitHub events of the type that match the
+// CEL filter, passing the revision to test from the event
+func trigger(template, eventType, filter, ref string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": template,
+		"interceptors": []interface{}{
+			map[string]interface{}{
+				"ref":    map[string]interface{}{"name": "github"},
+				"params": []interface{}{map[string]interface{}{"name": "eventTypes", "value": []interface{}{eventType}}},
+			},
+			map[string]interface{}{
+				"ref":    map[string]interface{}{"name": "cel"},
+				"params": []interface{}{map[string]interface{}{"name": "filter", "value": filter}},
+			},
+		},
+		"bindings": []interface{}{map[string]interface{}{"name": gitRefParam, "value": ref}},
+		"template": map[string]interface{}{"ref": template},
+	}
+}
+
+// triggerTemplateFor generates a TriggerTemplate that runs the job for the
+// revision given as a parameter, defaulting to the given one if any
+func triggerTemplateFor(job prowconfig.JobBase, spec *corev1.PodSpec, defaultRef, namespace string) (*unstructured.Unstructured, error) {
+	run, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pipelineRunFor(job, spec, fmt.Sprintf("$(tt.params.%s)", gitRefParam)))
+	if err != nil {
+		return nil, fmt.Errorf("could not convert the PipelineRun of job %s: %w", job.Name, err)
+	}
+	// Triggers fills in what the cluster sets on creation
+	delete(run, "status")
+	unstructured.RemoveNestedField(run, "metadata", "creationTimestamp")
+	param := map[string]interface{}{
+		"name":        gitRefParam,
+		"description": "The revision to test, as ORG/REPO@REF",
+	}
+	if defaultRef != "" {
+		param["default"] = defaultRef
+	}
+	template := triggersObject("TriggerTemplate", objectName(job.Name, 63), namespace)
+	template.SetAnnotations(map[string]string{JobNameAnnotation: job.Name})
+	template.Object["spec"] = map[string]interface{}{
+		"params":            []interface{}{param},
+		"resourcetemplates": []interface{}{run},
+	}
+	return template, nil
+}
+
+func pipelineRunFor(job prowconfig.JobBase, spec *corev1.PodSpec, ref string) *pipelinev1beta1.PipelineRun {
+	container := spec.Containers[0]
+	meta := objectMeta(job, "", "")
+	meta.GenerateName = objectName(job.Name, 57) + "-"
+	run := &pipelinev1beta1.PipelineRun{
+		TypeMeta:   metav1.TypeMeta{APIVersion: pipelinev1beta1.SchemeGroupVersion.String(), Kind: "PipelineRun"},
+		ObjectMeta: meta,
+		Spec: pipelinev1beta1.PipelineRunSpec{
+			Params: []pipelinev1beta1.Param{{Name: gitRefParam, Value: *pipelinev1beta1.NewArrayOrString(ref)}},
+			PipelineSpec: &pipelinev1beta1.PipelineSpec{
+				Params: []pipelinev1beta1.ParamSpec{{
+					Name:        gitRefParam,
+					Type:        pipelinev1beta1.ParamTypeString,
+					Description: "The revision to test, as ORG/REPO@REF",
+				}},
+				Tasks: []pipelinev1beta1.PipelineTask{{
+					Name:   "ci-operator",
+					Params: []pipelinev1beta1.Param{{Name: gitRefParam, Value: *pipelinev1beta1.NewArrayOrString(fmt.Sprintf("$(params.%s)", gitRefParam))}},
+					TaskSpec: &pipelinev1beta1.EmbeddedTask{TaskSpec: pipelinev1beta1.TaskSpec{
+						Params: []pipelinev1beta1.ParamSpec{{Name: gitRefParam, Type: pipelinev1beta1.ParamTypeString}},
+						Steps: []pipelinev1beta1.Step{{
+							Name:            "test",
+							Image:           container.Image,
+							ImagePullPolicy: container.ImagePullPolicy,
+							Command:         container.Command,
+							Args:            container.Args,
+							Env:             container.Env,
+							EnvFrom:         container.EnvFrom,
+							Resources:       container.Resources,
+							VolumeMounts:    container.VolumeMounts,
+						}},
+						Volumes: spec.Volumes,
+					}},
+				}},
+			},
+			ServiceAccountName: spec.ServiceAccountName,
+		},
+	}
+	if len(spec.NodeSelector) > 0 || len(spec.Tolerations) > 0 {
+		run.Spec.PodTemplate = &pipelinev1beta1.PodTemplate{NodeSelector: spec.NodeSelector, Tolerations: spec.Tolerations}
+	}
+	if job.DecorationConfig != nil && job.DecorationConfig.Timeout != nil {
+		run.Spec.Timeouts = &pipelinev1beta1.TimeoutFields{Pipeline: &metav1.Duration{Duration: job.DecorationConfig.Timeout.Duration}}
+	}
+	return run
+}
+
+// cronJobGenerator generates a CronJob for every periodic that runs on a cron
+// schedule. Presubmits and postsubmits need a scheduler that reacts to
+// changes, and periodics running on an interval or triggered by the release
+// controller have no cron schedule, so these jobs are not generated.
+type cronJobGenerator struct {
+	namespace string
+}
+
+func (g *cronJobGenerator) Generate(jobs *prowconfig.JobConfig) ([]ctrlruntimeclient.Object, error) {
+	var objects []ctrlruntimeclient.Object
+	for _, periodic := range jobs.Periodics {
+		if periodic.Cron == "" || periodic.Labels[jc.ReleaseControllerLabel] == jc.ReleaseControllerValue {
+			continue
+		}
+		if len(periodic.ExtraRefs) == 0 {
+			return nil, fmt.Errorf("periodic %s does not test any repository", periodic.Name)
+		}
+		ref := periodic.ExtraRefs[0]
+		spec, err := ciOperatorPodSpec(periodic.JobBase, ref.Org, ref.Repo, ref.BaseRef, gitRef(ref.Org, ref.Repo, ref.BaseRef))
+		if err != nil {
+			return nil, err
+		}
+		spec.RestartPolicy = corev1.RestartPolicyNever
+		var backoffLimit int32
+		job := batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     corev1.PodTemplateSpec{Spec: *spec},
+		}
+		if periodic.DecorationConfig != nil && periodic.DecorationConfig.Timeout != nil {
+			deadline := int64(periodic.DecorationConfig.Timeout.Duration.Seconds())
+			job.ActiveDeadlineSeconds = &deadline
+		}
+		// CronJob names are limited, as the names of Jobs created from them get a suffix
+		objects = append(objects, &batchv1.CronJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: batchv1.SchemeGroupVersion.String(), Kind: "CronJob"},
+			ObjectMeta: objectMeta(periodic.JobBase, objectName(periodic.Name, 52), g.namespace),
+			Spec: batchv1.CronJobSpec{
+				Schedule:          periodic.Cron,
+				ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				JobTemplate:       batchv1.JobTemplateSpec{Spec: job},
+			},
+		})
+	}
+	return objects, nil
+}
+
+func splitOrgRepo(orgRepo string) (string, string) {
+	org, repo, _ := strings.Cut(orgRepo, "/")
+	return org, repo
+}
+
+// branchOf returns the branch a generated job is configured for, from the
+// regular expression that matches exactly that branch
+func branchOf(branches []string) string {
+	for _, branch := range branches {
+		if strings.HasPrefix(branch, "^") && strings.HasSuffix(branch, "$") {
+			return strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(branch, "^"), "$"), "\\", "")
+		}
+	}
+	return ""
+}
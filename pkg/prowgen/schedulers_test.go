@@ -0,0 +1,151 @@
+package prowgen
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilpointer "k8s.io/utils/pointer"
+
+	ciop "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestJobGenerators(t *testing.T) {
+	configSpec := &ciop.ReleaseBuildConfiguration{
+		Tests: []ciop.TestStepConfiguration{
+			{As: "unit", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}},
+			{As: "nightly", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}, Cron: utilpointer.StringPtr("0 1 * * *")},
+			{As: "hourly", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}, Interval: utilpointer.StringPtr("1h")},
+		},
+		Images:                 []ciop.ProjectDirectoryImageBuildStepConfiguration{{To: "component"}},
+		PromotionConfiguration: &ciop.PromotionConfiguration{Namespace: "ocp", Name: "4.10"},
+	}
+	jobs, err := GenerateJobs(configSpec, &ProwgenInfo{Metadata: ciop.Metadata{Org: "organization", Repo: "repository", Branch: "release-4.10"}})
+	if err != nil {
+		t.Fatalf("failed to generate jobs: %v", err)
+	}
+	for _, scheduler := range []Scheduler{SchedulerTekton, SchedulerCronJob} {
+		t.Run(string(scheduler), func(t *testing.T) {
+			generator, err := JobGeneratorFor(scheduler, "ci")
+			if err != nil {
+				t.Fatalf("failed to get generator: %v", err)
+			}
+			objects, err := generator.Generate(jobs)
+			if err != nil {
+				t.Fatalf("failed to generate objects: %v", err)
+			}
+			testhelper.CompareWithFixture(t, objects)
+		})
+	}
+}
+
+func TestJobGeneratorForProw(t *testing.T) {
+	if _, err := JobGeneratorFor(SchedulerProw, "ci"); err == nil {
+		t.Error("expected an error for Prow, which uses the generated jobs directly")
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		job      string
+		limit    int
+		expected string
+	}{
+		{
+			name:     "short name is kept",
+			job:      "periodic-ci-org-repo-master-e2e",
+			limit:    52,
+			expected: "periodic-ci-org-repo-master-e2e",
+		},
+		{
+			name:     "long name is shortened with a hash",
+			job:      "periodic-ci-organization-repository-release-4.10-nightly-e2e-aws",
+			limit:    52,
+			expected: "periodic-ci-organization-repository-release-7b5e4dff",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := objectName(tc.job, tc.limit)
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+			if len(actual) > tc.limit {
+				t.Errorf("name %s is longer than %d", actual, tc.limit)
+			}
+		})
+	}
+}
+
+func TestBranchOf(t *testing.T) {
+	var testCases = []struct {
+		branches []string
+		expected string
+	}{
+		{branches: []string{"^release-4\\.10$", "^release-4\\.10-"}, expected: "release-4.10"},
+		{branches: []string{"^master-", "^master$"}, expected: "master"},
+		{branches: []string{"^feature-.*"}, expected: ""},
+	}
+	for _, tc := range testCases {
+		if actual := branchOf(tc.branches); actual != tc.expected {
+			t.Errorf("%v: expected %q, got %q", tc.branches, tc.expected, actual)
+		}
+	}
+}
+
+// TestTektonTriggersPinConfiguration ensures that runs started by events, whose
+// refs are not branches, still use the configuration of the job's branch
+func TestTektonTriggersPinConfiguration(t *testing.T) {
+	configSpec := &ciop.ReleaseBuildConfiguration{
+		Tests:                  []ciop.TestStepConfiguration{{As: "unit", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}}},
+		Images:                 []ciop.ProjectDirectoryImageBuildStepConfiguration{{To: "component"}},
+		PromotionConfiguration: &ciop.PromotionConfiguration{Namespace: "ocp", Name: "4.10"},
+	}
+	jobs, err := GenerateJobs(configSpec, &ProwgenInfo{Metadata: ciop.Metadata{Org: "organization", Repo: "repository", Branch: "release-4.10"}})
+	if err != nil {
+		t.Fatalf("failed to generate jobs: %v", err)
+	}
+	generator, err := JobGeneratorFor(SchedulerTekton, "ci")
+	if err != nil {
+		t.Fatalf("failed to get generator: %v", err)
+	}
+	objects, err := generator.Generate(jobs)
+	if err != nil {
+		t.Fatalf("failed to generate objects: %v", err)
+	}
+	args := map[string][]string{}
+	var triggers []interface{}
+	for _, object := range objects {
+		u := object.(*unstructured.Unstructured)
+		switch u.GetKind() {
+		case "TriggerTemplate":
+			templates, _, _ := unstructured.NestedSlice(u.Object, "spec", "resourcetemplates")
+			tasks, _, _ := unstructured.NestedSlice(templates[0].(map[string]interface{}), "spec", "pipelineSpec", "tasks")
+			steps, _, _ := unstructured.NestedSlice(tasks[0].(map[string]interface{}), "taskSpec", "steps")
+			stepArgs, _, _ := unstructured.NestedStringSlice(steps[0].(map[string]interface{}), "args")
+			args[u.GetName()] = stepArgs
+		case "EventListener":
+			triggers, _, _ = unstructured.NestedSlice(u.Object, "spec", "triggers")
+		}
+	}
+	if len(triggers) != 3 {
+		t.Fatalf("expected triggers for the two presubmits and the postsubmit, got %d", len(triggers))
+	}
+	for _, trigger := range triggers {
+		template, _, _ := unstructured.NestedString(trigger.(map[string]interface{}), "template", "ref")
+		bindings, _, _ := unstructured.NestedSlice(trigger.(map[string]interface{}), "bindings")
+		ref := bindings[0].(map[string]interface{})["value"].(string)
+		if !strings.Contains(ref, "$(body.") {
+			t.Errorf("%s: expected the ref to be bound from the event, got %s", template, ref)
+		}
+		pinned := sets.NewString(args[template]...)
+		for _, arg := range []string{"--org=organization", "--repo=repository", "--branch=release-4.10"} {
+			if !pinned.Has(arg) {
+				t.Errorf("%s: expected the configuration to be pinned with %s, got %v", template, arg, args[template])
+			}
+		}
+	}
+}
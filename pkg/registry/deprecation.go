@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// DeprecationByName maps names of deprecated registry components to their deprecation
+type DeprecationByName map[string]api.RegistryDeprecation
+
+// Deprecations holds the deprecations of all types of registry components,
+// which are kept separate as components of different types may share a name
+type Deprecations struct {
+	References DeprecationByName `json:"references,omitempty"`
+	Chains     DeprecationByName `json:"chains,omitempty"`
+	Workflows  DeprecationByName `json:"workflows,omitempty"`
+}
+
+// Get returns the deprecation of the component, if it is deprecated
+func (d Deprecations) Get(t Type, name string) (api.RegistryDeprecation, bool) {
+	var byName DeprecationByName
+	switch t {
+	case Reference:
+		byName = d.References
+	case Chain:
+		byName = d.Chains
+	case Workflow:
+		byName = d.Workflows
+	}
+	deprecation, ok := byName[name]
+	return deprecation, ok
+}
+
+// Empty determines whether no components are deprecated
+func (d Deprecations) Empty() bool {
+	return len(d.References) == 0 && len(d.Chains) == 0 && len(d.Workflows) == 0
+}
+
+// DeprecatedComponent is a deprecated registry component used by a test
+type DeprecatedComponent struct {
+	Type        Type
+	Name        string
+	Deprecation api.RegistryDeprecation
+}
+
+// String formats a warning about the usage of the component
+func (c DeprecatedComponent) String() string {
+	message := fmt.Sprintf("%s %q is deprecated", c.Type, c.Name)
+	if c.Deprecation.Reason != "" {
+		message += ": " + strings.TrimSuffix(c.Deprecation.Reason, ".")
+	}
+	if c.Deprecation.Replacement != "" {
+		message += fmt.Sprintf("; use %s %q instead", c.Type, c.Deprecation.Replacement)
+	}
+	if c.Deprecation.Deadline != "" {
+		message += fmt.Sprintf("; it will be removed after %s", c.Deprecation.Deadline)
+	}
+	return message
+}
+
+// DeprecatedComponents returns all deprecated components used by the test,
+// either directly or through its workflow and chains, sorted by type and name.
+func DeprecatedComponents(config api.MultiStageTestConfiguration, chains ChainByName, workflows WorkflowByName, deprecations Deprecations) []DeprecatedComponent {
+	if deprecations.Empty() {
+		return nil
+	}
+	var ret []DeprecatedComponent
+	seen := map[Type]sets.String{Workflow: sets.NewString(), Chain: sets.NewString(), Reference: sets.NewString()}
+	record := func(t Type, name string) {
+		if seen[t].Has(name) {
+			return
+		}
+		seen[t].Insert(name)
+		if deprecation, ok := deprecations.Get(t, name); ok {
+			ret = append(ret, DeprecatedComponent{Type: t, Name: name, Deprecation: deprecation})
+		}
+	}
+	var walk func(steps []api.TestStep)
+	walk = func(steps []api.TestStep) {
+		for _, step := range steps {
+			switch {
+			case step.Reference != nil:
+				record(Reference, *step.Reference)
+			case step.Chain != nil:
+				if seen[Chain].Has(*step.Chain) {
+					continue
+				}
+				record(Chain, *step.Chain)
+				walk(chains[*step.Chain].Steps)
+			}
+		}
+	}
+	pre, test, post := config.Pre, config.Test, config.Post
+	if config.Workflow != nil {
+		record(Workflow, *config.Workflow)
+		workflow := workflows[*config.Workflow]
+		if pre == nil {
+			pre = workflow.Pre
+		}
+		if test == nil {
+			test = workflow.Test
+		}
+		if post == nil {
+			post = workflow.Post
+		}
+	}
+	for _, steps := range [][]api.TestStep{pre, test, post} {
+		walk(steps)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Type != ret[j].Type {
+			return ret[i].Type < ret[j].Type
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// ValidateDeprecations verifies that all deprecations are explained and that
+// their replacements and deadlines are valid.
+func ValidateDeprecations(deprecations Deprecations, stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName) error {
+	var errs []error
+	validate := func(t Type, name string, deprecation api.RegistryDeprecation, exists func(string) bool) {
+		fieldRoot := fmt.Sprintf("%s %q: deprecation", t, name)
+		if deprecation.Reason == "" {
+			errs = append(errs, fmt.Errorf("%s.reason must be set", fieldRoot))
+		}
+		if deprecation.Replacement != "" {
+			if deprecation.Replacement == name {
+				errs = append(errs, fmt.Errorf("%s.replacement cannot be the deprecated %s itself", fieldRoot, t))
+			} else if !exists(deprecation.Replacement) {
+				errs = append(errs, fmt.Errorf("%s.replacement: no %s named %s", fieldRoot, t, deprecation.Replacement))
+			}
+		}
+		if deprecation.Deadline != "" {
+			if _, err := time.Parse(api.RegistryDeprecationDeadlineFormat, deprecation.Deadline); err != nil {
+				errs = append(errs, fmt.Errorf("%s.deadline must be a date in the YYYY-MM-DD format: %w", fieldRoot, err))
+			}
+		}
+	}
+	for name, deprecation := range deprecations.References {
+		validate(Reference, name, deprecation, func(s string) bool { _, ok := stepsByName[s]; return ok })
+	}
+	for name, deprecation := range deprecations.Chains {
+		validate(Chain, name, deprecation, func(s string) bool { _, ok := chainsByName[s]; return ok })
+	}
+	for name, deprecation := range deprecations.Workflows {
+		validate(Workflow, name, deprecation, func(s string) bool { _, ok := workflowsByName[s]; return ok })
+	}
+	return utilerrors.NewAggregate(errs)
+}
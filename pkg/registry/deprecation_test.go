@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestDeprecatedComponents(t *testing.T) {
+	install, oldInstall, gather, chain, workflow := "install", "old-install", "gather", "old-chain", "old-workflow"
+	chains := ChainByName{chain: {As: chain, Steps: []api.TestStep{{Reference: &oldInstall}, {Reference: &gather}}}}
+	workflows := WorkflowByName{workflow: {Pre: []api.TestStep{{Chain: &chain}}, Post: []api.TestStep{{Reference: &gather}}}}
+	deprecations := Deprecations{
+		References: DeprecationByName{oldInstall: {Reason: "It does not support proxies.", Replacement: install, Deadline: "2022-01-31"}},
+		Chains:     DeprecationByName{chain: {Reason: "unmaintained"}},
+		Workflows:  DeprecationByName{workflow: {Reason: "unmaintained", Replacement: "ipi"}},
+	}
+	testCases := []struct {
+		name     string
+		config   api.MultiStageTestConfiguration
+		expected []string
+	}{
+		{
+			name:   "components used through the workflow and chains are found",
+			config: api.MultiStageTestConfiguration{Workflow: &workflow},
+			expected: []string{
+				`workflow "old-workflow" is deprecated: unmaintained; use workflow "ipi" instead`,
+				`chain "old-chain" is deprecated: unmaintained`,
+				`reference "old-install" is deprecated: It does not support proxies; use reference "install" instead; it will be removed after 2022-01-31`,
+			},
+		},
+		{
+			name:     "steps overridden by the test are not used",
+			config:   api.MultiStageTestConfiguration{Workflow: &workflow, Pre: []api.TestStep{{Reference: &install}}},
+			expected: []string{`workflow "old-workflow" is deprecated: unmaintained; use workflow "ipi" instead`},
+		},
+		{
+			name:   "components used multiple times are reported once",
+			config: api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: &oldInstall}, {Chain: &chain}, {Reference: &oldInstall}}},
+			expected: []string{
+				`chain "old-chain" is deprecated: unmaintained`,
+				`reference "old-install" is deprecated: It does not support proxies; use reference "install" instead; it will be removed after 2022-01-31`,
+			},
+		},
+		{
+			name:   "no deprecated components",
+			config: api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: &install}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, component := range DeprecatedComponents(tc.config, chains, workflows, deprecations) {
+				actual = append(actual, component.String())
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("unexpected deprecated components: %s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveRecordsDeprecations(t *testing.T) {
+	install, oldInstall := "install", "old-install"
+	refs := ReferenceByName{
+		install:    {As: install, From: "cli", Commands: "install"},
+		oldInstall: {As: oldInstall, From: "cli", Commands: "install"},
+	}
+	deprecations := Deprecations{References: DeprecationByName{oldInstall: {Reason: "unmaintained", Replacement: install}}}
+	config := api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: &oldInstall}}}
+
+	literal, err := NewResolver(refs, ChainByName{}, WorkflowByName{}, ObserverByName{}, WithDeprecations(deprecations)).Resolve("test", config)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if diff := cmp.Diff([]string{`reference "old-install" is deprecated: unmaintained; use reference "install" instead`}, literal.Deprecations); diff != "" {
+		t.Errorf("unexpected deprecations: %s", diff)
+	}
+
+	literal, err = NewResolver(refs, ChainByName{}, WorkflowByName{}, ObserverByName{}).Resolve("test", config)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if literal.Deprecations != nil {
+		t.Errorf("expected no deprecations without the option, got %v", literal.Deprecations)
+	}
+}
+
+func TestValidateDeprecations(t *testing.T) {
+	refs := ReferenceByName{"install": {}, "old-install": {}}
+	testCases := []struct {
+		name         string
+		deprecations Deprecations
+		expected     error
+	}{
+		{
+			name:         "valid deprecation",
+			deprecations: Deprecations{References: DeprecationByName{"old-install": {Reason: "unmaintained", Replacement: "install", Deadline: "2022-01-31"}}},
+		},
+		{
+			name:         "missing reason",
+			deprecations: Deprecations{References: DeprecationByName{"old-install": {}}},
+			expected:     errors.New(`reference "old-install": deprecation.reason must be set`),
+		},
+		{
+			name:         "unknown replacement",
+			deprecations: Deprecations{References: DeprecationByName{"old-install": {Reason: "unmaintained", Replacement: "new-install"}}},
+			expected:     errors.New(`reference "old-install": deprecation.replacement: no reference named new-install`),
+		},
+		{
+			name:         "self replacement",
+			deprecations: Deprecations{References: DeprecationByName{"old-install": {Reason: "unmaintained", Replacement: "old-install"}}},
+			expected:     errors.New(`reference "old-install": deprecation.replacement cannot be the deprecated reference itself`),
+		},
+		{
+			name:         "invalid deadline",
+			deprecations: Deprecations{References: DeprecationByName{"old-install": {Reason: "unmaintained", Deadline: "31/01/2022"}}},
+			expected:     errors.New(`reference "old-install": deprecation.deadline must be a date in the YYYY-MM-DD format: parsing time "31/01/2022" as "2006-01-02": cannot parse "31/01/2022" as "2006"`),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDeprecations(tc.deprecations, refs, ChainByName{}, WorkflowByName{})
+			if diff := cmp.Diff(tc.expected, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// FieldDiff describes a single difference between two resolved tests. The path
+// identifies the field using dots for object keys; steps in a phase are
+// identified by their name instead of their index, e.g. `pre[ipi-install].commands`
+type FieldDiff struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// TestDiffStatus describes how the resolved configuration of a test changed
+type TestDiffStatus string
+
+const (
+	// TestChanged means the test resolved in both registries, with differences
+	TestChanged TestDiffStatus = "changed"
+	// TestBroken means the test resolved before, but does not resolve anymore
+	TestBroken TestDiffStatus = "broken"
+	// TestFixed means the test did not resolve before, but resolves now
+	TestFixed TestDiffStatus = "fixed"
+)
+
+// TestDiff holds the differences in the resolved configuration of a single test
+type TestDiff struct {
+	Test    string         `json:"test"`
+	Status  TestDiffStatus `json:"status"`
+	Changes []FieldDiff    `json:"changes,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// DiffResolvedTests resolves all multi-stage tests in the configuration with
+// both resolvers and returns the differences for the tests whose resolved
+// configuration is not the same. Tests that are literal in the configuration
+// do not depend on the registry and are therefore skipped.
+func DiffResolvedTests(config api.ReleaseBuildConfiguration, before, after Resolver) ([]TestDiff, error) {
+	var diffs []TestDiff
+	for _, test := range config.Tests {
+		if test.MultiStageTestConfiguration == nil {
+			continue
+		}
+		beforeLiteral, beforeErr := before.Resolve(test.As, *test.MultiStageTestConfiguration)
+		afterLiteral, afterErr := after.Resolve(test.As, *test.MultiStageTestConfiguration)
+		switch {
+		case beforeErr != nil && afterErr != nil:
+			continue
+		case afterErr != nil:
+			diffs = append(diffs, TestDiff{Test: test.As, Status: TestBroken, Error: afterErr.Error()})
+		case beforeErr != nil:
+			diffs = append(diffs, TestDiff{Test: test.As, Status: TestFixed, Error: beforeErr.Error()})
+		default:
+			changes, err := DiffLiteralConfigurations(beforeLiteral, afterLiteral)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare resolved configurations of test %s: %w", test.As, err)
+			}
+			if len(changes) > 0 {
+				diffs = append(diffs, TestDiff{Test: test.As, Status: TestChanged, Changes: changes})
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// DiffLiteralConfigurations returns the differences between two resolved test
+// configurations, sorted by their path
+func DiffLiteralConfigurations(before, after api.MultiStageTestConfigurationLiteral) ([]FieldDiff, error) {
+	beforeValue, err := toGeneric(before)
+	if err != nil {
+		return nil, err
+	}
+	afterValue, err := toGeneric(after)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []FieldDiff
+	diffValues("", beforeValue, afterValue, &diffs)
+	sort.SliceStable(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// toGeneric round-trips a value through JSON so that the comparison follows
+// the serialized form users see in their configuration
+func toGeneric(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal configuration: %w", err)
+	}
+	return generic, nil
+}
+
+func joinPath(base, key string) string {
+	if base == "" {
+		return key
+	}
+	return base + "." + key
+}
+
+func diffValues(path string, before, after interface{}, diffs *[]FieldDiff) {
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			keys := map[string]struct{}{}
+			for k := range b {
+				keys[k] = struct{}{}
+			}
+			for k := range a {
+				keys[k] = struct{}{}
+			}
+			for k := range keys {
+				diffValues(joinPath(path, k), b[k], a[k], diffs)
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			if beforeSteps, afterSteps := namedItems(b), namedItems(a); beforeSteps != nil && afterSteps != nil {
+				diffNamedItems(path, beforeSteps, afterSteps, diffs)
+				return
+			}
+			for i := 0; i < len(b) || i < len(a); i++ {
+				var beforeItem, afterItem interface{}
+				if i < len(b) {
+					beforeItem = b[i]
+				}
+				if i < len(a) {
+					afterItem = a[i]
+				}
+				diffValues(fmt.Sprintf("%s[%d]", path, i), beforeItem, afterItem, diffs)
+			}
+			return
+		}
+	}
+	*diffs = append(*diffs, FieldDiff{Path: path, Before: before, After: after})
+}
+
+type namedItem struct {
+	name  string
+	value interface{}
+}
+
+// namedItems returns the items of a list keyed by their `as` field, or nil if
+// any item is not a uniquely named object. Steps are identified by name so that
+// inserting a step in a phase is not reported as a change to all later steps.
+func namedItems(items []interface{}) []namedItem {
+	if len(items) == 0 {
+		return []namedItem{}
+	}
+	seen := map[string]bool{}
+	var named []namedItem
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		name, ok := object["as"].(string)
+		if !ok || name == "" || seen[name] {
+			return nil
+		}
+		seen[name] = true
+		named = append(named, namedItem{name: name, value: item})
+	}
+	return named
+}
+
+func diffNamedItems(path string, before, after []namedItem, diffs *[]FieldDiff) {
+	beforeByName := map[string]interface{}{}
+	var beforeOrder, afterOrder []string
+	for _, item := range before {
+		beforeByName[item.name] = item.value
+		beforeOrder = append(beforeOrder, item.name)
+	}
+	afterByName := map[string]interface{}{}
+	for _, item := range after {
+		afterByName[item.name] = item.value
+		afterOrder = append(afterOrder, item.name)
+	}
+	for _, item := range before {
+		if _, ok := afterByName[item.name]; !ok {
+			*diffs = append(*diffs, FieldDiff{Path: fmt.Sprintf("%s[%s]", path, item.name), Before: item.value})
+		}
+	}
+	for _, item := range after {
+		itemPath := fmt.Sprintf("%s[%s]", path, item.name)
+		if beforeValue, ok := beforeByName[item.name]; ok {
+			diffValues(itemPath, beforeValue, item.value, diffs)
+		} else {
+			*diffs = append(*diffs, FieldDiff{Path: itemPath, After: item.value})
+		}
+	}
+	if !reflect.DeepEqual(commonOrder(beforeOrder, afterByName), commonOrder(afterOrder, beforeByName)) {
+		*diffs = append(*diffs, FieldDiff{Path: path, Before: beforeOrder, After: afterOrder})
+	}
+}
+
+// commonOrder returns the names that are also present in the other list, in
+// their original order, to detect steps that were reordered
+func commonOrder(names []string, other map[string]interface{}) []string {
+	var kept []string
+	for _, name := range names {
+		if _, ok := other[name]; ok {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// SummarizeTestDiffs renders a short, human-readable Markdown summary of the
+// differences for a set of configurations, keyed by a configuration identifier
+func SummarizeTestDiffs(diffsByConfig map[string][]TestDiff) string {
+	var configs []string
+	for config, diffs := range diffsByConfig {
+		if len(diffs) > 0 {
+			configs = append(configs, config)
+		}
+	}
+	if len(configs) == 0 {
+		return "The resolved configuration of no test changed."
+	}
+	sort.Strings(configs)
+
+	var tests, broken int
+	var b strings.Builder
+	for _, config := range configs {
+		fmt.Fprintf(&b, "\n`%s`:\n", config)
+		for _, diff := range diffsByConfig[config] {
+			tests++
+			switch diff.Status {
+			case TestBroken:
+				broken++
+				fmt.Fprintf(&b, "- `%s`: **no longer resolves**: %s\n", diff.Test, diff.Error)
+			case TestFixed:
+				fmt.Fprintf(&b, "- `%s`: now resolves\n", diff.Test)
+			default:
+				var paths []string
+				for _, change := range diff.Changes {
+					paths = append(paths, "`"+change.Path+"`")
+				}
+				fmt.Fprintf(&b, "- `%s`: %d change(s) in %s\n", diff.Test, len(diff.Changes), strings.Join(paths, ", "))
+			}
+		}
+	}
+	header := fmt.Sprintf("The resolved configuration of %d test(s) in %d configuration(s) changed", tests, len(configs))
+	if broken > 0 {
+		header += fmt.Sprintf(", %d test(s) no longer resolve", broken)
+	}
+	return header + ":\n" + b.String()
+}
@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestDiffLiteralConfigurations(t *testing.T) {
+	base := api.MultiStageTestConfigurationLiteral{
+		ClusterProfile: api.ClusterProfileAWS,
+		Pre: []api.LiteralTestStep{
+			{As: "ipi-conf", From: "cli", Commands: "configure"},
+			{As: "ipi-install", From: "installer", Commands: "install"},
+		},
+		Environment: api.TestEnvironment{"SIZE": "large"},
+	}
+	var testCases = []struct {
+		name     string
+		modify   func(*api.MultiStageTestConfigurationLiteral)
+		expected []FieldDiff
+	}{
+		{
+			name:   "no changes",
+			modify: func(*api.MultiStageTestConfigurationLiteral) {},
+		},
+		{
+			name: "changed commands are identified by step name",
+			modify: func(c *api.MultiStageTestConfigurationLiteral) {
+				c.Pre[1].Commands = "install --verbose"
+			},
+			expected: []FieldDiff{{Path: "pre[ipi-install].commands", Before: "install", After: "install --verbose"}},
+		},
+		{
+			name: "inserted step is not reported as a change to later steps",
+			modify: func(c *api.MultiStageTestConfigurationLiteral) {
+				c.Pre = append([]api.LiteralTestStep{{As: "ipi-bastion", From: "cli"}}, c.Pre...)
+			},
+			expected: []FieldDiff{{Path: "pre[ipi-bastion]", After: map[string]interface{}{"as": "ipi-bastion", "from": "cli", "resources": map[string]interface{}{}}}},
+		},
+		{
+			name: "removed step and environment changes",
+			modify: func(c *api.MultiStageTestConfigurationLiteral) {
+				c.Pre = c.Pre[1:]
+				c.Environment = api.TestEnvironment{"SIZE": "small", "ZONE": "a"}
+			},
+			expected: []FieldDiff{
+				{Path: "env.SIZE", Before: "large", After: "small"},
+				{Path: "env.ZONE", After: "a"},
+				{Path: "pre[ipi-conf]", Before: map[string]interface{}{"as": "ipi-conf", "commands": "configure", "from": "cli", "resources": map[string]interface{}{}}},
+			},
+		},
+		{
+			name: "reordered steps",
+			modify: func(c *api.MultiStageTestConfigurationLiteral) {
+				c.Pre[0], c.Pre[1] = c.Pre[1], c.Pre[0]
+			},
+			expected: []FieldDiff{{Path: "pre", Before: []string{"ipi-conf", "ipi-install"}, After: []string{"ipi-install", "ipi-conf"}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			modified := base
+			modified.Pre = append([]api.LiteralTestStep{}, base.Pre...)
+			tc.modify(&modified)
+			diffs, err := DiffLiteralConfigurations(base, modified)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, diffs); diff != "" {
+				t.Errorf("unexpected diffs: %s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffResolvedTests(t *testing.T) {
+	workflow := "ipi"
+	before := NewResolver(
+		ReferenceByName{"install": {As: "install", From: "cli", Commands: "install"}},
+		ChainByName{},
+		WorkflowByName{"ipi": {Pre: []api.TestStep{{Reference: &[]string{"install"}[0]}}}},
+		ObserverByName{},
+	)
+	after := NewResolver(
+		ReferenceByName{"install": {As: "install", From: "cli", Commands: "install --verbose"}},
+		ChainByName{},
+		WorkflowByName{},
+		ObserverByName{},
+	)
+	config := api.ReleaseBuildConfiguration{
+		Tests: []api.TestStepConfiguration{
+			{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+			{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: &workflow}},
+			{As: "install", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: &[]string{"install"}[0]}}}},
+		},
+	}
+	diffs, err := DiffResolvedTests(config, before, after)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("expected two diffs, got %v", diffs)
+	}
+	if diffs[0].Test != "e2e" || diffs[0].Status != TestBroken || diffs[0].Error == "" {
+		t.Errorf("expected e2e to be broken, got %v", diffs[0])
+	}
+	expected := TestDiff{Test: "install", Status: TestChanged, Changes: []FieldDiff{{Path: "test[install].commands", Before: "install", After: "install --verbose"}}}
+	if diff := cmp.Diff(expected, diffs[1]); diff != "" {
+		t.Errorf("unexpected diff for install: %s", diff)
+	}
+
+	summary := SummarizeTestDiffs(map[string][]TestDiff{"org-repo-master.yaml": diffs, "org-other-master.yaml": nil})
+	expectedSummary := "The resolved configuration of 2 test(s) in 1 configuration(s) changed, 1 test(s) no longer resolve:\n\n`org-repo-master.yaml`:\n" +
+		"- `e2e`: **no longer resolves**: " + diffs[0].Error + "\n" +
+		"- `install`: 1 change(s) in `test[install].commands`\n"
+	if diff := cmp.Diff(expectedSummary, summary); diff != "" {
+		t.Errorf("unexpected summary: %s", diff)
+	}
+}
@@ -23,7 +23,7 @@ type ObserverByName map[string]api.Observer
 // A superset of this validation is performed later when actual test
 // configurations are resolved.
 func Validate(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName) error {
-	reg := registry{stepsByName, chainsByName, workflowsByName, observersByName}
+	reg := registry{stepsByName: stepsByName, chainsByName: chainsByName, workflowsByName: workflowsByName, observersByName: observersByName}
 	var ret []error
 	for k := range chainsByName {
 		if _, err := reg.process([]api.TestStep{{Chain: &k}}, sets.NewString(), stackForChain()); err != nil {
@@ -53,19 +53,36 @@ type registry struct {
 	chainsByName    ChainByName
 	workflowsByName WorkflowByName
 	observersByName ObserverByName
+	deprecations    Deprecations
 }
 
-func NewResolver(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName) Resolver {
-	return &registry{
+// ResolverOption configures optional behavior of the Resolver
+type ResolverOption func(*registry)
+
+// WithDeprecations makes the Resolver record warnings about the deprecated
+// components used by tests in the resolved configuration
+func WithDeprecations(deprecations Deprecations) ResolverOption {
+	return func(r *registry) {
+		r.deprecations = deprecations
+	}
+}
+
+func NewResolver(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName, opts ...ResolverOption) Resolver {
+	r := &registry{
 		stepsByName:     stepsByName,
 		chainsByName:    chainsByName,
 		workflowsByName: workflowsByName,
 		observersByName: observersByName,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *registry) Resolve(name string, config api.MultiStageTestConfiguration) (api.MultiStageTestConfigurationLiteral, error) {
 	var resolveErrors []error
+	deprecated := DeprecatedComponents(config, r.chainsByName, r.workflowsByName, r.deprecations)
 	var overridden [][]api.TestStep
 	if config.Workflow != nil {
 		workflow, ok := r.workflowsByName[*config.Workflow]
@@ -112,6 +129,9 @@ func (r *registry) Resolve(name string, config api.MultiStageTestConfiguration)
 		Leases:                   config.Leases,
 		DependencyOverrides:      config.DependencyOverrides,
 	}
+	for _, component := range deprecated {
+		expandedFlow.Deprecations = append(expandedFlow.Deprecations, component.String())
+	}
 	stack := stackForTest(name, config.Environment, config.Dependencies)
 	if config.Workflow != nil {
 		stack.push(stackRecordForTest("workflow/"+*config.Workflow, nil, nil))
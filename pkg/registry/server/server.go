@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/metrics"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/registry"
 )
 
 const (
@@ -24,12 +29,32 @@ const (
 	InjectFromBranchQuery  = "injectTestFromBranch"
 	InjectFromVariantQuery = "injectTestFromVariant"
 	InjectTestQuery        = "injectTest"
+
+	FromGenerationQuery = "fromGeneration"
+	ToGenerationQuery   = "toGeneration"
+	CandidateQuery      = "candidate"
 )
 
 type Resolver interface {
 	ResolveConfig(config api.ReleaseBuildConfiguration) (api.ReleaseBuildConfiguration, error)
 }
 
+// GenerationalResolver can provide resolvers for recent generations of the registry
+type GenerationalResolver interface {
+	GetGeneration() int
+	GetResolverForGeneration(generation int) (registry.Resolver, error)
+}
+
+// ResolvedConfigDiff is the response of the resolved config diff endpoint
+type ResolvedConfigDiff struct {
+	Metadata       api.Metadata `json:"metadata"`
+	FromGeneration int          `json:"fromGeneration"`
+	// ToGeneration is unset when the configuration was compared with a candidate registry
+	ToGeneration int                 `json:"toGeneration,omitempty"`
+	Candidate    string              `json:"candidate,omitempty"`
+	Tests        []registry.TestDiff `json:"tests"`
+}
+
 type Getter interface {
 	// GetMatchingConfig loads a configuration that matches the metadata,
 	// allowing for regex matching on branch names.
@@ -233,3 +258,116 @@ func ResolveLiteralConfig(resolver Resolver, resolverMetrics *metrics.Metrics) h
 		resolveAndRespond(resolver, unresolvedConfig, w, logger, resolverMetrics)
 	}
 }
+
+func generationFromQuery(r *http.Request, query string, defaultGeneration int) (int, error) {
+	value := r.URL.Query().Get(query)
+	if value == "" {
+		return defaultGeneration, nil
+	}
+	generation, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", query, err)
+	}
+	return generation, nil
+}
+
+// candidateResolver loads a registry from a directory under the candidate root
+func candidateResolver(candidateRoot, candidate string) (registry.Resolver, error) {
+	if candidateRoot == "" {
+		return nil, fmt.Errorf("comparing with a candidate registry is not enabled on this server")
+	}
+	path := filepath.Join(candidateRoot, candidate)
+	if rel, err := filepath.Rel(candidateRoot, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("candidate %s is not in the candidate registry directory", candidate)
+	}
+	references, chains, workflows, _, _, observers, _, err := load.Registry(path, load.RegistryFlag(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load candidate registry: %w", err)
+	}
+	return registry.NewResolver(references, chains, workflows, observers), nil
+}
+
+// ResolveConfigDiff returns a handler that compares the resolved configuration
+// of all multi-stage tests in a ci-operator configuration between two generations
+// of the registry, or between a generation and a candidate registry directory
+// under candidateRoot. The `to` side defaults to the current generation and the
+// `from` side to the one before it.
+func ResolveConfigDiff(configs Getter, resolvers GenerationalResolver, candidateRoot string, resolverMetrics *metrics.Metrics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metadata, err := MetadataFromQuery(w, r)
+		if err != nil {
+			// MetadataFromQuery deals with setting status code and writing response
+			// so we need to just log the error here
+			metrics.RecordError("invalid query", resolverMetrics.ErrorRate)
+			logrus.WithError(err).Warning("failed to read query from request")
+			return
+		}
+		logger := logrus.WithFields(api.LogFieldsFor(metadata))
+
+		current := resolvers.GetGeneration()
+		candidate := r.URL.Query().Get(CandidateQuery)
+		defaultFrom := current - 1
+		if candidate != "" {
+			defaultFrom = current
+		}
+		response := ResolvedConfigDiff{Metadata: metadata, Candidate: candidate}
+		if response.FromGeneration, err = generationFromQuery(r, FromGenerationQuery, defaultFrom); err == nil && candidate == "" {
+			response.ToGeneration, err = generationFromQuery(r, ToGenerationQuery, current)
+		}
+		if err != nil {
+			metrics.RecordError("invalid query", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "invalid generation: %v", err)
+			return
+		}
+
+		config, err := configs.GetMatchingConfig(metadata)
+		if err != nil {
+			metrics.RecordError("config not found", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "failed to get config: %v", err)
+			logger.WithError(err).Warning("failed to get config")
+			return
+		}
+
+		before, err := resolvers.GetResolverForGeneration(response.FromGeneration)
+		if err != nil {
+			metrics.RecordError("registry generation not found", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "failed to get registry: %v", err)
+			return
+		}
+		var after registry.Resolver
+		if candidate != "" {
+			after, err = candidateResolver(candidateRoot, candidate)
+		} else {
+			after, err = resolvers.GetResolverForGeneration(response.ToGeneration)
+		}
+		if err != nil {
+			metrics.RecordError("registry generation not found", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "failed to get registry: %v", err)
+			return
+		}
+
+		if response.Tests, err = registry.DiffResolvedTests(config, before, after); err != nil {
+			metrics.RecordError("failed to compare resolved configs", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to compare resolved configs: %v", err)
+			logger.WithError(err).Error("failed to compare resolved configs")
+			return
+		}
+		jsonDiff, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			metrics.RecordError("failed to marshal config diff", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to marshal config diff to JSON: %v", err)
+			logger.WithError(err).Errorf("failed to marshal config diff to JSON")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(jsonDiff); err != nil {
+			logrus.WithError(err).Error("Failed to write response")
+		}
+	}
+}
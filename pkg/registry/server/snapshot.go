@@ -0,0 +1,226 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/metrics"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/util/gzip"
+)
+
+// SnapshotVersion is the version of the snapshot format produced by this
+// package. It is bumped for every incompatible change in the format.
+const SnapshotVersion = 1
+
+// Snapshot is a self-contained copy of everything the configresolver needs
+// to resolve configurations, as it was at the recorded generations.
+type Snapshot struct {
+	// Version is the version of the snapshot format
+	Version int `json:"version"`
+	// ConfigGeneration is the generation of the ci-operator configuration
+	ConfigGeneration int `json:"configGeneration"`
+	// RegistryGeneration is the generation of the step registry
+	RegistryGeneration int `json:"registryGeneration"`
+
+	Configs    []api.ReleaseBuildConfiguration `json:"configs"`
+	References registry.ReferenceByName        `json:"references"`
+	Chains     registry.ChainByName            `json:"chains"`
+	Workflows  registry.WorkflowByName         `json:"workflows"`
+	Observers  registry.ObserverByName         `json:"observers"`
+
+	Deprecations registry.Deprecations `json:"deprecations,omitempty"`
+}
+
+// ConfigSnapshotter exposes all configurations and their generation
+type ConfigSnapshotter interface {
+	GetAll() config.ByOrgRepo
+	GetGeneration() int
+}
+
+// RegistrySnapshotter exposes all registry components and their generation
+type RegistrySnapshotter interface {
+	GetRegistrySnapshot() (int, registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, registry.ObserverByName, registry.Deprecations)
+}
+
+// snapshotAttempts is how many times we try to get all configurations without
+// them being reloaded in the meantime
+const snapshotAttempts = 3
+
+// NewSnapshot creates a snapshot of the current configurations and registry
+func NewSnapshot(configs ConfigSnapshotter, reg RegistrySnapshotter) (*Snapshot, error) {
+	snapshot := &Snapshot{Version: SnapshotVersion}
+	var all config.ByOrgRepo
+	for i := 0; ; i++ {
+		generation := configs.GetGeneration()
+		all = configs.GetAll()
+		if configs.GetGeneration() == generation {
+			snapshot.ConfigGeneration = generation
+			break
+		}
+		if i == snapshotAttempts-1 {
+			return nil, fmt.Errorf("configuration was reloaded %d times while taking the snapshot", snapshotAttempts)
+		}
+	}
+	for _, repos := range all {
+		for _, configs := range repos {
+			snapshot.Configs = append(snapshot.Configs, configs...)
+		}
+	}
+	sort.Slice(snapshot.Configs, func(i, j int) bool {
+		return snapshot.Configs[i].Metadata.AsString() < snapshot.Configs[j].Metadata.AsString()
+	})
+	snapshot.RegistryGeneration, snapshot.References, snapshot.Chains, snapshot.Workflows, snapshot.Observers, snapshot.Deprecations = reg.GetRegistrySnapshot()
+	return snapshot, nil
+}
+
+// ServeSnapshot returns a handler that serves a snapshot of the current
+// configurations and registry
+func ServeSnapshot(configs ConfigSnapshotter, reg RegistrySnapshotter, resolverMetrics *metrics.Metrics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		snapshot, err := NewSnapshot(configs, reg)
+		if err != nil {
+			metrics.RecordError("failed to create snapshot", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "failed to create snapshot: %v", err)
+			logrus.WithError(err).Warning("failed to create snapshot")
+			return
+		}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			metrics.RecordError("failed to marshal snapshot", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to marshal snapshot to JSON: %v", err)
+			logrus.WithError(err).Error("failed to marshal snapshot to JSON")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			logrus.WithError(err).Error("Failed to write response")
+		}
+	}
+}
+
+// FetchSnapshot downloads a snapshot from the configresolver at the address
+func FetchSnapshot(address string) (*Snapshot, error) {
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = 5
+	retryClient.Logger = adapter{}
+	resp, err := retryClient.StandardClient().Get(fmt.Sprintf("%s/snapshot", address))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request to configresolver: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configresolver response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got unexpected http %d status code from configresolver: %s", resp.StatusCode, string(data))
+	}
+	return ParseSnapshot(data)
+}
+
+// LoadSnapshot reads a snapshot from a file, which may be gzipped
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := gzip.ReadFileMaybeGZIP(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return ParseSnapshot(data)
+}
+
+// ParseSnapshot parses a serialized snapshot, ensuring its format is supported
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, only version %d is supported", snapshot.Version, SnapshotVersion)
+	}
+	return snapshot, nil
+}
+
+// NewSnapshotResolverClient returns a ResolverClient that resolves
+// configurations from the snapshot, without talking to the configresolver
+func NewSnapshotResolverClient(snapshot *Snapshot) ResolverClient {
+	configs := config.ByOrgRepo{}
+	for _, c := range snapshot.Configs {
+		if configs[c.Metadata.Org] == nil {
+			configs[c.Metadata.Org] = map[string][]api.ReleaseBuildConfiguration{}
+		}
+		configs[c.Metadata.Org][c.Metadata.Repo] = append(configs[c.Metadata.Org][c.Metadata.Repo], c)
+	}
+	return &snapshotResolverClient{
+		configs:            configs,
+		resolver:           registry.NewResolver(snapshot.References, snapshot.Chains, snapshot.Workflows, snapshot.Observers, registry.WithDeprecations(snapshot.Deprecations)),
+		configGeneration:   snapshot.ConfigGeneration,
+		registryGeneration: snapshot.RegistryGeneration,
+	}
+}
+
+type snapshotResolverClient struct {
+	configs            config.ByOrgRepo
+	resolver           registry.Resolver
+	configGeneration   int
+	registryGeneration int
+}
+
+func (s *snapshotResolverClient) Config(info *api.Metadata) (*api.ReleaseBuildConfiguration, error) {
+	logrus.Infof("Loading configuration from snapshot (config generation %d, registry generation %d) for %s", s.configGeneration, s.registryGeneration, info.AsString())
+	config, err := s.configs.GetMatchingConfig(*info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+	return s.resolve(config)
+}
+
+func (s *snapshotResolverClient) ConfigWithTest(base *api.Metadata, testSource *api.MetadataWithTest) (*api.ReleaseBuildConfiguration, error) {
+	logrus.Infof("Loading configuration from snapshot (config generation %d, registry generation %d) for %s", s.configGeneration, s.registryGeneration, base.AsString())
+	config, err := s.configs.GetMatchingConfig(*base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+	injectFromConfig, err := s.configs.GetMatchingConfig(testSource.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config to inject from: %w", err)
+	}
+	configWithInjectedTest, err := config.WithPresubmitFrom(&injectFromConfig, testSource.Test)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject test into config: %w", err)
+	}
+	return s.resolve(*configWithInjectedTest)
+}
+
+func (s *snapshotResolverClient) Resolve(raw []byte) (*api.ReleaseBuildConfiguration, error) {
+	unresolvedConfig := api.ReleaseBuildConfiguration{}
+	if err := yaml.UnmarshalStrict(raw, &unresolvedConfig); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal unresolved config: invalid configuration: %w, raw: %v", err, string(raw))
+	}
+	return s.resolve(unresolvedConfig)
+}
+
+func (s *snapshotResolverClient) resolve(config api.ReleaseBuildConfiguration) (*api.ReleaseBuildConfiguration, error) {
+	resolved, err := registry.ResolveConfig(s.resolver, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config with registry: %w", err)
+	}
+	return &resolved, nil
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+type fakeConfigSnapshotter struct {
+	configs     config.ByOrgRepo
+	generations []int
+}
+
+func (f *fakeConfigSnapshotter) GetAll() config.ByOrgRepo { return f.configs }
+
+func (f *fakeConfigSnapshotter) GetGeneration() int {
+	generation := f.generations[0]
+	if len(f.generations) > 1 {
+		f.generations = f.generations[1:]
+	}
+	return generation
+}
+
+type fakeRegistrySnapshotter struct {
+	generation int
+	references registry.ReferenceByName
+	workflows  registry.WorkflowByName
+}
+
+func (f *fakeRegistrySnapshotter) GetRegistrySnapshot() (int, registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, registry.ObserverByName, registry.Deprecations) {
+	return f.generation, f.references, registry.ChainByName{}, f.workflows, registry.ObserverByName{}, registry.Deprecations{}
+}
+
+func TestSnapshotResolverClient(t *testing.T) {
+	workflow := "ipi"
+	install := "install"
+	metadata := api.Metadata{Org: "org", Repo: "repo", Branch: "master"}
+	other := api.Metadata{Org: "org", Repo: "other", Branch: "release-4.10"}
+	configs := &fakeConfigSnapshotter{
+		configs: config.ByOrgRepo{"org": {
+			"repo": {{
+				Metadata: metadata,
+				Tests: []api.TestStepConfiguration{{
+					As:                          "e2e",
+					MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: &workflow},
+				}},
+			}},
+			"other": {{
+				Metadata: other,
+				Tests: []api.TestStepConfiguration{{
+					As:                          "install",
+					MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: &install}}},
+				}},
+			}},
+		}},
+		generations: []int{3, 4, 4, 4},
+	}
+	reg := &fakeRegistrySnapshotter{
+		generation: 7,
+		references: registry.ReferenceByName{"install": {As: "install", From: "cli", Commands: "install"}},
+		workflows:  registry.WorkflowByName{"ipi": {Pre: []api.TestStep{{Reference: &install}}}},
+	}
+
+	snapshot, err := NewSnapshot(configs, reg)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if snapshot.ConfigGeneration != 4 || snapshot.RegistryGeneration != 7 {
+		t.Errorf("expected generations 4 and 7, got %d and %d", snapshot.ConfigGeneration, snapshot.RegistryGeneration)
+	}
+	if diff := cmp.Diff([]string{"org/other@release-4.10", "org/repo@master"}, []string{snapshot.Configs[0].Metadata.AsString(), snapshot.Configs[1].Metadata.AsString()}); diff != "" {
+		t.Errorf("unexpected configs in snapshot: %s", diff)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("failed to marshal snapshot: %v", err)
+	}
+	parsed, err := ParseSnapshot(data)
+	if err != nil {
+		t.Fatalf("failed to parse snapshot: %v", err)
+	}
+	client := NewSnapshotResolverClient(parsed)
+
+	expectedStep := api.LiteralTestStep{As: "install", From: "cli", Commands: "install"}
+	resolved, err := client.Config(&metadata)
+	if err != nil {
+		t.Fatalf("failed to resolve config: %v", err)
+	}
+	literal := resolved.Tests[0].MultiStageTestConfigurationLiteral
+	if literal == nil || len(literal.Pre) != 1 || literal.Pre[0].As != "install" {
+		t.Errorf("expected the workflow to be resolved, got %#v", resolved.Tests[0])
+	}
+
+	withTest, err := client.ConfigWithTest(&metadata, &api.MetadataWithTest{Metadata: other, Test: "install"})
+	if err != nil {
+		t.Fatalf("failed to resolve config with injected test: %v", err)
+	}
+	if len(withTest.Tests) != 1 || withTest.Tests[0].MultiStageTestConfigurationLiteral == nil {
+		t.Fatalf("expected the injected test to be resolved, got %#v", withTest.Tests)
+	}
+	if diff := cmp.Diff(expectedStep, withTest.Tests[0].MultiStageTestConfigurationLiteral.Test[0]); diff != "" {
+		t.Errorf("unexpected injected test: %s", diff)
+	}
+
+	if _, err := client.Config(&api.Metadata{Org: "org", Repo: "missing", Branch: "master"}); err == nil {
+		t.Error("expected an error for a config missing from the snapshot")
+	}
+	if _, err := ParseSnapshot([]byte(`{"version":2}`)); err == nil {
+		t.Error("expected an error for an unsupported snapshot version")
+	}
+}
+
+func TestNewSnapshotReloading(t *testing.T) {
+	configs := &fakeConfigSnapshotter{configs: config.ByOrgRepo{}, generations: []int{1, 2, 3, 4, 5, 6}}
+	if _, err := NewSnapshot(configs, &fakeRegistrySnapshotter{}); err == nil {
+		t.Error("expected an error when the configuration keeps being reloaded")
+	}
+}
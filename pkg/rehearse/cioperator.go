@@ -0,0 +1,170 @@
+package rehearse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/jobconfig"
+)
+
+// JobKind describes which part of ci-operator a job mainly exercises
+type JobKind string
+
+const (
+	JobKindContainer  JobKind = "container test"
+	JobKindMultiStage JobKind = "multi-stage test"
+	JobKindTemplate   JobKind = "template test"
+	// JobKindImages jobs build all images of a configuration that promotes
+	// them. Promotion itself only happens in postsubmits and is not rehearsed.
+	JobKindImages JobKind = "image build"
+	JobKindBundle JobKind = "bundle"
+)
+
+// JobKinds are all kinds of jobs, in the order they are reported in
+var JobKinds = []JobKind{JobKindContainer, JobKindMultiStage, JobKindTemplate, JobKindImages, JobKindBundle}
+
+// jobKindFor determines the kind of the job running the given test of a
+// ci-operator configuration. Jobs building images are classified by what
+// the configuration builds and promotes.
+func jobKindFor(configuration api.ReleaseBuildConfiguration, testname string) (JobKind, bool) {
+	if testname == "images" {
+		switch {
+		case configuration.Operator != nil:
+			return JobKindBundle, true
+		case configuration.PromotionConfiguration != nil && len(configuration.Images) > 0:
+			return JobKindImages, true
+		default:
+			return "", false
+		}
+	}
+	for _, test := range configuration.Tests {
+		if test.As != testname {
+			continue
+		}
+		switch {
+		case test.ContainerTestConfiguration != nil:
+			return JobKindContainer, true
+		case test.MultiStageTestConfiguration != nil, test.MultiStageTestConfigurationLiteral != nil:
+			return JobKindMultiStage, true
+		case test.OpenshiftAnsibleClusterTestConfiguration != nil,
+			test.OpenshiftAnsibleSrcClusterTestConfiguration != nil,
+			test.OpenshiftAnsibleCustomClusterTestConfiguration != nil,
+			test.OpenshiftInstallerClusterTestConfiguration != nil,
+			test.OpenshiftInstallerUPIClusterTestConfiguration != nil,
+			test.OpenshiftInstallerUPISrcClusterTestConfiguration != nil,
+			test.OpenshiftInstallerCustomTestImageClusterTestConfiguration != nil:
+			return JobKindTemplate, true
+		}
+	}
+	return "", false
+}
+
+// SelectRepresentativeJobs selects up to perKind rehearsable ci-operator
+// presubmits of every kind of job. The selection is deterministic and prefers
+// jobs of different repositories, so that a kind is not represented by a
+// single configuration.
+func SelectRepresentativeJobs(presubmits map[string][]prowconfig.Presubmit, ciopConfigs config.DataByFilename, perKind int) (config.Presubmits, map[JobKind][]string) {
+	type candidate struct {
+		orgRepo string
+		job     prowconfig.Presubmit
+	}
+	candidates := map[JobKind][]candidate{}
+	for orgRepo, jobs := range presubmits {
+		splitOrgRepo := strings.Split(orgRepo, "/")
+		if len(splitOrgRepo) != 2 {
+			continue
+		}
+		for _, job := range jobs {
+			if job.Hidden || !hasRehearsableLabel(job.Labels) || job.Spec == nil || len(job.Spec.Containers) == 0 {
+				continue
+			}
+			if command := job.Spec.Containers[0].Command; len(command) == 0 || command[0] != "ci-operator" {
+				continue
+			}
+			metadata := api.Metadata{
+				Org:     splitOrgRepo[0],
+				Repo:    splitOrgRepo[1],
+				Branch:  BranchFromRegexes(job.Branches),
+				Variant: VariantFromLabels(job.Labels),
+			}
+			if metadata.IsComplete() != nil {
+				continue
+			}
+			ciopConfig, ok := ciopConfigs[metadata.Basename()]
+			if !ok {
+				continue
+			}
+			kind, ok := jobKindFor(ciopConfig.Configuration, metadata.TestNameFromJobName(job.Name, jobconfig.PresubmitPrefix))
+			if !ok {
+				continue
+			}
+			candidates[kind] = append(candidates[kind], candidate{orgRepo: orgRepo, job: job})
+		}
+	}
+
+	selected := config.Presubmits{}
+	names := map[JobKind][]string{}
+	for kind, jobs := range candidates {
+		sort.Slice(jobs, func(i, j int) bool {
+			return jobs[i].job.Name < jobs[j].job.Name
+		})
+		chosen := sets.NewString()
+		repos := sets.NewString()
+		// first pick one job per repository, then fill up with the rest
+		for _, distinctRepos := range []bool{true, false} {
+			for _, job := range jobs {
+				if chosen.Len() == perKind {
+					break
+				}
+				if chosen.Has(job.job.Name) || (distinctRepos && repos.Has(job.orgRepo)) {
+					continue
+				}
+				chosen.Insert(job.job.Name)
+				repos.Insert(job.orgRepo)
+				selected.Add(job.orgRepo, job.job, config.GetSourceType(job.job.Labels))
+			}
+		}
+		names[kind] = chosen.List()
+	}
+	return selected, names
+}
+
+// DetermineRepresentativeJobs selects the production jobs to rehearse a change
+// to ci-operator itself with, from a working copy of the release repository
+func (r RehearsalConfig) DetermineRepresentativeJobs(releaseRepoPath string, perKind int, logger *logrus.Entry) (config.Presubmits, map[JobKind][]string, error) {
+	releaseRepoConfig := config.GetAllConfigs(releaseRepoPath, logger)
+	if releaseRepoConfig.Prow == nil || releaseRepoConfig.CiOperator == nil {
+		return nil, nil, fmt.Errorf("could not load Prow and ci-operator configuration from the release repo")
+	}
+	presubmits, names := SelectRepresentativeJobs(releaseRepoConfig.Prow.JobConfig.PresubmitsStatic, releaseRepoConfig.CiOperator, perKind)
+	for _, kind := range JobKinds {
+		logger.WithField("kind", kind).Infof("Selected %d representative jobs", len(names[kind]))
+	}
+	return presubmits, names, nil
+}
+
+// RepresentativeJobsSummary renders the representative jobs as a Markdown list
+func RepresentativeJobsSummary(names map[JobKind][]string) string {
+	var lines []string
+	for _, kind := range JobKinds {
+		jobs := names[kind]
+		if len(jobs) == 0 {
+			lines = append(lines, fmt.Sprintf("- %s: no rehearsable job found", kind))
+			continue
+		}
+		var formatted []string
+		for _, job := range jobs {
+			formatted = append(formatted, fmt.Sprintf("`%s`", job))
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", kind, strings.Join(formatted, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
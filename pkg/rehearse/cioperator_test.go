@@ -0,0 +1,197 @@
+package rehearse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/jobconfig"
+)
+
+func TestJobKindFor(t *testing.T) {
+	tests := []api.TestStepConfiguration{
+		{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+		{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{}},
+		{As: "e2e-literal", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{}},
+		{As: "e2e-upi", OpenshiftInstallerUPIClusterTestConfiguration: &api.OpenshiftInstallerUPIClusterTestConfiguration{}},
+	}
+	var testCases = []struct {
+		name          string
+		configuration api.ReleaseBuildConfiguration
+		testname      string
+		expected      JobKind
+		expectedOK    bool
+	}{
+		{
+			name:          "container test",
+			configuration: api.ReleaseBuildConfiguration{Tests: tests},
+			testname:      "unit",
+			expected:      JobKindContainer,
+			expectedOK:    true,
+		},
+		{
+			name:          "multi-stage test",
+			configuration: api.ReleaseBuildConfiguration{Tests: tests},
+			testname:      "e2e",
+			expected:      JobKindMultiStage,
+			expectedOK:    true,
+		},
+		{
+			name:          "literal multi-stage test",
+			configuration: api.ReleaseBuildConfiguration{Tests: tests},
+			testname:      "e2e-literal",
+			expected:      JobKindMultiStage,
+			expectedOK:    true,
+		},
+		{
+			name:          "template test",
+			configuration: api.ReleaseBuildConfiguration{Tests: tests},
+			testname:      "e2e-upi",
+			expected:      JobKindTemplate,
+			expectedOK:    true,
+		},
+		{
+			name:          "unknown test",
+			configuration: api.ReleaseBuildConfiguration{Tests: tests},
+			testname:      "lint",
+		},
+		{
+			name: "images of a promoting configuration",
+			configuration: api.ReleaseBuildConfiguration{
+				Images:                 []api.ProjectDirectoryImageBuildStepConfiguration{{To: "component"}},
+				PromotionConfiguration: &api.PromotionConfiguration{Namespace: "ocp", Name: "4.10"},
+			},
+			testname:   "images",
+			expected:   JobKindImages,
+			expectedOK: true,
+		},
+		{
+			name: "images of a configuration building bundles",
+			configuration: api.ReleaseBuildConfiguration{
+				Images:                 []api.ProjectDirectoryImageBuildStepConfiguration{{To: "operator"}},
+				Operator:               &api.OperatorStepConfiguration{Bundles: []api.Bundle{{DockerfilePath: "bundle.Dockerfile"}}},
+				PromotionConfiguration: &api.PromotionConfiguration{Namespace: "ocp", Name: "4.10"},
+			},
+			testname:   "images",
+			expected:   JobKindBundle,
+			expectedOK: true,
+		},
+		{
+			name:          "images of a configuration that does not promote",
+			configuration: api.ReleaseBuildConfiguration{Images: []api.ProjectDirectoryImageBuildStepConfiguration{{To: "component"}}},
+			testname:      "images",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, ok := jobKindFor(tc.configuration, tc.testname)
+			if kind != tc.expected || ok != tc.expectedOK {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tc.expected, tc.expectedOK, kind, ok)
+			}
+		})
+	}
+}
+
+func TestSelectRepresentativeJobs(t *testing.T) {
+	ciopConfig := func(org, repo string) config.DataWithInfo {
+		return config.DataWithInfo{
+			Configuration: api.ReleaseBuildConfiguration{
+				Images:                 []api.ProjectDirectoryImageBuildStepConfiguration{{To: "component"}},
+				PromotionConfiguration: &api.PromotionConfiguration{Namespace: "ocp", Name: "4.10"},
+				Tests: []api.TestStepConfiguration{
+					{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+					{As: "lint", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+					{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{}},
+				},
+			},
+			Info: config.Info{Metadata: api.Metadata{Org: org, Repo: repo, Branch: "master"}},
+		}
+	}
+	ciopConfigs := config.DataByFilename{
+		"org-a-master.yaml": ciopConfig("org", "a"),
+		"org-b-master.yaml": ciopConfig("org", "b"),
+	}
+	job := func(org, repo, test string, rehearsable bool) prowconfig.Presubmit {
+		labels := map[string]string{}
+		if rehearsable {
+			labels[jobconfig.CanBeRehearsedLabel] = "true"
+		}
+		return prowconfig.Presubmit{
+			JobBase: prowconfig.JobBase{
+				Name:   "pull-ci-" + org + "-" + repo + "-master-" + test,
+				Labels: labels,
+				Spec:   &v1.PodSpec{Containers: []v1.Container{{Command: []string{"ci-operator"}}}},
+			},
+			Brancher: prowconfig.Brancher{Branches: []string{"^master$"}},
+		}
+	}
+	presubmits := map[string][]prowconfig.Presubmit{
+		"org/a": {job("org", "a", "unit", true), job("org", "a", "lint", true), job("org", "a", "e2e", true), job("org", "a", "images", true)},
+		"org/b": {job("org", "b", "unit", true), job("org", "b", "lint", true), job("org", "b", "e2e", false)},
+		"org/c": {job("org", "c", "unit", true)},
+	}
+
+	selected, names := SelectRepresentativeJobs(presubmits, ciopConfigs, 2)
+	expectedNames := map[JobKind][]string{
+		JobKindContainer:  {"pull-ci-org-a-master-lint", "pull-ci-org-b-master-lint"},
+		JobKindMultiStage: {"pull-ci-org-a-master-e2e"},
+		JobKindImages:     {"pull-ci-org-a-master-images"},
+	}
+	if diff := cmp.Diff(expectedNames, names); diff != "" {
+		t.Errorf("selected names differ from expected: %s", diff)
+	}
+	var selectedNames []string
+	for _, repo := range []string{"org/a", "org/b", "org/c"} {
+		for _, job := range selected[repo] {
+			selectedNames = append(selectedNames, job.Name)
+		}
+	}
+	if len(selectedNames) != 4 {
+		t.Errorf("expected 4 selected presubmits, got %v", selectedNames)
+	}
+}
+
+func TestRepresentativeJobsSummary(t *testing.T) {
+	summary := RepresentativeJobsSummary(map[JobKind][]string{
+		JobKindContainer: {"pull-ci-org-a-master-lint", "pull-ci-org-b-master-lint"},
+		JobKindBundle:    {"pull-ci-org-c-master-images"},
+	})
+	expected := "- container test: `pull-ci-org-a-master-lint`, `pull-ci-org-b-master-lint`\n" +
+		"- multi-stage test: no rehearsable job found\n" +
+		"- template test: no rehearsable job found\n" +
+		"- image build: no rehearsable job found\n" +
+		"- bundle: `pull-ci-org-c-master-images`"
+	if diff := cmp.Diff(expected, summary); diff != "" {
+		t.Errorf("summary differs from expected: %s", diff)
+	}
+}
+
+func TestConfigureJobSpecReplacesCIOperatorImage(t *testing.T) {
+	jc := NewJobConfigurer(config.DataByFilename{}, &prowconfig.Config{}, nil, 123, Loggers{Job: logrus.NewEntry(logrus.New()), Debug: logrus.NewEntry(logrus.New())}, nil, nil, nil)
+	jc.ciOperatorImage = "registry/ci-operator:pr-123"
+	spec := &v1.PodSpec{Containers: []v1.Container{{
+		Image:   "registry/ci-operator:latest",
+		Command: []string{"ci-operator"},
+		Env:     []v1.EnvVar{{Name: "CONFIG_SPEC", Value: "inlined"}},
+	}}}
+	if _, err := jc.configureJobSpec(spec, api.Metadata{}, "unit", logrus.NewEntry(logrus.New())); err != nil {
+		t.Fatalf("failed to configure job spec: %v", err)
+	}
+	if spec.Containers[0].Image != "registry/ci-operator:pr-123" {
+		t.Errorf("expected the ci-operator image to be replaced, got %s", spec.Containers[0].Image)
+	}
+
+	other := &v1.PodSpec{Containers: []v1.Container{{Image: "registry/other:latest", Command: []string{"other"}}}}
+	if _, err := jc.configureJobSpec(other, api.Metadata{}, "unit", logrus.NewEntry(logrus.New())); err != nil {
+		t.Fatalf("failed to configure job spec: %v", err)
+	}
+	if other.Containers[0].Image != "registry/other:latest" {
+		t.Errorf("expected the image of other containers to be kept, got %s", other.Containers[0].Image)
+	}
+}
@@ -129,7 +129,7 @@ func makeRehearsalPresubmit(source *prowconfig.Presubmit, repo string, prNumber
 		return nil, fmt.Errorf("deepCopy failed: %w", err)
 	}
 
-	rehearsal.Name = fmt.Sprintf("rehearse-%d-%s", prNumber, source.Name)
+	rehearsal.Name = rehearsalJobName(prNumber, source.Name)
 
 	var branch string
 	var ghContext string
@@ -187,6 +187,11 @@ func makeRehearsalPresubmit(source *prowconfig.Presubmit, repo string, prNumber
 	return &rehearsal, nil
 }
 
+// rehearsalJobName returns the name of the rehearsal of a job
+func rehearsalJobName(prNumber int, name string) string {
+	return fmt.Sprintf("rehearse-%d-%s", prNumber, name)
+}
+
 // contextFor returns the shortest context we can use to identify this job
 func contextFor(source *prowconfig.Presubmit) string {
 	if source.Context != "" {
@@ -389,6 +394,11 @@ type JobConfigurer struct {
 	prNumber              int
 	refs                  *pjapi.Refs
 	loggers               Loggers
+
+	// workflows are the workflows used by the configured rehearsals, by their name
+	workflows map[string]string
+	// ciOperatorImage replaces the image of ci-operator containers in rehearsals when set
+	ciOperatorImage string
 }
 
 // NewJobConfigurer filters the jobs and returns a new JobConfigurer.
@@ -402,6 +412,23 @@ func NewJobConfigurer(ciopConfigs config.DataByFilename, prowConfig *prowconfig.
 		prNumber:              prNumber,
 		refs:                  refs,
 		loggers:               loggers,
+		workflows:             map[string]string{},
+	}
+}
+
+// recordWorkflow remembers which workflow the rehearsal of a job uses, if any
+func (jc *JobConfigurer) recordWorkflow(rehearsal string, metadata api.Metadata, testname string) {
+	if metadata.IsComplete() != nil {
+		return
+	}
+	ciopConfig, ok := jc.ciopConfigs[metadata.Basename()]
+	if !ok {
+		return
+	}
+	for _, test := range ciopConfig.Configuration.Tests {
+		if test.As == testname && test.MultiStageTestConfiguration != nil && test.MultiStageTestConfiguration.Workflow != nil {
+			jc.workflows[rehearsal] = *test.MultiStageTestConfiguration.Workflow
+		}
 	}
 }
 
@@ -437,6 +464,7 @@ func (jc *JobConfigurer) ConfigurePeriodicRehearsals(periodics config.Periodics)
 			return nil, nil, err
 		}
 		apihelper.MergeImageStreamTagMaps(allImageStreamTags, imageStreamTags)
+		jc.recordWorkflow(rehearsalJobName(jc.prNumber, job.Name), metadata, testname)
 
 		jobLogger.Infof("Imagestream tags required for job %s: %s", job.Name, imageStreamTags.String())
 		jobLogger.WithField(logRehearsalJob, job.Name).Info("Created a rehearsal job to be submitted")
@@ -485,6 +513,7 @@ func (jc *JobConfigurer) ConfigurePresubmitRehearsals(presubmits config.Presubmi
 				return nil, nil, err
 			}
 			apihelper.MergeImageStreamTagMaps(allImageStreamTags, imageStreamTags)
+			jc.recordWorkflow(rehearsal.Name, metadata, testname)
 
 			jobLogger.Infof("Imagestream tags required for job %s: %s", job.Name, imageStreamTags.String())
 			jobLogger.WithField(logRehearsalJob, rehearsal.Name).Info("Created a rehearsal job to be submitted")
@@ -510,6 +539,9 @@ func (jc *JobConfigurer) configureJobSpec(spec *v1.PodSpec, metadata api.Metadat
 	var metadataFromFlags api.Metadata
 	if len(spec.Containers[0].Command) > 0 && spec.Containers[0].Command[0] == "ci-operator" {
 		spec.Containers[0].Args, metadataFromFlags = removeConfigResolverFlags(spec.Containers[0].Args)
+		if jc.ciOperatorImage != "" {
+			spec.Containers[0].Image = jc.ciOperatorImage
+		}
 	}
 
 	// Periodics may not be tied to a specific ci-operator configuration, but
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +22,7 @@ import (
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowconfig "k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/github"
 	prowplugins "k8s.io/test-infra/prow/plugins"
 	pjdwapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -54,6 +54,14 @@ type RehearsalConfig struct {
 	MoreLimit   int
 	MaxLimit    int
 
+	// PassRates are the historical pass rates of jobs, used to prefer reliable
+	// jobs when not all affected jobs can be rehearsed
+	PassRates PassRates
+
+	// CIOperatorImage, when set, replaces the ci-operator image in rehearsals,
+	// so that a build of ci-operator from a pull request can be rehearsed
+	CIOperatorImage string
+
 	DryRun bool
 }
 
@@ -88,6 +96,27 @@ func RehearsalCandidateFromJobSpec(jobSpec *pjdwapi.JobSpec) RehearsalCandidate
 	}
 }
 
+// RehearsalCandidateFromPullRequest creates a candidate from a pull request against
+// the release repository, tested on top of the given revision of its base branch
+func RehearsalCandidateFromPullRequest(pullRequest *github.PullRequest, baseSHA string) RehearsalCandidate {
+	return RehearsalCandidate{
+		org:  pullRequest.Base.Repo.Owner.Login,
+		repo: pullRequest.Base.Repo.Name,
+		base: ref{
+			sha: baseSHA,
+			ref: pullRequest.Base.Ref,
+		},
+		head: ref{
+			sha: pullRequest.Head.SHA,
+			ref: pullRequest.Head.Ref,
+		},
+		prNumber: pullRequest.Number,
+		author:   pullRequest.User.Login,
+		title:    pullRequest.Title,
+		link:     pullRequest.HTMLURL,
+	}
+}
+
 func (rc RehearsalCandidate) createRefs() *pjapi.Refs {
 	return &pjapi.Refs{
 		Org:     rc.org,
@@ -190,12 +219,12 @@ func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, can
 	return presubmits, periodics, changedTemplates, changedClusterProfiles, nil
 }
 
-func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath string, presubmits config.Presubmits, periodics config.Periodics, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, limit int, loggers Loggers) (*config.ReleaseRepoConfig, *pjapi.Refs, apihelper.ImageStreamTagMap, []*prowconfig.Presubmit, error) {
+func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath string, presubmits config.Presubmits, periodics config.Periodics, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, limit int, loggers Loggers) (*config.ReleaseRepoConfig, *pjapi.Refs, apihelper.ImageStreamTagMap, []*prowconfig.Presubmit, Selection, error) {
 	jobLogger := loggers.Job.WithFields(nil)
 
 	resolver, err := r.createResolver(candidatePath)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, Selection{}, err
 	}
 	prConfig := config.GetAllConfigs(candidatePath, jobLogger)
 	org := candidate.org
@@ -204,33 +233,35 @@ func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath s
 	prRefs := candidate.createRefs()
 
 	jobConfigurer := NewJobConfigurer(prConfig.CiOperator, prConfig.Prow, resolver, prNumber, loggers, rehearsalTemplates.Names, rehearsalClusterProfiles.Names, prRefs)
+	jobConfigurer.ciOperatorImage = r.CIOperatorImage
 	imageStreamTags, presubmitsToRehearse, err := jobConfigurer.ConfigurePresubmitRehearsals(presubmits)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, Selection{}, err
 	}
 
 	periodicImageStreamTags, periodicsToRehearse, err := jobConfigurer.ConfigurePeriodicRehearsals(periodics)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, Selection{}, err
 	}
 	apihelper.MergeImageStreamTagMaps(imageStreamTags, periodicImageStreamTags)
 
 	periodicPresubmits, err := jobConfigurer.ConvertPeriodicsToPresubmits(periodicsToRehearse)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, Selection{}, err
 	}
 	presubmitsToRehearse = append(presubmitsToRehearse, periodicPresubmits...)
 
+	var selection Selection
 	if rehearsals := len(presubmitsToRehearse); rehearsals == 0 {
 		jobLogger.Info("no jobs to rehearse have been found")
-		return nil, nil, nil, nil, nil
+		return nil, nil, nil, nil, Selection{}, nil
 	} else if rehearsals > limit {
 		jobCountFields := logrus.Fields{
 			"rehearsal-threshold": limit,
 			"rehearsal-jobs":      rehearsals,
 		}
 		jobLogger.WithFields(jobCountFields).Info("Would rehearse too many jobs, selecting a subset")
-		presubmitsToRehearse = determineSubsetToRehearse(presubmitsToRehearse, limit)
+		presubmitsToRehearse, selection = determineSubsetToRehearse(presubmitsToRehearse, limit, jobConfigurer.workflows, r.PassRates, prNumber)
 	}
 
 	if prConfig.Prow.JobConfig.PresubmitsStatic == nil {
@@ -240,7 +271,7 @@ func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath s
 		prConfig.Prow.JobConfig.PresubmitsStatic[org+"/"+repo] = append(prConfig.Prow.JobConfig.PresubmitsStatic[org+"/"+repo], *presubmit)
 	}
 
-	return prConfig, prRefs, imageStreamTags, presubmitsToRehearse, nil
+	return prConfig, prRefs, imageStreamTags, presubmitsToRehearse, selection, nil
 }
 
 func (r RehearsalConfig) createResolver(candidatePath string) (registry.Resolver, error) {
@@ -250,7 +281,7 @@ func (r RehearsalConfig) createResolver(candidatePath string) (registry.Resolver
 	var observers registry.ObserverByName
 	if !r.NoRegistry {
 		var err error
-		registryRefs, chains, workflows, _, _, observers, err = load.Registry(filepath.Join(candidatePath, config.RegistryPath), load.RegistryFlag(0))
+		registryRefs, chains, workflows, _, _, observers, _, err = load.Registry(filepath.Join(candidatePath, config.RegistryPath), load.RegistryFlag(0))
 		if err != nil {
 			return nil, fmt.Errorf("could not load step registry: %w", err)
 		}
@@ -259,6 +290,38 @@ func (r RehearsalConfig) createResolver(candidatePath string) (registry.Resolver
 	return resolver, nil
 }
 
+// DiffResolvedConfigs compares the resolved multi-stage tests of all ci-operator
+// configurations in the candidate between the step registry at the base revision
+// and the candidate registry, and returns a Markdown summary of the differences.
+func (r RehearsalConfig) DiffResolvedConfigs(candidate RehearsalCandidate, candidatePath string, logger *logrus.Entry) (string, error) {
+	refs, chains, workflows, observers, err := config.GetRegistryFromSHA(candidatePath, candidate.base.sha)
+	if err != nil {
+		return "", fmt.Errorf("could not load step registry from base revision of release repo: %w", err)
+	}
+	baseResolver := registry.NewResolver(refs, chains, workflows, observers)
+	candidateResolver, err := r.createResolver(candidatePath)
+	if err != nil {
+		return "", err
+	}
+	ciopConfigs, err := config.LoadDataByFilename(filepath.Join(candidatePath, config.CiopConfigInRepoPath))
+	if err != nil {
+		return "", fmt.Errorf("could not load ci-operator configs from tested revision of release repo: %w", err)
+	}
+
+	diffsByConfig := map[string][]registry.TestDiff{}
+	for filename, cfg := range ciopConfigs {
+		diffs, err := registry.DiffResolvedTests(cfg.Configuration, baseResolver, candidateResolver)
+		if err != nil {
+			return "", fmt.Errorf("could not compare resolved tests of %s: %w", filename, err)
+		}
+		if len(diffs) > 0 {
+			diffsByConfig[filename] = diffs
+		}
+	}
+	logger.Infof("The resolved configuration of tests in %d ci-operator configs changed", len(diffsByConfig))
+	return registry.SummarizeTestDiffs(diffsByConfig), nil
+}
+
 // RehearseJobs returns true if the jobs were triggered
 func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePath string, prConfig *config.ReleaseRepoConfig, prRefs *pjapi.Refs, imageStreamTags apihelper.ImageStreamTagMap, presubmitsToRehearse []*prowconfig.Presubmit, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, loggers Loggers) (bool, error) {
 	jobLogger := loggers.Job.WithFields(nil)
@@ -325,6 +388,41 @@ func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePat
 	return true, utilerrors.NewAggregate(errs)
 }
 
+// ProwJobClient creates a client for the cluster the ProwJobs of rehearsals live in
+func (r RehearsalConfig) ProwJobClient() (ctrlruntimeclient.Client, error) {
+	var prowJobConfig *rest.Config
+	if !r.DryRun {
+		clusterConfigs, err := r.KubernetesOptions.LoadClusterConfigs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubeconfigs: %w", err)
+		}
+		defaultKubeconfig := clusterConfigs[appCIContextName]
+		prowJobConfig, err = pjKubeconfig(r.ProwjobKubeconfig, &defaultKubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("could not load prowjob kubeconfig: %w", err)
+		}
+	}
+	pjclient, err := NewProwJobClient(prowJobConfig, r.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a ProwJob client: %w", err)
+	}
+	return pjclient, nil
+}
+
+// SummarizeRehearsals compares the failures of the completed rehearsals of the
+// candidate with recent runs of the rehearsed jobs and returns a Markdown summary
+func (r RehearsalConfig) SummarizeRehearsals(candidate RehearsalCandidate, namespace string, fetcher ResultsFetcher, baselineRuns int) (string, error) {
+	pjclient, err := r.ProwJobClient()
+	if err != nil {
+		return "", err
+	}
+	summaries, err := NewSummarizer(pjclient, namespace, fetcher, baselineRuns).Summarize(context.Background(), candidate.prNumber, candidate.head.sha)
+	if err != nil {
+		return "", err
+	}
+	return renderSummaries(summaries), nil
+}
+
 func determineChangedTemplates(candidate, baseSHA, id string, prNumber int, configUpdaterCfg prowplugins.ConfigUpdater, logger *logrus.Entry) (*ConfigMaps, error) {
 	var rehearsalTemplates ConfigMaps
 	changedTemplates, err := config.GetChangedTemplates(candidate, baseSHA)
@@ -346,7 +444,7 @@ func determineChangedTemplates(candidate, baseSHA, id string, prNumber int, conf
 
 func determineChangedRegistrySteps(candidate, baseSHA string, logger *logrus.Entry) ([]registry.Node, error) {
 	var changedRegistrySteps []registry.Node
-	refs, chains, workflows, _, _, observers, err := load.Registry(filepath.Join(candidate, config.RegistryPath), load.RegistryFlag(0))
+	refs, chains, workflows, _, _, observers, _, err := load.Registry(filepath.Join(candidate, config.RegistryPath), load.RegistryFlag(0))
 	if err != nil {
 		return nil, fmt.Errorf("could not load step registry: %w", err)
 	}
@@ -397,45 +495,6 @@ func loadConfigUpdaterCfg(candidate string) (ret prowplugins.ConfigUpdater, err
 	return
 }
 
-// determineSubsetToRehearse determines in a sophisticated way which subset of jobs should be chosen to be rehearsed.
-// First, it will create a list of the jobs mapped by the source type and calculates the maximum allowed jobs for each
-// source type. If there are jobs from a specific source type that are under the max allowed number, it will fill the gap
-// from the other not chosen jobs until it reaches the rehearsal limit.
-func determineSubsetToRehearse(presubmitsToRehearse []*prowconfig.Presubmit, rehearsalLimit int) []*prowconfig.Presubmit {
-	if len(presubmitsToRehearse) <= rehearsalLimit {
-		return presubmitsToRehearse
-	}
-
-	presubmitsBySourceType := make(map[config.SourceType][]*prowconfig.Presubmit)
-	for _, p := range presubmitsToRehearse {
-		sourceType := config.GetSourceType(p.Labels)
-		presubmitsBySourceType[sourceType] = append(presubmitsBySourceType[sourceType], p)
-	}
-
-	maxJobsPerSourceType := rehearsalLimit / len(presubmitsBySourceType)
-	var toRehearse []*prowconfig.Presubmit
-	var dropped []*prowconfig.Presubmit
-
-	for _, jobs := range presubmitsBySourceType {
-		if len(jobs) > maxJobsPerSourceType {
-			dropped = append(dropped, jobs[maxJobsPerSourceType:]...)
-			jobs = jobs[:maxJobsPerSourceType]
-		}
-		toRehearse = append(toRehearse, jobs...)
-	}
-
-	// There are two ways that we will hit this check. First, jobs from a specific resource are less than the
-	// maximum allowed, we will end up having less rehearsals than the limit. Second, summary of the maximum allowed jobs
-	// from each source can be lower than the rehearse limit  due to the rounding inherent in integer division.
-	// In both cases, we fill up the gap from the jobs that we didn't pick earlier.
-	if len(toRehearse) <= rehearsalLimit {
-		sort.Slice(dropped, func(a, b int) bool { return dropped[a].Name < dropped[b].Name })
-		toRehearse = append(toRehearse, dropped[:rehearsalLimit-len(toRehearse)]...)
-	}
-
-	return toRehearse
-}
-
 type cleanup func()
 type cleanups []cleanup
 
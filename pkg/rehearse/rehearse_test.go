@@ -22,6 +22,7 @@ import (
 
 	imagev1 "github.com/openshift/api/image/v1"
 
+	"github.com/openshift/ci-tools/pkg/api"
 	testimagestreamtagimportv1 "github.com/openshift/ci-tools/pkg/api/testimagestreamtagimport/v1"
 	"github.com/openshift/ci-tools/pkg/config"
 )
@@ -168,6 +169,8 @@ func TestDetermineSubsetToRehearse(t *testing.T) {
 		id                   string
 		presubmitsToRehearse []*prowconfig.Presubmit
 		rehearsalLimit       int
+		workflows            map[string]string
+		passRates            PassRates
 		expected             []*prowconfig.Presubmit
 	}{
 		{
@@ -215,10 +218,10 @@ func TestDetermineSubsetToRehearse(t *testing.T) {
 			rehearsalLimit: 5,
 			expected: []*prowconfig.Presubmit{
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-10", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 			},
 		},
 		{
@@ -239,13 +242,13 @@ func TestDetermineSubsetToRehearse(t *testing.T) {
 			expected: []*prowconfig.Presubmit{
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-10", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-9", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent"}}},
 			},
 		},
 		{
-			id: "summary of the maximum allowed jobs per source is lower that the rehearse limit (rounding inherent in integer division)",
+			id: "once all sources are covered, jobs are chosen by name",
 			presubmitsToRehearse: []*prowconfig.Presubmit{
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
@@ -268,10 +271,10 @@ func TestDetermineSubsetToRehearse(t *testing.T) {
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-12", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-6", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-7", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-9", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
 			},
 		},
 		{
@@ -288,11 +291,43 @@ func TestDetermineSubsetToRehearse(t *testing.T) {
 				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
 			},
 		},
+		{
+			id: "jobs covering more dimensions are preferred",
+			presubmitsToRehearse: []*prowconfig.Presubmit{
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "aws"}}, Brancher: prowconfig.Brancher{Branches: []string{"^master$"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "aws"}}, Brancher: prowconfig.Brancher{Branches: []string{"^master$"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "gcp"}}, Brancher: prowconfig.Brancher{Branches: []string{"^master$"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "aws"}}, Brancher: prowconfig.Brancher{Branches: []string{"^release-4.9$"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "aws"}}, Brancher: prowconfig.Brancher{Branches: []string{"^master$"}}},
+			},
+			rehearsalLimit: 3,
+			workflows:      map[string]string{"rehearsal-5": "ipi-aws"},
+			expected: []*prowconfig.Presubmit{
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "gcp"}}, Brancher: prowconfig.Brancher{Branches: []string{"^master$"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "aws"}}, Brancher: prowconfig.Brancher{Branches: []string{"^release-4.9$"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent", api.CloudClusterProfileLabel: "aws"}}, Brancher: prowconfig.Brancher{Branches: []string{"^master$"}}},
+			},
+		},
+		{
+			id: "jobs with higher pass rates are preferred",
+			presubmitsToRehearse: []*prowconfig.Presubmit{
+				{JobBase: prowconfig.JobBase{Name: "rehearse-0-job-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearse-0-job-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearse-0-job-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearse-0-job-4", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+			},
+			rehearsalLimit: 2,
+			passRates:      PassRates{"job-1": 0.2, "job-2": 0.4, "job-3": 0.95},
+			expected: []*prowconfig.Presubmit{
+				{JobBase: prowconfig.JobBase{Name: "rehearse-0-job-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+				{JobBase: prowconfig.JobBase{Name: "rehearse-0-job-4", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.id, func(t *testing.T) {
-			actual := determineSubsetToRehearse(tc.presubmitsToRehearse, tc.rehearsalLimit)
+			actual, _ := determineSubsetToRehearse(tc.presubmitsToRehearse, tc.rehearsalLimit, tc.workflows, tc.passRates, 0)
 			sort.Slice(actual, func(a, b int) bool { return actual[a].Name < actual[b].Name })
 
 			if diff := cmp.Diff(actual, tc.expected, allowUnexported); diff != "" {
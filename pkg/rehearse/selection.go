@@ -0,0 +1,270 @@
+package rehearse
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/util/gzip"
+)
+
+// dimensionKind is a property of a job that a change in the release repository
+// may affect; rehearsing jobs that differ in it exercises the change in more ways
+type dimensionKind string
+
+const (
+	dimensionSource         dimensionKind = "source"
+	dimensionClusterProfile dimensionKind = "cluster profile"
+	dimensionWorkflow       dimensionKind = "workflow"
+	dimensionBranch         dimensionKind = "branch"
+	dimensionArchitecture   dimensionKind = "architecture"
+	dimensionSteps          dimensionKind = "step sequence"
+)
+
+type dimension struct {
+	kind  dimensionKind
+	value string
+}
+
+func (d dimension) String() string {
+	if d.kind == dimensionSteps {
+		// sequences are long and only interesting for their uniqueness
+		return "a unique step sequence"
+	}
+	return fmt.Sprintf("%s `%s`", d.kind, d.value)
+}
+
+// dimensionsFor determines the dimensions of a rehearsal job from its labels and
+// from the ci-operator configuration inlined into it. The workflow is not part
+// of the resolved configuration, so it must be provided by the caller.
+func dimensionsFor(job *prowconfig.Presubmit, workflow string) []dimension {
+	dimensions := []dimension{{kind: dimensionSource, value: string(config.GetSourceType(job.Labels))}}
+	add := func(kind dimensionKind, value string) {
+		if value != "" {
+			dimensions = append(dimensions, dimension{kind: kind, value: value})
+		}
+	}
+	add(dimensionWorkflow, workflow)
+
+	branch := BranchFromRegexes(job.Branches)
+	if branch == "" && len(job.ExtraRefs) > 0 {
+		branch = job.ExtraRefs[0].BaseRef
+	}
+	add(dimensionBranch, branch)
+
+	clusterProfile := job.Labels[api.CloudClusterProfileLabel]
+	architectures := sets.NewString()
+	if job.Spec != nil {
+		if arch, ok := job.Spec.NodeSelector[v1.LabelArchStable]; ok {
+			architectures.Insert(arch)
+		}
+		if cfg := inlinedConfig(job.Spec); cfg != nil {
+			for _, release := range cfg.Releases {
+				switch {
+				case release.Candidate != nil && release.Candidate.Architecture != "":
+					architectures.Insert(string(release.Candidate.Architecture))
+				case release.Prerelease != nil && release.Prerelease.Architecture != "":
+					architectures.Insert(string(release.Prerelease.Architecture))
+				}
+			}
+			for _, test := range cfg.Tests {
+				literal := test.MultiStageTestConfigurationLiteral
+				if literal == nil {
+					continue
+				}
+				if clusterProfile == "" {
+					clusterProfile = string(literal.ClusterProfile)
+				}
+				var steps []string
+				for _, phase := range [][]api.LiteralTestStep{literal.Pre, literal.Test, literal.Post} {
+					var names []string
+					for _, step := range phase {
+						names = append(names, step.As)
+					}
+					steps = append(steps, strings.Join(names, ","))
+				}
+				add(dimensionSteps, strings.Join(steps, ";"))
+			}
+		}
+	}
+	add(dimensionClusterProfile, clusterProfile)
+	if architectures.Len() == 0 {
+		architectures.Insert(string(api.ReleaseArchitectureAMD64))
+	}
+	for _, arch := range architectures.List() {
+		add(dimensionArchitecture, arch)
+	}
+	return dimensions
+}
+
+// inlinedConfig returns the resolved ci-operator configuration inlined into the
+// job by inlineCiOpConfig, if any
+func inlinedConfig(spec *v1.PodSpec) *api.ReleaseBuildConfiguration {
+	if len(spec.Containers) == 0 {
+		return nil
+	}
+	for _, env := range spec.Containers[0].Env {
+		if env.Name != "CONFIG_SPEC" || env.Value == "" {
+			continue
+		}
+		raw := []byte(env.Value)
+		if decoded, err := base64.StdEncoding.DecodeString(env.Value); err == nil {
+			raw = decoded
+		}
+		content, err := gzip.ReadBytesMaybeGZIP(raw)
+		if err != nil {
+			return nil
+		}
+		var cfg api.ReleaseBuildConfiguration
+		if err := yaml.Unmarshal(content, &cfg); err != nil {
+			return nil
+		}
+		return &cfg
+	}
+	return nil
+}
+
+// PassRates maps job names to the fraction of their recent runs that passed
+type PassRates map[string]float64
+
+// LoadPassRates loads pass rates of jobs from a JSON file
+func LoadPassRates(path string) (PassRates, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read pass rates: %w", err)
+	}
+	var rates PassRates
+	if err := json.Unmarshal(raw, &rates); err != nil {
+		return nil, fmt.Errorf("could not unmarshal pass rates: %w", err)
+	}
+	for job, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("pass rate of job %s must be between 0 and 1, got %v", job, rate)
+		}
+	}
+	return rates, nil
+}
+
+// unknownPassRate is assumed for jobs without history, so that they are neither
+// preferred to jobs that pass reliably nor to jobs that fail often
+const unknownPassRate = 0.5
+
+func (r PassRates) of(name string) (float64, bool) {
+	rate, ok := r[name]
+	if !ok {
+		return unknownPassRate, false
+	}
+	return rate, true
+}
+
+// SelectedJob is a job chosen to be rehearsed along with the reasons for it
+type SelectedJob struct {
+	Name string
+	// PassRate is the historical pass rate of the job, if known
+	PassRate *float64
+	// Covers are the dimensions that no job selected earlier covered
+	Covers []string
+}
+
+// Selection describes the jobs chosen to be rehearsed out of all affected ones
+type Selection struct {
+	Affected int
+	Selected []SelectedJob
+}
+
+// Summary renders the selection as Markdown, to be posted on the pull request
+func (s Selection) Summary() string {
+	if len(s.Selected) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d jobs are affected by this change, which is more than can be rehearsed. ", s.Affected)
+	fmt.Fprintf(&b, "The following %d jobs were selected to cover as many distinct cluster profiles, workflows, branches, architectures and step sequences as possible, preferring jobs that usually pass:\n\n", len(s.Selected))
+	b.WriteString("| Job | Pass Rate | Newly Covers |\n| --- | --- | --- |\n")
+	for _, job := range s.Selected {
+		rate := "unknown"
+		if job.PassRate != nil {
+			rate = fmt.Sprintf("%.0f%%", *job.PassRate*100)
+		}
+		covers := "nothing new"
+		if len(job.Covers) > 0 {
+			covers = strings.Join(job.Covers, ", ")
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", job.Name, rate, covers)
+	}
+	return b.String()
+}
+
+// determineSubsetToRehearse selects which jobs should be rehearsed when there are
+// more than the limit. Jobs are chosen greedily: each next job is the one covering
+// the most dimensions no job chosen before covered, with ties broken by preferring
+// jobs with higher historical pass rates so that a failed rehearsal is meaningful.
+func determineSubsetToRehearse(presubmitsToRehearse []*prowconfig.Presubmit, rehearsalLimit int, workflows map[string]string, passRates PassRates, prNumber int) ([]*prowconfig.Presubmit, Selection) {
+	selection := Selection{Affected: len(presubmitsToRehearse)}
+	if len(presubmitsToRehearse) <= rehearsalLimit {
+		return presubmitsToRehearse, selection
+	}
+
+	type candidate struct {
+		job        *prowconfig.Presubmit
+		dimensions []dimension
+		rate       float64
+		known      bool
+	}
+	candidates := make([]candidate, 0, len(presubmitsToRehearse))
+	for _, job := range presubmitsToRehearse {
+		// pass rates are recorded for the jobs being rehearsed, not for the rehearsals
+		rate, known := passRates.of(strings.TrimPrefix(job.Name, rehearsalJobName(prNumber, "")))
+		candidates = append(candidates, candidate{job: job, dimensions: dimensionsFor(job, workflows[job.Name]), rate: rate, known: known})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].job.Name < candidates[j].job.Name })
+
+	covered := map[dimension]bool{}
+	uncovered := func(c candidate) []dimension {
+		var ret []dimension
+		for _, d := range c.dimensions {
+			if !covered[d] {
+				ret = append(ret, d)
+			}
+		}
+		return ret
+	}
+
+	var toRehearse []*prowconfig.Presubmit
+	for len(toRehearse) < rehearsalLimit {
+		best, bestNew := -1, []dimension(nil)
+		for i, c := range candidates {
+			newlyCovered := uncovered(c)
+			if best == -1 || len(newlyCovered) > len(bestNew) || (len(newlyCovered) == len(bestNew) && c.rate > candidates[best].rate) {
+				best, bestNew = i, newlyCovered
+			}
+		}
+		chosen := candidates[best]
+		candidates = append(candidates[:best], candidates[best+1:]...)
+
+		selected := SelectedJob{Name: chosen.job.Name}
+		if chosen.known {
+			rate := chosen.rate
+			selected.PassRate = &rate
+		}
+		for _, d := range bestNew {
+			covered[d] = true
+			selected.Covers = append(selected.Covers, d.String())
+		}
+		selection.Selected = append(selection.Selected, selected)
+		toRehearse = append(toRehearse, chosen.job)
+	}
+
+	return toRehearse, selection
+}
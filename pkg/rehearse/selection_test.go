@@ -0,0 +1,110 @@
+package rehearse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	v1 "k8s.io/api/core/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/util/gzip"
+)
+
+func TestDimensionsFor(t *testing.T) {
+	inlined, err := gzip.CompressStringAndBase64(`releases:
+  latest:
+    candidate:
+      architecture: arm64
+      product: ocp
+      stream: nightly
+      version: "4.9"
+tests:
+- as: e2e
+  literal_steps:
+    cluster_profile: aws-arm64
+    pre:
+    - as: ipi-install
+    test:
+    - as: e2e-test
+    post:
+    - as: gather
+    - as: ipi-deprovision
+`)
+	if err != nil {
+		t.Fatalf("failed to compress config: %v", err)
+	}
+
+	var testCases = []struct {
+		name     string
+		job      *prowconfig.Presubmit
+		workflow string
+		expected []dimension
+	}{
+		{
+			name: "job without inlined configuration",
+			job: &prowconfig.Presubmit{
+				JobBase: prowconfig.JobBase{
+					Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit", "ci-operator.openshift.io/cloud-cluster-profile": "gcp"},
+					Spec:   &v1.PodSpec{Containers: []v1.Container{{}}},
+				},
+				Brancher: prowconfig.Brancher{Branches: []string{"^release-4.9$"}},
+			},
+			expected: []dimension{
+				{kind: dimensionSource, value: "changedPresubmit"},
+				{kind: dimensionBranch, value: "release-4.9"},
+				{kind: dimensionClusterProfile, value: "gcp"},
+				{kind: dimensionArchitecture, value: "amd64"},
+			},
+		},
+		{
+			name: "job with inlined configuration",
+			job: &prowconfig.Presubmit{
+				JobBase: prowconfig.JobBase{
+					Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent"},
+					Spec:   &v1.PodSpec{Containers: []v1.Container{{Env: []v1.EnvVar{{Name: "CONFIG_SPEC", Value: inlined}}}}},
+				},
+				Brancher: prowconfig.Brancher{Branches: []string{"^master$"}},
+			},
+			workflow: "ipi-aws",
+			expected: []dimension{
+				{kind: dimensionSource, value: "changedRegistryContent"},
+				{kind: dimensionWorkflow, value: "ipi-aws"},
+				{kind: dimensionBranch, value: "master"},
+				{kind: dimensionSteps, value: "ipi-install;e2e-test;gather,ipi-deprovision"},
+				{kind: dimensionClusterProfile, value: "aws-arm64"},
+				{kind: dimensionArchitecture, value: "arm64"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, dimensionsFor(tc.job, tc.workflow), cmp.AllowUnexported(dimension{})); diff != "" {
+				t.Errorf("dimensions differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestSelectionSummary(t *testing.T) {
+	rate := 0.925
+	selection := Selection{
+		Affected: 120,
+		Selected: []SelectedJob{
+			{Name: "job-1", PassRate: &rate, Covers: []string{"cluster profile `aws`", "a unique step sequence"}},
+			{Name: "job-2"},
+		},
+	}
+	expected := "120 jobs are affected by this change, which is more than can be rehearsed. " +
+		"The following 2 jobs were selected to cover as many distinct cluster profiles, workflows, branches, architectures and step sequences as possible, preferring jobs that usually pass:\n\n" +
+		"| Job | Pass Rate | Newly Covers |\n| --- | --- | --- |\n" +
+		"| `job-1` | 92% | cluster profile `aws`, a unique step sequence |\n" +
+		"| `job-2` | unknown | nothing new |\n"
+	if diff := cmp.Diff(expected, selection.Summary()); diff != "" {
+		t.Errorf("summary differs from expected: %s", diff)
+	}
+	if summary := (Selection{Affected: 3}).Summary(); summary != "" {
+		t.Errorf("expected no summary when all jobs are rehearsed, got %q", summary)
+	}
+}
@@ -0,0 +1,337 @@
+package rehearse
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/pod-utils/decorate"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// FailureClass tells apart the failures of rehearsals caused by the change under
+// test from those that would have happened regardless.
+type FailureClass string
+
+const (
+	// FailureNew is a test that did not fail in any recent run of the original job
+	FailureNew FailureClass = "new failure"
+	// FailureKnownFlake is a test that also failed in recent runs of the original job
+	FailureKnownFlake FailureClass = "known flake"
+	// FailureInfrastructure is a failure of the infrastructure the rehearsal ran on
+	FailureInfrastructure FailureClass = "infra"
+)
+
+// severity orders the classes, so that a rehearsal is classified by the
+// failure that most likely needs the attention of the author
+func (c FailureClass) severity() int {
+	switch c {
+	case FailureNew:
+		return 2
+	case FailureKnownFlake:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// TestResults are the outcomes of the test cases in a run of a job
+type TestResults struct {
+	// Failed maps the names of failed test cases to the type of their failure
+	Failed map[string]string
+	Passed sets.String
+}
+
+// ResultsFetcher fetches the results of the test cases in a run of a job
+type ResultsFetcher interface {
+	Results(ctx context.Context, pj *pjapi.ProwJob) (*TestResults, error)
+}
+
+type gcsResultsFetcher struct {
+	client *storage.Client
+}
+
+// NewGCSResultsFetcher creates a ResultsFetcher reading the JUnit artifacts
+// uploaded to GCS by the jobs
+func NewGCSResultsFetcher(client *storage.Client) ResultsFetcher {
+	return &gcsResultsFetcher{client: client}
+}
+
+func (f *gcsResultsFetcher) Results(ctx context.Context, pj *pjapi.ProwJob) (*TestResults, error) {
+	bucket, path, err := artifactsPath(pj)
+	if err != nil {
+		return nil, err
+	}
+	ret := &TestResults{Failed: map[string]string{}, Passed: sets.NewString()}
+	objects := f.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: path})
+	for {
+		attrs, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not list artifacts of %s: %w", pj.Name, err)
+		}
+		if !strings.HasSuffix(attrs.Name, ".xml") || !strings.Contains(attrs.Name, "/junit") {
+			continue
+		}
+		reader, err := f.client.Bucket(bucket).Object(attrs.Name).NewReader(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %w", attrs.Name, err)
+		}
+		raw, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", attrs.Name, err)
+		}
+		if err := ret.add(raw); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", attrs.Name, err)
+		}
+	}
+	return ret, nil
+}
+
+// artifactsPath determines where in GCS the artifacts of the job are stored from
+// the link to the job in Spyglass, which has the form .../view/gs/<bucket>/<path>
+func artifactsPath(pj *pjapi.ProwJob) (string, string, error) {
+	const view = "/view/gs/"
+	idx := strings.Index(pj.Status.URL, view)
+	if idx == -1 {
+		return "", "", fmt.Errorf("could not determine the location of artifacts of %s from its URL %q", pj.Name, pj.Status.URL)
+	}
+	parts := strings.SplitN(pj.Status.URL[idx+len(view):], "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("could not determine the location of artifacts of %s from its URL %q", pj.Name, pj.Status.URL)
+	}
+	return parts[0], strings.TrimSuffix(parts[1], "/") + "/artifacts/", nil
+}
+
+// add records the outcomes of test cases in a JUnit file, which may hold one
+// or more test suites
+func (r *TestResults) add(raw []byte) error {
+	suites := &junit.TestSuites{}
+	if err := xml.Unmarshal(raw, suites); err != nil {
+		suite := &junit.TestSuite{}
+		if err := xml.Unmarshal(raw, suite); err != nil {
+			return err
+		}
+		suites.Suites = []*junit.TestSuite{suite}
+	}
+	var walk func(suite *junit.TestSuite)
+	walk = func(suite *junit.TestSuite) {
+		for _, test := range suite.TestCases {
+			switch {
+			case test.FailureOutput != nil:
+				r.Failed[test.Name] = test.FailureOutput.Type
+			case test.SkipMessage == nil:
+				r.Passed.Insert(test.Name)
+			}
+		}
+		for _, child := range suite.Children {
+			walk(child)
+		}
+	}
+	for _, suite := range suites.Suites {
+		walk(suite)
+	}
+	// a test that was retried and passed eventually is not a failure
+	for name := range r.Failed {
+		if r.Passed.Has(name) {
+			delete(r.Failed, name)
+		}
+	}
+	return nil
+}
+
+// FailedTest is a test case that failed in a rehearsal
+type FailedTest struct {
+	Name  string
+	Class FailureClass
+}
+
+// RehearsalSummary describes the outcome of a rehearsal compared to recent runs
+// of the job it rehearsed
+type RehearsalSummary struct {
+	Job   string
+	URL   string
+	State pjapi.ProwJobState
+	// Class is the class of the most severe failure of the rehearsal
+	Class FailureClass
+	// Baselines is how many recent runs of the original job were compared to
+	Baselines int
+	Failed    []FailedTest
+}
+
+// Summarizer compares the results of rehearsals with recent runs of the jobs
+// they rehearse, to tell failures caused by the change under test from flakes.
+type Summarizer struct {
+	pjclient     ctrlruntimeclient.Client
+	namespace    string
+	fetcher      ResultsFetcher
+	baselineRuns int
+}
+
+// NewSummarizer creates a Summarizer comparing rehearsals to at most the given
+// number of runs of the original jobs. Only runs whose ProwJobs were not yet
+// garbage-collected from the cluster are considered.
+func NewSummarizer(pjclient ctrlruntimeclient.Client, namespace string, fetcher ResultsFetcher, baselineRuns int) *Summarizer {
+	return &Summarizer{pjclient: pjclient, namespace: namespace, fetcher: fetcher, baselineRuns: baselineRuns}
+}
+
+// Summarize classifies the failures of the completed rehearsals for a revision of
+// a pull request. Successful rehearsals are not part of the summary.
+func (s *Summarizer) Summarize(ctx context.Context, prNumber int, sha string) ([]RehearsalSummary, error) {
+	rehearsals := &pjapi.ProwJobList{}
+	if err := s.pjclient.List(ctx, rehearsals, ctrlruntimeclient.MatchingLabels{Label: fmt.Sprintf("%d", prNumber)}, ctrlruntimeclient.InNamespace(s.namespace)); err != nil {
+		return nil, fmt.Errorf("could not list rehearsals: %w", err)
+	}
+
+	// a rehearsal may have been retested, only the latest run is interesting
+	latest := map[string]*pjapi.ProwJob{}
+	for i := range rehearsals.Items {
+		pj := &rehearsals.Items[i]
+		if pj.Spec.Refs == nil || len(pj.Spec.Refs.Pulls) == 0 || pj.Spec.Refs.Pulls[0].SHA != sha || !pj.Complete() {
+			continue
+		}
+		if current, ok := latest[pj.Spec.Job]; !ok || current.Status.StartTime.Before(&pj.Status.StartTime) {
+			latest[pj.Spec.Job] = pj
+		}
+	}
+
+	var summaries []RehearsalSummary
+	for _, pj := range latest {
+		if pj.Status.State == pjapi.SuccessState {
+			continue
+		}
+		summary, err := s.summarize(ctx, pj, strings.TrimPrefix(pj.Spec.Job, rehearsalJobName(prNumber, "")))
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Job < summaries[j].Job })
+	return summaries, nil
+}
+
+func (s *Summarizer) summarize(ctx context.Context, rehearsal *pjapi.ProwJob, original string) (RehearsalSummary, error) {
+	summary := RehearsalSummary{Job: original, URL: rehearsal.Status.URL, State: rehearsal.Status.State, Class: FailureInfrastructure}
+	if rehearsal.Status.State != pjapi.FailureState {
+		// aborted or errored rehearsals never got to run the tests
+		return summary, nil
+	}
+	current, err := s.fetcher.Results(ctx, rehearsal)
+	if err != nil {
+		return summary, fmt.Errorf("could not fetch results of rehearsal %s: %w", rehearsal.Name, err)
+	}
+
+	baselines, err := s.baselines(ctx, original, branchOf(rehearsal))
+	if err != nil {
+		return summary, err
+	}
+	summary.Baselines = len(baselines)
+	failedBefore := sets.NewString()
+	for _, baseline := range baselines {
+		previous, err := s.fetcher.Results(ctx, baseline)
+		if err != nil {
+			return summary, fmt.Errorf("could not fetch results of %s: %w", baseline.Name, err)
+		}
+		for name := range previous.Failed {
+			failedBefore.Insert(name)
+		}
+	}
+
+	for name, failureType := range current.Failed {
+		class := FailureNew
+		switch {
+		case results.Classification(failureType) == results.ClassificationInfrastructure:
+			class = FailureInfrastructure
+		case failedBefore.Has(name):
+			class = FailureKnownFlake
+		}
+		summary.Failed = append(summary.Failed, FailedTest{Name: name, Class: class})
+		if class.severity() > summary.Class.severity() {
+			summary.Class = class
+		}
+	}
+	sort.Slice(summary.Failed, func(i, j int) bool { return summary.Failed[i].Name < summary.Failed[j].Name })
+	return summary, nil
+}
+
+// baselines lists the most recent completed runs of the job on the branch
+func (s *Summarizer) baselines(ctx context.Context, job, branch string) ([]*pjapi.ProwJob, error) {
+	// Prow truncates long job names in labels, the full name is checked below
+	labels, _ := decorate.LabelsAndAnnotationsForSpec(pjapi.ProwJobSpec{Job: job}, nil, nil)
+	runs := &pjapi.ProwJobList{}
+	if err := s.pjclient.List(ctx, runs, ctrlruntimeclient.MatchingLabels{kube.ProwJobAnnotation: labels[kube.ProwJobAnnotation]}, ctrlruntimeclient.InNamespace(s.namespace)); err != nil {
+		return nil, fmt.Errorf("could not list runs of %s: %w", job, err)
+	}
+	var baselines []*pjapi.ProwJob
+	for i := range runs.Items {
+		run := &runs.Items[i]
+		if run.Spec.Job != job || branchOf(run) != branch {
+			continue
+		}
+		if run.Status.State != pjapi.SuccessState && run.Status.State != pjapi.FailureState {
+			continue
+		}
+		baselines = append(baselines, run)
+	}
+	sort.Slice(baselines, func(i, j int) bool { return baselines[j].Status.StartTime.Before(&baselines[i].Status.StartTime) })
+	if len(baselines) > s.baselineRuns {
+		baselines = baselines[:s.baselineRuns]
+	}
+	return baselines, nil
+}
+
+// branchOf determines the branch of the repository a job tests. Rehearsals test
+// the release repository, so the repository they rehearse is in the extra refs.
+func branchOf(pj *pjapi.ProwJob) string {
+	if _, isRehearsal := pj.Labels[Label]; !isRehearsal && pj.Spec.Refs != nil {
+		return pj.Spec.Refs.BaseRef
+	}
+	if len(pj.Spec.ExtraRefs) > 0 {
+		return pj.Spec.ExtraRefs[0].BaseRef
+	}
+	return ""
+}
+
+// renderSummaries renders the summaries of rehearsals as Markdown, to be posted
+// on the pull request
+func renderSummaries(summaries []RehearsalSummary) string {
+	if len(summaries) == 0 {
+		return "All rehearsals passed."
+	}
+	var b strings.Builder
+	b.WriteString("| Rehearsal | Result | Failed Tests |\n| --- | --- | --- |\n")
+	for _, summary := range summaries {
+		var tests []string
+		for _, test := range summary.Failed {
+			tests = append(tests, fmt.Sprintf("`%s` (%s)", test.Name, test.Class))
+		}
+		details := strings.Join(tests, "<br>")
+		if details == "" {
+			details = fmt.Sprintf("job ended in state `%s`", summary.State)
+		}
+		job := fmt.Sprintf("`%s`", summary.Job)
+		if summary.URL != "" {
+			job = fmt.Sprintf("[%s](%s)", job, summary.URL)
+		}
+		fmt.Fprintf(&b, "| %s | **%s** | %s |\n", job, summary.Class, details)
+	}
+	b.WriteString("\nA failure is **new** when the test did not fail in recent runs of the original job on the same branch, ")
+	b.WriteString("a **known flake** when it did, and **infra** when the infrastructure failed.\n")
+	return b.String()
+}
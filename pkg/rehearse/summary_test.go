@@ -0,0 +1,200 @@
+package rehearse
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestArtifactsPath(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		url            string
+		expectedBucket string
+		expectedPath   string
+		expectedErr    bool
+	}{
+		{
+			name:           "Spyglass link",
+			url:            "https://prow.ci.openshift.org/view/gs/origin-ci-test/pr-logs/pull/openshift_release/123/rehearse-123-job/456",
+			expectedBucket: "origin-ci-test",
+			expectedPath:   "pr-logs/pull/openshift_release/123/rehearse-123-job/456/artifacts/",
+		},
+		{
+			name:        "not a Spyglass link",
+			url:         "https://example.com/job/456",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket, path, err := artifactsPath(&pjapi.ProwJob{Status: pjapi.ProwJobStatus{URL: tc.url}})
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if bucket != tc.expectedBucket || path != tc.expectedPath {
+				t.Errorf("expected %s/%s, got %s/%s", tc.expectedBucket, tc.expectedPath, bucket, path)
+			}
+		})
+	}
+}
+
+func TestTestResultsAdd(t *testing.T) {
+	results := &TestResults{Failed: map[string]string{}, Passed: sets.NewString()}
+	for _, raw := range []string{
+		`<testsuites><testsuite name="a"><testcase name="passes"/><testcase name="fails"><failure type="test">oops</failure></testcase><testsuite name="nested"><testcase name="infra"><failure type="infrastructure">no quota</failure></testcase></testsuite></testsuite></testsuites>`,
+		`<testsuite name="b"><testcase name="retried"><failure>first</failure></testcase><testcase name="retried"/><testcase name="skipped"><skipped/></testcase></testsuite>`,
+	} {
+		if err := results.add([]byte(raw)); err != nil {
+			t.Fatalf("failed to add results: %v", err)
+		}
+	}
+	expected := &TestResults{
+		Failed: map[string]string{"fails": "test", "infra": "infrastructure"},
+		Passed: sets.NewString("passes", "retried"),
+	}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("results differ from expected: %s", diff)
+	}
+}
+
+type fakeResultsFetcher map[string]*TestResults
+
+func (f fakeResultsFetcher) Results(_ context.Context, pj *pjapi.ProwJob) (*TestResults, error) {
+	results, ok := f[pj.Name]
+	if !ok {
+		return nil, fmt.Errorf("no results for %s", pj.Name)
+	}
+	return results, nil
+}
+
+func failed(names ...string) *TestResults {
+	ret := &TestResults{Failed: map[string]string{}, Passed: sets.NewString()}
+	for _, name := range names {
+		ret.Failed[name] = ""
+	}
+	return ret
+}
+
+func TestSummarize(t *testing.T) {
+	start := metav1.NewTime(time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC))
+	completion := metav1.NewTime(start.Add(time.Hour))
+	rehearsal := func(name, job, sha string, state pjapi.ProwJobState, started time.Duration) ctrlruntimeclient.Object {
+		return &pjapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ci", Labels: map[string]string{Label: "123"}},
+			Spec: pjapi.ProwJobSpec{
+				Job:       "rehearse-123-" + job,
+				Refs:      &pjapi.Refs{Org: "openshift", Repo: "release", BaseRef: "master", Pulls: []pjapi.Pull{{Number: 123, SHA: sha}}},
+				ExtraRefs: []pjapi.Refs{{Org: "org", Repo: "repo", BaseRef: "release-4.9"}},
+			},
+			Status: pjapi.ProwJobStatus{State: state, StartTime: metav1.NewTime(start.Add(started)), CompletionTime: &completion, URL: "https://prow/view/gs/bucket/" + name},
+		}
+	}
+	run := func(name, job, branch string, state pjapi.ProwJobState, started time.Duration) ctrlruntimeclient.Object {
+		return &pjapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ci", Labels: map[string]string{kube.ProwJobAnnotation: job}},
+			Spec:       pjapi.ProwJobSpec{Job: job, Refs: &pjapi.Refs{Org: "org", Repo: "repo", BaseRef: branch}},
+			Status:     pjapi.ProwJobStatus{State: state, StartTime: metav1.NewTime(start.Add(started)), CompletionTime: &completion},
+		}
+	}
+
+	client := fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		rehearsal("old-sha", "pull-ci-org-repo-release-4.9-e2e", "old", pjapi.FailureState, 0),
+		rehearsal("retested", "pull-ci-org-repo-release-4.9-e2e", "sha", pjapi.FailureState, 0),
+		rehearsal("e2e", "pull-ci-org-repo-release-4.9-e2e", "sha", pjapi.FailureState, time.Minute),
+		rehearsal("unit", "pull-ci-org-repo-release-4.9-unit", "sha", pjapi.SuccessState, 0),
+		rehearsal("aborted", "pull-ci-org-repo-release-4.9-images", "sha", pjapi.AbortedState, 0),
+		rehearsal("infra", "pull-ci-org-repo-release-4.9-e2e-aws", "sha", pjapi.FailureState, 0),
+		run("baseline-1", "pull-ci-org-repo-release-4.9-e2e", "release-4.9", pjapi.FailureState, 3*time.Minute),
+		run("baseline-2", "pull-ci-org-repo-release-4.9-e2e", "release-4.9", pjapi.FailureState, 2*time.Minute),
+		run("too-old", "pull-ci-org-repo-release-4.9-e2e", "release-4.9", pjapi.FailureState, time.Minute),
+		run("other-branch", "pull-ci-org-repo-release-4.9-e2e", "master", pjapi.FailureState, 4*time.Minute),
+		run("pending", "pull-ci-org-repo-release-4.9-e2e", "release-4.9", pjapi.PendingState, 5*time.Minute),
+	).Build()
+	fetcher := fakeResultsFetcher{
+		"e2e":        failed("flaky", "broken", "infra"),
+		"infra":      {Failed: map[string]string{"install": "infrastructure"}, Passed: sets.NewString()},
+		"baseline-1": failed("flaky"),
+		"baseline-2": failed(),
+		"too-old":    failed("broken"),
+	}
+	fetcher["e2e"].Failed["infra"] = "infrastructure"
+
+	summaries, err := NewSummarizer(client, "ci", fetcher, 2).Summarize(context.Background(), 123, "sha")
+	if err != nil {
+		t.Fatalf("failed to summarize: %v", err)
+	}
+	expected := []RehearsalSummary{
+		{
+			Job:       "pull-ci-org-repo-release-4.9-e2e",
+			URL:       "https://prow/view/gs/bucket/e2e",
+			State:     pjapi.FailureState,
+			Class:     FailureNew,
+			Baselines: 2,
+			Failed: []FailedTest{
+				{Name: "broken", Class: FailureNew},
+				{Name: "flaky", Class: FailureKnownFlake},
+				{Name: "infra", Class: FailureInfrastructure},
+			},
+		},
+		{
+			Job:       "pull-ci-org-repo-release-4.9-e2e-aws",
+			URL:       "https://prow/view/gs/bucket/infra",
+			State:     pjapi.FailureState,
+			Class:     FailureInfrastructure,
+			Baselines: 0,
+			Failed:    []FailedTest{{Name: "install", Class: FailureInfrastructure}},
+		},
+		{
+			Job:   "pull-ci-org-repo-release-4.9-images",
+			URL:   "https://prow/view/gs/bucket/aborted",
+			State: pjapi.AbortedState,
+			Class: FailureInfrastructure,
+		},
+	}
+	if diff := cmp.Diff(expected, summaries); diff != "" {
+		t.Errorf("summaries differ from expected: %s", diff)
+	}
+}
+
+func TestRenderSummaries(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		summaries []RehearsalSummary
+		expected  string
+	}{
+		{
+			name:     "no failures",
+			expected: "All rehearsals passed.",
+		},
+		{
+			name: "failures",
+			summaries: []RehearsalSummary{
+				{Job: "e2e", URL: "https://prow/e2e", State: pjapi.FailureState, Class: FailureNew, Failed: []FailedTest{{Name: "broken", Class: FailureNew}, {Name: "flaky", Class: FailureKnownFlake}}},
+				{Job: "images", State: pjapi.AbortedState, Class: FailureInfrastructure},
+			},
+			expected: "| Rehearsal | Result | Failed Tests |\n| --- | --- | --- |\n" +
+				"| [`e2e`](https://prow/e2e) | **new failure** | `broken` (new failure)<br>`flaky` (known flake) |\n" +
+				"| `images` | **infra** | job ended in state `aborted` |\n" +
+				"\nA failure is **new** when the test did not fail in recent runs of the original job on the same branch, " +
+				"a **known flake** when it did, and **infra** when the infrastructure failed.\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, renderSummaries(tc.summaries)); diff != "" {
+				t.Errorf("rendered summaries differ from expected: %s", diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,10 @@ package results
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
 )
 
 // Error holds a message and a child, allowing for an error
@@ -15,6 +19,8 @@ type Error struct {
 	reason  Reason
 	message string
 	wrapped error
+	// step is the name of the step that failed, if known
+	step string
 }
 
 // Error makes an Error an error
@@ -39,21 +45,78 @@ func (e *Error) Is(target error) bool {
 // errors — are recursively expanded, generating a separate chain for each
 // child.
 func Reasons(errs ...error) (ret []string) {
+	for _, failure := range Failures(errs...) {
+		ret = append(ret, failure.Reason)
+	}
+	return
+}
+
+// Failure is a single chain of error reasons, with the step that failed, if known
+type Failure struct {
+	// Reason is the chain of error reasons, divided by colons
+	Reason string
+	// Step is the name of the innermost step in the chain that was recorded to fail
+	Step string
+	// Classification tells apart failures of the infrastructure from test and
+	// configuration failures
+	Classification Classification
+}
+
+// Failures provides the chains of error reasons, like Reasons, along with the
+// steps that failed and the classification of the failures.
+func Failures(errs ...error) []Failure {
+	failures := failures(errs...)
+	for i := range failures {
+		failures[i].Classification = Classify(failures[i].Reason)
+	}
+	return failures
+}
+
+// ClassificationOf determines the classification of all failures in the errors:
+// the first test or configuration failure determines the classification, as
+// neither goes away on a retry, while only infrastructure failures make for an
+// infrastructure failure.
+func ClassificationOf(errs ...error) Classification {
+	classification := ClassificationUnknown
+	for i, failure := range Failures(errs...) {
+		switch {
+		case failure.Classification == ClassificationTest, failure.Classification == ClassificationConfiguration:
+			return failure.Classification
+		case i == 0:
+			classification = failure.Classification
+		case failure.Classification != classification:
+			classification = ClassificationUnknown
+		}
+	}
+	return classification
+}
+
+func failures(errs ...error) (ret []Failure) {
 	for _, err := range errs {
 		switch err := err.(type) {
 		case *Error:
-			children := Reasons(err.Unwrap())
+			children := failures(err.Unwrap())
 			if len(children) == 0 {
-				ret = append(ret, string(err.reason))
+				reason := string(err.reason)
+				// errors from the API are common enough at the bottom of a chain
+				// that we classify them even when they were not given a reason
+				if classified, ok := reasonFor(err.Unwrap()); ok && err.reason.Classification() == ClassificationUnknown {
+					reason = fmt.Sprintf("%s:%s", reason, classified)
+				}
+				ret = append(ret, Failure{Reason: reason, Step: err.step})
 				break
 			}
-			for _, r := range children {
-				ret = append(ret, fmt.Sprintf("%s:%s", err.reason, r))
+			for _, child := range children {
+				step := child.Step
+				if step == "" {
+					step = err.step
+				}
+				ret = append(ret, Failure{Reason: fmt.Sprintf("%s:%s", err.reason, child.Reason), Step: step})
 			}
 		case interface{ Errors() []error }:
-			ret = append(ret, Reasons(err.Errors()...)...)
+			ret = append(ret, failures(err.Errors()...)...)
 		case interface{ Unwrap() error }:
-			ret = append(ret, Reasons(err.Unwrap())...)
+			ret = append(ret, failures(err.Unwrap())...)
 		}
 	}
 	return
@@ -79,6 +142,13 @@ func ForReason(reason Reason) *BuilderWithReason {
 	}
 }
 
+// ForStep records the step that failed on the Error, so that failures
+// can be aggregated by the step in which they occur.
+func (e *BuilderWithReason) ForStep(step string) *BuilderWithReason {
+	e.step = step
+	return e
+}
+
 // BuilderWithReasonAndError adds a child error to the builder
 type BuilderWithReasonAndError struct {
 	Error
@@ -118,6 +188,7 @@ func (e *BuilderWithReason) ForError(err error) error {
 
 // DefaultReason is a constructor that adds a reason if needed, when we
 // want to ensure that consumers downstream of a callsite have an Error.
+// Errors from the API are classified, others get the unknown reason.
 //
 // annotated := DefaultReason(doSomething())
 func DefaultReason(err error) error {
@@ -125,5 +196,26 @@ func DefaultReason(err error) error {
 		return err
 	}
 
-	return ForReason(ReasonUnknown).ForError(err)
+	reason, ok := reasonFor(err)
+	if !ok {
+		reason = ReasonUnknown
+	}
+	return ForReason(reason).ForError(err)
+}
+
+// reasonFor classifies Kubernetes and OpenShift API errors by their status
+func reasonFor(err error) (Reason, bool) {
+	if err == nil {
+		return "", false
+	}
+	switch {
+	case kerrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota"):
+		return ReasonQuotaExceeded, true
+	case kerrors.IsServerTimeout(err), kerrors.IsTimeout(err), kerrors.IsServiceUnavailable(err),
+		kerrors.IsTooManyRequests(err), kerrors.IsInternalError(err),
+		utilnet.IsConnectionRefused(err), utilnet.IsProbableEOF(err):
+		return ReasonAPIUnavailable, true
+	default:
+		return "", false
+	}
 }
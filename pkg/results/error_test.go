@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"testing"
 
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"github.com/openshift/ci-tools/pkg/testhelper"
@@ -170,3 +172,136 @@ func TestReasons(t *testing.T) {
 		})
 	}
 }
+
+func TestFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected []Failure
+	}{{
+		name: "regular error",
+		err:  errors.New("regular"),
+	}, {
+		name:     "reason without step",
+		err:      ForReason("reason").ForError(errors.New("error")),
+		expected: []Failure{{Reason: "reason", Classification: ClassificationUnknown}},
+	}, {
+		name:     "step recorded on the outer error",
+		err:      ForReason("step_failed").ForStep("outer").WithError(ForReason("inner").ForError(errors.New("error"))).Errorf("msg"),
+		expected: []Failure{{Reason: "step_failed:inner", Step: "outer", Classification: ClassificationUnknown}},
+	}, {
+		name: "innermost step wins",
+		err: ForReason("step_failed").ForStep("outer").WithError(
+			ForReason("step_failed").ForStep("inner").ForError(errors.New("error")),
+		).Errorf("msg"),
+		expected: []Failure{{Reason: "step_failed:step_failed", Step: "inner", Classification: ClassificationUnknown}},
+	}, {
+		name: "aggregate of steps",
+		err: utilerrors.NewAggregate([]error{
+			ForReason("step_failed").ForStep("one").ForError(errors.New("error")),
+			ForReason("step_failed").ForStep("two").ForError(errors.New("error")),
+		}),
+		expected: []Failure{{Reason: "step_failed", Step: "one", Classification: ClassificationUnknown}, {Reason: "step_failed", Step: "two", Classification: ClassificationUnknown}},
+	}, {
+		name:     "classified reason",
+		err:      ForReason("step_failed").ForStep("unit").WithError(ForReason(ReasonTestFailure).ForError(errors.New("error"))).Errorf("msg"),
+		expected: []Failure{{Reason: "step_failed:test/failure", Step: "unit", Classification: ClassificationTest}},
+	}, {
+		name:     "API error at the bottom of the chain is classified",
+		err:      ForReason("creating_pod").ForError(kerrors.NewServiceUnavailable("down")),
+		expected: []Failure{{Reason: "creating_pod:infrastructure/api_unavailable", Classification: ClassificationInfrastructure}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			testhelper.Diff(t, "failures", Failures(tc.err), tc.expected)
+		})
+	}
+}
+
+func TestDefaultReasonClassifiesAPIErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected []string
+	}{{
+		name:     "unknown error",
+		err:      errors.New("oops"),
+		expected: []string{"unknown"},
+	}, {
+		name:     "quota exceeded",
+		err:      kerrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "pod", errors.New("exceeded quota: compute-resources")),
+		expected: []string{"infrastructure/quota_exceeded"},
+	}, {
+		name:     "forbidden without a quota",
+		err:      kerrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "pod", errors.New("no")),
+		expected: []string{"unknown"},
+	}, {
+		name:     "server timeout",
+		err:      fmt.Errorf("could not get pod: %w", kerrors.NewServerTimeout(schema.GroupResource{Resource: "pods"}, "get", 1)),
+		expected: []string{"infrastructure/api_unavailable"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			testhelper.Diff(t, "reasons", Reasons(DefaultReason(tc.err)), tc.expected)
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		chain    string
+		expected Classification
+	}{
+		{chain: "unknown", expected: ClassificationUnknown},
+		{chain: "step_failed:running_pod", expected: ClassificationUnknown},
+		{chain: "step_failed:running_pod:infrastructure/pod_evicted", expected: ClassificationInfrastructure},
+		{chain: "step_failed:test/failure", expected: ClassificationTest},
+		{chain: "infrastructure:test/failure", expected: ClassificationTest},
+		{chain: "step_failed:running_pod:configuration/invalid_image_name", expected: ClassificationConfiguration},
+	} {
+		t.Run(tc.chain, func(t *testing.T) {
+			if actual := Classify(tc.chain); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReasonParent(t *testing.T) {
+	if parent, ok := ReasonImagePull.Parent(); !ok || parent != ReasonInfrastructure {
+		t.Errorf("expected parent %q, got %q (ok: %v)", ReasonInfrastructure, parent, ok)
+	}
+	if _, ok := ReasonInfrastructure.Parent(); ok {
+		t.Errorf("expected %q to have no parent", ReasonInfrastructure)
+	}
+}
+
+func TestClassificationOf(t *testing.T) {
+	infra := ForReason(ReasonPodEvicted).ForError(errors.New("evicted"))
+	test := ForReason(ReasonTestFailure).ForError(errors.New("failed"))
+	unknown := ForReason("oops").ForError(errors.New("oops"))
+	for _, tc := range []struct {
+		name     string
+		errs     []error
+		expected Classification
+	}{{
+		name:     "no errors",
+		expected: ClassificationUnknown,
+	}, {
+		name:     "only infrastructure failures",
+		errs:     []error{infra, infra},
+		expected: ClassificationInfrastructure,
+	}, {
+		name:     "any test failure",
+		errs:     []error{infra, unknown, test},
+		expected: ClassificationTest,
+	}, {
+		name:     "infrastructure and unknown failures",
+		errs:     []error{infra, unknown},
+		expected: ClassificationUnknown,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := ClassificationOf(tc.errs...); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -93,6 +94,35 @@ type Request struct {
 	State string `json:"state"`
 	// Reason is a colon-delimited list of reasons for failure
 	Reason string `json:"reason"`
+	// Org, Repo and Branch identify the ci-operator configuration the job ran for
+	Org    string `json:"org,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	// Step is the step that failed, if known
+	Step string `json:"step,omitempty"`
+	// ProwJobID identifies the execution of the job
+	ProwJobID string `json:"prowjob_id,omitempty"`
+	// Classification tells apart failures of the infrastructure, which
+	// are worth retrying, from test and configuration failures
+	Classification string `json:"classification,omitempty"`
+}
+
+// CostRequest holds the resources consumed by a step of a job, used to report
+// them to an aggregation server for accounting
+type CostRequest struct {
+	// JobName is the name of the job that ran the step
+	JobName string `json:"job_name"`
+	// Type is the type of job ("presubmit", "postsubmit", "periodic" or "batch")
+	Type string `json:"type"`
+	// Cluster is the cluster's console hostname
+	Cluster string `json:"cluster"`
+	// Org, Repo and Branch identify the ci-operator configuration the job ran for
+	Org    string `json:"org,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	// Step is the step that consumed the resources
+	Step string `json:"step"`
+	api.CIOperatorStepCost
 }
 
 // PodScalerRequest holds the data from pod-scaler used to report a result to an aggregation server
@@ -102,6 +132,18 @@ type PodScalerRequest struct {
 	DeterminedMemory string
 }
 
+// PodScalerDownscalingRequest holds the data from pod-scaler about a reduction of
+// a resource request, used to report it to an aggregation server
+type PodScalerDownscalingRequest struct {
+	WorkloadName string `json:"workload_name"`
+	Resource     string `json:"resource"`
+	Configured   string `json:"configured"`
+	Determined   string `json:"determined"`
+	// RolledBack is set when the request was no longer reduced as the
+	// workload ran out of the resource
+	RolledBack bool `json:"rolled_back"`
+}
+
 const (
 	StateSucceeded string = "succeeded"
 	StateFailed    string = "failed"
@@ -112,12 +154,18 @@ type Reporter interface {
 	// This action is best-effort and errors are logged but not exposed.
 	// Err may be nil in which case a success is reported.
 	Report(err error)
+	// ReportCost sends the resources consumed by steps, by step name, to an
+	// aggregation server in one request.
+	// This action is best-effort and errors are logged but not exposed.
+	ReportCost(costs map[string]api.CIOperatorStepCost)
 }
 
 type noopReporter struct{}
 
 func (r *noopReporter) Report(err error) {}
 
+func (r *noopReporter) ReportCost(costs map[string]api.CIOperatorStepCost) {}
+
 type reporter struct {
 	client             *http.Client
 	username, password string
@@ -132,18 +180,27 @@ func (r *reporter) Report(err error) {
 	if err != nil {
 		state = StateFailed
 	}
-	reasons := Reasons(err)
-	if len(reasons) == 0 {
-		reasons = []string{string(ReasonUnknown)}
-	}
-	for _, reason := range reasons {
-		r.report(Request{
-			JobName: r.spec.Job,
-			Type:    string(r.spec.Type),
-			Cluster: r.consoleHost,
-			State:   state,
-			Reason:  reason,
-		})
+	failures := Failures(err)
+	if len(failures) == 0 {
+		failures = []Failure{{Reason: string(ReasonUnknown)}}
+	}
+	for _, failure := range failures {
+		request := Request{
+			JobName:   r.spec.Job,
+			Type:      string(r.spec.Type),
+			Cluster:   r.consoleHost,
+			State:     state,
+			Reason:    failure.Reason,
+			Org:       r.spec.Metadata.Org,
+			Repo:      r.spec.Metadata.Repo,
+			Branch:    r.spec.Metadata.Branch,
+			Step:      failure.Step,
+			ProwJobID: r.spec.ProwJobID,
+		}
+		if state == StateFailed {
+			request.Classification = string(Classify(failure.Reason))
+		}
+		r.report(request)
 	}
 }
 
@@ -168,8 +225,46 @@ func (r *reporter) report(request Request) {
 	sendRequest(req, r.client, r.username, r.password)
 }
 
+func (r *reporter) ReportCost(costs map[string]api.CIOperatorStepCost) {
+	if len(costs) == 0 {
+		return
+	}
+	var steps []string
+	for step := range costs {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	var requests []CostRequest
+	for _, step := range steps {
+		requests = append(requests, CostRequest{
+			JobName:            r.spec.Job,
+			Type:               string(r.spec.Type),
+			Cluster:            r.consoleHost,
+			Org:                r.spec.Metadata.Org,
+			Repo:               r.spec.Metadata.Repo,
+			Branch:             r.spec.Metadata.Branch,
+			Step:               step,
+			CIOperatorStepCost: costs[step],
+		})
+	}
+	data, err := json.Marshal(requests)
+	if err != nil {
+		logrus.Tracef("could not marshal cost request: %v", err)
+		return
+	}
+
+	logrus.Debugf("Reporting cost of steps %s", strings.Join(steps, ", "))
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/cost", r.address), bytes.NewReader(data))
+	if err != nil {
+		logrus.Tracef("could not create cost request: %v", err)
+		return
+	}
+	sendRequest(req, r.client, r.username, r.password)
+}
+
 type PodScalerReporter interface {
 	ReportMemoryConfigurationWarning(workloadName, configuredMemory, determinedMemory string)
+	ReportDownscaling(workloadName, resource, configured, determined string, rolledBack bool)
 }
 
 type podScalerReporter struct {
@@ -216,6 +311,32 @@ func (r *podScalerReporter) ReportMemoryConfigurationWarning(workloadName, confi
 	sendRequest(httpRequest, r.client, r.username, r.password)
 }
 
+// ReportDownscaling is used to send the information about reductions of resource
+// requests from pod-scaler-admission to result-aggregator.
+func (r *podScalerReporter) ReportDownscaling(workloadName, resource, configured, determined string, rolledBack bool) {
+	request := PodScalerDownscalingRequest{
+		WorkloadName: workloadName,
+		Resource:     resource,
+		Configured:   configured,
+		Determined:   determined,
+		RolledBack:   rolledBack,
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		logrus.Tracef("could not marshal pod-scaler downscaling request: %v", err)
+		return
+	}
+
+	httpRequest, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/pod-scaler/downscaling", r.address), bytes.NewReader(data))
+	if err != nil {
+		logrus.Tracef("could not create pod-scaler downscaling request: %v", err)
+		return
+	}
+
+	sendRequest(httpRequest, r.client, r.username, r.password)
+}
+
 func sendRequest(req *http.Request, client *http.Client, username, password string) {
 	req.Header.Set("Content-Type", "application/json")
 	req.SetBasicAuth(username, password)
@@ -42,21 +42,38 @@ func TestReporter_Report(t *testing.T) {
 			spec:        &api.JobSpec{JobSpec: downwardapi.JobSpec{Job: "runme", Type: v1.PresubmitJob}},
 			consoleHost: "foo.com",
 			err:         errors.New("something"),
-			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"unknown"}`,
+			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"unknown","classification":"unknown"}`,
 		},
 		{
 			name:        "reasoned err reports failure with specific reason",
 			spec:        &api.JobSpec{JobSpec: downwardapi.JobSpec{Job: "runme", Type: v1.PresubmitJob}},
 			consoleHost: "foo.com",
 			err:         ForReason("because").ForError(errors.New("oops")),
-			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"because"}`,
+			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"because","classification":"unknown"}`,
 		},
 		{
 			name:        "nested reasoned err reports failure with specific reason",
 			spec:        &api.JobSpec{JobSpec: downwardapi.JobSpec{Job: "runme", Type: v1.PresubmitJob}},
 			consoleHost: "foo.com",
 			err:         ForReason("because").WithError(ForReason("something").ForError(errors.New("oops"))).Errorf("argh"),
-			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"because:something"}`,
+			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"because:something","classification":"unknown"}`,
+		},
+		{
+			name: "failed step and job metadata are reported",
+			spec: &api.JobSpec{
+				JobSpec:  downwardapi.JobSpec{Job: "runme", Type: v1.PresubmitJob, ProwJobID: "uuid"},
+				Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+			},
+			consoleHost: "foo.com",
+			err:         ForReason("step_failed").ForStep("unit").WithError(ForReason("something").ForError(errors.New("oops"))).Errorf("argh"),
+			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"step_failed:something","org":"org","repo":"repo","branch":"master","step":"unit","prowjob_id":"uuid","classification":"unknown"}`,
+		},
+		{
+			name:        "infrastructure failures are classified",
+			spec:        &api.JobSpec{JobSpec: downwardapi.JobSpec{Job: "runme", Type: v1.PresubmitJob}},
+			consoleHost: "foo.com",
+			err:         ForReason("step_failed").WithError(ForReason(ReasonPodEvicted).ForError(errors.New("evicted"))).Errorf("argh"),
+			expected:    `{"job_name":"runme","type":"presubmit","cluster":"foo.com","state":"failed","reason":"step_failed:infrastructure/pod_evicted","classification":"infrastructure"}`,
 		},
 	}
 
@@ -104,6 +121,46 @@ func TestReporter_Report(t *testing.T) {
 	}
 }
 
+func TestReporter_ReportCost(t *testing.T) {
+	var actual string
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cost" {
+			t.Errorf("incorrect path to report cost: %s", r.URL.Path)
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+		raw, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read cost body: %v", err)
+		}
+		actual = string(raw)
+	}))
+	defer testServer.Close()
+
+	reporter := reporter{
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+		address: testServer.URL,
+		spec: &api.JobSpec{
+			JobSpec:  downwardapi.JobSpec{Job: "runme", Type: v1.PresubmitJob},
+			Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+		},
+		consoleHost: "foo.com",
+	}
+	reporter.ReportCost(map[string]api.CIOperatorStepCost{
+		"e2e": {CPUCoreHours: 2, PodHours: 1, LeaseHours: map[string]float64{"aws-quota-slice": 1.5}},
+		"src": {BuildHours: 0.5},
+	})
+	expected := `[{"job_name":"runme","type":"presubmit","cluster":"foo.com","org":"org","repo":"repo","branch":"master","step":"e2e","cpu_core_hours":2,"pod_hours":1,"lease_hours":{"aws-quota-slice":1.5}},` +
+		`{"job_name":"runme","type":"presubmit","cluster":"foo.com","org":"org","repo":"repo","branch":"master","step":"src","build_hours":0.5}]`
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("got incorrect cost report: %v", diff)
+	}
+}
+
 func TestOptions_Reporter(t *testing.T) {
 	// this simulates the flow for ci-operator while we migrate to using the tool
 	options := Options{} // no flags set
@@ -249,6 +306,69 @@ func TestReportMemoryConfigurationWarning(t *testing.T) {
 	}
 }
 
+func TestReportDownscaling(t *testing.T) {
+	testCases := []struct {
+		name       string
+		resource   string
+		configured string
+		determined string
+		rolledBack bool
+		expected   string
+	}{
+		{
+			name:       "reduction",
+			resource:   "memory",
+			configured: "2Gi",
+			determined: "1Gi",
+			expected:   `{"workload_name":"name","resource":"memory","configured":"2Gi","determined":"1Gi","rolled_back":false}`,
+		},
+		{
+			name:       "rollback",
+			resource:   "cpu",
+			configured: "2",
+			determined: "2",
+			rolledBack: true,
+			expected:   `{"workload_name":"name","resource":"cpu","configured":"2","determined":"2","rolled_back":true}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testServer := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				if request.Method != http.MethodPost {
+					t.Errorf("incorrect method: %s", request.Method)
+					return
+				}
+
+				if !strings.HasSuffix(request.URL.Path, "/pod-scaler/downscaling") {
+					t.Errorf("incorrect path: %s", request.URL.Path)
+					return
+				}
+
+				requestBody, err := ioutil.ReadAll(request.Body)
+				if err != nil {
+					t.Errorf("failed to read request body: %v", err)
+				}
+
+				if diff := cmp.Diff(tc.expected, string(requestBody)); diff != "" {
+					t.Errorf("actual and expected response don't match, diff: %v", diff)
+				}
+			}))
+			defer testServer.Close()
+
+			podScalerReporter := podScalerReporter{
+				client: &http.Client{
+					Transport: &http.Transport{
+						TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+					},
+				},
+				address: testServer.URL,
+			}
+			podScalerReporter.ReportDownscaling("name", tc.resource, tc.configured, tc.determined, tc.rolledBack)
+		})
+	}
+}
+
 func TestOptions_Validate(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -1,9 +1,112 @@
 package results
 
+import (
+	"strings"
+)
+
+// Reason identifies why an operation failed. Reasons are hierarchical: a reason
+// nested under another is divided from its parent by a slash, so that failures
+// can be aggregated at any level of detail.
 type Reason string
 
 const (
 	// ReasonUnknown is default reason. Occurrences of this reason in metrics
 	// indicate a bug, a failure to identify the reason for an error somewhere.
 	ReasonUnknown Reason = "unknown"
+
+	// ReasonInfrastructure is the root of failures caused by the infrastructure
+	// a job runs on, which are not expected to recur when the job is retried.
+	ReasonInfrastructure Reason = "infrastructure"
+	// ReasonImagePull is a failure to pull an image for a Pod.
+	ReasonImagePull Reason = "infrastructure/image_pull"
+	// ReasonQuotaExceeded is a failure to create a resource due to a quota.
+	ReasonQuotaExceeded Reason = "infrastructure/quota_exceeded"
+	// ReasonLeaseTimeout is a failure to acquire a lease in time.
+	ReasonLeaseTimeout Reason = "infrastructure/lease_timeout"
+	// ReasonPodEvicted is a Pod that was evicted from its node.
+	ReasonPodEvicted Reason = "infrastructure/pod_evicted"
+	// ReasonPodDeleted is a Pod that was deleted before it completed.
+	ReasonPodDeleted Reason = "infrastructure/pod_deleted"
+	// ReasonPodStartTimeout is a Pod that was not scheduled or did not start in time.
+	ReasonPodStartTimeout Reason = "infrastructure/pod_start_timeout"
+	// ReasonAPIUnavailable is a failure to talk to the API server of a cluster.
+	ReasonAPIUnavailable Reason = "infrastructure/api_unavailable"
+	// ReasonOOMKilled is a container that was killed for running out of memory.
+	ReasonOOMKilled Reason = "infrastructure/oom_killed"
+	// ReasonNodeLost is a Pod that failed because its node went away.
+	ReasonNodeLost Reason = "infrastructure/node_lost"
+
+	// ReasonConfiguration is the root of failures caused by the configuration
+	// of a job, which are expected to recur until the configuration is fixed.
+	ReasonConfiguration Reason = "configuration"
+	// ReasonInvalidImageName is a Pod that references an image by a malformed name.
+	ReasonInvalidImageName Reason = "configuration/invalid_image_name"
+
+	// ReasonTest is the root of failures of the code under test, which are
+	// expected to recur when the job is retried.
+	ReasonTest Reason = "test"
+	// ReasonTestFailure is a test container that exited with a failure.
+	ReasonTestFailure Reason = "test/failure"
 )
+
+// Parent is the reason this reason is nested under, if any
+func (r Reason) Parent() (Reason, bool) {
+	idx := strings.LastIndex(string(r), "/")
+	if idx == -1 {
+		return "", false
+	}
+	return r[:idx], true
+}
+
+// Classification tells apart failures of the infrastructure from failures of the
+// code under test or of the job's configuration.
+type Classification string
+
+const (
+	ClassificationUnknown        Classification = "unknown"
+	ClassificationInfrastructure Classification = "infrastructure"
+	ClassificationTest           Classification = "test"
+	ClassificationConfiguration  Classification = "configuration"
+)
+
+// Retryable determines if a failure of the classification is expected to go
+// away when the job is retried.
+func (c Classification) Retryable() bool {
+	return c == ClassificationInfrastructure
+}
+
+// Classification determines the classification of the reason from the root of
+// its hierarchy.
+func (r Reason) Classification() Classification {
+	root := r
+	for {
+		parent, ok := root.Parent()
+		if !ok {
+			break
+		}
+		root = parent
+	}
+	switch root {
+	case ReasonInfrastructure:
+		return ClassificationInfrastructure
+	case ReasonTest:
+		return ClassificationTest
+	case ReasonConfiguration:
+		return ClassificationConfiguration
+	default:
+		return ClassificationUnknown
+	}
+}
+
+// Classify determines the classification of a chain of reasons, as provided by
+// Reasons. The innermost reason with a known classification is the most specific
+// and therefore wins.
+func Classify(chain string) Classification {
+	reasons := strings.Split(chain, ":")
+	for i := len(reasons) - 1; i >= 0; i-- {
+		if classification := Reason(reasons[i]).Classification(); classification != ClassificationUnknown {
+			return classification
+		}
+	}
+	return ClassificationUnknown
+}
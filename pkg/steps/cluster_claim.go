@@ -38,6 +38,9 @@ type clusterClaimStep struct {
 	jobSpec      *api.JobSpec
 	wrapped      api.Step
 	censor       *secrets.DynamicCensor
+
+	// claimed and released record how long the cluster was held
+	claimed, released time.Time
 }
 
 func (s clusterClaimStep) Inputs() (api.InputDefinition, error) {
@@ -60,6 +63,25 @@ func (s *clusterClaimStep) Creates() []api.StepLink             { return s.wrapp
 func (s *clusterClaimStep) Objects() []ctrlruntimeclient.Object { return s.wrapped.Objects() }
 func (s *clusterClaimStep) Provides() api.ParameterMap          { return s.wrapped.Provides() }
 
+func (s *clusterClaimStep) SubSteps() []api.CIOperatorStepDetailInfo {
+	if subSteps, ok := s.wrapped.(SubStepReporter); ok {
+		return subSteps.SubSteps()
+	}
+	return nil
+}
+
+// Cost reports how long the cluster was held, on top of the cost of the wrapped step
+func (s *clusterClaimStep) Cost() api.CIOperatorStepCost {
+	var cost api.CIOperatorStepCost
+	if reporter, ok := s.wrapped.(CostReporter); ok {
+		cost.Add(reporter.Cost())
+	}
+	if !s.claimed.IsZero() && s.released.After(s.claimed) {
+		cost.ClusterClaimHours += s.released.Sub(s.claimed).Hours()
+	}
+	return cost
+}
+
 func (s *clusterClaimStep) Run(ctx context.Context) error {
 	return results.ForReason("utilizing_cluster_claim").ForError(s.run(ctx))
 }
@@ -114,8 +136,10 @@ func (s *clusterClaimStep) run(ctx context.Context) error {
 		return aggregateWrappedErrorAndReleaseError(acquireErr, releaseErr)
 	}
 
+	s.claimed = time.Now()
 	wrappedErr := results.ForReason("executing_test").ForError(s.wrapped.Run(ctx))
 	releaseErr := results.ForReason("releasing_cluster_claim").ForError(s.releaseCluster(CleanupCtx, clusterClaim, false))
+	s.released = time.Now()
 
 	return aggregateWrappedErrorAndReleaseError(wrappedErr, releaseErr)
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -33,6 +34,9 @@ type leaseStep struct {
 
 	// for sending heartbeats during lease acquisition
 	namespace func() string
+
+	// acquired and released record how long the leases were held
+	acquired, released time.Time
 }
 
 func LeaseStep(client *lease.Client, leases []api.StepLease, wrapped api.Step, namespace func() string) api.Step {
@@ -101,6 +105,29 @@ func (s *leaseStep) SubTests() []*junit.TestCase {
 	return nil
 }
 
+func (s *leaseStep) SubSteps() []api.CIOperatorStepDetailInfo {
+	if subSteps, ok := s.wrapped.(SubStepReporter); ok {
+		return subSteps.SubSteps()
+	}
+	return nil
+}
+
+// Cost reports how long the leases were held, on top of the cost of the wrapped step
+func (s *leaseStep) Cost() api.CIOperatorStepCost {
+	var cost api.CIOperatorStepCost
+	if reporter, ok := s.wrapped.(CostReporter); ok {
+		cost.Add(reporter.Cost())
+	}
+	if s.acquired.IsZero() || !s.released.After(s.acquired) {
+		return cost
+	}
+	held := s.released.Sub(s.acquired).Hours()
+	for _, l := range s.leases {
+		cost.Add(api.CIOperatorStepCost{LeaseHours: map[string]float64{l.ResourceType: held * float64(l.Count)}})
+	}
+	return cost
+}
+
 func (s *leaseStep) Run(ctx context.Context) error {
 	return results.ForReason("utilizing_lease").ForError(s.run(ctx))
 }
@@ -116,9 +143,11 @@ func (s *leaseStep) run(ctx context.Context) error {
 	if err := acquireLeases(client, ctx, cancel, s.leases); err != nil {
 		return err
 	}
+	s.acquired = time.Now()
 	wrappedErr := results.ForReason("executing_test").ForError(s.wrapped.Run(ctx))
 	logrus.Infof("Releasing leases for test %s", s.Name())
 	releaseErr := results.ForReason("releasing_lease").ForError(releaseLeases(client, s.leases))
+	s.released = time.Now()
 
 	return aggregateWrappedErrorAndReleaseError(wrappedErr, releaseErr)
 }
@@ -157,6 +186,9 @@ func acquireLeases(
 			if err == lease.ErrNotFound {
 				printResourceMetrics(client, l.ResourceType)
 			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = results.ForReason(results.ReasonLeaseTimeout).ForError(err)
+			}
 			errs = append(errs, results.ForReason(results.Reason("acquiring_lease")).WithError(err).Errorf("failed to acquire lease for %q: %v", l.ResourceType, err))
 			break
 		}
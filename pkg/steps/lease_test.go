@@ -5,6 +5,9 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -248,3 +251,20 @@ func TestAcquireRelease(t *testing.T) {
 		t.Fatalf("wrong calls to the lease client: %s", diff.ObjectDiff(calls, expected))
 	}
 }
+
+func TestLeaseStepCost(t *testing.T) {
+	acquired := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	step := &leaseStep{
+		leases:   []stepLease{{StepLease: api.StepLease{ResourceType: "aws-quota-slice", Count: 2}}, {StepLease: api.StepLease{ResourceType: "gcp-quota-slice", Count: 1}}},
+		wrapped:  &stepNeedsLease{},
+		acquired: acquired,
+		released: acquired.Add(90 * time.Minute),
+	}
+	expected := api.CIOperatorStepCost{LeaseHours: map[string]float64{"aws-quota-slice": 3, "gcp-quota-slice": 1.5}}
+	if diff := cmp.Diff(expected, step.Cost()); diff != "" {
+		t.Errorf("got incorrect cost: %v", diff)
+	}
+	if cost := (&leaseStep{wrapped: &stepNeedsLease{}}).Cost(); !cost.IsZero() {
+		t.Errorf("expected no cost for leases that were never acquired, got %v", cost)
+	}
+}
@@ -35,11 +35,12 @@ func (s *multiStageTestStep) generateObservers(
 	for _, observer := range observers {
 		// observers are just like steps, so we can adapt one to the other
 		adapted = append(adapted, api.LiteralTestStep{
-			As:        observer.Name,
-			From:      observer.From,
-			FromImage: observer.FromImage,
-			Commands:  observer.Commands,
-			Resources: observer.Resources,
+			As:          observer.Name,
+			From:        observer.From,
+			FromImage:   observer.FromImage,
+			Commands:    observer.Commands,
+			Resources:   observer.Resources,
+			GracePeriod: observer.GracePeriod,
 		})
 	}
 	pods, _, err := s.generatePods(adapted, nil, secretVolumes, secretVolumeMounts, genPodOpts)
@@ -177,6 +178,9 @@ func (s *multiStageTestStep) generatePods(
 			{Name: "JOB_NAME_SAFE", Value: strings.Replace(s.name, "_", "-", -1)},
 			{Name: "JOB_NAME_HASH", Value: s.jobSpec.JobNameHash()},
 		}...)
+		if genPodOpts.IsObserver {
+			container.Env = append(container.Env, coreapi.EnvVar{Name: api.ObserverPodNameEnv, Value: name})
+		}
 		container.Env = append(container.Env, env...)
 		container.Env = append(container.Env, s.generateParams(step.Environment)...)
 		depEnv, depErrs := s.envForDependencies(step)
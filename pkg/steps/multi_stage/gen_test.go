@@ -99,6 +99,66 @@ func TestGeneratePods(t *testing.T) {
 	testhelper.CompareWithFixture(t, ret)
 }
 
+func TestGenerateObservers(t *testing.T) {
+	config := api.ReleaseBuildConfiguration{}
+	jobSpec := api.JobSpec{
+		JobSpec: prowdapi.JobSpec{
+			Job:       "job",
+			BuildID:   "build id",
+			ProwJobID: "prow job id",
+			Type:      prowapi.PeriodicJob,
+			DecorationConfig: &prowapi.DecorationConfig{
+				Timeout:     &prowapi.Duration{Duration: time.Minute},
+				GracePeriod: &prowapi.Duration{Duration: time.Second},
+				UtilityImages: &prowapi.UtilityImages{
+					Sidecar:    "sidecar",
+					Entrypoint: "entrypoint",
+				},
+			},
+		},
+	}
+	jobSpec.SetNamespace("namespace")
+	step := newMultiStageTestStep(api.TestStepConfiguration{
+		As:                                 "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{},
+	}, &config, nil, nil, &jobSpec, nil, "node-name")
+	opts := defaultGeneratePodOptions()
+	opts.IsObserver = true
+	observers := []api.Observer{{
+		Name:        "monitor",
+		From:        "src",
+		Commands:    "monitor",
+		GracePeriod: &prowapi.Duration{Duration: 2 * time.Minute},
+	}}
+	pods, err := step.generateObservers(observers, nil, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected one pod, got %d", len(pods))
+	}
+	pod := pods[0]
+	if pod.Spec.TerminationGracePeriodSeconds == nil || *pod.Spec.TerminationGracePeriodSeconds != 150 {
+		t.Errorf("expected a termination grace period of 150s, got %v", pod.Spec.TerminationGracePeriodSeconds)
+	}
+	container := pod.Spec.Containers[0]
+	if !sets.NewString(container.Args...).Has("--mode=observer") {
+		t.Errorf("expected the entrypoint wrapper to run in observer mode, got args %v", container.Args)
+	}
+	var found bool
+	for _, env := range container.Env {
+		if env.Name == api.ObserverPodNameEnv {
+			found = true
+			if env.Value != pod.Name {
+				t.Errorf("expected %s to be %q, got %q", api.ObserverPodNameEnv, pod.Name, env.Value)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected %s to be set", api.ObserverPodNameEnv)
+	}
+}
+
 func TestGeneratePodsEnvironment(t *testing.T) {
 	value := "test"
 	defValue := "default"
@@ -101,6 +101,9 @@ type multiStageTestStep struct {
 	leases          []api.StepLease
 	clusterClaim    *api.ClusterClaim
 	vpnConf         *vpnConf
+
+	// observerEvents are the events sent to observers so far, guarded by subLock
+	observerEvents []api.ObserverEvent
 }
 
 func MultiStageTestStep(
@@ -210,11 +213,17 @@ func (s *multiStageTestStep) run(ctx context.Context) error {
 	observerDone := make(chan struct{})
 	go s.runObservers(observerContext, ctx, observers, observerDone)
 	s.flags |= shortCircuit
-	if err := s.runSteps(ctx, "pre", s.pre, env, secretVolumes, secretVolumeMounts); err != nil {
-		errs = append(errs, fmt.Errorf("%q pre steps failed: %w", s.name, err))
-	} else if err := s.runSteps(ctx, "test", s.test, env, secretVolumes, secretVolumeMounts); err != nil {
-		errs = append(errs, fmt.Errorf("%q test steps failed: %w", s.name, err))
+	preErr := s.runSteps(ctx, "pre", s.pre, env, secretVolumes, secretVolumeMounts)
+	s.notifyObservers(ctx, observers, api.ObserverEventPreFinished)
+	if preErr != nil {
+		errs = append(errs, fmt.Errorf("%q pre steps failed: %w", s.name, preErr))
+	} else {
+		s.notifyObservers(ctx, observers, api.ObserverEventTestStarted)
+		if err := s.runSteps(ctx, "test", s.test, env, secretVolumes, secretVolumeMounts); err != nil {
+			errs = append(errs, fmt.Errorf("%q test steps failed: %w", s.name, err))
+		}
 	}
+	s.notifyObservers(context.Background(), observers, api.ObserverEventPostStarted)
 	cancel() // signal to observers that we're tearing down
 	s.flags &= ^shortCircuit
 	if err := s.runSteps(context.Background(), "post", s.post, env, secretVolumes, secretVolumeMounts); err != nil {
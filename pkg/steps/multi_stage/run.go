@@ -2,6 +2,7 @@ package multi_stage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,6 +13,8 @@ import (
 
 	coreapi "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	utilpointer "k8s.io/utils/pointer"
@@ -20,6 +23,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/junit"
 	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/utils"
 	"github.com/openshift/ci-tools/pkg/util"
 )
 
@@ -100,7 +104,7 @@ func (s *multiStageTestStep) runSteps(
 func (s *multiStageTestStep) runPods(ctx context.Context, pods []coreapi.Pod, bestEffortSteps sets.String) error {
 	var errs []error
 	for _, pod := range pods {
-		err := s.runPod(ctx, &pod, base_steps.NewTestCaseNotifier(util.NopNotifier))
+		err := s.runPod(ctx, &pod, base_steps.NewTestCaseNotifier(util.NopNotifier), nil)
 		if err == nil {
 			continue
 		}
@@ -121,15 +125,44 @@ func (s *multiStageTestStep) runObservers(ctx, textCtx context.Context, pods []c
 	wg.Add(len(pods))
 	errs := make(chan error, len(pods))
 	for _, pod := range pods {
+		stopped := make(chan struct{})
 		go func(p coreapi.Pod) {
 			<-ctx.Done()
-			logrus.Info("Signalling observers to terminate...")
-			if err := s.client.Delete(context.Background(), &p); err != nil {
+			// observers are interrupted when the post steps start, the Pod is
+			// only deleted when the observer does not stop in its grace period
+			var gracePeriod time.Duration
+			if p.Spec.TerminationGracePeriodSeconds != nil {
+				gracePeriod = time.Duration(*p.Spec.TerminationGracePeriodSeconds) * time.Second
+			}
+			select {
+			case <-stopped:
+				return
+			case <-time.After(gracePeriod):
+			}
+			logrus.Infof("Observer %s did not stop in %s, deleting it...", p.Name, gracePeriod)
+			if err := s.client.Delete(context.Background(), &p); err != nil && !kerrors.IsNotFound(err) {
 				logrus.WithError(err).Warn("failed to trigger observer to stop")
 			}
 		}(pod)
 		go func(p coreapi.Pod) {
-			err := s.runPod(textCtx, &p, base_steps.NewTestCaseNotifier(util.NopNotifier))
+			defer close(stopped)
+			s.subLock.Lock()
+			sent := len(s.observerEvents)
+			if sent != 0 {
+				// the observer missed events sent before it was created
+				p.Annotations[api.ObserverEventsAnnotation] = joinObserverEvents(s.observerEvents)
+			}
+			s.subLock.Unlock()
+			// events sent while the Pod was being created could not be set on it
+			created := func() {
+				s.subLock.Lock()
+				defer s.subLock.Unlock()
+				if len(s.observerEvents) != sent {
+					s.patchObserverEvents(textCtx, p, joinObserverEvents(s.observerEvents))
+				}
+			}
+			// the results of the observer are gathered by runPod like those of any other step
+			err := s.runPod(textCtx, &p, base_steps.NewTestCaseNotifier(util.NopNotifier), created)
 			if ctx.Err() == nil {
 				// when the observer is cancelled, we get an error here that we need to ignore, as it's not an error
 				// for the Pod to be deleted when it's cancelled, it's just expected
@@ -150,13 +183,61 @@ func (s *multiStageTestStep) runObservers(ctx, textCtx context.Context, pods []c
 	done <- struct{}{}
 }
 
-func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier) error {
+// notifyObservers records an event in the lifecycle of the test and sets the
+// list of all events sent so far on the observer Pods, from where the
+// entrypoint wrapper relays them to the observer process. Observers that have
+// not been created yet receive the recorded events when they are.
+func (s *multiStageTestStep) notifyObservers(ctx context.Context, pods []coreapi.Pod, event api.ObserverEvent) {
+	if len(pods) == 0 {
+		return
+	}
+	s.subLock.Lock()
+	s.observerEvents = append(s.observerEvents, event)
+	events := joinObserverEvents(s.observerEvents)
+	s.subLock.Unlock()
+	logrus.Infof("Notifying observers: %s", event)
+	for _, pod := range pods {
+		s.patchObserverEvents(ctx, pod, events)
+	}
+}
+
+// patchObserverEvents sets the list of events on an observer Pod, ignoring
+// observers that have not been created yet.
+func (s *multiStageTestStep) patchObserverEvents(ctx context.Context, pod coreapi.Pod, events string) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{api.ObserverEventsAnnotation: events},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal observer notification")
+		return
+	}
+	p := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name}}
+	if err := s.client.Patch(ctx, p, ctrlruntimeclient.RawPatch(types.MergePatchType, patch)); err != nil && !kerrors.IsNotFound(err) {
+		logrus.WithError(err).Warnf("failed to notify observer %s", pod.Name)
+	}
+}
+
+func joinObserverEvents(events []api.ObserverEvent) string {
+	var values []string
+	for _, event := range events {
+		values = append(values, string(event))
+	}
+	return strings.Join(values, ",")
+}
+
+// runPod runs the Pod to completion, calling created, if set, once it exists.
+func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier, created func()) error {
 	start := time.Now()
 	logrus.Infof("Running step %s.", pod.Name)
 	client := s.client.WithNewLoggingClient()
 	if _, err := util.CreateOrRestartPod(ctx, client, pod); err != nil {
 		return fmt.Errorf("failed to create or restart %s pod: %w", pod.Name, err)
 	}
+	if created != nil {
+		created()
+	}
 	newPod, err := util.WaitForPodCompletion(ctx, client, pod.Namespace, pod.Name, notifier, false)
 	if newPod != nil {
 		pod = newPod
@@ -168,6 +249,11 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 		verb = "failed"
 	}
 	logrus.Infof("Step %s %s after %s.", pod.Name, verb, duration.Truncate(time.Second))
+	objects := client.Objects()
+	var cost *api.CIOperatorStepCost
+	if c := utils.Cost(objects, finished); !c.IsZero() {
+		cost = &c
+	}
 	s.subLock.Lock()
 	s.subSteps = append(s.subSteps, api.CIOperatorStepDetailInfo{
 		StepName:    pod.Name,
@@ -176,7 +262,8 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 		FinishedAt:  &finished,
 		Duration:    &duration,
 		Failed:      utilpointer.BoolPtr(err != nil),
-		Manifests:   client.Objects(),
+		Manifests:   objects,
+		Cost:        cost,
 	})
 	s.subTests = append(s.subTests, notifier.SubTests(fmt.Sprintf("%s - %s ", s.Description(), pod.Name))...)
 	s.subLock.Unlock()
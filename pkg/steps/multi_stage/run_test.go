@@ -3,6 +3,7 @@ package multi_stage
 import (
 	"context"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -225,3 +226,107 @@ func TestJUnit(t *testing.T) {
 		})
 	}
 }
+
+func TestNotifyObservers(t *testing.T) {
+	observer := &coreapi.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-monitor", Namespace: "ns"}}
+	client := &testhelper.FakePodClient{FakePodExecutor: &testhelper.FakePodExecutor{
+		LoggingClient: loggingclient.New(fakectrlruntimeclient.NewFakeClient(observer.DeepCopyObject())),
+	}}
+	step := &multiStageTestStep{client: client, subLock: &sync.Mutex{}}
+	pods := []coreapi.Pod{*observer, {ObjectMeta: metav1.ObjectMeta{Name: "test-not-created", Namespace: "ns"}}}
+	step.notifyObservers(context.Background(), pods, api.ObserverEventPreFinished)
+	step.notifyObservers(context.Background(), pods, api.ObserverEventTestStarted)
+	pod := &coreapi.Pod{}
+	if err := client.LoggingClient.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "test-monitor"}, pod); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("pre-finished,test-started", pod.Annotations[api.ObserverEventsAnnotation]); diff != "" {
+		t.Errorf("unexpected events on the observer: %s", diff)
+	}
+	if diff := cmp.Diff([]api.ObserverEvent{api.ObserverEventPreFinished, api.ObserverEventTestStarted}, step.observerEvents); diff != "" {
+		t.Errorf("unexpected recorded events: %s", diff)
+	}
+}
+
+// notifyingClient sends an event to the observers while an observer is being created
+type notifyingClient struct {
+	loggingclient.LoggingClient
+	notify func()
+}
+
+func (c *notifyingClient) Create(ctx context.Context, o ctrlruntimeclient.Object, opts ...ctrlruntimeclient.CreateOption) error {
+	c.notify()
+	return c.LoggingClient.Create(ctx, o, opts...)
+}
+
+func TestRunObserversReceiveEventsSentDuringCreation(t *testing.T) {
+	observer := coreapi.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-monitor", Namespace: "ns", Annotations: map[string]string{}},
+		Spec:       coreapi.PodSpec{Containers: []coreapi.Container{{Name: "test", Image: "monitor"}}},
+	}
+	underlying := loggingclient.New(fakectrlruntimeclient.NewFakeClient())
+	client := &notifyingClient{LoggingClient: underlying}
+	step := &multiStageTestStep{
+		name:           "test",
+		client:         &testhelper.FakePodClient{FakePodExecutor: &testhelper.FakePodExecutor{LoggingClient: client}},
+		subLock:        &sync.Mutex{},
+		observerEvents: []api.ObserverEvent{api.ObserverEventPreFinished},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pods := []coreapi.Pod{observer}
+	client.notify = func() {
+		step.notifyObservers(ctx, pods, api.ObserverEventTestStarted)
+	}
+	done := make(chan struct{}, 1)
+	step.runObservers(ctx, ctx, pods, done)
+	pod := &coreapi.Pod{}
+	if err := underlying.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "test-monitor"}, pod); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("pre-finished,test-started", pod.Annotations[api.ObserverEventsAnnotation]); diff != "" {
+		t.Errorf("unexpected events on the observer: %s", diff)
+	}
+}
+
+func TestObserverJUnit(t *testing.T) {
+	sa := &coreapi.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{"ci.openshift.io/multi-stage-test": "test"}}}
+	crclient := &testhelper.FakePodExecutor{
+		LoggingClient: loggingclient.New(fakectrlruntimeclient.NewFakeClient(sa.DeepCopyObject())),
+	}
+	jobSpec := api.JobSpec{
+		JobSpec: prowdapi.JobSpec{
+			Job:       "job",
+			BuildID:   "build_id",
+			ProwJobID: "prow_job_id",
+			Type:      prowapi.PeriodicJob,
+			DecorationConfig: &prowapi.DecorationConfig{
+				Timeout:     &prowapi.Duration{Duration: time.Minute},
+				GracePeriod: &prowapi.Duration{Duration: time.Second},
+				UtilityImages: &prowapi.UtilityImages{
+					Sidecar:    "sidecar",
+					Entrypoint: "entrypoint",
+				},
+			},
+		},
+	}
+	jobSpec.SetNamespace("test-namespace")
+	client := &testhelper.FakePodClient{FakePodExecutor: crclient}
+	step := MultiStageTestStep(api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test:      []api.LiteralTestStep{{As: "test0"}},
+			Observers: []api.Observer{{Name: "monitor"}},
+		},
+	}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name")
+	if err := step.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	names := sets.NewString()
+	for _, t := range step.(steps.SubtestReporter).SubTests() {
+		names.Insert(t.Name)
+	}
+	if expected := "Run multi-stage test test - test-monitor container test"; !names.Has(expected) {
+		t.Errorf("expected the results of the observer to be gathered as %q, got %v", expected, names.List())
+	}
+}
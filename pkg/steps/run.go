@@ -6,9 +6,12 @@ import (
 	"sync"
 	"time"
 
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/junit"
 	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/steps/utils"
 )
 
 type message struct {
@@ -57,8 +60,8 @@ func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIO
 			testCase := &junit.TestCase{Name: out.node.Step.Description(), Duration: out.duration.Seconds()}
 			stepDetails = append(stepDetails, out.stepDetails)
 			if out.err != nil {
-				testCase.FailureOutput = &junit.FailureOutput{Output: out.err.Error()}
-				executionErrors = append(executionErrors, results.ForReason("step_failed").WithError(out.err).Errorf("step %s failed: %v", out.node.Step.Name(), out.err))
+				testCase.FailureOutput = &junit.FailureOutput{Output: out.err.Error(), Type: string(results.ClassificationOf(out.err))}
+				executionErrors = append(executionErrors, results.ForReason("step_failed").ForStep(out.node.Step.Name()).WithError(out.err).Errorf("step %s failed: %v", out.node.Step.Name(), out.err))
 			} else {
 				seen = append(seen, out.node.Step.Creates()...)
 				if !interrupted {
@@ -116,6 +119,30 @@ type SubStepReporter interface {
 	SubSteps() []api.CIOperatorStepDetailInfo
 }
 
+// CostReporter allows steps to report the cost of what they held that is not
+// visible in the objects they created, like leases.
+type CostReporter interface {
+	Cost() api.CIOperatorStepCost
+}
+
+// StepCost determines the cost of a step from the objects it created, its
+// substeps and what it reports itself.
+func StepCost(step api.Step, objects []ctrlruntimeclient.Object, subSteps []api.CIOperatorStepDetailInfo, until time.Time) *api.CIOperatorStepCost {
+	cost := utils.Cost(objects, until)
+	for _, subStep := range subSteps {
+		if subStep.Cost != nil {
+			cost.Add(*subStep.Cost)
+		}
+	}
+	if reporter, ok := step.(CostReporter); ok {
+		cost.Add(reporter.Cost())
+	}
+	if cost.IsZero() {
+		return nil
+	}
+	return &cost
+}
+
 func runStep(ctx context.Context, node *api.StepNode, out chan<- message) {
 	start := time.Now()
 	err := node.Step.Run(ctx)
@@ -132,6 +159,7 @@ func runStep(ctx context.Context, node *api.StepNode, out chan<- message) {
 		subSteps = x.SubSteps()
 	}
 
+	objects := node.Step.Objects()
 	out <- message{
 		node:            node,
 		duration:        duration,
@@ -144,8 +172,9 @@ func runStep(ctx context.Context, node *api.StepNode, out chan<- message) {
 				StartedAt:   &start,
 				FinishedAt:  &finishedAt,
 				Duration:    &duration,
-				Manifests:   node.Step.Objects(),
+				Manifests:   objects,
 				Failed:      &failed,
+				Cost:        StepCost(node.Step, objects, subSteps, finishedAt),
 			},
 			Substeps: subSteps,
 		},
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	buildapi "github.com/openshift/api/build/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const gibibyte = 1024 * 1024 * 1024
+
+// Cost determines the resources consumed by the Pods and Builds among the objects,
+// as the resources they requested multiplied by their runtime. Objects that are
+// still running are accounted for until the given time.
+func Cost(objects []ctrlruntimeclient.Object, until time.Time) api.CIOperatorStepCost {
+	var cost api.CIOperatorStepCost
+	for _, object := range objects {
+		switch o := object.(type) {
+		case *corev1.Pod:
+			hours := podRuntime(o, until).Hours()
+			cost.PodHours += hours
+			addRequests(&cost, podRequests(o), hours)
+		case *buildapi.Build:
+			hours := buildRuntime(o, until).Hours()
+			cost.BuildHours += hours
+			addRequests(&cost, o.Spec.Resources.Requests, hours)
+		}
+	}
+	return cost
+}
+
+func addRequests(cost *api.CIOperatorStepCost, requests corev1.ResourceList, hours float64) {
+	cpu, memory := requests[corev1.ResourceCPU], requests[corev1.ResourceMemory]
+	cost.CPUCoreHours += float64(cpu.MilliValue()) / 1000 * hours
+	cost.MemoryGiBHours += float64(memory.Value()) / gibibyte * hours
+}
+
+// podRequests determines the effective requests of the Pod, which are the larger of
+// the sum of the requests of its containers and the requests of any init container
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, set := requests[name]; !set || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
+
+// podRuntime determines how long the Pod ran, from its start until the last of
+// its containers terminated
+func podRuntime(pod *corev1.Pod, until time.Time) time.Duration {
+	if pod.Status.StartTime == nil {
+		return 0
+	}
+	end := until
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		var finished time.Time
+		for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			if terminated := status.State.Terminated; terminated != nil && terminated.FinishedAt.Time.After(finished) {
+				finished = terminated.FinishedAt.Time
+			}
+		}
+		if !finished.IsZero() {
+			end = finished
+		}
+	}
+	if runtime := end.Sub(pod.Status.StartTime.Time); runtime > 0 {
+		return runtime
+	}
+	return 0
+}
+
+// buildRuntime determines how long the Build ran
+func buildRuntime(build *buildapi.Build, until time.Time) time.Duration {
+	if build.Status.StartTimestamp == nil {
+		return 0
+	}
+	end := until
+	if build.Status.CompletionTimestamp != nil {
+		end = build.Status.CompletionTimestamp.Time
+	}
+	if runtime := end.Sub(build.Status.StartTimestamp.Time); runtime > 0 {
+		return runtime
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	buildapi "github.com/openshift/api/build/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestCost(t *testing.T) {
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	until := start.Add(4 * time.Hour)
+	requests := func(cpu, memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+	var testCases = []struct {
+		name     string
+		objects  []ctrlruntimeclient.Object
+		expected api.CIOperatorStepCost
+	}{
+		{
+			name: "no objects",
+		},
+		{
+			name:    "pod that never started",
+			objects: []ctrlruntimeclient.Object{&corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Resources: requests("1", "1Gi")}}}}},
+		},
+		{
+			name: "finished pod",
+			objects: []ctrlruntimeclient.Object{&corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Resources: requests("100m", "4Gi")}},
+					Containers:     []corev1.Container{{Resources: requests("1", "1Gi")}, {Resources: requests("1", "1Gi")}},
+				},
+				Status: corev1.PodStatus{
+					Phase:     corev1.PodSucceeded,
+					StartTime: &metav1.Time{Time: start},
+					ContainerStatuses: []corev1.ContainerStatus{
+						{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: start.Add(time.Hour)}}}},
+						{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: start.Add(2 * time.Hour)}}}},
+					},
+				},
+			}},
+			expected: api.CIOperatorStepCost{CPUCoreHours: 4, MemoryGiBHours: 8, PodHours: 2},
+		},
+		{
+			name: "running pod and finished build",
+			objects: []ctrlruntimeclient.Object{
+				&corev1.Pod{
+					Spec:   corev1.PodSpec{Containers: []corev1.Container{{Resources: requests("500m", "1Gi")}}},
+					Status: corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &metav1.Time{Time: start}},
+				},
+				&buildapi.Build{
+					Spec: buildapi.BuildSpec{CommonSpec: buildapi.CommonSpec{Resources: requests("2", "2Gi")}},
+					Status: buildapi.BuildStatus{
+						StartTimestamp:      &metav1.Time{Time: start},
+						CompletionTimestamp: &metav1.Time{Time: start.Add(30 * time.Minute)},
+					},
+				},
+			},
+			expected: api.CIOperatorStepCost{CPUCoreHours: 3, MemoryGiBHours: 5, PodHours: 4, BuildHours: 0.5},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if diff := cmp.Diff(testCase.expected, Cost(testCase.objects, until)); diff != "" {
+				t.Errorf("got incorrect cost: %v", diff)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/results"
 )
 
 func CreateOrRestartPod(ctx context.Context, podClient ctrlruntimeclient.Client, pod *corev1.Pod) (*corev1.Pod, error) {
@@ -156,7 +157,7 @@ func waitForPodCompletionOrTimeout(ctx context.Context, podClient kubernetes.Pod
 			notifier.Complete(name)
 			logrus.Infof("error: could not wait for pod '%s': it is no longer present on the cluster"+
 				" (usually a result of a race or resource pressure. re-running the job should help)", name)
-			return nil, fmt.Errorf("pod was deleted while ci-operator step was waiting for it")
+			return nil, results.ForReason(results.ReasonPodDeleted).ForError(fmt.Errorf("pod was deleted while ci-operator step was waiting for it"))
 		}
 		return nil, fmt.Errorf("could not list pod: %w", err)
 	}
@@ -172,7 +173,7 @@ func waitForPodCompletionOrTimeout(ctx context.Context, podClient kubernetes.Pod
 		return pod, nil
 	}
 	if podJobIsFailed(pod) {
-		return pod, AppendLogToError(fmt.Errorf("the pod %s/%s failed after %s (failed containers: %s): %s", pod.Namespace, pod.Name, podDuration(pod).Truncate(time.Second), strings.Join(failedContainerNames(pod), ", "), podReason(pod)), podMessages(pod))
+		return pod, podFailure(pod)
 	}
 	done := ctx.Done()
 
@@ -188,7 +189,7 @@ func waitForPodCompletionOrTimeout(ctx context.Context, podClient kubernetes.Pod
 		case <-podCheckTicker.C:
 			if err := podClient.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: name}, pod); err != nil {
 				if kerrors.IsNotFound(err) {
-					return pod, AppendLogToError(fmt.Errorf("the pod %s/%s was deleted without completing after %s (failed containers: %s)", pod.Namespace, pod.Name, podDuration(pod).Truncate(time.Second), strings.Join(failedContainerNames(pod), ", ")), podMessages(pod))
+					return pod, results.ForReason(results.ReasonPodDeleted).ForError(AppendLogToError(fmt.Errorf("the pod %s/%s was deleted without completing after %s (failed containers: %s)", pod.Namespace, pod.Name, podDuration(pod).Truncate(time.Second), strings.Join(failedContainerNames(pod), ", ")), podMessages(pod)))
 				}
 				logrus.WithError(err).Warnf("Failed to get pod %s.", name)
 				continue
@@ -201,7 +202,7 @@ func waitForPodCompletionOrTimeout(ctx context.Context, podClient kubernetes.Pod
 					message := fmt.Sprintf("pod didn't start running within %s: %s\n%s", podStartTimeout, getReasonsForUnreadyContainers(pod), getEventsForPod(ctx, pod, podClient))
 					logrus.Infof(message)
 					notifier.Complete(name)
-					return pod, errors.New(message)
+					return pod, results.ForReason(podStartReason(pod)).ForError(errors.New(message))
 				}
 			}
 			podLogNewFailedContainers(podClient, pod, completed, notifier, skipLogs)
@@ -212,12 +213,59 @@ func waitForPodCompletionOrTimeout(ctx context.Context, podClient kubernetes.Pod
 				return pod, nil
 			}
 			if podJobIsFailed(pod) {
-				return pod, AppendLogToError(fmt.Errorf("the pod %s/%s failed after %s (failed containers: %s): %s", pod.Namespace, pod.Name, podDuration(pod).Truncate(time.Second), strings.Join(failedContainerNames(pod), ", "), podReason(pod)), podMessages(pod))
+				return pod, podFailure(pod)
 			}
 		}
 	}
 }
 
+// podFailure describes why the pod failed, classifying evictions, lost nodes and
+// containers running out of memory as failures of the infrastructure and anything
+// else as a failure of the workload itself
+func podFailure(pod *corev1.Pod) error {
+	return results.ForReason(podFailureReason(pod)).ForError(AppendLogToError(fmt.Errorf("the pod %s/%s failed after %s (failed containers: %s): %s", pod.Namespace, pod.Name, podDuration(pod).Truncate(time.Second), strings.Join(failedContainerNames(pod), ", "), podReason(pod)), podMessages(pod)))
+}
+
+// podFailureReason determines why the pod failed
+func podFailureReason(pod *corev1.Pod) results.Reason {
+	switch pod.Status.Reason {
+	case "Evicted":
+		return results.ReasonPodEvicted
+	// the node controller marks pods on unreachable nodes as lost, while the
+	// kubelet terminates them on a graceful node shutdown
+	case "NodeLost", "Terminated", "Shutdown":
+		return results.ReasonNodeLost
+	}
+	for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if terminated := status.State.Terminated; terminated != nil {
+			switch terminated.Reason {
+			case "OOMKilled":
+				return results.ReasonOOMKilled
+			// the kubelet can no longer tell what happened to containers when
+			// their node went away under them
+			case "ContainerStatusUnknown":
+				return results.ReasonNodeLost
+			}
+		}
+	}
+	return results.ReasonTestFailure
+}
+
+// podStartReason determines why the pod did not start
+func podStartReason(pod *corev1.Pod) results.Reason {
+	for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "ErrImagePull", "ImagePullBackOff":
+				return results.ReasonImagePull
+			case "InvalidImageName":
+				return results.ReasonInvalidImageName
+			}
+		}
+	}
+	return results.ReasonPodStartTimeout
+}
+
 // podReason returns the pod's reason and message for exit or tries to find one from the pod.
 func podReason(pod *corev1.Pod) string {
 	reason := pod.Status.Reason
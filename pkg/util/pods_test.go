@@ -1,9 +1,13 @@
 package util
 
 import (
+	"errors"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
 func TestPodHasStarted(t *testing.T) {
@@ -76,3 +80,81 @@ func TestPodHasStarted(t *testing.T) {
 		})
 	}
 }
+
+func TestPodFailureReasons(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected []string
+	}{{
+		name:     "failed pod",
+		err:      podFailure(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed}}),
+		expected: []string{"test/failure"},
+	}, {
+		name:     "evicted pod",
+		err:      podFailure(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"}}),
+		expected: []string{"infrastructure/pod_evicted"},
+	}, {
+		name:     "pod on a lost node",
+		err:      podFailure(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "NodeLost"}}),
+		expected: []string{"infrastructure/node_lost"},
+	}, {
+		name: "container with unknown status",
+		err: podFailure(&corev1.Pod{Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "test",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "ContainerStatusUnknown", ExitCode: 137}},
+			}},
+		}}),
+		expected: []string{"infrastructure/node_lost"},
+	}, {
+		name: "container killed for running out of memory",
+		err: podFailure(&corev1.Pod{Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "test",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+			}},
+		}}),
+		expected: []string{"infrastructure/oom_killed"},
+	}, {
+		name: "container that failed on its own",
+		err: podFailure(&corev1.Pod{Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "test",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}},
+			}},
+		}}),
+		expected: []string{"test/failure"},
+	}, {
+		name: "pod not starting due to image pulls",
+		err: results.ForReason(podStartReason(&corev1.Pod{Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "test",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}},
+		}})).ForError(errors.New("timeout")),
+		expected: []string{"infrastructure/image_pull"},
+	}, {
+		name: "pod not starting due to a malformed image name",
+		err: results.ForReason(podStartReason(&corev1.Pod{Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "test",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "InvalidImageName"}},
+			}},
+		}})).ForError(errors.New("timeout")),
+		expected: []string{"configuration/invalid_image_name"},
+	}, {
+		name:     "pod not starting",
+		err:      results.ForReason(podStartReason(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}})).ForError(errors.New("timeout")),
+		expected: []string{"infrastructure/pod_start_timeout"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			testhelper.Diff(t, "reasons", results.Reasons(tc.err), tc.expected)
+		})
+	}
+}
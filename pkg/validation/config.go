@@ -544,6 +544,9 @@ func Observer(observer api.Observer) []error {
 		errs = append(errs, fmt.Errorf("%s.commands cannot be empty", fieldRoot))
 	}
 	errs = append(errs, validateResourceRequirements(fieldRoot+".resources", observer.Resources)...)
+	if observer.GracePeriod != nil && observer.GracePeriod.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("%s.grace_period must be positive", fieldRoot))
+	}
 	// we're validating unresolved configuration outside of a full test config, so
 	// we cannot know the releases that may or may not be contained in a config using
 	// this observer in the future. This technically disallows users from using `from:`
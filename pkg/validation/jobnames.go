@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	prowconfig "k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/kube"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// JobNames collects the jobs generated for the ci-operator configurations in
+// a directory to find the job names and contexts that Prow cannot tell apart.
+// Prow labels the resources it creates with the job name and context, which it
+// truncates to the length limit of label values, so different names may still
+// collide. JobNames is safe for concurrent use.
+type JobNames struct {
+	lock sync.Mutex
+	// configs maps job names to the configurations that generate them
+	configs map[string]sets.String
+	// contexts maps org/repo@branch to the presubmit contexts to the
+	// configurations generating the presubmits that report them
+	contexts map[string]map[string]sets.String
+}
+
+// NewJobNames creates an empty JobNames.
+func NewJobNames() *JobNames {
+	return &JobNames{
+		configs:  map[string]sets.String{},
+		contexts: map[string]map[string]sets.String{},
+	}
+}
+
+// Add records the jobs generated for a configuration and validates the labels
+// of the jobs, which do not depend on other configurations.
+func (n *JobNames) Add(metadata api.Metadata, jobs *prowconfig.JobConfig) []error {
+	filename := metadata.RelativePath()
+	var errs []error
+	var names []string
+	contexts := sets.NewString()
+	for _, presubmits := range jobs.PresubmitsStatic {
+		for _, presubmit := range presubmits {
+			names = append(names, presubmit.Name)
+			errs = append(errs, validateJobLabels(presubmit.JobBase)...)
+			contexts.Insert(presubmit.Context)
+		}
+	}
+	for _, postsubmits := range jobs.PostsubmitsStatic {
+		for _, postsubmit := range postsubmits {
+			names = append(names, postsubmit.Name)
+			errs = append(errs, validateJobLabels(postsubmit.JobBase)...)
+		}
+	}
+	for _, periodic := range jobs.Periodics {
+		names = append(names, periodic.Name)
+		errs = append(errs, validateJobLabels(periodic.JobBase)...)
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, name := range names {
+		if _, ok := n.configs[name]; !ok {
+			n.configs[name] = sets.NewString()
+		}
+		n.configs[name].Insert(filename)
+	}
+	branch := fmt.Sprintf("%s/%s@%s", metadata.Org, metadata.Repo, metadata.Branch)
+	if _, ok := n.contexts[branch]; !ok {
+		n.contexts[branch] = map[string]sets.String{}
+	}
+	for _, context := range contexts.UnsortedList() {
+		if _, ok := n.contexts[branch][context]; !ok {
+			n.contexts[branch][context] = sets.NewString()
+		}
+		n.contexts[branch][context].Insert(filename)
+	}
+	return errs
+}
+
+// Validate returns the collisions between the job names and contexts of all
+// configurations that were added.
+func (n *JobNames) Validate() []error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	var errs []error
+	truncated := map[string]sets.String{}
+	for _, name := range sets.StringKeySet(n.configs).List() {
+		if configs := n.configs[name]; configs.Len() > 1 {
+			errs = append(errs, fmt.Errorf("job %s is generated by more than one configuration: %s; rename a test or variant in one of them", name, strings.Join(configs.List(), ", ")))
+		}
+		label := truncatedLabelValue(name)
+		if _, ok := truncated[label]; !ok {
+			truncated[label] = sets.NewString()
+		}
+		truncated[label].Insert(name)
+	}
+	for _, label := range sets.StringKeySet(truncated).List() {
+		if names := truncated[label]; names.Len() > 1 {
+			errs = append(errs, fmt.Errorf("jobs %s are all labeled %s=%s because Prow truncates label values to %d characters; rename their tests or variants so that the job names differ within the first %d characters", strings.Join(names.List(), ", "), kube.ProwJobAnnotation, label, validation.LabelValueMaxLength, validation.LabelValueMaxLength))
+		}
+	}
+
+	for _, branch := range sets.StringKeySet(n.contexts).List() {
+		truncated := map[string]sets.String{}
+		for _, context := range sets.StringKeySet(n.contexts[branch]).List() {
+			if configs := n.contexts[branch][context]; configs.Len() > 1 {
+				errs = append(errs, fmt.Errorf("presubmits for %s generated by more than one configuration report the context %s: %s; rename a test or variant in one of them", branch, context, strings.Join(configs.List(), ", ")))
+			}
+			label := truncatedLabelValue(context)
+			if _, ok := truncated[label]; !ok {
+				truncated[label] = sets.NewString()
+			}
+			truncated[label].Insert(context)
+		}
+		for _, label := range sets.StringKeySet(truncated).List() {
+			if contexts := truncated[label]; contexts.Len() > 1 {
+				errs = append(errs, fmt.Errorf("contexts %s for %s are all labeled %s=%s because Prow truncates label values to %d characters; rename their tests or variants so that the contexts differ within the first %d characters", strings.Join(contexts.List(), ", "), branch, kube.ContextAnnotation, label, validation.LabelValueMaxLength, validation.LabelValueMaxLength))
+			}
+		}
+	}
+	return errs
+}
+
+// truncatedLabelValue truncates a value the way Prow does for labels
+func truncatedLabelValue(value string) string {
+	if len(value) <= validation.LabelValueMaxLength {
+		return value
+	}
+	return strings.TrimRight(value[:validation.LabelValueMaxLength], "._-")
+}
+
+func validateJobLabels(job prowconfig.JobBase) []error {
+	var errs []error
+	for _, key := range sets.StringKeySet(job.Labels).List() {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Errorf("job %s has an invalid label key %s: %s", job.Name, key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(job.Labels[key]) {
+			errs = append(errs, fmt.Errorf("job %s has an invalid value %q for label %s: %s", job.Name, job.Labels[key], key, msg))
+		}
+	}
+	return errs
+}
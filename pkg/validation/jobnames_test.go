@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestJobNames(t *testing.T) {
+	presubmits := func(metadata api.Metadata, tests ...string) *prowconfig.JobConfig {
+		var jobs []prowconfig.Presubmit
+		for _, test := range tests {
+			jobs = append(jobs, prowconfig.Presubmit{
+				JobBase:  prowconfig.JobBase{Name: metadata.JobName("pull", test)},
+				Reporter: prowconfig.Reporter{Context: "ci/prow/" + metadata.TestName(test)},
+			})
+		}
+		orgRepo := metadata.Org + "/" + metadata.Repo
+		return &prowconfig.JobConfig{PresubmitsStatic: map[string][]prowconfig.Presubmit{orgRepo: jobs}}
+	}
+	type config struct {
+		metadata api.Metadata
+		tests    []string
+	}
+	var testCases = []struct {
+		name     string
+		configs  []config
+		expected []string
+	}{
+		{
+			name: "distinct names",
+			configs: []config{
+				{metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, tests: []string{"unit", "e2e"}},
+				{metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master", Variant: "v2"}, tests: []string{"unit", "e2e"}},
+				{metadata: api.Metadata{Org: "org", Repo: "other", Branch: "master"}, tests: []string{"unit"}},
+			},
+		},
+		{
+			name: "org and repo that join to the same name",
+			configs: []config{
+				{metadata: api.Metadata{Org: "org", Repo: "team-repo", Branch: "master"}, tests: []string{"unit"}},
+				{metadata: api.Metadata{Org: "org-team", Repo: "repo", Branch: "master"}, tests: []string{"unit"}},
+			},
+			expected: []string{
+				"job pull-ci-org-team-repo-master-unit is generated by more than one configuration: org-team/repo/org-team-repo-master.yaml, org/team-repo/org-team-repo-master.yaml; rename a test or variant in one of them",
+			},
+		},
+		{
+			name: "variant and test that join to the same context",
+			configs: []config{
+				{metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master", Variant: "aws"}, tests: []string{"upgrade-e2e"}},
+				{metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master", Variant: "aws-upgrade"}, tests: []string{"e2e"}},
+			},
+			expected: []string{
+				"job pull-ci-org-repo-master-aws-upgrade-e2e is generated by more than one configuration: org/repo/org-repo-master__aws-upgrade.yaml, org/repo/org-repo-master__aws.yaml; rename a test or variant in one of them",
+				"presubmits for org/repo@master generated by more than one configuration report the context ci/prow/aws-upgrade-e2e: org/repo/org-repo-master__aws-upgrade.yaml, org/repo/org-repo-master__aws.yaml; rename a test or variant in one of them",
+			},
+		},
+		{
+			name: "long names that are the same once truncated",
+			configs: []config{
+				{metadata: api.Metadata{Org: "openshift", Repo: "cluster-network-operator", Branch: "release-4.10"}, tests: []string{"e2e-aws-ovn-upgrade", "e2e-aws-ovn-upgrade-local-gateway"}},
+			},
+			expected: []string{
+				"jobs pull-ci-openshift-cluster-network-operator-release-4.10-e2e-aws-ovn-upgrade, pull-ci-openshift-cluster-network-operator-release-4.10-e2e-aws-ovn-upgrade-local-gateway are all labeled prow.k8s.io/job=pull-ci-openshift-cluster-network-operator-release-4.10-e2e-aws because Prow truncates label values to 63 characters; rename their tests or variants so that the job names differ within the first 63 characters",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jobNames := NewJobNames()
+			for _, config := range tc.configs {
+				if errs := jobNames.Add(config.metadata, presubmits(config.metadata, config.tests...)); len(errs) != 0 {
+					t.Fatalf("unexpected errors adding jobs: %v", errs)
+				}
+			}
+			var actual []string
+			for _, err := range jobNames.Validate() {
+				actual = append(actual, err.Error())
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("errors differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestJobNamesAddValidatesLabels(t *testing.T) {
+	metadata := api.Metadata{Org: "org", Repo: "repo", Branch: "master", Variant: "a-variant-that-is-much-too-long-to-be-used-as-the-value-of-a-label"}
+	jobs := &prowconfig.JobConfig{Periodics: []prowconfig.Periodic{{JobBase: prowconfig.JobBase{
+		Name:   metadata.JobName("periodic", "e2e"),
+		Labels: map[string]string{"ci-operator.openshift.io/variant": metadata.Variant, "team": "storage"},
+	}}}}
+	errs := NewJobNames().Add(metadata, jobs)
+	testhelper.Diff(t, "errors", errs, []error{
+		errors.New("job periodic-ci-org-repo-master-a-variant-that-is-much-too-long-to-be-used-as-the-value-of-a-label-e2e has an invalid value \"a-variant-that-is-much-too-long-to-be-used-as-the-value-of-a-label\" for label ci-operator.openshift.io/variant: must be no more than 63 characters"),
+	}, testhelper.EquateErrorMessage)
+}
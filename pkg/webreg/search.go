@@ -0,0 +1,476 @@
+package webreg
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	// RegistrySearchQuery is the query parameter holding the terms for a
+	// full-text registry search
+	RegistrySearchQuery = "q"
+
+	referenceType         = "reference"
+	chainType             = "chain"
+	workflowComponentType = "workflow"
+
+	// snippetContext is the number of characters shown on either side of
+	// the first match in a snippet
+	snippetContext = 80
+	// maxSearchResults caps the number of results rendered on a page
+	maxSearchResults = 100
+)
+
+// searchField identifies the part of a registry component a term was found in
+type searchField string
+
+const (
+	fieldName          searchField = "name"
+	fieldDocumentation searchField = "documentation"
+	fieldCommands      searchField = "commands"
+	fieldEnvironment   searchField = "environment"
+	fieldImage         searchField = "image"
+	fieldCredentials   searchField = "credentials"
+	fieldSteps         searchField = "steps"
+)
+
+// fieldWeights determine how much a match in a field contributes to the score
+// of a document: matches in names and explicitly declared parameters are more
+// significant than a term appearing somewhere in a script
+var fieldWeights = map[searchField]float64{
+	fieldName:          10,
+	fieldEnvironment:   5,
+	fieldImage:         4,
+	fieldCredentials:   4,
+	fieldDocumentation: 3,
+	fieldSteps:         2,
+	fieldCommands:      1,
+}
+
+// fieldOrder is the order in which fields are considered for snippets
+var fieldOrder = []searchField{fieldName, fieldDocumentation, fieldEnvironment, fieldImage, fieldCredentials, fieldSteps, fieldCommands}
+
+// searchDocument is a single indexed registry component
+type searchDocument struct {
+	Name   string
+	Type   string
+	fields map[searchField]string
+	// terms holds the term frequency for each field
+	terms map[searchField]map[string]int
+}
+
+// searchResult is a ranked match for a query
+type searchResult struct {
+	Name    string
+	Type    string
+	Score   float64
+	Fields  []string
+	Snippet template.HTML
+}
+
+// registryIndex is a full-text index over the step registry which is rebuilt
+// whenever the generation of the registry agent changes
+type registryIndex struct {
+	agent agents.RegistryAgent
+
+	lock       sync.RWMutex
+	generation int
+	documents  []*searchDocument
+	// postings maps a term to the indices of documents containing it
+	postings map[string][]int
+}
+
+func newRegistryIndex(agent agents.RegistryAgent) *registryIndex {
+	return &registryIndex{agent: agent, generation: -1}
+}
+
+// tokenize splits text into lowercase terms. Underscores and dashes are kept
+// as part of terms so that environment variables and component names can be
+// searched for as a whole, but their parts are emitted as terms as well.
+func tokenize(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+	}) {
+		word = strings.Trim(word, "_-")
+		if word == "" {
+			continue
+		}
+		terms = append(terms, word)
+		if parts := strings.FieldsFunc(word, func(r rune) bool { return r == '_' || r == '-' }); len(parts) > 1 {
+			terms = append(terms, parts...)
+		}
+	}
+	return terms
+}
+
+func newSearchDocument(name, componentType string, fields map[searchField]string) *searchDocument {
+	doc := &searchDocument{Name: name, Type: componentType, fields: fields, terms: map[searchField]map[string]int{}}
+	for field, text := range fields {
+		if text == "" {
+			continue
+		}
+		counts := map[string]int{}
+		for _, term := range tokenize(text) {
+			counts[term]++
+		}
+		doc.terms[field] = counts
+	}
+	return doc
+}
+
+func environmentText(params []api.StepParameter) string {
+	var lines []string
+	for _, param := range params {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("%s %s", param.Name, param.Documentation)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func stepsText(stepLists ...[]api.TestStep) string {
+	var names []string
+	for _, steps := range stepLists {
+		for _, step := range steps {
+			switch {
+			case step.Reference != nil:
+				names = append(names, *step.Reference)
+			case step.Chain != nil:
+				names = append(names, *step.Chain)
+			case step.LiteralTestStep != nil:
+				names = append(names, step.LiteralTestStep.As)
+			}
+		}
+	}
+	return strings.Join(names, "\n")
+}
+
+func referenceDocument(name string, ref api.LiteralTestStep, documentation string) *searchDocument {
+	var images []string
+	if ref.From != "" {
+		images = append(images, ref.From)
+	}
+	if ref.FromImage != nil {
+		images = append(images, ref.FromImage.ISTagName())
+	}
+	var credentials []string
+	for _, credential := range ref.Credentials {
+		credentials = append(credentials, fmt.Sprintf("%s/%s", credential.Namespace, credential.Name))
+	}
+	return newSearchDocument(name, referenceType, map[searchField]string{
+		fieldName:          name,
+		fieldDocumentation: documentation,
+		fieldCommands:      ref.Commands,
+		fieldEnvironment:   environmentText(ref.Environment),
+		fieldImage:         strings.Join(images, "\n"),
+		fieldCredentials:   strings.Join(credentials, "\n"),
+	})
+}
+
+func chainDocument(name string, chain api.RegistryChain, documentation string) *searchDocument {
+	return newSearchDocument(name, chainType, map[searchField]string{
+		fieldName:          name,
+		fieldDocumentation: documentation,
+		fieldEnvironment:   environmentText(chain.Environment),
+		fieldSteps:         stepsText(chain.Steps),
+	})
+}
+
+func workflowDocument(name string, workflow api.MultiStageTestConfiguration, documentation string) *searchDocument {
+	var env []string
+	for key, value := range workflow.Environment {
+		env = append(env, fmt.Sprintf("%s %s", key, value))
+	}
+	sort.Strings(env)
+	if workflow.ClusterProfile != "" {
+		env = append(env, string(workflow.ClusterProfile))
+	}
+	return newSearchDocument(name, workflowComponentType, map[searchField]string{
+		fieldName:          name,
+		fieldDocumentation: documentation,
+		fieldEnvironment:   strings.Join(env, "\n"),
+		fieldSteps:         stepsText(workflow.Pre, workflow.Test, workflow.Post),
+	})
+}
+
+// buildDocuments creates the search documents for all registry components
+func buildDocuments(refs registry.ReferenceByName, chains registry.ChainByName, workflows registry.WorkflowByName, docs map[string]string) []*searchDocument {
+	var documents []*searchDocument
+	for name, ref := range refs {
+		documents = append(documents, referenceDocument(name, ref, docs[name]))
+	}
+	for name, chain := range chains {
+		documents = append(documents, chainDocument(name, chain, docs[name]))
+	}
+	for name, workflow := range workflows {
+		documents = append(documents, workflowDocument(name, workflow, docs[name]))
+	}
+	// keep the order stable so that equally scored results are deterministic
+	sort.Slice(documents, func(i, j int) bool {
+		if documents[i].Type != documents[j].Type {
+			return documents[i].Type < documents[j].Type
+		}
+		return documents[i].Name < documents[j].Name
+	})
+	return documents
+}
+
+func buildPostings(documents []*searchDocument) map[string][]int {
+	postings := map[string][]int{}
+	for i, doc := range documents {
+		seen := map[string]bool{}
+		for _, counts := range doc.terms {
+			for term := range counts {
+				if seen[term] {
+					continue
+				}
+				seen[term] = true
+				postings[term] = append(postings[term], i)
+			}
+		}
+	}
+	return postings
+}
+
+// refresh rebuilds the index if the registry has been reloaded since it was last built
+func (idx *registryIndex) refresh() {
+	generation := idx.agent.GetGeneration()
+	idx.lock.RLock()
+	current := idx.generation == generation
+	idx.lock.RUnlock()
+	if current {
+		return
+	}
+
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if idx.generation == generation {
+		return
+	}
+	start := time.Now()
+	refs, chains, workflows, docs, _ := idx.agent.GetRegistryComponents()
+	idx.documents = buildDocuments(refs, chains, workflows, docs)
+	idx.postings = buildPostings(idx.documents)
+	idx.generation = generation
+	logrus.WithField("generation", generation).Infof("Rebuilt registry search index with %d components in %s", len(idx.documents), time.Since(start))
+}
+
+// Search returns the components matching all terms in the query, ordered by
+// descending score
+func (idx *registryIndex) Search(query string) []searchResult {
+	idx.refresh()
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return search(idx.documents, idx.postings, query)
+}
+
+func search(documents []*searchDocument, postings map[string][]int, query string) []searchResult {
+	terms := uniqueTerms(tokenize(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	// documents must contain every term of the query
+	var candidates map[int]bool
+	for _, term := range terms {
+		matching := map[int]bool{}
+		for _, i := range postings[term] {
+			if candidates == nil || candidates[i] {
+				matching[i] = true
+			}
+		}
+		candidates = matching
+		if len(candidates) == 0 {
+			return nil
+		}
+	}
+
+	var results []searchResult
+	for i := range candidates {
+		doc := documents[i]
+		result := searchResult{Name: doc.Name, Type: doc.Type}
+		for _, field := range fieldOrder {
+			counts, ok := doc.terms[field]
+			if !ok {
+				continue
+			}
+			var matched bool
+			for _, term := range terms {
+				if count := counts[term]; count > 0 {
+					matched = true
+					// dampen repeated occurrences so a long script does not
+					// outrank a precise name match
+					result.Score += fieldWeights[field] * (1 + float64(count-1)*0.1)
+				}
+			}
+			if matched {
+				result.Fields = append(result.Fields, string(field))
+				if result.Snippet == "" && field != fieldName {
+					result.Snippet = snippet(doc.fields[field], terms)
+				}
+			}
+		}
+		if strings.Contains(doc.Name, strings.ToLower(strings.TrimSpace(query))) {
+			result.Score += fieldWeights[fieldName]
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Type != results[j].Type {
+			return results[i].Type < results[j].Type
+		}
+		return results[i].Name < results[j].Name
+	})
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+	return results
+}
+
+// uniqueTerms de-duplicates terms while keeping their order
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// snippet returns the text surrounding the first occurrence of any of the
+// terms, with the occurrences highlighted
+func snippet(text string, terms []string) template.HTML {
+	lower := strings.ToLower(text)
+	first := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i != -1 && (first == -1 || i < first) {
+			first = i
+		}
+	}
+	if first == -1 {
+		return ""
+	}
+	start, end := first-snippetContext, first+snippetContext
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	// do not cut multi-byte runes in half
+	for start > 0 && !isRuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !isRuneStart(text[end]) {
+		end++
+	}
+	excerpt := text[start:end]
+	lowerExcerpt := strings.ToLower(excerpt)
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for i := 0; i < len(excerpt); {
+		matched := ""
+		for _, term := range terms {
+			if strings.HasPrefix(lowerExcerpt[i:], term) && len(term) > len(matched) {
+				matched = term
+			}
+		}
+		if matched == "" {
+			next := i + 1
+			for next < len(excerpt) && !isRuneStart(excerpt[next]) {
+				next++
+			}
+			b.WriteString(template.HTMLEscapeString(excerpt[i:next]))
+			i = next
+			continue
+		}
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(excerpt[i : i+len(matched)]))
+		b.WriteString("</mark>")
+		i += len(matched)
+	}
+	b.WriteString(suffix)
+	return template.HTML(b.String())
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+const registrySearchPage = `
+<h2 id="search"><a href="#search">Registry Search</a></h2>
+<form class="form-inline my-2" role="search" action="/registry-search" method="get">
+  <input class="form-control mr-sm-2" type="search" placeholder="Steps, chains, workflows" aria-label="Search" name="q" value="{{ .Query }}">
+  <button class="btn btn-outline-success my-2 my-sm-0" type="submit">Search Registry</button>
+</form>
+{{ if .Query }}
+<p>{{ len .Results }} result(s) for <span style="font-family:monospace">{{ .Query }}</span></p>
+<table class="table">
+	<thead>
+		<tr>
+			<th title="The type of the registry component" class="info">Type</th>
+			<th title="The name of the registry component" class="info">Name</th>
+			<th title="Where the search terms were found" class="info">Matched In</th>
+			<th title="An excerpt of the matching content" class="info">Excerpt</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{ range .Results }}
+			<tr>
+				<td>{{ .Type }}</td>
+				<td>{{ template "nameWithLink" . }}</td>
+				<td>{{ range $i, $field := .Fields }}{{ if $i }}, {{ end }}{{ $field }}{{ end }}</td>
+				<td><pre style="white-space:pre-wrap;font-size:small">{{ .Snippet }}</pre></td>
+			</tr>
+		{{ end }}
+	</tbody>
+</table>
+{{ end }}
+`
+
+func registrySearchHandler(index *registryIndex, w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	defer func() { logrus.Infof("rendered in %s", time.Since(start)) }()
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	query := req.URL.Query().Get(RegistrySearchQuery)
+	page, err := baseTemplate.Clone()
+	if err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+	page = setDeprecations(page, index.agent.GetDeprecations())
+	if page, err = page.Parse(registrySearchPage); err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		Query   string
+		Results []searchResult
+	}{
+		Query: query,
+	}
+	if query != "" {
+		data.Results = index.Search(query)
+	}
+	writePage(w, "Registry Search Page", page, data)
+}
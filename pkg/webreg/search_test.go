@@ -0,0 +1,130 @@
+package webreg
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+type fakeRegistryAgent struct {
+	agents.RegistryAgent
+	generation int
+	refs       registry.ReferenceByName
+	chains     registry.ChainByName
+	workflows  registry.WorkflowByName
+	docs       map[string]string
+}
+
+func (a *fakeRegistryAgent) GetGeneration() int { return a.generation }
+
+func (a *fakeRegistryAgent) GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata) {
+	return a.refs, a.chains, a.workflows, a.docs, nil
+}
+
+func TestTokenize(t *testing.T) {
+	expected := []string{"export", "cluster_profile_dir", "cluster", "profile", "dir", "ipi-install", "ipi", "install", "bastion"}
+	if diff := cmp.Diff(expected, tokenize(`export ${CLUSTER_PROFILE_DIR} "ipi-install" -- Bastion!`)); diff != "" {
+		t.Errorf("unexpected terms: %s", diff)
+	}
+}
+
+func TestRegistryIndexSearch(t *testing.T) {
+	reference := "ipi-conf-bastion"
+	agent := &fakeRegistryAgent{
+		generation: 1,
+		refs: registry.ReferenceByName{
+			"ipi-conf-bastion": {
+				As:          "ipi-conf-bastion",
+				From:        "cli",
+				Commands:    "#!/bin/bash\necho 'creating the bastion host'\n",
+				Environment: []api.StepParameter{{Name: "BASTION_IMAGE", Documentation: "The image for the proxy."}},
+				Credentials: []api.CredentialReference{{Namespace: "test-credentials", Name: "aws-secret"}},
+			},
+			"ipi-install": {
+				As:        "ipi-install",
+				FromImage: &api.ImageStreamTagReference{Namespace: "ocp", Name: "4.10", Tag: "installer"},
+				Commands:  "openshift-install create cluster",
+			},
+		},
+		chains: registry.ChainByName{
+			"ipi-aws-pre": {As: "ipi-aws-pre", Steps: []api.TestStep{{Reference: &reference}}},
+		},
+		workflows: registry.WorkflowByName{
+			"ipi-azure": {ClusterProfile: api.ClusterProfileAzure4},
+		},
+		docs: map[string]string{
+			"ipi-conf-bastion": "Sets up a <b>bastion</b> host.",
+			"ipi-aws-pre":      "The pre chain for AWS.",
+		},
+	}
+	index := newRegistryIndex(agent)
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []searchResult
+	}{
+		{
+			name:  "documentation, commands and parameters are searched",
+			query: "bastion",
+			expected: []searchResult{
+				{Name: "ipi-conf-bastion", Type: referenceType, Score: 29, Fields: []string{"name", "documentation", "environment", "commands"}, Snippet: template.HTML("Sets up a &lt;b&gt;<mark>bastion</mark>&lt;/b&gt; host.")},
+				{Name: "ipi-aws-pre", Type: chainType, Score: 2, Fields: []string{"steps"}, Snippet: template.HTML("ipi-conf-<mark>bastion</mark>")},
+			},
+		},
+		{
+			name:  "all terms must match",
+			query: "bastion aws",
+			expected: []searchResult{
+				{Name: "ipi-conf-bastion", Type: referenceType, Score: 23, Fields: []string{"name", "documentation", "environment", "credentials", "commands"}, Snippet: template.HTML("Sets up a &lt;b&gt;<mark>bastion</mark>&lt;/b&gt; host.")},
+				{Name: "ipi-aws-pre", Type: chainType, Score: 15, Fields: []string{"name", "documentation", "steps"}, Snippet: template.HTML("The pre chain for <mark>AWS</mark>.")},
+			},
+		},
+		{
+			name:  "images are searched",
+			query: "installer",
+			expected: []searchResult{
+				{Name: "ipi-install", Type: referenceType, Score: 4, Fields: []string{"image"}, Snippet: template.HTML("ocp/4.10:<mark>installer</mark>")},
+			},
+		},
+		{
+			name:  "credentials are searched",
+			query: "test-credentials",
+			expected: []searchResult{
+				{Name: "ipi-conf-bastion", Type: referenceType, Score: 12, Fields: []string{"credentials"}, Snippet: template.HTML("<mark>test-credentials</mark>/aws-secret")},
+			},
+		},
+		{
+			name:  "workflow cluster profiles are searched",
+			query: "azure4",
+			expected: []searchResult{
+				{Name: "ipi-azure", Type: workflowComponentType, Score: 5, Fields: []string{"environment"}, Snippet: template.HTML("<mark>azure4</mark>")},
+			},
+		},
+		{
+			name:  "no match",
+			query: "gcp",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, index.Search(tc.query)); diff != "" {
+				t.Errorf("unexpected results: %s", diff)
+			}
+		})
+	}
+
+	agent.refs["gcp-step"] = api.LiteralTestStep{As: "gcp-step"}
+	if results := index.Search("gcp"); len(results) != 0 {
+		t.Errorf("expected index not to be rebuilt for the same generation, got %v", results)
+	}
+	agent.generation++
+	if results := index.Search("gcp"); len(results) != 1 {
+		t.Errorf("expected index to be rebuilt for a new generation, got %v", results)
+	}
+}
@@ -69,6 +69,9 @@ const bodyStart = `
       <li class="nav-item">
         <a class="nav-link" href="/search">Jobs</a>
       </li>
+      <li class="nav-item">
+        <a class="nav-link" href="/registry-search">Search Registry</a>
+      </li>
       <li class="nav-item">
         <a class="nav-link" href="http://docs.ci.openshift.org">Help</a>
       </li>
@@ -101,6 +104,7 @@ const mainPage = `
 
 const referencePage = `
 <h2 id="title"><a href="#title">Step:</a> <nobr style="font-family:monospace">{{ .Reference.As }}</nobr></h2>
+{{ template "deprecationNotice" (deprecationFor "reference" .Reference.As) }}
 <p id="documentation">{{ .Reference.Documentation }}</p>
 <h3 id="image"><a href="#image">Container image used for this step:</a> <span style="font-family:monospace">{{ fromImage .Reference.From .Reference.FromImage }}</span></h3>
 <p id="image">{{ fromImageDescription .Reference.From .Reference.FromImage }}<d/p>
@@ -116,6 +120,7 @@ const referencePage = `
 
 const chainPage = `
 <h2 id="title"><a href="#title">Chain:</a> <nobr style="font-family:monospace">{{ .Chain.As }}</nobr></h2>
+{{ template "deprecationNotice" (deprecationFor "chain" .Chain.As) }}
 <p id="documentation">{{ .Chain.Documentation }}</p>
 <h3 id="steps" title="Step run by the chain, in runtime order"><a href="#steps">Steps</a></h3>
 {{ template "stepTable" .Chain.Steps}}
@@ -134,6 +139,14 @@ const chainPage = `
 const workflowJobPage = `
 {{ $type := .Workflow.Type }}
 <h2 id="title"><a href="#title">{{ $type }}:</a> <nobr style="font-family:monospace">{{ .Workflow.As }}</nobr></h2>
+{{ if eq $type "Workflow" }}
+	{{ template "deprecationNotice" (deprecationFor "workflow" .Workflow.As) }}
+{{ else if .Deprecations }}
+	<div class="alert alert-warning" id="deprecation" role="alert">
+		<b>This job uses deprecated registry components:</b>
+		<ul>{{ range .Deprecations }}<li>{{ . }}</li>{{ end }}</ul>
+	</div>
+{{ end }}
 {{ if .Workflow.Documentation }}
 	<p id="documentation">{{ .Workflow.Documentation }}</p>
 {{ end }}
@@ -165,19 +178,33 @@ const jobSearchPage = `
 
 const templateDefinitions = `
 {{ define "nameWithLink" }}
-	<nobr><a href="/{{ .Type }}/{{ .Name }}" style="font-family:monospace">{{ .Name }}</a></nobr>
+	<nobr><a href="/{{ .Type }}/{{ .Name }}" style="font-family:monospace">{{ .Name }}</a>{{ template "deprecationBadge" (deprecationFor .Type .Name) }}</nobr>
 {{ end }}
 
 {{ define "nameWithLinkReference" }}
-	<nobr><a href="/reference/{{ . }}" style="font-family:monospace">{{ . }}</a></nobr>
+	<nobr><a href="/reference/{{ . }}" style="font-family:monospace">{{ . }}</a>{{ template "deprecationBadge" (deprecationFor "reference" .) }}</nobr>
 {{ end }}
 
 {{ define "nameWithLinkChain" }}
-	<nobr><a href="/chain/{{ . }}" style="font-family:monospace">{{ . }}</a></nobr>
+	<nobr><a href="/chain/{{ . }}" style="font-family:monospace">{{ . }}</a>{{ template "deprecationBadge" (deprecationFor "chain" .) }}</nobr>
 {{ end }}
 
 {{ define "nameWithLinkWorkflow" }}
-	<nobr><a href="/workflow/{{ . }}" style="font-family:monospace">{{ . }}</a></nobr>
+	<nobr><a href="/workflow/{{ . }}" style="font-family:monospace">{{ . }}</a>{{ template "deprecationBadge" (deprecationFor "workflow" .) }}</nobr>
+{{ end }}
+
+{{ define "deprecationBadge" }}
+	{{- with . }} <span class="badge badge-warning" title="Deprecated: {{ .Reason }}">deprecated</span>{{ end -}}
+{{ end }}
+
+{{ define "deprecationNotice" }}
+	{{ with . }}
+	<div class="alert alert-warning" id="deprecation" role="alert">
+		<b>This {{ .Type }} is deprecated:</b> {{ .Reason }}
+		{{ if .Replacement }}<br>Use <a href="/{{ .Type }}/{{ .Replacement }}" style="font-family:monospace">{{ .Replacement }}</a> instead.{{ end }}
+		{{ if .Deadline }}<br>It will be removed from the registry after {{ .Deadline }}.{{ end }}
+	</div>
+	{{ end }}
 {{ end }}
 
 {{ define "referenceProperties" }}
@@ -661,6 +688,7 @@ func getBaseTemplate() (*template.Template, error) {
 			"workflowGraph":   func(_, _ string) string { return "" },
 			"chainGraph":      func(string) string { return "" },
 			"getDependencies": func(string) dependencyData { return dependencyData{} },
+			"deprecationFor":  func(_, _ string) *deprecationData { return nil },
 			"getEnvironment": func(string) environmentData {
 				return environmentData{}
 			},
@@ -784,6 +812,30 @@ func setDocs(t *template.Template, docs map[string]string) *template.Template {
 		})
 }
 
+// deprecationData is the deprecation of a registry component of the given type
+type deprecationData struct {
+	Type string
+	api.RegistryDeprecation
+}
+
+func setDeprecations(t *template.Template, deprecations registry.Deprecations) *template.Template {
+	byType := map[string]registry.DeprecationByName{
+		"reference": deprecations.References,
+		"chain":     deprecations.Chains,
+		"workflow":  deprecations.Workflows,
+	}
+	return t.Funcs(
+		template.FuncMap{
+			"deprecationFor": func(componentType, name string) *deprecationData {
+				deprecation, ok := byType[componentType][name]
+				if !ok {
+					return nil
+				}
+				return &deprecationData{Type: componentType, RegistryDeprecation: deprecation}
+			},
+		})
+}
+
 func setWorkflowGraph(t *template.Template, chains registry.ChainByName, workflows registry.WorkflowByName) *template.Template {
 	return t.Funcs(
 		template.FuncMap{
@@ -1045,6 +1097,7 @@ func mainPageHandler(agent agents.RegistryAgent, templateString string, w http.R
 		return
 	}
 	page = setDocs(page, docs)
+	page = setDeprecations(page, agent.GetDeprecations())
 	page = setWorkflowGraph(page, chains, workflows)
 	page = setChainGraph(page, chains)
 	if page, err = page.Parse(templateString); err != nil {
@@ -1064,6 +1117,7 @@ func mainPageHandler(agent agents.RegistryAgent, templateString string, w http.R
 }
 
 func WebRegHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent) http.HandlerFunc {
+	index := newRegistryIndex(regAgent)
 	return func(w http.ResponseWriter, req *http.Request) {
 		trimmedPath := strings.TrimPrefix(req.URL.Path, req.URL.Host)
 		// remove leading slash
@@ -1077,6 +1131,8 @@ func WebRegHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent)
 				mainPageHandler(regAgent, mainPage, w, req)
 			case "search":
 				searchHandler(confAgent, w, req)
+			case "registry-search":
+				registrySearchHandler(index, w, req)
 			case "job":
 				jobHandler(regAgent, confAgent, w, req)
 			case "ci-operator-reference":
@@ -1267,6 +1323,7 @@ func referenceHandler(agent agents.RegistryAgent, w http.ResponseWriter, req *ht
 		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
 		return
 	}
+	page = setDeprecations(page, agent.GetDeprecations())
 	page, err = page.Funcs(
 		template.FuncMap{
 			"syntaxedSource": func(source string) template.HTML {
@@ -1337,6 +1394,7 @@ func chainHandler(agent agents.RegistryAgent, w http.ResponseWriter, req *http.R
 		return
 	}
 	page = setDocs(page, docs)
+	page = setDeprecations(page, agent.GetDeprecations())
 	page = setChainGraph(page, chains)
 	page = setChainDependencies(page, refs, chains)
 	page = setChainEnvironment(page, refs, chains)
@@ -1381,6 +1439,7 @@ func workflowHandler(agent agents.RegistryAgent, w http.ResponseWriter, req *htt
 	}
 
 	page = setDocs(page, docs)
+	page = setDeprecations(page, agent.GetDeprecations())
 	page = setWorkflowGraph(page, chains, workflows)
 	page = setChainGraph(page, chains)
 	page = setWorkflowDependencies(page, refs, chains, workflows)
@@ -1400,8 +1459,9 @@ func workflowHandler(agent agents.RegistryAgent, w http.ResponseWriter, req *htt
 		return
 	}
 	workflow := struct {
-		Workflow workflowJob
-		Metadata api.RegistryInfo
+		Workflow     workflowJob
+		Metadata     api.RegistryInfo
+		Deprecations []string
 	}{
 		Workflow: workflowJob{
 			RegistryWorkflow: api.RegistryWorkflow{
@@ -1465,7 +1525,9 @@ func jobHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent, w h
 		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
 		return
 	}
+	deprecations := regAgent.GetDeprecations()
 	page = setDocs(page, docs)
+	page = setDeprecations(page, deprecations)
 	page = setWorkflowGraph(page, chains, workflows)
 	page = setChainGraph(page, chains)
 
@@ -1473,12 +1535,18 @@ func jobHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent, w h
 		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
 		return
 	}
+	var deprecated []string
+	for _, component := range registry.DeprecatedComponents(config, chains, workflows, deprecations) {
+		deprecated = append(deprecated, component.String())
+	}
 	workflow := struct {
-		Workflow workflowJob
-		Metadata api.RegistryInfo
+		Workflow     workflowJob
+		Metadata     api.RegistryInfo
+		Deprecations []string
 	}{
-		Workflow: jobWorkflow,
-		Metadata: api.RegistryInfo{},
+		Workflow:     jobWorkflow,
+		Metadata:     api.RegistryInfo{},
+		Deprecations: deprecated,
 	}
 	writePage(w, "Job Test Workflow Help Page", page, workflow)
 }
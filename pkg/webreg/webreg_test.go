@@ -2,6 +2,7 @@ package webreg
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -314,3 +315,30 @@ func TestGetEnvironmentDataItems(t *testing.T) {
 		})
 	}
 }
+
+func TestDeprecationBadges(t *testing.T) {
+	base, err := getBaseTemplate()
+	if err != nil {
+		t.Fatalf("failed to parse base template: %v", err)
+	}
+	page, err := setDeprecations(base, registry.Deprecations{
+		Chains: registry.DeprecationByName{"old-chain": {Reason: "unmaintained", Replacement: "chain"}},
+	}).Parse(`{{ template "nameWithLinkChain" "old-chain" }}|{{ template "nameWithLinkReference" "old-chain" }}|{{ template "deprecationNotice" (deprecationFor "chain" "old-chain") }}`)
+	if err != nil {
+		t.Fatalf("failed to parse page: %v", err)
+	}
+	var out strings.Builder
+	if err := page.Execute(&out, nil); err != nil {
+		t.Fatalf("failed to render page: %v", err)
+	}
+	parts := strings.Split(out.String(), "|")
+	if !strings.Contains(parts[0], `<span class="badge badge-warning" title="Deprecated: unmaintained">deprecated</span>`) {
+		t.Errorf("expected the deprecated chain to be badged, got %q", parts[0])
+	}
+	if strings.Contains(parts[1], "badge") {
+		t.Errorf("expected the reference with the same name not to be badged, got %q", parts[1])
+	}
+	if !strings.Contains(parts[2], `Use <a href="/chain/chain" style="font-family:monospace">chain</a> instead.`) {
+		t.Errorf("expected the notice to link the replacement, got %q", parts[2])
+	}
+}
@@ -472,6 +472,9 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"            dependency_overrides:\n" +
 	"                \"\": \"\"\n" +
+	"            # Deprecations are warnings about deprecated registry components used by the test.\n" +
+	"            deprecations:\n" +
+	"                - \"\"\n" +
 	"            # DnsConfig for step's Pod.\n" +
 	"            dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -502,6 +505,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    name: ' '\n" +
 	"                    namespace: ' '\n" +
 	"                    tag: ' '\n" +
+	"                  # GracePeriod is how long we will wait after sending SIGINT to send\n" +
+	"                  # SIGKILL when the observer is stopped, which is the time the observer is\n" +
+	"                  # guaranteed to have to flush its artifacts.\n" +
+	"                  grace_period: 0s\n" +
 	"                  # Name is the name of this observer\n" +
 	"                  name: ' '\n" +
 	"                  # Resources defines the resource requirements for the step.\n" +
@@ -1243,6 +1250,9 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"        dependency_overrides:\n" +
 	"            \"\": \"\"\n" +
+	"        # Deprecations are warnings about deprecated registry components used by the test.\n" +
+	"        deprecations:\n" +
+	"            - \"\"\n" +
 	"        # DnsConfig for step's Pod.\n" +
 	"        dnsConfig:\n" +
 	"            # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1273,6 +1283,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                name: ' '\n" +
 	"                namespace: ' '\n" +
 	"                tag: ' '\n" +
+	"              # GracePeriod is how long we will wait after sending SIGINT to send\n" +
+	"              # SIGKILL when the observer is stopped, which is the time the observer is\n" +
+	"              # guaranteed to have to flush its artifacts.\n" +
+	"              grace_period: 0s\n" +
 	"              # Name is the name of this observer\n" +
 	"              name: ' '\n" +
 	"              # Resources defines the resource requirements for the step.\n" +
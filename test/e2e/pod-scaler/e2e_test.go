@@ -148,7 +148,7 @@ func TestBuildPodAdmission(t *testing.T) {
 	}()
 	dataDir := T.TempDir()
 	for _, set := range []string{"pods", "prowjobs", "steps"} {
-		for _, metric := range []string{"container_memory_working_set_bytes", "container_cpu_usage_seconds_total"} {
+		for _, metric := range []string{"container_memory_working_set_bytes", "container_cpu_usage_seconds_total", "container_fs_usage_bytes"} {
 			if err := os.MkdirAll(filepath.Join(dataDir, set), 0777); err != nil {
 				t.Fatalf("could not seed data dir: %v", err)
 			}
@@ -212,6 +212,16 @@ func metricGenerators() []metricGenerator {
 				return *metric.Gauge.Value
 			},
 		},
+		{
+			metricName: "container_fs_usage_bytes",
+			metricType: prometheus_client.MetricType_GAUGE,
+			addValue: func(metric *prometheus_client.Metric, f, _ float64) {
+				metric.Gauge = &prometheus_client.Gauge{Value: pointer.Float64Ptr(f)}
+			},
+			getValue: func(metric *prometheus_client.Metric) float64 {
+				return *metric.Gauge.Value
+			},
+		},
 	}
 }
 